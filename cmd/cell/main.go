@@ -16,6 +16,7 @@ type Config struct {
 		Port int    `json:"port"`
 		Host string `json:"host"`
 	} `json:"server"`
+	Trainer *TrainerConfig `json:"trainer"` // 非空时作为数据并行训练的 cell 运行
 }
 
 // loadConfig 从指定的 JSON 文件加载配置，若加载失败则使用默认配置
@@ -73,6 +74,8 @@ func main() {
 
 	// 定义命令行参数
 	configPath := flag.String("conf", "configs/config.json", "配置文件路径")
+	organPath := flag.String("organ", "", "organ 配置文件路径，与 -stage 一起使用时作为流水线的一个阶段运行")
+	stage := flag.Int("stage", 0, "本 cell 负责的流水线阶段")
 	flag.Parse()
 
 	// 加载配置文件
@@ -82,6 +85,19 @@ func main() {
 		return
 	}
 
+	if *organPath != "" {
+		if err := runPipelineStage(*organPath, *stage, log); err != nil {
+			log.Error("流水线训练失败: ", err)
+		}
+		return
+	}
+	if config.Trainer != nil {
+		if err := runTrainer(config.Trainer, log); err != nil {
+			log.Error("训练失败: ", err)
+		}
+		return
+	}
+
 	// 输出加载的配置
 	log.Info(fmt.Sprintf("服务器启动在 %s:%d", config.Server.Host, config.Server.Port))
 
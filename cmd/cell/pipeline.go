@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/distributed"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/logger"
+)
+
+// PipelineStageConfig organ 配置中的一个流水线阶段
+type PipelineStageConfig struct {
+	Cell   string `json:"cell"`   // 运行该阶段的 cell 的配置文件，其 server 地址用于阶段间通信
+	Layers int    `json:"layers"` // 该阶段包含的连续子模块个数
+}
+
+// PipelineConfig organ 配置中的流水线并行配置，由 organsys 与各阶段的 cell 共同读取
+type PipelineConfig struct {
+	MicroBatches int                   `json:"micro_batches"` // 每个批次的微批次个数
+	Epochs       int                   `json:"epochs"`
+	BatchSize    int                   `json:"batch_size"`
+	LR           float64               `json:"lr"`
+	Seed         int64                 `json:"seed"`
+	Samples      int                   `json:"samples"`
+	Timeout      int                   `json:"timeout"` // 等待其他阶段启动的秒数，默认 30
+	Stages       []PipelineStageConfig `json:"stages"`
+}
+
+// 从 organ 配置文件读取流水线配置
+func loadPipelineConfig(path string) (*PipelineConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var organ struct {
+		Pipeline *PipelineConfig `json:"pipeline"`
+	}
+	if err := json.NewDecoder(f).Decode(&organ); err != nil {
+		return nil, err
+	}
+	if organ.Pipeline == nil || len(organ.Pipeline.Stages) == 0 {
+		return nil, fmt.Errorf("%s 中没有流水线配置", path)
+	}
+	return organ.Pipeline, nil
+}
+
+// 流水线演示使用的模型，共 5 个子模块
+var pipelineLayers = []func() dubtorch.Module{
+	func() dubtorch.Module { return dubtorch.NewLinear(2, 32, true) },
+	func() dubtorch.Module { return dubtorch.NewReLU() },
+	func() dubtorch.Module { return dubtorch.NewLinear(32, 32, true) },
+	func() dubtorch.Module { return dubtorch.NewReLU() },
+	func() dubtorch.Module { return dubtorch.NewLinear(32, 3, true) },
+}
+
+// 只构建模型的第 [start, end) 个子模块。第 i 个子模块以 seed+i 为种子初始化，
+// 因此各阶段拼接起来与 newPipelineModel(seed, 0, len(pipelineLayers)) 的初始参数一致
+func newPipelineModel(seed int64, start, end int) *dubtorch.Sequential {
+	model := dubtorch.NewSequential()
+	for i := start; i < end; i++ {
+		dubtorch.ManualSeed(seed + int64(i))
+		model.Append(pipelineLayers[i]())
+	}
+	return model
+}
+
+// runPipelineStage 作为流水线的第 stage 个阶段参与训练
+func runPipelineStage(organPath string, stage int, log *logger.Logger) error {
+	cfg, err := loadPipelineConfig(organPath)
+	if err != nil {
+		return err
+	}
+	if stage < 0 || stage >= len(cfg.Stages) {
+		return fmt.Errorf("阶段 %d 超出范围 [0, %d)", stage, len(cfg.Stages))
+	}
+	if cfg.Epochs <= 0 {
+		cfg.Epochs = 5
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 32
+	}
+	if cfg.LR <= 0 {
+		cfg.LR = 0.05
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 600
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30
+	}
+
+	// 阶段 i 由配置文件 Stages[i].Cell 描述的 cell 运行，通信地址取自其 server 配置
+	peers := make([]string, len(cfg.Stages))
+	start, total := 0, 0
+	for i, s := range cfg.Stages {
+		if s.Layers <= 0 {
+			return fmt.Errorf("阶段 %d 至少包含一个子模块", i)
+		}
+		if _, err := os.Stat(s.Cell); err != nil {
+			return fmt.Errorf("阶段 %d 的 cell 配置不可用: %v", i, err)
+		}
+		cellCfg, err := loadConfig(s.Cell, log)
+		if err != nil {
+			return err
+		}
+		peers[i] = fmt.Sprintf("%s:%d", cellCfg.Server.Host, cellCfg.Server.Port)
+		if i < stage {
+			start += s.Layers
+		}
+		total += s.Layers
+	}
+	if total != len(pipelineLayers) {
+		return fmt.Errorf("各阶段的子模块数之和 %d 与模型的 %d 不一致", total, len(pipelineLayers))
+	}
+	// 每个阶段只构建自己的子模块
+	module := newPipelineModel(cfg.Seed, start, start+cfg.Stages[stage].Layers)
+
+	ctx := context.Background()
+	connectCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	transport, err := distributed.NewTCPTransport(connectCtx, stage, peers)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer transport.Close()
+	var names []string
+	for _, p := range module.NamedParameters() {
+		names = append(names, p.Name)
+	}
+	log.Info(fmt.Sprintf("流水线阶段 %d/%d 已连接，参数 %v", stage, len(peers), names))
+
+	sgd, err := optim.NewSGD(module.NamedParameters(), optim.SGDOptions{LR: cfg.LR, Momentum: 0.9})
+	if err != nil {
+		return err
+	}
+	pipe, err := distributed.NewPipelineStage(transport, module, sgd, crossEntropy, distributed.PipelineOptions{MicroBatches: cfg.MicroBatches})
+	if err != nil {
+		return err
+	}
+	data, err := blobs(cfg.Seed, cfg.Samples)
+	if err != nil {
+		return err
+	}
+	// 各阶段使用相同的打乱种子，保证第一个阶段的输入与最后一个阶段的目标属于同一批样本
+	loader, err := dubtorch.NewDataLoader(data, dubtorch.DataLoaderOptions{BatchSize: cfg.BatchSize, Shuffle: true, Seed: cfg.Seed})
+	if err != nil {
+		return err
+	}
+	last := stage == len(peers)-1
+	for epoch := 1; epoch <= cfg.Epochs; epoch++ {
+		it := loader.Iter()
+		total, count := 0.0, 0
+		for {
+			b, err := it.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				it.Close()
+				return err
+			}
+			input, target := b[0], b[1]
+			if stage != 0 {
+				input = nil
+			}
+			if !last {
+				target = nil
+			}
+			loss, err := pipe.Step(ctx, input, target)
+			if err != nil {
+				it.Close()
+				return err
+			}
+			total += loss
+			count++
+		}
+		it.Close()
+		if last {
+			log.Info(fmt.Sprintf("流水线 epoch %d/%d loss=%.6f", epoch, cfg.Epochs, total/float64(count)))
+		}
+	}
+	log.Info(fmt.Sprintf("流水线阶段 %d 训练完成，参数校验和 %.12f", stage, checksum(module)))
+	return nil
+}
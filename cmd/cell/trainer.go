@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/distributed"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/logger"
+)
+
+// 分布式训练中 cell 的角色
+const (
+	RoleAllReduce = "allreduce" // 与其他 cell 组成环，用 all-reduce 同步梯度（默认）
+	RoleServer    = "server"    // 参数服务器，保存参数并应用梯度
+	RoleWorker    = "worker"    // 参数服务器模式下的工作者
+)
+
+// TrainerConfig 分布式训练的配置，所有 cell 除 Rank 与 Role 外应使用相同的配置
+type TrainerConfig struct {
+	Rank         int      `json:"rank"`          // 本 cell 的编号
+	Role         string   `json:"role"`          // 本 cell 的角色，为空时使用 allreduce
+	Peers        []string `json:"peers"`         // 所有 cell 用于梯度同步的 TCP 地址，按编号排列
+	ServerRank   int      `json:"server_rank"`   // 参数服务器的编号
+	MaxStaleness int      `json:"max_staleness"` // 参数服务器允许的最大梯度延迟，负数表示不限制
+	Epochs       int      `json:"epochs"`        // 训练轮数
+	BatchSize    int      `json:"batch_size"`    // 每个 cell 的批大小
+	LR           float64  `json:"lr"`            // 学习率
+	Seed         int64    `json:"seed"`          // 数据与初始化的随机种子
+	Samples      int      `json:"samples"`       // 合成数据集的样本总数
+	ChunkSize    int      `json:"chunk_size"`    // 每条梯度消息的元素个数，0 表示默认值
+	Timeout      int      `json:"timeout"`       // 等待其他 cell 启动的秒数，默认 30
+}
+
+// 生成三类二维高斯点，所有 cell 以相同种子生成同一份数据，再各自取自己的分片
+func blobs(seed int64, n int) (*dubtorch.TensorDataset, error) {
+	r := rand.New(rand.NewSource(seed))
+	centers := [][2]float64{{-2, 0}, {2, 0}, {0, 2.5}}
+	x, y := dubnp.Zeros(n, 2), dubnp.Zeros(n)
+	for i := 0; i < n; i++ {
+		c := r.Intn(len(centers))
+		x.Data[2*i] = centers[c][0] + r.NormFloat64()*0.7
+		x.Data[2*i+1] = centers[c][1] + r.NormFloat64()*0.7
+		y.Data[i] = float64(c)
+	}
+	return dubtorch.NewTensorDataset(x, y)
+}
+
+func crossEntropy(output, target *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	return dubtorch.CrossEntropyLoss(output, target, dubtorch.ClassLossOptions{})
+}
+
+// 构建模型；种子只影响各 cell 的初始值，训练开始前会统一为同一份参数
+func newModel(seed int64) *dubtorch.Sequential {
+	dubtorch.ManualSeed(seed)
+	return dubtorch.NewSequential(
+		dubtorch.NewLinear(2, 16, true),
+		dubtorch.NewReLU(),
+		dubtorch.NewLinear(16, 3, true),
+	)
+}
+
+// 参数的校验和，各副本的参数一致时校验和相同，便于在日志中比对
+func checksum(model dubtorch.Module) float64 {
+	sum := 0.0
+	for _, p := range model.Parameters() {
+		for _, v := range p.Data.Data {
+			sum += v
+		}
+	}
+	return sum
+}
+
+// runTrainer 按配置的角色参与分布式训练
+func runTrainer(cfg *TrainerConfig, log *logger.Logger) error {
+	if cfg.Epochs <= 0 {
+		cfg.Epochs = 5
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 16
+	}
+	if cfg.LR <= 0 {
+		cfg.LR = 0.05
+	}
+	if cfg.Samples <= 0 {
+		cfg.Samples = 600
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30
+	}
+	switch cfg.Role {
+	case "":
+		cfg.Role = RoleAllReduce
+	case RoleAllReduce, RoleServer, RoleWorker:
+	default:
+		return fmt.Errorf("未知的角色 %q", cfg.Role)
+	}
+	ctx := context.Background()
+
+	connectCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	transport, err := distributed.NewTCPTransport(connectCtx, cfg.Rank, cfg.Peers)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer transport.Close()
+	log.Info(fmt.Sprintf("cell %d/%d 已连接，角色 %s", cfg.Rank, len(cfg.Peers), cfg.Role))
+
+	data, err := blobs(cfg.Seed, cfg.Samples)
+	if err != nil {
+		return err
+	}
+	model := newModel(cfg.Seed + int64(cfg.Rank))
+
+	switch cfg.Role {
+	case RoleAllReduce:
+		ring, err := distributed.NewRing(transport, distributed.RingOptions{ChunkSize: cfg.ChunkSize})
+		if err != nil {
+			return err
+		}
+		sgd, err := optim.NewSGD(model.NamedParameters(), optim.SGDOptions{LR: cfg.LR, Momentum: 0.9})
+		if err != nil {
+			return err
+		}
+		opt, err := distributed.NewDataParallel(ctx, ring, model.Parameters(), sgd)
+		if err != nil {
+			return err
+		}
+		return fit(ctx, cfg, log, model, opt, data, cfg.Rank, len(cfg.Peers))
+	case RoleServer:
+		return serve(ctx, cfg, log, transport, model, data)
+	default: // RoleWorker，角色已在连接前校验
+		opt, err := distributed.NewWorker(ctx, transport, cfg.ServerRank, model.Parameters(), distributed.WorkerOptions{ChunkSize: cfg.ChunkSize})
+		if err != nil {
+			return err
+		}
+		// 工作者之间按去掉服务器后的序号划分数据
+		index := cfg.Rank
+		if cfg.Rank > cfg.ServerRank {
+			index--
+		}
+		err = fit(ctx, cfg, log, model, opt, data, index, len(cfg.Peers)-1)
+		if stopErr := opt.Stop(ctx); err == nil {
+			err = stopErr
+		}
+		log.Info(fmt.Sprintf("cell %d 有 %d 个梯度因过期被丢弃", cfg.Rank, opt.Rejected()))
+		return err
+	}
+}
+
+// 在第 index 个数据分片上训练本地副本，opt 负责与其他 cell 同步
+func fit(ctx context.Context, cfg *TrainerConfig, log *logger.Logger, model dubtorch.Module, opt dubtorch.Optimizer, data dubtorch.Dataset, index, count int) error {
+	shard, err := distributed.Shard(data, index, count)
+	if err != nil {
+		return err
+	}
+	loader, err := dubtorch.NewDataLoader(shard, dubtorch.DataLoaderOptions{BatchSize: cfg.BatchSize, Shuffle: true, Seed: cfg.Seed + int64(cfg.Rank)})
+	if err != nil {
+		return err
+	}
+	trainer, err := dubtorch.NewTrainer(model, opt, crossEntropy, loader, dubtorch.TrainerOptions{
+		Epochs:  cfg.Epochs,
+		Metrics: []dubtorch.Metric{dubtorch.NewAccuracy()},
+		Logger:  log,
+	})
+	if err != nil {
+		return err
+	}
+	if err := trainer.Fit(ctx); err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("cell %d 训练完成，参数校验和 %.12f", cfg.Rank, checksum(model)))
+	return nil
+}
+
+// 作为参数服务器运行，所有工作者结束后在完整数据集上评估模型
+func serve(ctx context.Context, cfg *TrainerConfig, log *logger.Logger, transport distributed.Transport, model dubtorch.Module, data *dubtorch.TensorDataset) error {
+	sgd, err := optim.NewSGD(model.NamedParameters(), optim.SGDOptions{LR: cfg.LR, Momentum: 0.9})
+	if err != nil {
+		return err
+	}
+	server, err := distributed.NewParameterServer(transport, model.Parameters(), sgd, distributed.ParameterServerOptions{
+		MaxStaleness: cfg.MaxStaleness,
+		ChunkSize:    cfg.ChunkSize,
+	})
+	if err != nil {
+		return err
+	}
+	var workers []int
+	for rank := range cfg.Peers {
+		if rank != cfg.Rank {
+			workers = append(workers, rank)
+		}
+	}
+	if err := server.Serve(ctx, workers); err != nil {
+		return err
+	}
+	accepted, rejected := server.Stats()
+
+	loader, err := dubtorch.NewDataLoader(data, dubtorch.DataLoaderOptions{BatchSize: data.Len()})
+	if err != nil {
+		return err
+	}
+	it := loader.Iter()
+	defer it.Close()
+	batch, err := it.Next()
+	if err != nil {
+		return err
+	}
+	acc := dubtorch.NewAccuracy()
+	dubtorch.NoGrad(func() {
+		var out *dubtorch.Tensor
+		if out, err = model.Forward(dubtorch.NewTensor(batch[0], false)); err == nil {
+			err = acc.Update(out.Data, batch[1])
+		}
+	})
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("参数服务器完成 %d 次更新，丢弃 %d 个过期梯度，准确率 %.4f，参数校验和 %.12f",
+		accepted, rejected, acc.Values()["accuracy"], checksum(model)))
+	return nil
+}
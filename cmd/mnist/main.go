@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/onnx"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/quant"
+	"github.com/duringbug/go-web-net/pkg/logger"
+)
+
+// MNIST 官方发布的文件名
+var files = map[string]string{
+	"train-images": "train-images-idx3-ubyte.gz",
+	"train-labels": "train-labels-idx1-ubyte.gz",
+	"test-images":  "t10k-images-idx3-ubyte.gz",
+	"test-labels":  "t10k-labels-idx1-ubyte.gz",
+}
+
+// 七段数码管中每一段的起止坐标（以 20x12 的网格为单位）
+var segments = [7][4]int{
+	{0, 0, 0, 11},    // 上
+	{0, 11, 9, 11},   // 右上
+	{10, 11, 19, 11}, // 右下
+	{19, 0, 19, 11},  // 下
+	{10, 0, 19, 0},   // 左下
+	{0, 0, 9, 0},     // 左上
+	{9, 0, 9, 11},    // 中
+}
+
+// 每个数字点亮的段
+var digitSegments = [10][]int{
+	{0, 1, 2, 3, 4, 5}, {1, 2}, {0, 1, 6, 4, 3}, {0, 1, 6, 2, 3}, {5, 6, 1, 2},
+	{0, 5, 6, 2, 3}, {0, 5, 6, 4, 2, 3}, {0, 1, 2}, {0, 1, 2, 3, 4, 5, 6}, {0, 1, 2, 3, 5, 6},
+}
+
+// 生成 n 张 28x28 的合成数字图像：七段数码管笔画加上随机平移、亮度与噪声
+func synthesize(r *rand.Rand, n int) (*dubnp.Array, *dubnp.Array) {
+	images := dubnp.Zeros(n, 28, 28)
+	labels := dubnp.Zeros(n)
+	for i := 0; i < n; i++ {
+		digit := r.Intn(10)
+		labels.Data[i] = float64(digit)
+		img := images.Data[i*784 : (i+1)*784]
+		dy, dx := 4+r.Intn(5)-2, 8+r.Intn(5)-2
+		intensity := 180 + r.Float64()*75
+		for _, s := range digitSegments[digit] {
+			seg := segments[s]
+			for y := seg[0]; y <= seg[2]; y++ {
+				for x := seg[1]; x <= seg[3]; x++ {
+					img[(y+dy)*28+x+dx] = intensity
+				}
+			}
+		}
+		for k := range img {
+			v := img[k] + r.NormFloat64()*20
+			img[k] = float64(int(min(max(v, 0), 255)))
+		}
+	}
+	return images, labels
+}
+
+// 在 dir 中写出合成的 MNIST 格式数据集
+func writeSynthetic(dir string, seed int64, trainSize, testSize int) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	r := rand.New(rand.NewSource(seed))
+	for _, split := range []struct {
+		prefix string
+		n      int
+	}{{"train", trainSize}, {"test", testSize}} {
+		images, labels := synthesize(r, split.n)
+		if err := dubtorch.SaveIDXFile(filepath.Join(dir, files[split.prefix+"-images"]), images, dubtorch.IDXUint8); err != nil {
+			return err
+		}
+		if err := dubtorch.SaveIDXFile(filepath.Join(dir, files[split.prefix+"-labels"]), labels, dubtorch.IDXUint8); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func crossEntropy(output, target *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	return dubtorch.CrossEntropyLoss(output, target, dubtorch.ClassLossOptions{})
+}
+
+func run(log *logger.Logger) error {
+	dataDir := flag.String("data", "data/mnist", "MNIST IDX 文件所在目录")
+	synthetic := flag.Bool("synthetic", false, "数据目录中没有文件时生成合成数据集")
+	epochs := flag.Int("epochs", 5, "训练轮数")
+	batchSize := flag.Int("batch", 64, "批大小")
+	lr := flag.Float64("lr", 1e-3, "学习率")
+	seed := flag.Int64("seed", 1, "随机种子")
+	ckptDir := flag.String("ckpt", "", "检查点目录，为空时不保存")
+	onnxPath := flag.String("onnx", "", "训练结束后导出 ONNX 模型的路径，为空时不导出")
+	useInt8 := flag.Bool("int8", false, "训练结束后做 int8 训练后量化，并报告测试集上准确率的变化")
+	trace := flag.Bool("trace", false, "训练结束后把模型编译为静态图，比较重复推理同一批次的耗时")
+	flag.Parse()
+
+	trainImages := filepath.Join(*dataDir, files["train-images"])
+	if _, err := os.Stat(trainImages); errors.Is(err, os.ErrNotExist) {
+		if !*synthetic {
+			return fmt.Errorf("%s 不存在，请下载 MNIST 或使用 -synthetic", trainImages)
+		}
+		log.Info("生成合成数据集到 ", *dataDir)
+		if err := writeSynthetic(*dataDir, *seed, 6000, 1000); err != nil {
+			return err
+		}
+	}
+	train, err := dubtorch.NewMNIST(trainImages, filepath.Join(*dataDir, files["train-labels"]))
+	if err != nil {
+		return err
+	}
+	test, err := dubtorch.NewMNIST(filepath.Join(*dataDir, files["test-images"]), filepath.Join(*dataDir, files["test-labels"]))
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("训练集 %d 张，测试集 %d 张", train.Len(), test.Len()))
+
+	// 固定种子保证初始化、打乱顺序与 dropout 均可复现
+	dubtorch.ManualSeed(*seed)
+	trainLoader, err := dubtorch.NewDataLoader(train, dubtorch.DataLoaderOptions{BatchSize: *batchSize, Shuffle: true, Seed: *seed})
+	if err != nil {
+		return err
+	}
+	testLoader, err := dubtorch.NewDataLoader(test, dubtorch.DataLoaderOptions{BatchSize: 256})
+	if err != nil {
+		return err
+	}
+	model := dubtorch.NewSequential(
+		dubtorch.NewFlatten(),
+		dubtorch.NewLinear(784, 128, true),
+		dubtorch.NewReLU(),
+		dubtorch.NewDropout(0.1),
+		dubtorch.NewLinear(128, 10, true),
+	)
+	summary, err := dubtorch.Summary(model, []int{1, 1, 28, 28})
+	if err != nil {
+		return err
+	}
+	log.Info("模型摘要:\n" + summary.String())
+	opt, err := optim.NewAdam(model.NamedParameters(), optim.AdamOptions{LR: *lr})
+	if err != nil {
+		return err
+	}
+	cm, err := dubtorch.NewConfusionMatrix(10)
+	if err != nil {
+		return err
+	}
+	trainer, err := dubtorch.NewTrainer(model, opt, crossEntropy, trainLoader, dubtorch.TrainerOptions{
+		Epochs:        *epochs,
+		Validation:    testLoader,
+		CheckpointDir: *ckptDir,
+		Metrics:       []dubtorch.Metric{cm},
+		Callbacks:     []dubtorch.Callback{&dubtorch.EarlyStopping{Patience: 2}},
+		Logger:        log,
+		LogEvery:      50,
+	})
+	if err != nil {
+		return err
+	}
+	if err := trainer.Fit(context.Background()); err != nil {
+		return err
+	}
+
+	result, err := trainer.Evaluate(context.Background(), testLoader)
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("测试集 loss=%.4f accuracy=%.4f f1=%.4f", result["loss"], result["accuracy"], result["f1"]))
+	for digit, row := range cm.Counts {
+		log.Info(fmt.Sprintf("混淆矩阵 %d: %v", digit, row))
+	}
+	if *useInt8 {
+		if err := quantize(model, train, testLoader, log); err != nil {
+			return err
+		}
+	}
+	if *trace {
+		if err := compare(model, testLoader, log); err != nil {
+			return err
+		}
+	}
+	if *onnxPath != "" {
+		// 以一张测试图片作为示例输入，批次维度导出为动态维度
+		it := testLoader.Iter()
+		b, err := it.Next()
+		it.Close()
+		if err != nil {
+			return err
+		}
+		example := &dubnp.Array{Data: b[0].Data[:784], Shape: append([]int{1}, b[0].Shape[1:]...)}
+		if err := onnx.SaveFile(*onnxPath, model, example, onnx.ExportOptions{DynamicBatch: true}); err != nil {
+			return err
+		}
+		log.Info("模型已导出到 ", *onnxPath)
+	}
+	return nil
+}
+
+// 用训练集的前 1024 张图片校准，逐通道对称量化权重、非对称量化激活
+func quantize(model dubtorch.Module, train dubtorch.Dataset, test *dubtorch.DataLoader, log *logger.Logger) error {
+	indices := make([]int, min(1024, train.Len()))
+	for i := range indices {
+		indices[i] = i
+	}
+	subset, err := dubtorch.NewSubset(train, indices)
+	if err != nil {
+		return err
+	}
+	calib, err := dubtorch.NewDataLoader(subset, dubtorch.DataLoaderOptions{BatchSize: 256})
+	if err != nil {
+		return err
+	}
+	quantized, err := quant.QuantizeModel(model, calib, quant.Config{PerChannel: true, ActivationScheme: quant.Asymmetric})
+	if err != nil {
+		return err
+	}
+	report, err := quant.Evaluate(model, quantized, test)
+	if err != nil {
+		return err
+	}
+	log.Info("int8 量化: ", report)
+	return nil
+}
+
+// 在测试集的第一个批次上编译静态图，与逐层执行比较结果与平均耗时
+func compare(model dubtorch.Module, test *dubtorch.DataLoader, log *logger.Logger) error {
+	it := test.Iter()
+	b, err := it.Next()
+	it.Close()
+	if err != nil {
+		return err
+	}
+	x := dubtorch.NewTensor(b[0], false)
+	graph, err := dubtorch.Trace(model, x)
+	if err != nil {
+		return err
+	}
+	log.Info(graph.String())
+
+	const runs = 20
+	var want, got *dubtorch.Tensor
+	model.Eval()
+	defer model.Train()
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		dubtorch.NoGrad(func() {
+			want, err = model.Forward(x)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	eager := time.Since(start) / runs
+	start = time.Now()
+	for i := 0; i < runs; i++ {
+		if got, err = graph.Forward(x); err != nil {
+			return err
+		}
+	}
+	static := time.Since(start) / runs
+	maxErr := 0.0
+	for i, v := range want.Data.Data {
+		maxErr = math.Max(maxErr, math.Abs(got.Data.Data[i]-v))
+	}
+	log.Info(fmt.Sprintf("静态图: 批大小 %d，逐层执行 %v，静态图 %v，加速 %.2fx，最大误差 %.2e",
+		x.Shape()[0], eager, static, float64(eager)/float64(static), maxErr))
+	return nil
+}
+
+func main() {
+	if err := os.MkdirAll("log", 0755); err != nil {
+		fmt.Println("无法创建日志目录: ", err)
+		os.Exit(1)
+	}
+	log, err := logger.NewLogger("log/mnist.log")
+	if err != nil {
+		fmt.Println("无法创建日志文件: ", err)
+		os.Exit(1)
+	}
+	defer log.Close()
+
+	if err := run(log); err != nil {
+		log.Error(err)
+		log.Close()
+		os.Exit(1)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"os"
 	"os/exec"
+	"strconv"
 	"sync"
 
 	"github.com/duringbug/go-web-net/pkg/logger" // 导入自定义的日志包
@@ -15,8 +16,33 @@ type Command struct {
 	Args    []string `json:"args"`
 }
 
+// Pipeline 流水线并行的阶段到 cell 的映射，其余训练参数由各阶段的 cell 从同一文件读取
+type Pipeline struct {
+	Command string `json:"command"` // cell 可执行文件，默认 ./build/cell
+	Stages  []struct {
+		Cell string `json:"cell"` // 运行该阶段的 cell 的配置文件
+	} `json:"stages"`
+}
+
 type Config struct {
 	Commands []Command `json:"commands"`
+	Pipeline *Pipeline `json:"pipeline"`
+}
+
+// pipelineCommands 为流水线的每个阶段生成启动 cell 的命令
+func pipelineCommands(p *Pipeline, confPath string) []Command {
+	command := p.Command
+	if command == "" {
+		command = "./build/cell"
+	}
+	var cmds []Command
+	for i, s := range p.Stages {
+		cmds = append(cmds, Command{
+			Command: command,
+			Args:    []string{"-conf", s.Cell, "-organ", confPath, "-stage", strconv.Itoa(i)},
+		})
+	}
+	return cmds
 }
 
 // loadConfig 从 JSON 文件加载配置
@@ -58,6 +84,10 @@ func main() {
 		return
 	}
 
+	if config.Pipeline != nil {
+		config.Commands = append(config.Commands, pipelineCommands(config.Pipeline, *confPath)...)
+	}
+
 	var wg sync.WaitGroup
 
 	// 遍历并并行执行每个命令
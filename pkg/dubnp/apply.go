@@ -0,0 +1,163 @@
+package dubnp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Map 对每个元素并行执行 fn，返回新的数组
+func (a *Array) Map(fn func(x float64) float64) *Array {
+	resultData := make([]float64, len(a.Data))
+	parallelChunks(len(a.Data), func(start, end int) {
+		for i := start; i < end; i++ {
+			resultData[i] = fn(a.Data[i])
+		}
+	})
+	return &Array{Data: resultData, Shape: append([]int(nil), a.Shape...)}
+}
+
+// Map2 将 a 与 b 广播到同一形状后逐元素并行执行 fn
+func (a *Array) Map2(b *Array, fn func(x, y float64) float64) (*Array, error) {
+	// 形状相同时无需广播
+	if sameShape(a.Shape, b.Shape) {
+		resultData := make([]float64, len(a.Data))
+		parallelChunks(len(a.Data), func(start, end int) {
+			for i := start; i < end; i++ {
+				resultData[i] = fn(a.Data[i], b.Data[i])
+			}
+		})
+		return &Array{Data: resultData, Shape: append([]int(nil), a.Shape...)}, nil
+	}
+
+	it, err := NewNdIter(NdIterOptions{}, a, b)
+	if err != nil {
+		return nil, err
+	}
+	resultData := make([]float64, it.Size())
+	parallelChunks(len(resultData), func(start, end int) {
+		local := it.clone()
+		local.seek(start)
+		for i := start; i < end; i++ {
+			resultData[i] = fn(a.Data[local.offsets[0]], b.Data[local.offsets[1]])
+			local.Next()
+		}
+	})
+	return &Array{Data: resultData, Shape: append([]int(nil), it.Shape()...)}, nil
+}
+
+// MapN 将所有操作数广播到同一形状后逐元素并行执行 fn，xs 依次为各操作数的当前元素
+func MapN(fn func(xs []float64) float64, operands ...*Array) (*Array, error) {
+	it, err := NewNdIter(NdIterOptions{}, operands...)
+	if err != nil {
+		return nil, err
+	}
+	resultData := make([]float64, it.Size())
+	parallelChunks(len(resultData), func(start, end int) {
+		local := it.clone()
+		local.seek(start)
+		xs := make([]float64, len(operands))
+		for i := start; i < end; i++ {
+			for op, operand := range operands {
+				xs[op] = operand.Data[local.offsets[op]]
+			}
+			resultData[i] = fn(xs)
+			local.Next()
+		}
+	})
+	return &Array{Data: resultData, Shape: append([]int(nil), it.Shape()...)}, nil
+}
+
+// 沿 axis 的所有一维切片（lane）并行执行 fn，lane 为复制出的连续切片
+func (a *Array) forEachLane(axis int, fn func(lane int, data []float64)) error {
+	outer, n, inner := axisGeometry(a.Shape, axis)
+	numLanes := outer * inner
+	lanesPerTask := max(1, reduceChunkSize/max(n, 1))
+	numTasks := (numLanes + lanesPerTask - 1) / lanesPerTask
+
+	return runTasksCtx(context.Background(), numTasks, func(t int) {
+		buffer := make([]float64, n)
+		start := t * lanesPerTask
+		end := min(start+lanesPerTask, numLanes)
+		for lane := start; lane < end; lane++ {
+			o, i := lane/inner, lane%inner
+			for k := range buffer {
+				buffer[k] = a.Data[o*n*inner+k*inner+i]
+			}
+			fn(lane, buffer)
+		}
+	})
+}
+
+// Apply 对沿 axis 的每个一维切片执行 fn，fn 返回的切片长度必须一致，
+// 结果中 axis 维的长度替换为该长度。lane 在调用之间复用，fn 不应保留它
+func (a *Array) Apply(axis int, fn func(lane []float64) []float64) (*Array, error) {
+	axis, err := normalizeAxis(axis, len(a.Shape))
+	if err != nil {
+		return nil, err
+	}
+	outer, n, inner := axisGeometry(a.Shape, axis)
+	if outer*inner == 0 {
+		return nil, fmt.Errorf("形状 %v 中没有可供 Apply 的切片", a.Shape)
+	}
+
+	// 先计算第一个切片以确定输出长度
+	first := make([]float64, n)
+	for k := range first {
+		first[k] = a.Data[k*inner]
+	}
+	firstOut := fn(first)
+	outLen := len(firstOut)
+
+	shape := append([]int(nil), a.Shape...)
+	shape[axis] = outLen
+	resultData := make([]float64, outer*outLen*inner)
+	for k, v := range firstOut {
+		resultData[k*inner] = v
+	}
+
+	var mu sync.Mutex
+	var applyErr error
+	err = a.forEachLane(axis, func(lane int, data []float64) {
+		if lane == 0 {
+			return
+		}
+		out := fn(data)
+		if len(out) != outLen {
+			mu.Lock()
+			applyErr = fmt.Errorf("Apply 返回的长度不一致: %d 与 %d", len(out), outLen)
+			mu.Unlock()
+			return
+		}
+		o, i := lane/inner, lane%inner
+		for k, v := range out {
+			resultData[o*outLen*inner+k*inner+i] = v
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if applyErr != nil {
+		return nil, applyErr
+	}
+	return &Array{Data: resultData, Shape: shape}, nil
+}
+
+// ReduceFunc 用 fn 将沿 axis 的每个一维切片归约为一个值，结果去掉 axis 维。
+// lane 在调用之间复用，fn 不应保留它
+func (a *Array) ReduceFunc(axis int, fn func(lane []float64) float64) (*Array, error) {
+	axis, err := normalizeAxis(axis, len(a.Shape))
+	if err != nil {
+		return nil, err
+	}
+	outer, _, inner := axisGeometry(a.Shape, axis)
+	resultData := make([]float64, outer*inner)
+
+	err = a.forEachLane(axis, func(lane int, data []float64) {
+		resultData[lane] = fn(data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Array{Data: resultData, Shape: reducedShape(a.Shape, axis, false)}, nil
+}
@@ -0,0 +1,117 @@
+package dubnp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BatchMultiply 批量矩阵乘法：a 为 (..., M, K)，b 为 (..., K, N)，批次维度按广播规则匹配
+func (a *Array) BatchMultiply(b *Array) (*Array, error) {
+	return a.BatchMultiplyCtx(context.Background(), b)
+}
+
+// BatchMultiplyCtx 批量矩阵乘法（可取消版本），每个批次作为一个并行任务
+func (a *Array) BatchMultiplyCtx(ctx context.Context, b *Array) (*Array, error) {
+	na, nb := len(a.Shape), len(b.Shape)
+	if na < 2 || nb < 2 {
+		return nil, errors.New("批量矩阵乘法的操作数至少为二维")
+	}
+	m, k, n := a.Shape[na-2], a.Shape[na-1], b.Shape[nb-1]
+	if b.Shape[nb-2] != k {
+		return nil, fmt.Errorf("矩阵的维度不匹配，无法进行乘法运算: %v 与 %v", a.Shape, b.Shape)
+	}
+	batch, err := BroadcastShapes(a.Shape[:na-2], b.Shape[:nb-2])
+	if err != nil {
+		return nil, err
+	}
+
+	// 批次维度上的步长以矩阵为单位
+	stridesA := broadcastStrides(a.Shape[:na-2], batch)
+	stridesB := broadcastStrides(b.Shape[:nb-2], batch)
+	result := Zeros(append(append([]int(nil), batch...), m, n)...)
+	err = runTasksCtx(ctx, shapeSize(batch), func(t int) {
+		offA, offB := 0, 0
+		rest := t
+		for d := len(batch) - 1; d >= 0; d-- {
+			idx := rest % batch[d]
+			rest /= batch[d]
+			offA += idx * stridesA[d]
+			offB += idx * stridesB[d]
+		}
+		am := a.Data[offA*m*k:][:m*k]
+		bm := b.Data[offB*k*n:][:k*n]
+		out := result.Data[t*m*n:][:m*n]
+		// i-k-j 顺序，内层循环连续访问 b 与结果的行
+		for i := 0; i < m; i++ {
+			row := out[i*n : (i+1)*n]
+			for p, av := range am[i*k : (i+1)*k] {
+				for j, bv := range bm[p*n : (p+1)*n] {
+					row[j] += av * bv
+				}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Permute 按 axes 重新排列维度，返回新的连续数组
+func (a *Array) Permute(axes ...int) (*Array, error) {
+	ndim := len(a.Shape)
+	if len(axes) != ndim {
+		return nil, fmt.Errorf("Permute 需要 %d 个轴，实际为 %v", ndim, axes)
+	}
+	seen := make([]bool, ndim)
+	shape := make([]int, ndim)
+	for i, ax := range axes {
+		if ax < 0 || ax >= ndim || seen[ax] {
+			return nil, fmt.Errorf("无效的轴排列: %v", axes)
+		}
+		seen[ax] = true
+		shape[i] = a.Shape[ax]
+	}
+	// 输出第 i 维在输入中的步长
+	inStrides := a.Strides()
+	strides := make([]int, ndim)
+	for i, ax := range axes {
+		strides[i] = inStrides[ax]
+	}
+	out := Zeros(shape...)
+	index := make([]int, ndim)
+	src := 0
+	for k := range out.Data {
+		out.Data[k] = a.Data[src]
+		// 按行优先顺序递增输出下标，并同步更新输入偏移
+		for d := ndim - 1; d >= 0; d-- {
+			index[d]++
+			src += strides[d]
+			if index[d] < shape[d] {
+				break
+			}
+			src -= strides[d] * shape[d]
+			index[d] = 0
+		}
+	}
+	return out, nil
+}
+
+// SwapAxes 交换两个维度，支持负数轴，返回新的连续数组
+func (a *Array) SwapAxes(i, j int) (*Array, error) {
+	ndim := len(a.Shape)
+	i, err := normalizeAxis(i, ndim)
+	if err != nil {
+		return nil, err
+	}
+	if j, err = normalizeAxis(j, ndim); err != nil {
+		return nil, err
+	}
+	axes := make([]int, ndim)
+	for k := range axes {
+		axes[k] = k
+	}
+	axes[i], axes[j] = j, i
+	return a.Permute(axes...)
+}
@@ -0,0 +1,142 @@
+package dubnp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Trapz 沿 axis 用梯形公式积分，采样点间距为 dx
+func (a *Array) Trapz(axis int, dx float64) (*Array, error) {
+	return a.ReduceFunc(axis, func(lane []float64) float64 {
+		sum := 0.0
+		for i := 1; i < len(lane); i++ {
+			sum += (lane[i-1] + lane[i]) / 2
+		}
+		return sum * dx
+	})
+}
+
+// TrapzX 沿 axis 用梯形公式积分，x 为各采样点的坐标
+func (a *Array) TrapzX(axis int, x []float64) (*Array, error) {
+	if err := a.checkAxisLen(axis, len(x)); err != nil {
+		return nil, err
+	}
+	return a.ReduceFunc(axis, func(lane []float64) float64 {
+		sum := 0.0
+		for i := 1; i < len(lane); i++ {
+			sum += (x[i] - x[i-1]) * (lane[i-1] + lane[i]) / 2
+		}
+		return sum
+	})
+}
+
+// Simpson 沿 axis 用复合辛普森公式积分，采样点间距为 dx。
+// 区间数为奇数时最后三个区间使用辛普森 3/8 公式，只有一个区间时退化为梯形公式
+func (a *Array) Simpson(axis int, dx float64) (*Array, error) {
+	return a.ReduceFunc(axis, func(lane []float64) float64 {
+		intervals := len(lane) - 1
+		switch {
+		case intervals < 1:
+			return 0
+		case intervals == 1:
+			return dx * (lane[0] + lane[1]) / 2
+		}
+
+		sum := 0.0
+		even := intervals
+		if intervals%2 == 1 {
+			even = intervals - 3
+			y := lane[even:]
+			sum += 3 * dx / 8 * (y[0] + 3*y[1] + 3*y[2] + y[3])
+		}
+		for i := 0; i+2 <= even; i += 2 {
+			sum += dx / 3 * (lane[i] + 4*lane[i+1] + lane[i+2])
+		}
+		return sum
+	})
+}
+
+// 检查 axis 方向的长度是否为 n
+func (a *Array) checkAxisLen(axis, n int) error {
+	axis, err := normalizeAxis(axis, len(a.Shape))
+	if err != nil {
+		return err
+	}
+	if a.Shape[axis] != n {
+		return fmt.Errorf("坐标长度 %d 与轴 %d 的长度 %d 不一致", n, axis, a.Shape[axis])
+	}
+	return nil
+}
+
+// Gradient 沿 axis 计算数值梯度，内部点使用中心差分，两端使用一阶单侧差分
+func (a *Array) Gradient(axis int, spacing float64) (*Array, error) {
+	axis, err := normalizeAxis(axis, len(a.Shape))
+	if err != nil {
+		return nil, err
+	}
+	if a.Shape[axis] < 2 {
+		return nil, errors.New("计算梯度至少需要两个采样点")
+	}
+	return a.Apply(axis, func(lane []float64) []float64 {
+		n := len(lane)
+		out := make([]float64, n)
+		out[0] = (lane[1] - lane[0]) / spacing
+		out[n-1] = (lane[n-1] - lane[n-2]) / spacing
+		for i := 1; i < n-1; i++ {
+			out[i] = (lane[i+1] - lane[i-1]) / (2 * spacing)
+		}
+		return out
+	})
+}
+
+// Diff 沿 axis 计算 n 阶差分，axis 方向的长度减少 n
+func (a *Array) Diff(n, axis int) (*Array, error) {
+	axis, err := normalizeAxis(axis, len(a.Shape))
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n > a.Shape[axis] {
+		return nil, fmt.Errorf("差分阶数 %d 超出轴长度 %d", n, a.Shape[axis])
+	}
+	if n == a.Shape[axis] {
+		shape := append([]int(nil), a.Shape...)
+		shape[axis] = 0
+		return &Array{Data: []float64{}, Shape: shape}, nil
+	}
+	return a.Apply(axis, func(lane []float64) []float64 {
+		out := append([]float64(nil), lane...)
+		for k := 0; k < n; k++ {
+			for i := 0; i < len(out)-1; i++ {
+				out[i] = out[i+1] - out[i]
+			}
+			out = out[:len(out)-1]
+		}
+		return out
+	})
+}
+
+// CumSum 沿 axis 计算累加和
+func (a *Array) CumSum(axis int) (*Array, error) {
+	return a.Apply(axis, func(lane []float64) []float64 {
+		out := make([]float64, len(lane))
+		acc := 0.0
+		for i, v := range lane {
+			acc += v
+			out[i] = acc
+		}
+		return out
+	})
+}
+
+// CumProd 沿 axis 计算累乘积
+func (a *Array) CumProd(axis int) (*Array, error) {
+	return a.Apply(axis, func(lane []float64) []float64 {
+		out := make([]float64, len(lane))
+		acc := 1.0
+		for i, v := range lane {
+			acc *= v
+			out[i] = acc
+		}
+		return out
+	})
+}
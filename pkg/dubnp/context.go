@@ -0,0 +1,87 @@
+package dubnp
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// ProgressFunc 进度回调，done 为已完成的块数，total 为总块数
+type ProgressFunc func(done, total int)
+
+type progressKey struct{}
+
+// WithProgress 返回携带进度回调的 context，供 ...Ctx 系列函数在每完成一个块后回调
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// 屏蔽进度回调，用于内部子步骤，避免与外层操作的进度混在一起
+func withoutProgress(ctx context.Context) context.Context {
+	return context.WithValue(ctx, progressKey{}, ProgressFunc(nil))
+}
+
+// 从 context 中取出进度回调，未设置时返回 nil
+func progressFromContext(ctx context.Context) ProgressFunc {
+	fn, _ := ctx.Value(progressKey{}).(ProgressFunc)
+	return fn
+}
+
+// 并行执行 numTasks 个任务，每个任务开始前检查 ctx 是否已取消
+// 任务被取消时返回 ctx.Err()，已开始的任务会执行完毕
+func runTasksCtx(ctx context.Context, numTasks int, task func(t int)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if numTasks == 0 {
+		return nil
+	}
+
+	progress := progressFromContext(ctx)
+	var progressMu sync.Mutex
+	var next int64
+	done := 0
+
+	// 设置并行的 goroutine 数量
+	numWorkers := runtime.NumCPU()
+	if numWorkers > numTasks {
+		numWorkers = numTasks
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+
+	for worker := 0; worker < numWorkers; worker++ {
+		go func() {
+			defer wg.Done()
+			for {
+				// 每个块开始前检查是否被取消
+				if ctx.Err() != nil {
+					return
+				}
+				t := int(atomic.AddInt64(&next, 1) - 1)
+				if t >= numTasks {
+					return
+				}
+				task(t)
+
+				// 串行化计数与回调，保证回调收到的进度单调递增
+				progressMu.Lock()
+				done++
+				if progress != nil {
+					progress(done, numTasks)
+				}
+				progressMu.Unlock()
+			}
+		}()
+	}
+
+	// 等待所有 goroutine 完成
+	wg.Wait()
+
+	if done < numTasks {
+		return ctx.Err()
+	}
+	return nil
+}
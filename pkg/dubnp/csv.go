@@ -0,0 +1,250 @@
+package dubnp
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// CSVOptions 读取 CSV/TSV 的选项，零值表示逗号分隔、无表头、读取全部列、缺失值填充 NaN
+type CSVOptions struct {
+	Delimiter rune // 分隔符，为 0 时使用 ','，TSV 使用 '\t'
+	Comment   rune // 注释行的起始字符，为 0 时不识别注释
+	SkipRows  int  // 跳过开头的行数（在表头之前）
+	Header    bool // 是否将第一行（跳过 SkipRows 之后）作为表头
+
+	Columns     []int    // 仅读取这些列（从 0 开始），为空时读取全部列
+	ColumnNames []string // 按表头名称选择列，需要 Header 为 true，优先于 Columns
+
+	MissingValues []string // 视为缺失值的字符串，空字符串总是视为缺失值
+	UseFillValue  bool     // 为 true 时缺失值填充 FillValue，否则填充 NaN
+	FillValue     float64
+}
+
+// CSVReader 流式读取 CSV，每次读取若干行组成一个二维数组，适用于大于内存的文件
+type CSVReader struct {
+	reader  *csv.Reader
+	opts    CSVOptions
+	header  []string
+	columns []int // 实际读取的列，为 nil 时在读取第一行数据时确定
+	missing map[string]bool
+	fill    float64
+	line    int // 已读取的数据行数，用于错误信息
+	started bool
+}
+
+// NewCSVReader 创建流式 CSV 读取器，表头在第一次读取时解析
+func NewCSVReader(r io.Reader, opts CSVOptions) *CSVReader {
+	reader := csv.NewReader(r)
+	if opts.Delimiter != 0 {
+		reader.Comma = opts.Delimiter
+	}
+	reader.Comment = opts.Comment
+	reader.FieldsPerRecord = -1 // 列数由 CSVReader 自行检查，以便给出更清晰的错误
+	reader.ReuseRecord = true
+
+	missing := map[string]bool{"": true}
+	for _, v := range opts.MissingValues {
+		missing[v] = true
+	}
+	fill := math.NaN()
+	if opts.UseFillValue {
+		fill = opts.FillValue
+	}
+	return &CSVReader{reader: reader, opts: opts, missing: missing, fill: fill}
+}
+
+// 跳过开头的行并解析表头与列选择
+func (r *CSVReader) start() error {
+	r.started = true
+	for i := 0; i < r.opts.SkipRows; i++ {
+		if _, err := r.reader.Read(); err != nil {
+			return err
+		}
+	}
+	if r.opts.Header {
+		record, err := r.reader.Read()
+		if err != nil {
+			return err
+		}
+		r.header = make([]string, len(record))
+		for i, name := range record {
+			r.header[i] = strings.TrimSpace(name)
+		}
+	}
+
+	if len(r.opts.ColumnNames) > 0 {
+		if !r.opts.Header {
+			return errors.New("按名称选择列需要启用 Header")
+		}
+		r.columns = make([]int, len(r.opts.ColumnNames))
+		for i, name := range r.opts.ColumnNames {
+			r.columns[i] = -1
+			for j, h := range r.header {
+				if h == name {
+					r.columns[i] = j
+					break
+				}
+			}
+			if r.columns[i] < 0 {
+				return fmt.Errorf("表头中不存在列 %q", name)
+			}
+		}
+	} else if len(r.opts.Columns) > 0 {
+		r.columns = append([]int(nil), r.opts.Columns...)
+	} else if r.header != nil {
+		r.columns = identityColumns(len(r.header))
+	}
+	return nil
+}
+
+func identityColumns(n int) []int {
+	columns := make([]int, n)
+	for i := range columns {
+		columns[i] = i
+	}
+	return columns
+}
+
+// Header 返回表头（按选择后的列顺序），未启用 Header 时返回 nil
+func (r *CSVReader) Header() ([]string, error) {
+	if !r.started {
+		if err := r.start(); err != nil && err != io.EOF {
+			return nil, err
+		}
+	}
+	if r.header == nil {
+		return nil, nil
+	}
+	names := make([]string, len(r.columns))
+	for i, c := range r.columns {
+		if c < len(r.header) {
+			names[i] = r.header[c]
+		}
+	}
+	return names, nil
+}
+
+// ReadChunk 读取最多 maxRows 行数据，返回形状为 (行数, 列数) 的数组；
+// maxRows <= 0 时读取剩余的全部数据。没有更多数据时返回 io.EOF
+func (r *CSVReader) ReadChunk(maxRows int) (*Array, error) {
+	if !r.started {
+		if err := r.start(); err != nil {
+			return nil, err
+		}
+	}
+
+	var data []float64
+	rows := 0
+	for maxRows <= 0 || rows < maxRows {
+		record, err := r.reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.line++
+
+		if r.columns == nil {
+			r.columns = identityColumns(len(record))
+		}
+		for _, c := range r.columns {
+			if c < 0 || c >= len(record) {
+				return nil, fmt.Errorf("第 %d 行数据只有 %d 列，无法读取第 %d 列", r.line, len(record), c)
+			}
+		}
+		if len(r.opts.Columns) == 0 && len(r.opts.ColumnNames) == 0 && len(record) != len(r.columns) {
+			return nil, fmt.Errorf("第 %d 行数据有 %d 列，期望 %d 列", r.line, len(record), len(r.columns))
+		}
+
+		for _, c := range r.columns {
+			value, err := r.parseField(record[c])
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 行第 %d 列: %v", r.line, c, err)
+			}
+			data = append(data, value)
+		}
+		rows++
+	}
+
+	if rows == 0 {
+		return nil, io.EOF
+	}
+	return &Array{Data: data, Shape: []int{rows, len(r.columns)}}, nil
+}
+
+// 解析单个字段，缺失值替换为填充值
+func (r *CSVReader) parseField(field string) (float64, error) {
+	field = strings.TrimSpace(field)
+	if r.missing[field] {
+		return r.fill, nil
+	}
+	return strconv.ParseFloat(field, 64)
+}
+
+// LoadCSV 读取全部 CSV 数据为形状 (行数, 列数) 的二维数组
+func LoadCSV(reader io.Reader, opts CSVOptions) (*Array, error) {
+	r := NewCSVReader(reader, opts)
+	array, err := r.ReadChunk(0)
+	if err == io.EOF {
+		// 没有数据行时返回空数组
+		columns := len(r.columns)
+		return &Array{Data: []float64{}, Shape: []int{0, columns}}, nil
+	}
+	return array, err
+}
+
+// CSVWriteOptions 写出 CSV/TSV 的选项
+type CSVWriteOptions struct {
+	Delimiter rune     // 分隔符，为 0 时使用 ','
+	Header    []string // 表头，为空时不写表头
+	Format    string   // 数值格式，如 "%.6f"，为空时使用能精确还原的最短表示
+}
+
+// SaveCSV 将一维或二维数组写出为 CSV，一维数组写为单列
+func SaveCSV(w io.Writer, a *Array, opts CSVWriteOptions) error {
+	var rows, cols int
+	switch len(a.Shape) {
+	case 1:
+		rows, cols = a.Shape[0], 1
+	case 2:
+		rows, cols = a.Shape[0], a.Shape[1]
+	default:
+		return errors.New("仅支持保存一维或二维数组")
+	}
+	if len(opts.Header) > 0 && len(opts.Header) != cols {
+		return fmt.Errorf("表头有 %d 列，数组有 %d 列", len(opts.Header), cols)
+	}
+
+	writer := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		writer.Comma = opts.Delimiter
+	}
+	if len(opts.Header) > 0 {
+		if err := writer.Write(opts.Header); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			value := a.Data[i*cols+j]
+			if opts.Format != "" {
+				record[j] = fmt.Sprintf(opts.Format, value)
+			} else {
+				record[j] = strconv.FormatFloat(value, 'g', -1, 64)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
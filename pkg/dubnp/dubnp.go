@@ -1,11 +1,13 @@
 package dubnp
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
-	"unsafe"
 )
 
 type Array struct {
@@ -13,10 +15,8 @@ type Array struct {
 	Shape []int     // 数组的形状（维度）
 }
 
-// 获取系统的内存页面大小，单位为字节
-func getPageSize() int {
-	return int(unsafe.Sizeof(uintptr(0))) * 8 // 假设每个指针的大小是 8 字节
-}
+// 分块计算时每个块的边长（元素个数）
+const tileSize = 64
 
 // 创建新的矩阵
 func NewArray(data []float64, shape []int) (*Array, error) {
@@ -36,16 +36,11 @@ func (a *Array) Print() {
 	fmt.Println("Data:", a.Data)
 }
 
-// 矩阵加法（并行加速版）
+// 矩阵加法（并行加速版），形状不同时按广播规则计算
 func (a *Array) Add(b *Array) (*Array, error) {
-	// 检查形状是否相同
-	if len(a.Shape) != len(b.Shape) {
-		return nil, errors.New("矩阵的维度不相同")
-	}
-	for i := range a.Shape {
-		if a.Shape[i] != b.Shape[i] {
-			return nil, errors.New("矩阵的形状不匹配")
-		}
+	// 形状不同时按广播规则计算，无法广播时返回错误
+	if !sameShape(a.Shape, b.Shape) {
+		return a.Map2(b, add)
 	}
 
 	// 创建结果数组
@@ -103,8 +98,42 @@ func (a *Array) PrintMatrix(decimalPlaces int) {
 	fmt.Println()
 }
 
+// FormatMatrix 按最后一维分行格式化数组，同一列右对齐到相同宽度，decimalPlaces 控制小数位数
+func (a *Array) FormatMatrix(decimalPlaces int) []string {
+	cols := 1
+	if len(a.Shape) > 0 {
+		cols = a.Shape[len(a.Shape)-1]
+	}
+	if cols == 0 || len(a.Data) == 0 {
+		return nil
+	}
+	cells := make([]string, len(a.Data))
+	widths := make([]int, cols)
+	for i, v := range a.Data {
+		cells[i] = strconv.FormatFloat(v, 'f', decimalPlaces, 64)
+		widths[i%cols] = max(widths[i%cols], len(cells[i]))
+	}
+	rows := make([]string, 0, len(a.Data)/cols)
+	for start := 0; start < len(cells); start += cols {
+		var sb strings.Builder
+		for c, cell := range cells[start : start+cols] {
+			if c > 0 {
+				sb.WriteByte(' ')
+			}
+			fmt.Fprintf(&sb, "%*s", widths[c], cell)
+		}
+		rows = append(rows, sb.String())
+	}
+	return rows
+}
+
 // 矩阵乘法（优化版，内存访问优化 + 并行化 + 分块优化）
 func (a *Array) Multiply(b *Array) (*Array, error) {
+	return a.MultiplyCtx(context.Background(), b)
+}
+
+// 矩阵乘法（可取消版本），每个块开始前检查 ctx，取消时返回 ctx.Err()
+func (a *Array) MultiplyCtx(ctx context.Context, b *Array) (*Array, error) {
 	// 检查矩阵维度是否符合乘法要求
 	if len(a.Shape) != 2 || len(b.Shape) != 2 {
 		return nil, errors.New("仅支持二维矩阵乘法")
@@ -113,107 +142,80 @@ func (a *Array) Multiply(b *Array) (*Array, error) {
 		return nil, errors.New("矩阵的维度不匹配，无法进行乘法运算")
 	}
 
+	rows, inner, cols := a.Shape[0], a.Shape[1], b.Shape[1]
+
 	// 创建结果矩阵
-	resultData := make([]float64, a.Shape[0]*b.Shape[1])
+	resultData := make([]float64, rows*cols)
 
-	// 转置矩阵 b，优化列访问
-	bTransposed, err := b.Transpose()
+	// 转置矩阵 b，优化列访问（转置不计入乘法的进度）
+	bTransposed, err := b.TransposeCtx(withoutProgress(ctx))
 	if err != nil {
 		return nil, err
 	}
 
-	// 获取系统的内存页面大小，用于确定合理的块大小
-	pageSize := getPageSize()
-	blockSize := pageSize // 设置块大小为内存页面大小
-
-	// 设置并行的 goroutine 数量
-	numWorkers := runtime.NumCPU()
-
-	// 计算每个块的维度
-	blockRows := blockSize / 8 // 每块的行数 (假设每个浮点数占 8 字节)
-	blockCols := blockSize / 8 // 每块的列数 (假设每个浮点数占 8 字节)
-
-	// 确保块的维度不超过矩阵的实际维度
-	if blockRows > a.Shape[0] {
-		blockRows = a.Shape[0]
-	}
-	if blockCols > b.Shape[1] {
-		blockCols = b.Shape[1]
-	}
-
-	var wg sync.WaitGroup
-	wg.Add(numWorkers)
-
-	// 并行处理每个块
-	for worker := 0; worker < numWorkers; worker++ {
-		go func(worker int) {
-			defer wg.Done()
-
-			// 计算当前 goroutine 需要处理的块的起始和结束索引
-			for i := worker * blockRows; i < a.Shape[0]; i += blockRows {
-				for j := worker * blockCols; j < b.Shape[1]; j += blockCols {
-					// 对每个小块进行矩阵计算
-					for k := 0; k < a.Shape[1]; k++ {
-						// 执行实际的乘法和加法操作
-						resultData[i*b.Shape[1]+j] += a.Data[i*a.Shape[1]+k] * bTransposed.Data[j*b.Shape[0]+k]
-					}
+	// 按 tileSize x tileSize 划分结果矩阵，每个块作为一个并行任务
+	rowTiles := (rows + tileSize - 1) / tileSize
+	colTiles := (cols + tileSize - 1) / tileSize
+
+	err = runTasksCtx(ctx, rowTiles*colTiles, func(t int) {
+		rowStart := (t / colTiles) * tileSize
+		colStart := (t % colTiles) * tileSize
+		rowEnd := min(rowStart+tileSize, rows)
+		colEnd := min(colStart+tileSize, cols)
+
+		// 对每个小块进行矩阵计算
+		for i := rowStart; i < rowEnd; i++ {
+			aRow := a.Data[i*inner : (i+1)*inner]
+			for j := colStart; j < colEnd; j++ {
+				bCol := bTransposed.Data[j*inner : (j+1)*inner]
+				sum := 0.0
+				for k := range aRow {
+					sum += aRow[k] * bCol[k]
 				}
+				resultData[i*cols+j] = sum
 			}
-		}(worker)
+		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 等待所有 goroutine 完成
-	wg.Wait()
-
 	// 返回新的矩阵
-	return &Array{Data: resultData, Shape: []int{a.Shape[0], b.Shape[1]}}, nil
+	return &Array{Data: resultData, Shape: []int{rows, cols}}, nil
 }
 
 // 转置矩阵（并行加速版，简单并行化）
 func (a *Array) Transpose() (*Array, error) {
+	return a.TransposeCtx(context.Background())
+}
+
+// 转置矩阵（可取消版本），按行块划分任务
+func (a *Array) TransposeCtx(ctx context.Context) (*Array, error) {
 	// 检查矩阵是否为二维
 	if len(a.Shape) != 2 {
 		return nil, fmt.Errorf("仅支持二维矩阵转置")
 	}
 
-	// 创建结果矩阵，大小为 a.Shape[1] x a.Shape[0]
-	resultData := make([]float64, a.Shape[1]*a.Shape[0])
-
-	// 设置并行的 goroutine 数量
-	numWorkers := runtime.NumCPU()
-
-	// 设置每个 goroutine 需要处理的数据范围
-	chunkSize := (a.Shape[0]*a.Shape[1] + numWorkers - 1) / numWorkers
-
-	var wg sync.WaitGroup
-	wg.Add(numWorkers)
-
-	// 并行处理每个块
-	for worker := 0; worker < numWorkers; worker++ {
-		go func(worker int) {
-			defer wg.Done()
-
-			// 计算当前 goroutine 需要处理的起始和结束索引
-			start := worker * chunkSize
-			end := start + chunkSize
-			if end > a.Shape[0]*a.Shape[1] {
-				end = a.Shape[0] * a.Shape[1]
-			}
+	rows, cols := a.Shape[0], a.Shape[1]
 
-			// 执行矩阵转置操作
-			for i := start; i < end; i++ {
-				// 计算行和列的索引
-				row := i / a.Shape[1]
-				col := i % a.Shape[1]
+	// 创建结果矩阵，大小为 a.Shape[1] x a.Shape[0]
+	resultData := make([]float64, cols*rows)
+
+	rowTiles := (rows + tileSize - 1) / tileSize
+	err := runTasksCtx(ctx, rowTiles, func(t int) {
+		rowStart := t * tileSize
+		rowEnd := min(rowStart+tileSize, rows)
+		for row := rowStart; row < rowEnd; row++ {
+			for col := 0; col < cols; col++ {
 				// 转置过程：将 (row, col) 元素转置到 (col, row)
-				resultData[col*a.Shape[0]+row] = a.Data[row*a.Shape[1]+col]
+				resultData[col*rows+row] = a.Data[row*cols+col]
 			}
-		}(worker)
+		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// 等待所有 goroutine 完成
-	wg.Wait()
-
 	// 返回新的转置矩阵
-	return &Array{Data: resultData, Shape: []int{a.Shape[1], a.Shape[0]}}, nil
+	return &Array{Data: resultData, Shape: []int{cols, rows}}, nil
 }
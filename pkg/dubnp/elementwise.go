@@ -0,0 +1,38 @@
+package dubnp
+
+// Sub 逐元素相减，支持广播
+func (a *Array) Sub(b *Array) (*Array, error) {
+	return a.Map2(b, func(x, y float64) float64 { return x - y })
+}
+
+// Mul 逐元素相乘，支持广播
+func (a *Array) Mul(b *Array) (*Array, error) {
+	return a.Map2(b, func(x, y float64) float64 { return x * y })
+}
+
+// Div 逐元素相除，支持广播
+func (a *Array) Div(b *Array) (*Array, error) {
+	return a.Map2(b, func(x, y float64) float64 { return x / y })
+}
+
+// Scale 所有元素乘以 s
+func (a *Array) Scale(s float64) *Array {
+	return a.Map(func(x float64) float64 { return x * s })
+}
+
+// AddInPlace 将 b（可广播到 a 的形状）累加到 a 上，用于梯度累加
+func (a *Array) AddInPlace(b *Array) error {
+	if sameShape(a.Shape, b.Shape) {
+		parallelChunks(len(a.Data), func(start, end int) {
+			for i := start; i < end; i++ {
+				a.Data[i] += b.Data[i]
+			}
+		})
+		return nil
+	}
+	broadcast, err := b.BroadcastTo(a.Shape...)
+	if err != nil {
+		return err
+	}
+	return a.AddInPlace(broadcast)
+}
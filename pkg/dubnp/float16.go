@@ -0,0 +1,174 @@
+package dubnp
+
+import (
+	"math"
+	"sync"
+)
+
+// Float16 IEEE 754 半精度浮点数（1 位符号，5 位指数，10 位尾数）
+type Float16 uint16
+
+// BFloat16 brain 浮点数（1 位符号，8 位指数，7 位尾数），即 float32 的高 16 位
+type BFloat16 uint16
+
+const (
+	float16ExpBits   = 5
+	float16MantBits  = 10
+	bfloat16ExpBits  = 8
+	bfloat16MantBits = 7
+)
+
+// Float16FromFloat64 将 float64 转换为 Float16，采用就近舍入（偶数优先）
+func Float16FromFloat64(f float64) Float16 {
+	return Float16(encodeSmallFloat(f, float16ExpBits, float16MantBits))
+}
+
+// Float16FromFloat32 将 float32 转换为 Float16，采用就近舍入（偶数优先）
+func Float16FromFloat32(f float32) Float16 {
+	// float32 到 float64 的转换是精确的，因此不会产生二次舍入
+	return Float16FromFloat64(float64(f))
+}
+
+// Float32 将 Float16 转换为 float32（精确转换）
+func (h Float16) Float32() float32 {
+	float16TableOnce.Do(initFloat16Table)
+	return float16Table[h]
+}
+
+// Float64 将 Float16 转换为 float64（精确转换）
+func (h Float16) Float64() float64 {
+	return float64(h.Float32())
+}
+
+// IsNaN 判断是否为 NaN
+func (h Float16) IsNaN() bool {
+	return h&0x7c00 == 0x7c00 && h&0x03ff != 0
+}
+
+// BFloat16FromFloat64 将 float64 转换为 BFloat16，采用就近舍入（偶数优先）
+func BFloat16FromFloat64(f float64) BFloat16 {
+	return BFloat16(encodeSmallFloat(f, bfloat16ExpBits, bfloat16MantBits))
+}
+
+// BFloat16FromFloat32 将 float32 转换为 BFloat16，采用就近舍入（偶数优先）
+func BFloat16FromFloat32(f float32) BFloat16 {
+	bits := math.Float32bits(f)
+	if bits&0x7fffffff > 0x7f800000 {
+		// NaN：保留符号与高位尾数，并置位静默位
+		return BFloat16(bits>>16 | 0x0040)
+	}
+	// 加上 0x7fff 与保留位的最低位实现偶数优先的舍入
+	bits += 0x7fff + (bits>>16)&1
+	return BFloat16(bits >> 16)
+}
+
+// Float32 将 BFloat16 转换为 float32（精确转换）
+func (h BFloat16) Float32() float32 {
+	return math.Float32frombits(uint32(h) << 16)
+}
+
+// Float64 将 BFloat16 转换为 float64（精确转换）
+func (h BFloat16) Float64() float64 {
+	return float64(h.Float32())
+}
+
+// IsNaN 判断是否为 NaN
+func (h BFloat16) IsNaN() bool {
+	return h&0x7f80 == 0x7f80 && h&0x007f != 0
+}
+
+// Float16 的 65536 个取值对应的 float32，首次使用时构建
+var (
+	float16Table     []float32
+	float16TableOnce sync.Once
+)
+
+func initFloat16Table() {
+	float16Table = make([]float32, 1<<16)
+	for i := range float16Table {
+		float16Table[i] = float32(decodeSmallFloat(uint16(i), float16ExpBits, float16MantBits))
+	}
+}
+
+// 将 float64 编码为 expBits 位指数、mantBits 位尾数的小浮点数，支持非规格化数、Inf 与 NaN
+func encodeSmallFloat(f float64, expBits, mantBits uint) uint16 {
+	bits := math.Float64bits(f)
+	sign := uint16(bits>>63) << (expBits + mantBits)
+	exp := int(bits>>52) & 0x7ff
+	mant := bits & (1<<52 - 1)
+
+	maxExp := uint64(1)<<expBits - 1
+	bias := 1<<(expBits-1) - 1
+	inf := sign | uint16(maxExp<<mantBits)
+
+	if exp == 0x7ff {
+		if mant != 0 {
+			// NaN：置位静默位并尽量保留高位尾数
+			return inf | 1<<(mantBits-1) | uint16(mant>>(52-mantBits))
+		}
+		return inf
+	}
+	if exp == 0 {
+		// float64 的零与非规格化数远小于目标类型的最小值
+		return sign
+	}
+
+	// 目标类型中的带偏移指数
+	e := exp - 1023 + bias
+	full := mant | 1<<52
+
+	var q uint64
+	if e >= 1 {
+		// 规格化数：截掉多余的尾数位后舍入，进位会自然进入指数位
+		shift := 52 - mantBits
+		q = roundHalfEven(uint64(e)<<mantBits|mant>>shift, mant, shift)
+	} else {
+		// 非规格化数：以最小非规格化数为单位表示
+		shift := uint(53-int(mantBits)) + uint(-e)
+		if shift > 53 {
+			return sign
+		}
+		q = roundHalfEven(full>>shift, full, shift)
+	}
+
+	if q >= maxExp<<mantBits {
+		return inf
+	}
+	return sign | uint16(q)
+}
+
+// 按被截掉的低 shift 位对 q 做就近舍入（偶数优先）
+func roundHalfEven(q, bits uint64, shift uint) uint64 {
+	rem := bits & (1<<shift - 1)
+	halfway := uint64(1) << (shift - 1)
+	if rem > halfway || (rem == halfway && q&1 == 1) {
+		q++
+	}
+	return q
+}
+
+// 将小浮点数解码为 float64
+func decodeSmallFloat(h uint16, expBits, mantBits uint) float64 {
+	maxExp := uint16(1)<<expBits - 1
+	bias := 1<<(expBits-1) - 1
+
+	negative := h>>(expBits+mantBits) != 0
+	exp := (h >> mantBits) & maxExp
+	mant := h & (1<<mantBits - 1)
+
+	var value float64
+	switch {
+	case exp == maxExp && mant != 0:
+		return math.NaN()
+	case exp == maxExp:
+		value = math.Inf(1)
+	case exp == 0:
+		value = math.Ldexp(float64(mant), 1-bias-int(mantBits))
+	default:
+		value = math.Ldexp(float64(1<<mantBits|mant), int(exp)-bias-int(mantBits))
+	}
+	if negative {
+		value = -value
+	}
+	return value
+}
@@ -0,0 +1,273 @@
+package dubnp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+)
+
+// Half 半精度元素类型的约束
+type Half interface {
+	Float16 | BFloat16
+	Float32() float32
+}
+
+// HalfArray 以半精度存储的数组，用于减小 cell 间传输与检查点的体积
+type HalfArray[T Half] struct {
+	Data  []T   // 存储数据的扁平化数组
+	Shape []int // 数组的形状（维度）
+}
+
+// Float16Array 以 Float16 存储的数组
+type Float16Array = HalfArray[Float16]
+
+// BFloat16Array 以 BFloat16 存储的数组
+type BFloat16Array = HalfArray[BFloat16]
+
+// NewHalfArray 创建新的半精度数组
+func NewHalfArray[T Half](data []T, shape []int) (*HalfArray[T], error) {
+	if shapeSize(shape) != len(data) {
+		return nil, errors.New("数据大小与形状不匹配")
+	}
+	return &HalfArray[T]{Data: data, Shape: shape}, nil
+}
+
+// 计算形状对应的元素个数
+func shapeSize(shape []int) int {
+	totalSize := 1
+	for _, s := range shape {
+		totalSize *= s
+	}
+	return totalSize
+}
+
+// 返回 float32 到 T 的转换函数
+func halfEncoder[T Half]() func(float32) T {
+	var zero T
+	if _, ok := any(zero).(Float16); ok {
+		return func(f float32) T { return T(Float16FromFloat32(f)) }
+	}
+	return func(f float32) T { return T(BFloat16FromFloat32(f)) }
+}
+
+// 返回 float64 到 T 的转换函数（直接舍入，不经过 float32）
+func halfEncoder64[T Half]() func(float64) T {
+	var zero T
+	if _, ok := any(zero).(Float16); ok {
+		return func(f float64) T { return T(Float16FromFloat64(f)) }
+	}
+	return func(f float64) T { return T(BFloat16FromFloat64(f)) }
+}
+
+// 按块并行执行 n 个元素的转换
+func parallelChunks(n int, fn func(start, end int)) {
+	numTasks := (n + reduceChunkSize - 1) / reduceChunkSize
+	// 使用 Background，不会被取消
+	_ = runTasksCtx(context.Background(), numTasks, func(t int) {
+		start := t * reduceChunkSize
+		fn(start, min(start+reduceChunkSize, n))
+	})
+}
+
+// ConvertFromFloat32 将 float32 批量转换为半精度，dst 与 src 长度必须一致
+func ConvertFromFloat32[T Half](dst []T, src []float32) {
+	encode := halfEncoder[T]()
+	parallelChunks(len(src), func(start, end int) {
+		for i := start; i < end; i++ {
+			dst[i] = encode(src[i])
+		}
+	})
+}
+
+// ConvertToFloat32 将半精度批量转换为 float32，dst 与 src 长度必须一致
+func ConvertToFloat32[T Half](dst []float32, src []T) {
+	parallelChunks(len(src), func(start, end int) {
+		for i := start; i < end; i++ {
+			dst[i] = src[i].Float32()
+		}
+	})
+}
+
+// ConvertFromFloat64 将 float64 批量转换为半精度，dst 与 src 长度必须一致
+func ConvertFromFloat64[T Half](dst []T, src []float64) {
+	encode := halfEncoder64[T]()
+	parallelChunks(len(src), func(start, end int) {
+		for i := start; i < end; i++ {
+			dst[i] = encode(src[i])
+		}
+	})
+}
+
+// ConvertToFloat64 将半精度批量转换为 float64，dst 与 src 长度必须一致
+func ConvertToFloat64[T Half](dst []float64, src []T) {
+	parallelChunks(len(src), func(start, end int) {
+		for i := start; i < end; i++ {
+			dst[i] = float64(src[i].Float32())
+		}
+	})
+}
+
+// ToFloat16 将数组转换为 Float16 存储
+func (a *Array) ToFloat16() *Float16Array {
+	return toHalfArray[Float16](a)
+}
+
+// ToBFloat16 将数组转换为 BFloat16 存储
+func (a *Array) ToBFloat16() *BFloat16Array {
+	return toHalfArray[BFloat16](a)
+}
+
+func toHalfArray[T Half](a *Array) *HalfArray[T] {
+	data := make([]T, len(a.Data))
+	ConvertFromFloat64(data, a.Data)
+	return &HalfArray[T]{Data: data, Shape: append([]int(nil), a.Shape...)}
+}
+
+// ToArray 将半精度数组转换为 float64 数组
+func (h *HalfArray[T]) ToArray() *Array {
+	data := make([]float64, len(h.Data))
+	ConvertToFloat64(data, h.Data)
+	return &Array{Data: data, Shape: append([]int(nil), h.Shape...)}
+}
+
+// Float32s 将数据转换为 float32 切片
+func (h *HalfArray[T]) Float32s() []float32 {
+	data := make([]float32, len(h.Data))
+	ConvertToFloat32(data, h.Data)
+	return data
+}
+
+// Bytes 按小端序编码数据，每个元素 2 字节
+func (h *HalfArray[T]) Bytes() []byte {
+	buf := make([]byte, 2*len(h.Data))
+	for i, v := range h.Data {
+		binary.LittleEndian.PutUint16(buf[2*i:], uint16(v))
+	}
+	return buf
+}
+
+// HalfArrayFromBytes 从小端序字节解码半精度数组
+func HalfArrayFromBytes[T Half](buf []byte, shape []int) (*HalfArray[T], error) {
+	if len(buf) != 2*shapeSize(shape) {
+		return nil, errors.New("字节长度与形状不匹配")
+	}
+	data := make([]T, len(buf)/2)
+	for i := range data {
+		data[i] = T(binary.LittleEndian.Uint16(buf[2*i:]))
+	}
+	return &HalfArray[T]{Data: data, Shape: append([]int(nil), shape...)}, nil
+}
+
+// 检查两个数组形状是否完全相同
+func sameShape(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// 逐元素运算：以 float32 计算，最后舍入回半精度
+func (h *HalfArray[T]) elementwise(b *HalfArray[T], op func(x, y float32) float32) (*HalfArray[T], error) {
+	if !sameShape(h.Shape, b.Shape) {
+		return nil, errors.New("矩阵的形状不匹配")
+	}
+	encode := halfEncoder[T]()
+	data := make([]T, len(h.Data))
+	parallelChunks(len(data), func(start, end int) {
+		for i := start; i < end; i++ {
+			data[i] = encode(op(h.Data[i].Float32(), b.Data[i].Float32()))
+		}
+	})
+	return &HalfArray[T]{Data: data, Shape: append([]int(nil), h.Shape...)}, nil
+}
+
+// Add 逐元素相加（float32 计算）
+func (h *HalfArray[T]) Add(b *HalfArray[T]) (*HalfArray[T], error) {
+	return h.elementwise(b, func(x, y float32) float32 { return x + y })
+}
+
+// Mul 逐元素相乘（float32 计算）
+func (h *HalfArray[T]) Mul(b *HalfArray[T]) (*HalfArray[T], error) {
+	return h.elementwise(b, func(x, y float32) float32 { return x * y })
+}
+
+// Sum 求所有元素之和，以 float32 累加
+func (h *HalfArray[T]) Sum() float32 {
+	numTasks := (len(h.Data) + reduceChunkSize - 1) / reduceChunkSize
+	partials := make([]float32, numTasks)
+	parallelChunks(len(h.Data), func(start, end int) {
+		var acc float32
+		for _, v := range h.Data[start:end] {
+			acc += v.Float32()
+		}
+		partials[start/reduceChunkSize] = acc
+	})
+
+	// 按块顺序合并，保证结果确定
+	var sum float32
+	for _, p := range partials {
+		sum += p
+	}
+	return sum
+}
+
+// Multiply 半精度矩阵乘法，输入先展开为 float32，以 float32 累加后舍入回半精度
+func (h *HalfArray[T]) Multiply(b *HalfArray[T]) (*HalfArray[T], error) {
+	return h.MultiplyCtx(context.Background(), b)
+}
+
+// MultiplyCtx 半精度矩阵乘法（可取消版本）
+func (h *HalfArray[T]) MultiplyCtx(ctx context.Context, b *HalfArray[T]) (*HalfArray[T], error) {
+	// 检查矩阵维度是否符合乘法要求
+	if len(h.Shape) != 2 || len(b.Shape) != 2 {
+		return nil, errors.New("仅支持二维矩阵乘法")
+	}
+	if h.Shape[1] != b.Shape[0] {
+		return nil, errors.New("矩阵的维度不匹配，无法进行乘法运算")
+	}
+
+	rows, inner, cols := h.Shape[0], h.Shape[1], b.Shape[1]
+
+	// 展开 a 与转置后的 b，优化列访问
+	aData := h.Float32s()
+	bTransposed := make([]float32, inner*cols)
+	for k := 0; k < inner; k++ {
+		for j := 0; j < cols; j++ {
+			bTransposed[j*inner+k] = b.Data[k*cols+j].Float32()
+		}
+	}
+
+	encode := halfEncoder[T]()
+	resultData := make([]T, rows*cols)
+
+	rowTiles := (rows + tileSize - 1) / tileSize
+	colTiles := (cols + tileSize - 1) / tileSize
+	err := runTasksCtx(ctx, rowTiles*colTiles, func(t int) {
+		rowStart := (t / colTiles) * tileSize
+		colStart := (t % colTiles) * tileSize
+		rowEnd := min(rowStart+tileSize, rows)
+		colEnd := min(colStart+tileSize, cols)
+
+		for i := rowStart; i < rowEnd; i++ {
+			aRow := aData[i*inner : (i+1)*inner]
+			for j := colStart; j < colEnd; j++ {
+				bCol := bTransposed[j*inner : (j+1)*inner]
+				var sum float32
+				for k := range aRow {
+					sum += aRow[k] * bCol[k]
+				}
+				resultData[i*cols+j] = encode(sum)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &HalfArray[T]{Data: resultData, Shape: []int{rows, cols}}, nil
+}
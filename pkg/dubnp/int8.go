@@ -0,0 +1,156 @@
+package dubnp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Int8Array 以 int8 存储的量化数组，实际值为 scale * (q - zeroPoint)，量化参数由调用方保存
+type Int8Array struct {
+	Data  []int8 // 存储数据的扁平化数组
+	Shape []int  // 数组的形状（维度）
+}
+
+// Int32Array int8 矩阵乘法的 int32 累加结果
+type Int32Array struct {
+	Data  []int32
+	Shape []int
+}
+
+// NewInt8Array 创建新的 int8 数组
+func NewInt8Array(data []int8, shape []int) (*Int8Array, error) {
+	if shapeSize(shape) != len(data) {
+		return nil, errors.New("数据大小与形状不匹配")
+	}
+	return &Int8Array{Data: data, Shape: shape}, nil
+}
+
+// int8 矩阵乘法允许的最大内积长度：零点修正后每个乘积的绝对值不超过 255*255，
+// 超过该长度时 int32 累加可能溢出
+const maxInt8Inner = math.MaxInt32 / (255 * 255)
+
+// 检查量化参数并返回沿 axis 的通道数与每个通道内连续元素的个数。
+// axis 为 -1 时为逐张量量化，scale 与 zero 各有一个值；否则长度等于 shape[axis]
+func quantLayout(shape []int, scale []float64, zero []int32, axis int) (channels, inner int, err error) {
+	channels, inner = 1, shapeSize(shape)
+	if axis >= 0 {
+		if axis >= len(shape) {
+			return 0, 0, fmt.Errorf("量化轴 %d 超出形状 %v 的范围", axis, shape)
+		}
+		channels, inner = shape[axis], shapeSize(shape[axis+1:])
+	}
+	if len(scale) != channels || len(zero) != channels {
+		return 0, 0, fmt.Errorf("量化参数应有 %d 个，实际 scale %d 个、零点 %d 个", channels, len(scale), len(zero))
+	}
+	for i, s := range scale {
+		if !(s > 0) || math.IsInf(s, 0) {
+			return 0, 0, fmt.Errorf("第 %d 个 scale 必须为有限正数: %v", i, s)
+		}
+		if zero[i] < math.MinInt8 || zero[i] > math.MaxInt8 {
+			return 0, 0, fmt.Errorf("第 %d 个零点超出 int8 范围: %d", i, zero[i])
+		}
+	}
+	return channels, inner, nil
+}
+
+// QuantizeInt8 按 q = clamp(round(x/scale) + zero, -128, 127) 量化，舍入方式为四舍六入五成双。
+// axis 为 -1 时逐张量量化，否则沿 axis 逐通道量化
+func (a *Array) QuantizeInt8(scale []float64, zero []int32, axis int) (*Int8Array, error) {
+	channels, inner, err := quantLayout(a.Shape, scale, zero, axis)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]int8, len(a.Data))
+	parallelChunks(len(a.Data), func(start, end int) {
+		for i := start; i < end; i++ {
+			c := (i / inner) % channels
+			q := math.RoundToEven(a.Data[i]/scale[c]) + float64(zero[c])
+			out[i] = int8(math.Max(math.MinInt8, math.Min(math.MaxInt8, q)))
+		}
+	})
+	return &Int8Array{Data: out, Shape: append([]int(nil), a.Shape...)}, nil
+}
+
+// Dequantize 按 x = scale * (q - zero) 还原为 float64，参数含义与 QuantizeInt8 相同
+func (q *Int8Array) Dequantize(scale []float64, zero []int32, axis int) (*Array, error) {
+	channels, inner, err := quantLayout(q.Shape, scale, zero, axis)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(q.Data))
+	parallelChunks(len(q.Data), func(start, end int) {
+		for i := start; i < end; i++ {
+			c := (i / inner) % channels
+			out[i] = scale[c] * float64(int32(q.Data[i])-zero[c])
+		}
+	})
+	return &Array{Data: out, Shape: append([]int(nil), q.Shape...)}, nil
+}
+
+// Int8MatMulTransB 计算 (a - za)(b - zb)^T，a 为 (M, K)，b 为 (N, K)，结果为 (M, N)，以 int32 累加。
+// za 为 a 每行的零点，zb 为 b 每行的零点，长度为 1 时所有行共用。
+// b 按行存储使两个操作数的内积都是连续访问，适合 (out, in) 布局的权重
+func Int8MatMulTransB(a, b *Int8Array, za, zb []int32) (*Int32Array, error) {
+	return Int8MatMulTransBCtx(context.Background(), a, b, za, zb)
+}
+
+// Int8MatMulTransBCtx int8 矩阵乘法（可取消版本）
+func Int8MatMulTransBCtx(ctx context.Context, a, b *Int8Array, za, zb []int32) (*Int32Array, error) {
+	if len(a.Shape) != 2 || len(b.Shape) != 2 {
+		return nil, errors.New("仅支持二维矩阵乘法")
+	}
+	rows, inner, cols := a.Shape[0], a.Shape[1], b.Shape[0]
+	if b.Shape[1] != inner {
+		return nil, fmt.Errorf("矩阵的维度不匹配，无法进行乘法运算: %v 与 %v 的转置", a.Shape, b.Shape)
+	}
+	if inner > maxInt8Inner {
+		return nil, fmt.Errorf("内积长度 %d 超过 %d，int32 累加可能溢出", inner, maxInt8Inner)
+	}
+	if (len(za) != 1 && len(za) != rows) || (len(zb) != 1 && len(zb) != cols) {
+		return nil, fmt.Errorf("零点个数应为 1 或行数：a 有 %d 行 %d 个零点，b 有 %d 行 %d 个零点", rows, len(za), cols, len(zb))
+	}
+
+	// (a - za)·(b - zb) = a·b - zb·Σa - za·Σb + K·za·zb，行和只需计算一次
+	sumA, sumB := rowSums(a.Data, rows, inner), rowSums(b.Data, cols, inner)
+	result := make([]int32, rows*cols)
+	rowTiles := (rows + tileSize - 1) / tileSize
+	colTiles := (cols + tileSize - 1) / tileSize
+	err := runTasksCtx(ctx, rowTiles*colTiles, func(t int) {
+		rowStart := (t / colTiles) * tileSize
+		colStart := (t % colTiles) * tileSize
+		rowEnd := min(rowStart+tileSize, rows)
+		colEnd := min(colStart+tileSize, cols)
+
+		for i := rowStart; i < rowEnd; i++ {
+			aRow := a.Data[i*inner : (i+1)*inner]
+			zi := za[min(i, len(za)-1)]
+			for j := colStart; j < colEnd; j++ {
+				bRow := b.Data[j*inner : (j+1)*inner]
+				var dot int32
+				for k := range aRow {
+					dot += int32(aRow[k]) * int32(bRow[k])
+				}
+				zj := zb[min(j, len(zb)-1)]
+				// 中间项可能溢出，但 int32 按补码回绕，最终结果在范围内时仍然正确
+				result[i*cols+j] = dot - zj*sumA[i] - zi*sumB[j] + int32(inner)*zi*zj
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Int32Array{Data: result, Shape: []int{rows, cols}}, nil
+}
+
+// 每行元素之和
+func rowSums(data []int8, rows, inner int) []int32 {
+	sums := make([]int32, rows)
+	for i := range sums {
+		for _, v := range data[i*inner : (i+1)*inner] {
+			sums[i] += int32(v)
+		}
+	}
+	return sums
+}
@@ -0,0 +1,122 @@
+package dubnp
+
+import (
+	"errors"
+	"sort"
+)
+
+// InterpMethod 插值方法
+type InterpMethod int
+
+const (
+	// InterpLinear 分段线性插值，超出范围时取端点值
+	InterpLinear InterpMethod = iota
+	// InterpCubic 自然三次样条插值，超出范围时按端点处的多项式外推
+	InterpCubic
+)
+
+// 检查插值节点：长度一致、至少两个点、严格递增
+func checkInterpNodes(xp, fp []float64) error {
+	if len(xp) != len(fp) {
+		return errors.New("xp 与 fp 的长度不一致")
+	}
+	if len(xp) < 2 {
+		return errors.New("插值至少需要两个节点")
+	}
+	for i := 1; i < len(xp); i++ {
+		if xp[i] <= xp[i-1] {
+			return errors.New("xp 必须严格递增")
+		}
+	}
+	return nil
+}
+
+// Interp 一维插值，xp 与 fp 为一维数组，结果与 x 形状相同
+func Interp(x, xp, fp *Array, method InterpMethod) (*Array, error) {
+	if len(xp.Shape) != 1 || len(fp.Shape) != 1 {
+		return nil, errors.New("xp 与 fp 必须是一维数组")
+	}
+	if err := checkInterpNodes(xp.Data, fp.Data); err != nil {
+		return nil, err
+	}
+
+	switch method {
+	case InterpLinear:
+		return x.Map(func(v float64) float64 { return linearAt(xp.Data, fp.Data, v) }), nil
+	case InterpCubic:
+		spline, err := NewCubicSpline(xp.Data, fp.Data)
+		if err != nil {
+			return nil, err
+		}
+		return x.Map(spline.At), nil
+	default:
+		return nil, errors.New("未知的插值方法")
+	}
+}
+
+// 找到满足 xp[i] <= v < xp[i+1] 的区间 i，结果限制在 [0, len(xp)-2]
+func searchInterval(xp []float64, v float64) int {
+	i := sort.SearchFloat64s(xp, v) - 1
+	return min(max(i, 0), len(xp)-2)
+}
+
+func linearAt(xp, fp []float64, v float64) float64 {
+	if v <= xp[0] {
+		return fp[0]
+	}
+	if v >= xp[len(xp)-1] {
+		return fp[len(fp)-1]
+	}
+	i := searchInterval(xp, v)
+	t := (v - xp[i]) / (xp[i+1] - xp[i])
+	return fp[i] + t*(fp[i+1]-fp[i])
+}
+
+// CubicSpline 自然三次样条（两端二阶导数为 0）
+type CubicSpline struct {
+	xp, fp []float64
+	m      []float64 // 每个节点处的二阶导数
+}
+
+// NewCubicSpline 根据严格递增的节点构造自然三次样条
+func NewCubicSpline(xp, fp []float64) (*CubicSpline, error) {
+	if err := checkInterpNodes(xp, fp); err != nil {
+		return nil, err
+	}
+	n := len(xp)
+	m := make([]float64, n)
+
+	// 用追赶法求解三对角方程组，m[0] = m[n-1] = 0
+	if n > 2 {
+		diag := make([]float64, n)
+		rhs := make([]float64, n)
+		for i := 1; i < n-1; i++ {
+			h0, h1 := xp[i]-xp[i-1], xp[i+1]-xp[i]
+			diag[i] = 2 * (h0 + h1)
+			rhs[i] = 6 * ((fp[i+1]-fp[i])/h1 - (fp[i]-fp[i-1])/h0)
+			if i > 1 {
+				// 消去下对角元素 h0
+				w := h0 / diag[i-1]
+				diag[i] -= w * h0
+				rhs[i] -= w * rhs[i-1]
+			}
+		}
+		for i := n - 2; i >= 1; i-- {
+			m[i] = rhs[i]
+			if i < n-2 {
+				m[i] -= (xp[i+1] - xp[i]) * m[i+1]
+			}
+			m[i] /= diag[i]
+		}
+	}
+	return &CubicSpline{xp: xp, fp: fp, m: m}, nil
+}
+
+// At 计算样条在 v 处的值
+func (s *CubicSpline) At(v float64) float64 {
+	i := searchInterval(s.xp, v)
+	h := s.xp[i+1] - s.xp[i]
+	a := (s.xp[i+1] - v) / h
+	b := (v - s.xp[i]) / h
+	return a*s.fp[i] + b*s.fp[i+1] + ((a*a*a-a)*s.m[i]+(b*b*b-b)*s.m[i+1])*h*h/6
+}
@@ -0,0 +1,267 @@
+package dubnp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Order 多维遍历的顺序
+type Order int
+
+const (
+	// COrder 行优先，最后一维变化最快
+	COrder Order = iota
+	// FOrder 列优先，第一维变化最快
+	FOrder
+)
+
+// Strides 返回行优先存储下每一维的步长（单位：元素）
+func (a *Array) Strides() []int {
+	return contiguousStrides(a.Shape)
+}
+
+func contiguousStrides(shape []int) []int {
+	strides := make([]int, len(shape))
+	stride := 1
+	for i := len(shape) - 1; i >= 0; i-- {
+		strides[i] = stride
+		stride *= shape[i]
+	}
+	return strides
+}
+
+// FlatIndex 将多维下标转换为 Data 中的扁平下标
+func (a *Array) FlatIndex(index ...int) (int, error) {
+	if len(index) != len(a.Shape) {
+		return 0, fmt.Errorf("下标维度 %d 与数组维度 %d 不一致", len(index), len(a.Shape))
+	}
+	flat := 0
+	for i, idx := range index {
+		if idx < 0 || idx >= a.Shape[i] {
+			return 0, fmt.Errorf("下标 %v 超出形状 %v", index, a.Shape)
+		}
+		flat = flat*a.Shape[i] + idx
+	}
+	return flat, nil
+}
+
+// BroadcastShapes 按 numpy 规则计算多个形状广播后的形状
+func BroadcastShapes(shapes ...[]int) ([]int, error) {
+	ndim := 0
+	for _, s := range shapes {
+		ndim = max(ndim, len(s))
+	}
+	result := make([]int, ndim)
+	for i := range result {
+		result[i] = 1
+	}
+	for _, s := range shapes {
+		offset := ndim - len(s)
+		for i, d := range s {
+			switch {
+			case d == result[offset+i] || d == 1:
+			case result[offset+i] == 1:
+				result[offset+i] = d
+			default:
+				return nil, fmt.Errorf("形状 %v 无法广播到 %v", s, result)
+			}
+		}
+	}
+	return result, nil
+}
+
+// 计算 shape 广播到 target 后每一维的步长，被广播的维度步长为 0
+func broadcastStrides(shape, target []int) []int {
+	strides := make([]int, len(target))
+	own := contiguousStrides(shape)
+	offset := len(target) - len(shape)
+	for i, d := range shape {
+		if d != 1 || target[offset+i] == 1 {
+			strides[offset+i] = own[i]
+		}
+	}
+	return strides
+}
+
+// NdIterOptions 多维迭代器选项
+type NdIterOptions struct {
+	Order Order // 遍历顺序
+	// ExternalLoop 为 true 时每次迭代返回最内层的一段连续元素，
+	// 调用方用 ChunkLen 与 ChunkStride 自行完成内层循环
+	ExternalLoop bool
+}
+
+// NdIter 在一个或多个广播后的数组上同时进行多维遍历
+type NdIter struct {
+	operands []*Array
+	shape    []int   // 广播后的形状
+	strides  [][]int // 每个操作数在广播形状下的步长
+	axes     []int   // 按变化快慢排列的轴，axes[0] 变化最快
+	inner    int     // 外部循环模式下，合并为一段的轴个数（axes[:inner]）
+	chunkLen int     // 每次迭代覆盖的元素个数
+
+	index   []int // 当前（段起始处的）多维下标
+	offsets []int // 每个操作数的当前扁平下标
+	size    int
+	started bool
+	done    bool
+}
+
+// NewNdIter 创建多维迭代器，操作数按 numpy 规则广播到同一形状
+func NewNdIter(opts NdIterOptions, operands ...*Array) (*NdIter, error) {
+	if len(operands) == 0 {
+		return nil, errors.New("迭代器至少需要一个操作数")
+	}
+	shapes := make([][]int, len(operands))
+	for i, op := range operands {
+		shapes[i] = op.Shape
+	}
+	shape, err := BroadcastShapes(shapes...)
+	if err != nil {
+		return nil, err
+	}
+
+	it := &NdIter{
+		operands: operands,
+		shape:    shape,
+		strides:  make([][]int, len(operands)),
+		axes:     make([]int, len(shape)),
+		index:    make([]int, len(shape)),
+		offsets:  make([]int, len(operands)),
+		size:     shapeSize(shape),
+		chunkLen: 1,
+	}
+	for i, op := range operands {
+		it.strides[i] = broadcastStrides(op.Shape, shape)
+	}
+	for i := range it.axes {
+		if opts.Order == FOrder {
+			it.axes[i] = i
+		} else {
+			it.axes[i] = len(shape) - 1 - i
+		}
+	}
+
+	if opts.ExternalLoop && len(shape) > 0 {
+		// 合并所有操作数在内存中都能连续步进的相邻轴
+		it.inner = 1
+		it.chunkLen = shape[it.axes[0]]
+		for it.inner < len(it.axes) && it.canCoalesce(it.axes[it.inner-1], it.axes[it.inner]) {
+			it.chunkLen *= shape[it.axes[it.inner]]
+			it.inner++
+		}
+	}
+	return it, nil
+}
+
+// 判断轴 next 能否与变化更快的轴 prev 合并
+func (it *NdIter) canCoalesce(prev, next int) bool {
+	for _, s := range it.strides {
+		if s[next] != s[prev]*it.shape[prev] {
+			return false
+		}
+	}
+	return true
+}
+
+// Shape 返回广播后的形状
+func (it *NdIter) Shape() []int {
+	return it.shape
+}
+
+// Size 返回广播后的元素总数
+func (it *NdIter) Size() int {
+	return it.size
+}
+
+// Next 前进到下一个元素（或下一段），遍历结束时返回 false
+func (it *NdIter) Next() bool {
+	if it.done {
+		return false
+	}
+	if !it.started {
+		it.started = true
+		it.done = it.size == 0
+		return !it.done
+	}
+	// 从变化最快的外层轴开始进位
+	for _, ax := range it.axes[it.inner:] {
+		if it.index[ax]+1 < it.shape[ax] {
+			it.index[ax]++
+			for op, s := range it.strides {
+				it.offsets[op] += s[ax]
+			}
+			return true
+		}
+		for op, s := range it.strides {
+			it.offsets[op] -= s[ax] * it.index[ax]
+		}
+		it.index[ax] = 0
+	}
+	it.done = true
+	return false
+}
+
+// Reset 回到遍历起点
+func (it *NdIter) Reset() {
+	for i := range it.index {
+		it.index[i] = 0
+	}
+	for i := range it.offsets {
+		it.offsets[i] = 0
+	}
+	it.started = false
+	it.done = false
+}
+
+// Index 返回当前元素（外部循环模式下为段起点）的多维下标，调用方不应修改
+func (it *NdIter) Index() []int {
+	return it.index
+}
+
+// Offset 返回第 op 个操作数当前元素在 Data 中的下标
+func (it *NdIter) Offset(op int) int {
+	return it.offsets[op]
+}
+
+// Value 返回第 op 个操作数的当前元素
+func (it *NdIter) Value(op int) float64 {
+	return it.operands[op].Data[it.offsets[op]]
+}
+
+// ChunkLen 返回每次迭代覆盖的元素个数，非外部循环模式下为 1
+func (it *NdIter) ChunkLen() int {
+	return it.chunkLen
+}
+
+// ChunkStride 返回第 op 个操作数在段内相邻元素之间的步长
+func (it *NdIter) ChunkStride(op int) int {
+	if it.inner == 0 {
+		return 0
+	}
+	return it.strides[op][it.axes[0]]
+}
+
+// 将行优先、非外部循环的迭代器定位到第 flat 个元素，用于并行切分
+func (it *NdIter) seek(flat int) {
+	for i := range it.offsets {
+		it.offsets[i] = 0
+	}
+	for ax := len(it.shape) - 1; ax >= 0; ax-- {
+		it.index[ax] = flat % it.shape[ax]
+		flat /= it.shape[ax]
+		for op, s := range it.strides {
+			it.offsets[op] += s[ax] * it.index[ax]
+		}
+	}
+	it.started = true
+	it.done = false
+}
+
+// 复制迭代器的状态，使多个 goroutine 可以独立遍历
+func (it *NdIter) clone() *NdIter {
+	c := *it
+	c.index = append([]int(nil), it.index...)
+	c.offsets = append([]int(nil), it.offsets...)
+	return &c
+}
@@ -0,0 +1,225 @@
+package dubnp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// 多项式系数按降幂排列，p[0] 为最高次项系数（与 numpy 一致）
+
+// PolyFit 用最小二乘拟合 deg 次多项式，返回降幂排列的系数
+func PolyFit(x, y []float64, deg int) ([]float64, error) {
+	return PolyFitCtx(context.Background(), x, y, deg)
+}
+
+// PolyFitCtx 可取消的 PolyFit，每次 Householder 变换前检查 ctx，进度按已消去的列数回调
+func PolyFitCtx(ctx context.Context, x, y []float64, deg int) ([]float64, error) {
+	if len(x) != len(y) {
+		return nil, errors.New("x 与 y 的长度不一致")
+	}
+	if deg < 0 {
+		return nil, errors.New("多项式次数不能为负")
+	}
+	m, n := len(x), deg+1
+	if m < n {
+		return nil, errors.New("采样点个数少于多项式系数个数")
+	}
+
+	// 构造范德蒙德矩阵，按列存储以便 Householder 变换
+	cols := make([][]float64, n)
+	for j := range cols {
+		cols[j] = make([]float64, m)
+		for i, xi := range x {
+			cols[j][i] = math.Pow(xi, float64(deg-j))
+		}
+	}
+	rhs := append([]float64(nil), y...)
+
+	// Householder QR 分解，同时把变换作用到右端项
+	progress := progressFromContext(ctx)
+	for k := 0; k < n; k++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		col := cols[k]
+		norm := 0.0
+		for i := k; i < m; i++ {
+			norm = math.Hypot(norm, col[i])
+		}
+		if norm == 0 {
+			return nil, errors.New("范德蒙德矩阵秩亏，无法拟合")
+		}
+		if col[k] > 0 {
+			norm = -norm
+		}
+		// v = col[k:] - norm*e1，H = I - 2vv^T/(v^T v)
+		v := append([]float64(nil), col[k:]...)
+		v[0] -= norm
+		vv := 0.0
+		for _, vi := range v {
+			vv += vi * vi
+		}
+		reflect := func(target []float64) {
+			dot := 0.0
+			for i, vi := range v {
+				dot += vi * target[k+i]
+			}
+			scale := 2 * dot / vv
+			for i, vi := range v {
+				target[k+i] -= scale * vi
+			}
+		}
+		for j := k; j < n; j++ {
+			reflect(cols[j])
+		}
+		reflect(rhs)
+		if progress != nil {
+			progress(k+1, n)
+		}
+	}
+
+	// 回代求解上三角方程组 R p = Q^T y
+	p := make([]float64, n)
+	for k := n - 1; k >= 0; k-- {
+		sum := rhs[k]
+		for j := k + 1; j < n; j++ {
+			sum -= cols[j][k] * p[j]
+		}
+		if math.Abs(cols[k][k]) < 1e-300 {
+			return nil, errors.New("范德蒙德矩阵秩亏，无法拟合")
+		}
+		p[k] = sum / cols[k][k]
+	}
+	return p, nil
+}
+
+// PolyVal 用秦九韶（Horner）算法逐元素计算多项式的值
+func PolyVal(p []float64, x *Array) *Array {
+	return x.Map(func(v float64) float64 {
+		return polyAt(p, v)
+	})
+}
+
+func polyAt(p []float64, v float64) float64 {
+	result := 0.0
+	for _, c := range p {
+		result = result*v + c
+	}
+	return result
+}
+
+// PolyRoots 用 Aberth-Ehrlich 迭代求多项式的全部复数根，结果按实部、虚部排序
+func PolyRoots(p []float64) ([]complex128, error) {
+	return PolyRootsCtx(context.Background(), p)
+}
+
+// PolyRootsCtx 可取消的 PolyRoots，每轮迭代前检查 ctx
+func PolyRootsCtx(ctx context.Context, p []float64) ([]complex128, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// 去掉最高次的零系数
+	for len(p) > 0 && p[0] == 0 {
+		p = p[1:]
+	}
+	if len(p) == 0 {
+		return nil, errors.New("零多项式的根不确定")
+	}
+
+	// 末尾的零系数对应 0 根
+	var roots []complex128
+	for len(p) > 1 && p[len(p)-1] == 0 {
+		roots = append(roots, 0)
+		p = p[:len(p)-1]
+	}
+
+	n := len(p) - 1
+	if n > 0 {
+		z, err := aberthRoots(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, z...)
+	}
+
+	sort.Slice(roots, func(i, j int) bool {
+		if real(roots[i]) != real(roots[j]) {
+			return real(roots[i]) < real(roots[j])
+		}
+		return imag(roots[i]) < imag(roots[j])
+	})
+	return roots, nil
+}
+
+// Aberth-Ehrlich 迭代，p 的最高次与最低次系数均不为 0，ctx 取消时返回 ctx.Err()
+func aberthRoots(ctx context.Context, p []float64) ([]complex128, error) {
+	n := len(p) - 1
+
+	// 初值取在 Cauchy 根界的圆上，错开角度避免对称性导致的停滞
+	bound := 0.0
+	for _, c := range p[1:] {
+		bound = max(bound, math.Abs(c/p[0]))
+	}
+	radius := 1 + bound
+	z := make([]complex128, n)
+	for k := range z {
+		angle := 2*math.Pi*float64(k)/float64(n) + 0.4
+		z[k] = cmplx.Rect(radius, angle)
+	}
+
+	// 导数的系数
+	dp := make([]float64, n)
+	for i := range dp {
+		dp[i] = p[i] * float64(n-i)
+	}
+
+	evaluate := func(coeffs []float64, v complex128) complex128 {
+		result := complex128(0)
+		for _, c := range coeffs {
+			result = result*v + complex(c, 0)
+		}
+		return result
+	}
+
+	for iter := 0; iter < 500; iter++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		converged := true
+		for k := range z {
+			value := evaluate(p, z[k])
+			if value == 0 {
+				continue
+			}
+			ratio := value / evaluate(dp, z[k])
+			sum := complex128(0)
+			for j := range z {
+				if j != k {
+					sum += 1 / (z[k] - z[j])
+				}
+			}
+			step := ratio / (1 - ratio*sum)
+			z[k] -= step
+			if cmplx.Abs(step) > 1e-14*max(1, cmplx.Abs(z[k])) {
+				converged = false
+			}
+		}
+		if converged {
+			break
+		}
+	}
+
+	// 去掉可忽略的实部或虚部，虚部可忽略的根视为实根
+	for k, r := range z {
+		tol := 1e-12 * max(1, cmplx.Abs(r))
+		if math.Abs(imag(r)) <= tol {
+			z[k] = complex(real(r), 0)
+		} else if math.Abs(real(r)) <= tol {
+			z[k] = complex(0, imag(r))
+		}
+	}
+	return z, nil
+}
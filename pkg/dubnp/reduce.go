@@ -0,0 +1,226 @@
+package dubnp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// 全局归约时每个并行任务处理的元素个数
+const reduceChunkSize = tileSize * tileSize
+
+// 规范化轴编号，支持负数（-1 表示最后一维）
+func normalizeAxis(axis, ndim int) (int, error) {
+	if axis < 0 {
+		axis += ndim
+	}
+	if axis < 0 || axis >= ndim {
+		return 0, fmt.Errorf("轴 %d 超出维度范围 %d", axis, ndim)
+	}
+	return axis, nil
+}
+
+// 将形状按 axis 分解为 outer x n x inner，沿 axis 的第 k 个元素位于 o*n*inner + k*inner + i
+func axisGeometry(shape []int, axis int) (outer, n, inner int) {
+	outer, inner = 1, 1
+	for i := 0; i < axis; i++ {
+		outer *= shape[i]
+	}
+	for i := axis + 1; i < len(shape); i++ {
+		inner *= shape[i]
+	}
+	return outer, shape[axis], inner
+}
+
+// 计算沿 axis 归约后的形状
+func reducedShape(shape []int, axis int, keepDims bool) []int {
+	result := make([]int, 0, len(shape))
+	for i, s := range shape {
+		if i == axis {
+			if keepDims {
+				result = append(result, 1)
+			}
+			continue
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+// 对全部元素做归约，各块的部分结果按块顺序合并，保证结果确定
+func (a *Array) reduceAllCtx(ctx context.Context, init float64, combine func(acc, x float64) float64) (float64, error) {
+	numTasks := (len(a.Data) + reduceChunkSize - 1) / reduceChunkSize
+	partials := make([]float64, numTasks)
+
+	err := runTasksCtx(ctx, numTasks, func(t int) {
+		start := t * reduceChunkSize
+		end := min(start+reduceChunkSize, len(a.Data))
+		acc := init
+		for _, x := range a.Data[start:end] {
+			acc = combine(acc, x)
+		}
+		partials[t] = acc
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	result := init
+	for _, p := range partials {
+		result = combine(result, p)
+	}
+	return result, nil
+}
+
+// 沿 axis 归约，按 (outer, inner 块) 划分并行任务
+func (a *Array) reduceAxisCtx(ctx context.Context, axis int, keepDims bool, init float64, combine func(acc, x float64) float64) (*Array, error) {
+	axis, err := normalizeAxis(axis, len(a.Shape))
+	if err != nil {
+		return nil, err
+	}
+	outer, n, inner := axisGeometry(a.Shape, axis)
+
+	resultData := make([]float64, outer*inner)
+	innerTiles := (inner + reduceChunkSize - 1) / reduceChunkSize
+
+	err = runTasksCtx(ctx, outer*innerTiles, func(t int) {
+		o := t / innerTiles
+		start := (t % innerTiles) * reduceChunkSize
+		end := min(start+reduceChunkSize, inner)
+		acc := resultData[o*inner+start : o*inner+end]
+		for i := range acc {
+			acc[i] = init
+		}
+		for k := 0; k < n; k++ {
+			lane := a.Data[o*n*inner+k*inner+start : o*n*inner+k*inner+end]
+			for i, x := range lane {
+				acc[i] = combine(acc[i], x)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Array{Data: resultData, Shape: reducedShape(a.Shape, axis, keepDims)}, nil
+}
+
+func add(acc, x float64) float64 { return acc + x }
+
+// Sum 求所有元素之和
+func (a *Array) Sum() (float64, error) {
+	return a.SumCtx(context.Background())
+}
+
+// SumCtx 求所有元素之和（可取消版本）
+func (a *Array) SumCtx(ctx context.Context) (float64, error) {
+	return a.reduceAllCtx(ctx, 0, add)
+}
+
+// SumAxis 沿 axis 求和，keepDims 为 true 时保留长度为 1 的维度
+func (a *Array) SumAxis(axis int, keepDims bool) (*Array, error) {
+	return a.SumAxisCtx(context.Background(), axis, keepDims)
+}
+
+// SumAxisCtx 沿 axis 求和（可取消版本）
+func (a *Array) SumAxisCtx(ctx context.Context, axis int, keepDims bool) (*Array, error) {
+	return a.reduceAxisCtx(ctx, axis, keepDims, 0, add)
+}
+
+// Mean 求所有元素的平均值，空数组返回 NaN
+func (a *Array) Mean() (float64, error) {
+	return a.MeanCtx(context.Background())
+}
+
+// MeanCtx 求所有元素的平均值（可取消版本）
+func (a *Array) MeanCtx(ctx context.Context) (float64, error) {
+	sum, err := a.SumCtx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return sum / float64(len(a.Data)), nil
+}
+
+// MeanAxis 沿 axis 求平均值
+func (a *Array) MeanAxis(axis int, keepDims bool) (*Array, error) {
+	return a.MeanAxisCtx(context.Background(), axis, keepDims)
+}
+
+// MeanAxisCtx 沿 axis 求平均值（可取消版本）
+func (a *Array) MeanAxisCtx(ctx context.Context, axis int, keepDims bool) (*Array, error) {
+	result, err := a.SumAxisCtx(ctx, axis, keepDims)
+	if err != nil {
+		return nil, err
+	}
+	axis, _ = normalizeAxis(axis, len(a.Shape))
+	n := float64(a.Shape[axis])
+	for i := range result.Data {
+		result.Data[i] /= n
+	}
+	return result, nil
+}
+
+// Max 求所有元素的最大值，存在 NaN 时返回 NaN
+func (a *Array) Max() (float64, error) {
+	return a.MaxCtx(context.Background())
+}
+
+// MaxCtx 求所有元素的最大值（可取消版本）
+func (a *Array) MaxCtx(ctx context.Context) (float64, error) {
+	if len(a.Data) == 0 {
+		return 0, errors.New("空数组无法求最大值")
+	}
+	return a.reduceAllCtx(ctx, math.Inf(-1), math.Max)
+}
+
+// MaxAxis 沿 axis 求最大值
+func (a *Array) MaxAxis(axis int, keepDims bool) (*Array, error) {
+	return a.MaxAxisCtx(context.Background(), axis, keepDims)
+}
+
+// MaxAxisCtx 沿 axis 求最大值（可取消版本）
+func (a *Array) MaxAxisCtx(ctx context.Context, axis int, keepDims bool) (*Array, error) {
+	if err := a.checkNonEmptyAxis(axis); err != nil {
+		return nil, err
+	}
+	return a.reduceAxisCtx(ctx, axis, keepDims, math.Inf(-1), math.Max)
+}
+
+// Min 求所有元素的最小值，存在 NaN 时返回 NaN
+func (a *Array) Min() (float64, error) {
+	return a.MinCtx(context.Background())
+}
+
+// MinCtx 求所有元素的最小值（可取消版本）
+func (a *Array) MinCtx(ctx context.Context) (float64, error) {
+	if len(a.Data) == 0 {
+		return 0, errors.New("空数组无法求最小值")
+	}
+	return a.reduceAllCtx(ctx, math.Inf(1), math.Min)
+}
+
+// MinAxis 沿 axis 求最小值
+func (a *Array) MinAxis(axis int, keepDims bool) (*Array, error) {
+	return a.MinAxisCtx(context.Background(), axis, keepDims)
+}
+
+// MinAxisCtx 沿 axis 求最小值（可取消版本）
+func (a *Array) MinAxisCtx(ctx context.Context, axis int, keepDims bool) (*Array, error) {
+	if err := a.checkNonEmptyAxis(axis); err != nil {
+		return nil, err
+	}
+	return a.reduceAxisCtx(ctx, axis, keepDims, math.Inf(1), math.Min)
+}
+
+// 最大值/最小值要求归约轴的长度大于 0
+func (a *Array) checkNonEmptyAxis(axis int) error {
+	axis, err := normalizeAxis(axis, len(a.Shape))
+	if err != nil {
+		return err
+	}
+	if a.Shape[axis] == 0 {
+		return fmt.Errorf("轴 %d 长度为 0，无法归约", axis)
+	}
+	return nil
+}
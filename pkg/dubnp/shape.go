@@ -0,0 +1,147 @@
+package dubnp
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Zeros 创建全 0 数组
+func Zeros(shape ...int) *Array {
+	return &Array{Data: make([]float64, shapeSize(shape)), Shape: append([]int(nil), shape...)}
+}
+
+// Ones 创建全 1 数组
+func Ones(shape ...int) *Array {
+	return Full(1, shape...)
+}
+
+// Full 创建所有元素均为 value 的数组
+func Full(value float64, shape ...int) *Array {
+	a := Zeros(shape...)
+	for i := range a.Data {
+		a.Data[i] = value
+	}
+	return a
+}
+
+// Size 返回元素总数
+func (a *Array) Size() int {
+	return len(a.Data)
+}
+
+// Copy 深拷贝数组
+func (a *Array) Copy() *Array {
+	return &Array{Data: append([]float64(nil), a.Data...), Shape: append([]int(nil), a.Shape...)}
+}
+
+// Reshape 改变形状，返回的数组与原数组共享 Data，至多一个维度可以为 -1（自动推断）
+func (a *Array) Reshape(shape ...int) (*Array, error) {
+	newShape := append([]int(nil), shape...)
+	infer := -1
+	known := 1
+	for i, s := range newShape {
+		switch {
+		case s == -1 && infer >= 0:
+			return nil, errors.New("至多一个维度可以为 -1")
+		case s == -1:
+			infer = i
+		case s < 0:
+			return nil, fmt.Errorf("非法的形状 %v", shape)
+		default:
+			known *= s
+		}
+	}
+	if infer >= 0 {
+		if known == 0 || len(a.Data)%known != 0 {
+			return nil, fmt.Errorf("无法将大小为 %d 的数组变形为 %v", len(a.Data), shape)
+		}
+		newShape[infer] = len(a.Data) / known
+	}
+	if shapeSize(newShape) != len(a.Data) {
+		return nil, fmt.Errorf("无法将大小为 %d 的数组变形为 %v", len(a.Data), shape)
+	}
+	return &Array{Data: a.Data, Shape: newShape}, nil
+}
+
+// BroadcastTo 将数组按广播规则扩展为 shape，返回新的数组
+func (a *Array) BroadcastTo(shape ...int) (*Array, error) {
+	target := &Array{Shape: shape}
+	it, err := NewNdIter(NdIterOptions{}, a, target)
+	if err != nil {
+		return nil, err
+	}
+	if !sameShape(it.Shape(), shape) {
+		return nil, fmt.Errorf("形状 %v 无法广播到 %v", a.Shape, shape)
+	}
+	resultData := make([]float64, it.Size())
+	parallelChunks(len(resultData), func(start, end int) {
+		local := it.clone()
+		local.seek(start)
+		for i := start; i < end; i++ {
+			resultData[i] = a.Data[local.offsets[0]]
+			local.Next()
+		}
+	})
+	return &Array{Data: resultData, Shape: append([]int(nil), shape...)}, nil
+}
+
+// SumTo 将数组求和归约到 shape，是 BroadcastTo 的逆运算，常用于广播运算的梯度
+func (a *Array) SumTo(shape ...int) (*Array, error) {
+	if sameShape(a.Shape, shape) {
+		return a, nil
+	}
+	broadcast, err := BroadcastShapes(a.Shape, shape)
+	if err != nil || !sameShape(broadcast, a.Shape) {
+		return nil, fmt.Errorf("形状 %v 无法归约到 %v", a.Shape, shape)
+	}
+
+	result := a
+	// 先归约多出来的前导维度
+	for len(result.Shape) > len(shape) {
+		if result, err = result.SumAxis(0, false); err != nil {
+			return nil, err
+		}
+	}
+	// 再归约目标形状中长度为 1 的维度
+	for i, s := range shape {
+		if s == 1 && result.Shape[i] != 1 {
+			if result, err = result.SumAxis(i, true); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if result == a {
+		result = a.Copy()
+	}
+	result.Shape = append([]int(nil), shape...)
+	return result, nil
+}
+
+// Index 返回沿第 0 维的第 i 个子数组，与原数组共享 Data
+func (a *Array) Index(i int) (*Array, error) {
+	if len(a.Shape) == 0 {
+		return nil, errors.New("0 维数组不能按下标取子数组")
+	}
+	if i < 0 || i >= a.Shape[0] {
+		return nil, fmt.Errorf("下标 %d 超出范围 [0, %d)", i, a.Shape[0])
+	}
+	step := shapeSize(a.Shape[1:])
+	return &Array{Data: a.Data[i*step : (i+1)*step : (i+1)*step], Shape: append([]int(nil), a.Shape[1:]...)}, nil
+}
+
+// Stack 将形状相同的数组沿新的第 0 维堆叠
+func Stack(arrays ...*Array) (*Array, error) {
+	if len(arrays) == 0 {
+		return nil, errors.New("至少需要一个数组")
+	}
+	shape := arrays[0].Shape
+	step := len(arrays[0].Data)
+	resultData := make([]float64, 0, step*len(arrays))
+	for _, a := range arrays {
+		if !sameShape(a.Shape, shape) {
+			return nil, fmt.Errorf("无法堆叠形状 %v 与 %v", shape, a.Shape)
+		}
+		resultData = append(resultData, a.Data...)
+	}
+	return &Array{Data: resultData, Shape: append([]int{len(arrays)}, shape...)}, nil
+}
@@ -0,0 +1,191 @@
+package dubtorch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// GELU 逐元素求 x*Φ(x)，Φ 为标准正态分布的累积分布函数
+func (a *Tensor) GELU() *Tensor {
+	return a.unary("GELU", func(x float64) float64 {
+		return 0.5 * x * (1 + math.Erf(x/math.Sqrt2))
+	}, func(x, y float64) float64 {
+		cdf := 0.5 * (1 + math.Erf(x/math.Sqrt2))
+		pdf := math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
+		return cdf + x*pdf
+	})
+}
+
+// 将形状按 axis 划分为 outer × n × inner
+func laneGeometry(shape []int, axis int) (outer, n, inner int, err error) {
+	if axis < 0 {
+		axis += len(shape)
+	}
+	if axis < 0 || axis >= len(shape) {
+		return 0, 0, 0, fmt.Errorf("轴 %d 超出范围，数组维度为 %d", axis, len(shape))
+	}
+	outer, inner = 1, 1
+	for _, d := range shape[:axis] {
+		outer *= d
+	}
+	for _, d := range shape[axis+1:] {
+		inner *= d
+	}
+	return outer, shape[axis], inner, nil
+}
+
+// 对每条沿 axis 的数据调用 fn，lane 中第 j 个元素位于 base+j*inner
+func forEachLane(outer, n, inner int, fn func(base int)) {
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			fn(o*n*inner + i)
+		}
+	}
+}
+
+// Softmax 沿 axis 计算 softmax，减去最大值保证数值稳定
+func (a *Tensor) Softmax(axis int) (*Tensor, error) {
+	outer, n, inner, err := laneGeometry(a.Data.Shape, axis)
+	if err != nil {
+		return nil, err
+	}
+	x := a.Data.Data
+	y := make([]float64, len(x))
+	forEachLane(outer, n, inner, func(base int) {
+		m := math.Inf(-1)
+		for j := 0; j < n; j++ {
+			m = math.Max(m, x[base+j*inner])
+		}
+		// 整行均被屏蔽（全为 -Inf）时输出 0，而不是 NaN
+		if math.IsInf(m, -1) {
+			return
+		}
+		sum := 0.0
+		for j := 0; j < n; j++ {
+			e := math.Exp(x[base+j*inner] - m)
+			y[base+j*inner] = e
+			sum += e
+		}
+		for j := 0; j < n; j++ {
+			y[base+j*inner] /= sum
+		}
+	})
+	out := &dubnp.Array{Data: y, Shape: append([]int(nil), a.Data.Shape...)}
+	return newResult("Softmax", out, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		// dx = y * (g - sum(g*y))
+		gx := make([]float64, len(y))
+		forEachLane(outer, n, inner, func(base int) {
+			dot := 0.0
+			for j := 0; j < n; j++ {
+				dot += g.Data[base+j*inner] * y[base+j*inner]
+			}
+			for j := 0; j < n; j++ {
+				k := base + j*inner
+				gx[k] = y[k] * (g.Data[k] - dot)
+			}
+		})
+		return []*dubnp.Array{{Data: gx, Shape: out.Shape}}, nil
+	}), nil
+}
+
+// LogSoftmax 沿 axis 计算 log(softmax(x))，使用 log-sum-exp 保证数值稳定
+func (a *Tensor) LogSoftmax(axis int) (*Tensor, error) {
+	outer, n, inner, err := laneGeometry(a.Data.Shape, axis)
+	if err != nil {
+		return nil, err
+	}
+	x := a.Data.Data
+	y := make([]float64, len(x))
+	forEachLane(outer, n, inner, func(base int) {
+		m := math.Inf(-1)
+		for j := 0; j < n; j++ {
+			m = math.Max(m, x[base+j*inner])
+		}
+		// 整行均被屏蔽（全为 -Inf）时输出 -Inf，与 Softmax 输出 0 一致，而不是 NaN
+		if math.IsInf(m, -1) {
+			for j := 0; j < n; j++ {
+				y[base+j*inner] = m
+			}
+			return
+		}
+		sum := 0.0
+		for j := 0; j < n; j++ {
+			sum += math.Exp(x[base+j*inner] - m)
+		}
+		lse := m + math.Log(sum)
+		for j := 0; j < n; j++ {
+			y[base+j*inner] = x[base+j*inner] - lse
+		}
+	})
+	out := &dubnp.Array{Data: y, Shape: append([]int(nil), a.Data.Shape...)}
+	return newResult("LogSoftmax", out, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		// dx = g - softmax * sum(g)，被屏蔽的整行输出为常数，梯度为 0
+		gx := make([]float64, len(y))
+		forEachLane(outer, n, inner, func(base int) {
+			masked := true
+			sum := 0.0
+			for j := 0; j < n; j++ {
+				masked = masked && math.IsInf(y[base+j*inner], -1)
+				sum += g.Data[base+j*inner]
+			}
+			if masked {
+				return
+			}
+			for j := 0; j < n; j++ {
+				k := base + j*inner
+				gx[k] = g.Data[k] - math.Exp(y[k])*sum
+			}
+		})
+		return []*dubnp.Array{{Data: gx, Shape: out.Shape}}, nil
+	}), nil
+}
+
+// ReLU 激活层
+type ReLU struct{ BaseModule }
+
+// NewReLU 创建 ReLU 激活层
+func NewReLU() *ReLU { return &ReLU{} }
+
+// Forward 执行 ReLU
+func (m *ReLU) Forward(x *Tensor) (*Tensor, error) { return x.ReLU(), nil }
+
+// GELU 激活层
+type GELU struct{ BaseModule }
+
+// NewGELU 创建 GELU 激活层
+func NewGELU() *GELU { return &GELU{} }
+
+// Forward 执行 GELU
+func (m *GELU) Forward(x *Tensor) (*Tensor, error) { return x.GELU(), nil }
+
+// Tanh 激活层
+type Tanh struct{ BaseModule }
+
+// NewTanh 创建 Tanh 激活层
+func NewTanh() *Tanh { return &Tanh{} }
+
+// Forward 执行 Tanh
+func (m *Tanh) Forward(x *Tensor) (*Tensor, error) { return x.Tanh(), nil }
+
+// Sigmoid 激活层
+type Sigmoid struct{ BaseModule }
+
+// NewSigmoid 创建 Sigmoid 激活层
+func NewSigmoid() *Sigmoid { return &Sigmoid{} }
+
+// Forward 执行 Sigmoid
+func (m *Sigmoid) Forward(x *Tensor) (*Tensor, error) { return x.Sigmoid(), nil }
+
+// Softmax 沿 Axis 做 softmax 的层
+type Softmax struct {
+	BaseModule
+	Axis int
+}
+
+// NewSoftmax 创建 Softmax 层
+func NewSoftmax(axis int) *Softmax { return &Softmax{Axis: axis} }
+
+// Forward 执行 Softmax
+func (m *Softmax) Forward(x *Tensor) (*Tensor, error) { return x.Softmax(m.Axis) }
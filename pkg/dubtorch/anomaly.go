@@ -0,0 +1,168 @@
+package dubtorch
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// anomalyScope 一次 DetectAnomaly 调用记录到的第一个前向异常
+type anomalyScope struct {
+	mu    sync.Mutex
+	first *AnomalyError
+}
+
+// 进行中的 DetectAnomaly 调用，按 goroutine 编号保存，同一 goroutine 内嵌套时最后一个在栈顶
+var (
+	anomalyMu     sync.Mutex
+	anomalyActive int32
+	anomalyScopes = map[uint64][]*anomalyScope{}
+)
+
+// DetectAnomaly 的帧，见 scope.go
+//
+//go:noinline
+func anomalyFrame(fn func()) {
+	fn()
+}
+
+var anomalyPC = frameReturnPC(anomalyFrame)
+
+// AnomalyError 异常检测模式下首先产生 NaN 或 Inf 的运算
+type AnomalyError struct {
+	Op       string
+	Backward bool    // 在反向传播中产生
+	Input    int     // 反向传播时得到异常梯度的输入下标，前向时为 -1
+	Index    int     // 第一个异常值在结果中的下标
+	Value    float64 // 第一个异常值
+	Stack    string  // 该运算在前向执行时的调用栈
+}
+
+func (e *AnomalyError) Error() string {
+	if e.Backward {
+		return fmt.Sprintf("%s 的反向传播在第 %d 个输入的梯度中产生了 %v（第 %d 个元素），该运算创建于:\n%s", e.Op, e.Input, e.Value, e.Index, e.Stack)
+	}
+	return fmt.Sprintf("%s 的前向计算产生了 %v（第 %d 个元素），调用栈:\n%s", e.Op, e.Value, e.Index, e.Stack)
+}
+
+// IsAnomalyEnabled 返回当前 goroutine 是否处于 DetectAnomaly 之中
+func IsAnomalyEnabled() bool {
+	return atomic.LoadInt32(&anomalyActive) > 0 && onStack(anomalyPC)
+}
+
+// DetectAnomaly 在异常检测模式下执行 fn：记录 fn 执行期间创建的每个运算（包括只由参数参与的运算）的调用栈，
+// 检查前向结果与反向传播得到的梯度，找出输入均为有限值、输出却首先出现 NaN 或 Inf 的运算。
+// 反向传播中的异常使 Backward 立即返回 *AnomalyError；前向中的异常不打断计算，
+// fn 正常结束后返回记录到的第一个。该模式只作用于调用它的 goroutine，并且会明显变慢，只用于调试
+func DetectAnomaly(fn func() error) error {
+	scope := &anomalyScope{}
+	id := goroutineID()
+	anomalyMu.Lock()
+	anomalyScopes[id] = append(anomalyScopes[id], scope)
+	anomalyMu.Unlock()
+	atomic.AddInt32(&anomalyActive, 1)
+	defer func() {
+		atomic.AddInt32(&anomalyActive, -1)
+		anomalyMu.Lock()
+		if scopes := anomalyScopes[id][:len(anomalyScopes[id])-1]; len(scopes) > 0 {
+			anomalyScopes[id] = scopes
+		} else {
+			delete(anomalyScopes, id)
+		}
+		anomalyMu.Unlock()
+	}()
+	var err error
+	anomalyFrame(func() {
+		err = fn()
+	})
+	if err != nil {
+		return err
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	if scope.first != nil {
+		return scope.first
+	}
+	return nil
+}
+
+// 当前 goroutine 最内层的 DetectAnomaly 调用
+func currentAnomalyScope() *anomalyScope {
+	id := goroutineID()
+	anomalyMu.Lock()
+	defer anomalyMu.Unlock()
+	scopes := anomalyScopes[id]
+	if len(scopes) == 0 {
+		return nil
+	}
+	return scopes[len(scopes)-1]
+}
+
+// 第一个 NaN 或 Inf 的下标，没有时返回 -1
+func firstNonFinite(data []float64) int {
+	for i, v := range data {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return i
+		}
+	}
+	return -1
+}
+
+// 当前调用栈，跳过 skip 层（不含本函数），每帧为函数名与文件位置
+func callerStack(skip int) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var sb strings.Builder
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// 检查前向结果，输入已含 NaN 或 Inf 时不是该运算首先产生的，不记录
+func checkForward(op string, data []float64, inputs []*Tensor, stack string) {
+	i := firstNonFinite(data)
+	if i < 0 {
+		return
+	}
+	for _, in := range inputs {
+		if firstNonFinite(in.Data.Data) >= 0 {
+			return
+		}
+	}
+	s := currentAnomalyScope()
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.first == nil {
+		s.first = &AnomalyError{Op: op, Input: -1, Index: i, Value: data[i], Stack: stack}
+	}
+}
+
+// 检查反向函数得到的输入梯度，输出的梯度已含 NaN 或 Inf 时不是该运算首先产生的，不检查
+func checkBackward(n *node, grad []float64, inputGrads []*dubnp.Array) error {
+	if firstNonFinite(grad) >= 0 {
+		return nil
+	}
+	for i, g := range inputGrads {
+		if g == nil || !n.inputs[i].RequiresGrad {
+			continue
+		}
+		if j := firstNonFinite(g.Data); j >= 0 {
+			return &AnomalyError{Op: n.op, Backward: true, Input: i, Index: j, Value: g.Data[j], Stack: n.stack}
+		}
+	}
+	return nil
+}
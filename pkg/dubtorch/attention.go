@@ -0,0 +1,244 @@
+package dubtorch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// AttentionOptions 注意力的可选掩码，被屏蔽的位置在 softmax 前置为 -Inf
+type AttentionOptions struct {
+	KeyPaddingMask *dubnp.Array // (N, S)，非 0 表示该键为填充位置
+	AttnMask       *dubnp.Array // (L, S)，加到注意力分数上，-Inf 表示屏蔽
+	Causal         bool         // 查询 i 只能看到键 j <= i
+}
+
+// MultiheadAttention 多头注意力，输入输出均为 batch-first 布局 (N, L, E)
+type MultiheadAttention struct {
+	BaseModule
+	EmbedDim, NumHeads int
+	Dropout            float64 // 作用于注意力权重
+
+	InProjWeight, InProjBias *Tensor // (3E, E) 与 (3E)，依次为 q、k、v 的投影
+	OutProj                  *Linear
+}
+
+// NewMultiheadAttention 创建多头注意力，embedDim 必须能被 numHeads 整除
+func NewMultiheadAttention(embedDim, numHeads int, dropout float64) (*MultiheadAttention, error) {
+	if embedDim <= 0 || numHeads <= 0 || embedDim%numHeads != 0 {
+		return nil, fmt.Errorf("embedDim %d 必须是 numHeads %d 的正整数倍", embedDim, numHeads)
+	}
+	if dropout < 0 || dropout > 1 {
+		return nil, fmt.Errorf("Dropout 的概率必须在 [0, 1] 之间: %v", dropout)
+	}
+	m := &MultiheadAttention{EmbedDim: embedDim, NumHeads: numHeads, Dropout: dropout}
+	// 输入投影按 Xavier 均匀分布初始化，偏置为 0
+	bound := math.Sqrt(6 / float64(4*embedDim))
+	m.InProjWeight = m.RegisterParameter("in_proj_weight", NewTensor(uniformArray(-bound, bound, 3*embedDim, embedDim), true))
+	m.InProjBias = m.RegisterParameter("in_proj_bias", NewTensor(dubnp.Zeros(3*embedDim), true))
+	m.OutProj = NewLinear(embedDim, embedDim, true)
+	m.OutProj.Bias.Data = dubnp.Zeros(embedDim)
+	m.RegisterModule("out_proj", m.OutProj)
+	return m, nil
+}
+
+// Forward 无掩码的自注意力
+func (m *MultiheadAttention) Forward(x *Tensor) (*Tensor, error) {
+	out, _, err := m.Attend(x, x, x, AttentionOptions{})
+	return out, err
+}
+
+// Attend query 为 (N, L, E)，key 与 value 为 (N, S, E)；
+// 返回输出 (N, L, E) 与 dropout 之前的注意力权重 (N, H, L, S)
+func (m *MultiheadAttention) Attend(query, key, value *Tensor, opts AttentionOptions) (*Tensor, *Tensor, error) {
+	qs, ks, vs := query.Shape(), key.Shape(), value.Shape()
+	e := m.EmbedDim
+	if len(qs) != 3 || len(ks) != 3 || len(vs) != 3 || qs[2] != e || ks[2] != e || vs[2] != e ||
+		ks[0] != qs[0] || vs[0] != qs[0] || vs[1] != ks[1] {
+		return nil, nil, fmt.Errorf("MultiheadAttention 期望 (N, L, %d) 与 (N, S, %d)，输入形状为 %v、%v、%v", e, e, qs, ks, vs)
+	}
+	n, l, s := qs[0], qs[1], ks[1]
+	mask, err := attentionMask(opts, n, l, s)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 分别投影并拆分为多头 (N, H, len, Dh)
+	heads := make([]*Tensor, 3)
+	for i, x := range []*Tensor{query, key, value} {
+		w, err := m.InProjWeight.Narrow(0, i*e, e)
+		if err != nil {
+			return nil, nil, err
+		}
+		b, err := m.InProjBias.Narrow(0, i*e, e)
+		if err != nil {
+			return nil, nil, err
+		}
+		if heads[i], err = m.splitHeads(x, w, b); err != nil {
+			return nil, nil, err
+		}
+	}
+	kt, err := heads[1].TransposeAxes(-1, -2)
+	if err != nil {
+		return nil, nil, err
+	}
+	scores, err := heads[0].BatchMatMul(kt)
+	if err != nil {
+		return nil, nil, err
+	}
+	scores = scores.MulScalar(1 / math.Sqrt(float64(e/m.NumHeads)))
+	if mask != nil {
+		if scores, err = scores.Add(NewTensor(mask, false)); err != nil {
+			return nil, nil, err
+		}
+	}
+	weights, err := scores.Softmax(-1)
+	if err != nil {
+		return nil, nil, err
+	}
+	dropped, err := dropout(weights, m.Dropout, m.IsTraining())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 合并多头 (N, H, L, Dh) -> (N, L, E)
+	ctx, err := dropped.BatchMatMul(heads[2])
+	if err != nil {
+		return nil, nil, err
+	}
+	if ctx, err = ctx.Permute(0, 2, 1, 3); err != nil {
+		return nil, nil, err
+	}
+	if ctx, err = ctx.Reshape(n, l, e); err != nil {
+		return nil, nil, err
+	}
+	out, err := m.OutProj.Forward(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, weights, nil
+}
+
+// 投影后将 (N, len, E) 变为 (N, H, len, Dh)
+func (m *MultiheadAttention) splitHeads(x, w, b *Tensor) (*Tensor, error) {
+	shape := x.Shape()
+	y, err := linear(x, w, b)
+	if err != nil {
+		return nil, err
+	}
+	if y, err = y.Reshape(shape[0], shape[1], m.NumHeads, m.EmbedDim/m.NumHeads); err != nil {
+		return nil, err
+	}
+	return y.Permute(0, 2, 1, 3)
+}
+
+// 将各种掩码合并为形状 (N, 1, L, S) 的加性掩码，没有掩码时返回 nil
+func attentionMask(opts AttentionOptions, n, l, s int) (*dubnp.Array, error) {
+	if opts.KeyPaddingMask == nil && opts.AttnMask == nil && !opts.Causal {
+		return nil, nil
+	}
+	if p := opts.KeyPaddingMask; p != nil && !sameShape(p.Shape, []int{n, s}) {
+		return nil, fmt.Errorf("KeyPaddingMask 的形状应为 [%d %d]，实际为 %v", n, s, p.Shape)
+	}
+	if a := opts.AttnMask; a != nil && !sameShape(a.Shape, []int{l, s}) {
+		return nil, fmt.Errorf("AttnMask 的形状应为 [%d %d]，实际为 %v", l, s, a.Shape)
+	}
+	inf := math.Inf(-1)
+	mask := dubnp.Zeros(n, 1, l, s)
+	for b := 0; b < n; b++ {
+		for i := 0; i < l; i++ {
+			row := mask.Data[(b*l+i)*s : (b*l+i+1)*s]
+			for j := range row {
+				switch {
+				case opts.Causal && j > i:
+					row[j] = inf
+				case opts.KeyPaddingMask != nil && opts.KeyPaddingMask.Data[b*s+j] != 0:
+					row[j] = inf
+				case opts.AttnMask != nil:
+					row[j] = opts.AttnMask.Data[i*s+j]
+				}
+			}
+		}
+	}
+	return mask, nil
+}
+
+// CausalMask 返回 (size, size) 的加性掩码，上三角（不含对角线）为 -Inf
+func CausalMask(size int) *dubnp.Array {
+	mask := dubnp.Zeros(size, size)
+	for i := 0; i < size; i++ {
+		for j := i + 1; j < size; j++ {
+			mask.Data[i*size+j] = math.Inf(-1)
+		}
+	}
+	return mask
+}
+
+// PositionalEncoding 正弦位置编码，输入 (N, L, D) 加上前 L 个位置的编码
+type PositionalEncoding struct {
+	BaseModule
+	DModel, MaxLen int
+	Dropout        float64
+	pe             *dubnp.Array // (MaxLen, DModel)
+}
+
+// NewPositionalEncoding 创建正弦位置编码：
+// PE(pos, 2i) = sin(pos / 10000^(2i/d))，PE(pos, 2i+1) = cos(pos / 10000^(2i/d))
+func NewPositionalEncoding(dModel, maxLen int, dropout float64) *PositionalEncoding {
+	pe := dubnp.Zeros(maxLen, dModel)
+	for pos := 0; pos < maxLen; pos++ {
+		for i := 0; i < dModel; i += 2 {
+			angle := float64(pos) / math.Pow(10000, float64(i)/float64(dModel))
+			pe.Data[pos*dModel+i] = math.Sin(angle)
+			if i+1 < dModel {
+				pe.Data[pos*dModel+i+1] = math.Cos(angle)
+			}
+		}
+	}
+	p := &PositionalEncoding{DModel: dModel, MaxLen: maxLen, Dropout: dropout}
+	p.pe = p.RegisterBuffer("pe", pe)
+	return p
+}
+
+// Forward 输入形状为 (N, L, DModel)，L 不超过 MaxLen
+func (p *PositionalEncoding) Forward(x *Tensor) (*Tensor, error) {
+	table := NewTensor(p.pe, false)
+	y, err := addPositions(x, table, p.MaxLen, p.DModel)
+	if err != nil {
+		return nil, err
+	}
+	return dropout(y, p.Dropout, p.IsTraining())
+}
+
+// LearnedPositionalEncoding 可学习的位置编码，每个位置对应一个参数向量
+type LearnedPositionalEncoding struct {
+	BaseModule
+	DModel, MaxLen int
+	Weight         *Tensor // (MaxLen, DModel)
+}
+
+// NewLearnedPositionalEncoding 创建可学习的位置编码，权重按 N(0, 0.02^2) 初始化
+func NewLearnedPositionalEncoding(dModel, maxLen int) *LearnedPositionalEncoding {
+	p := &LearnedPositionalEncoding{DModel: dModel, MaxLen: maxLen}
+	p.Weight = p.RegisterParameter("weight", Randn(maxLen, dModel).MulScalar(0.02).Detach())
+	return p
+}
+
+// Forward 输入形状为 (N, L, DModel)，L 不超过 MaxLen
+func (p *LearnedPositionalEncoding) Forward(x *Tensor) (*Tensor, error) {
+	return addPositions(x, p.Weight, p.MaxLen, p.DModel)
+}
+
+// 将 table 的前 L 行广播加到 (N, L, D) 的输入上
+func addPositions(x, table *Tensor, maxLen, d int) (*Tensor, error) {
+	shape := x.Shape()
+	if len(shape) != 3 || shape[2] != d || shape[1] > maxLen {
+		return nil, fmt.Errorf("位置编码期望输入 (N, L<=%d, %d)，输入形状为 %v", maxLen, d, shape)
+	}
+	rows, err := table.Narrow(0, 0, shape[1])
+	if err != nil {
+		return nil, err
+	}
+	return x.Add(rows)
+}
@@ -0,0 +1,527 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// ConvOptions 卷积选项，各字段按空间维度给出，只给一个值时用于所有维度
+type ConvOptions struct {
+	Stride        []int // 步长，默认 1
+	Padding       []int // 两侧补零的个数，默认 0
+	Dilation      []int // 卷积核元素的间隔，默认 1
+	OutputPadding []int // 仅用于转置卷积，输出在末尾多出的尺寸，默认 0
+	Groups        int   // 分组数，默认 1
+}
+
+// 展开为二维卷积的参数，一维卷积视为高为 1 的二维卷积
+type convParams struct {
+	stride, padding, dilation, outputPadding [2]int
+	groups                                   int
+}
+
+// 将按空间维度给出的选项展开为长度为 dims 的切片
+func expandSpatial(name string, v []int, dims, def int) ([]int, error) {
+	out := make([]int, dims)
+	switch len(v) {
+	case 0:
+		for i := range out {
+			out[i] = def
+		}
+	case 1:
+		for i := range out {
+			out[i] = v[0]
+		}
+	case dims:
+		copy(out, v)
+	default:
+		return nil, fmt.Errorf("%s 应有 1 或 %d 个值，实际为 %v", name, dims, v)
+	}
+	return out, nil
+}
+
+func (o ConvOptions) resolve(dims int) (convParams, error) {
+	p := convParams{groups: o.Groups}
+	if p.groups == 0 {
+		p.groups = 1
+	}
+	if p.groups < 0 {
+		return p, fmt.Errorf("Groups 不能为负: %d", o.Groups)
+	}
+	fields := []struct {
+		name string
+		v    []int
+		def  int
+		dst  *[2]int
+		min  int
+	}{
+		{"Stride", o.Stride, 1, &p.stride, 1},
+		{"Padding", o.Padding, 0, &p.padding, 0},
+		{"Dilation", o.Dilation, 1, &p.dilation, 1},
+		{"OutputPadding", o.OutputPadding, 0, &p.outputPadding, 0},
+	}
+	for _, f := range fields {
+		v, err := expandSpatial(f.name, f.v, dims, f.def)
+		if err != nil {
+			return p, err
+		}
+		// 一维时高度方向使用默认值
+		f.dst[0] = f.def
+		copy(f.dst[2-dims:], v)
+		if f.dst[0] < f.min || f.dst[1] < f.min {
+			return p, fmt.Errorf("%s 不能小于 %d: %v", f.name, f.min, f.v)
+		}
+	}
+	return p, nil
+}
+
+// 单组二维卷积的几何参数
+type convGeom struct {
+	c, h, w                int // 单组通道数与输入尺寸
+	kh, kw                 int
+	sh, sw, ph, pw, dh, dw int
+	oh, ow                 int
+}
+
+// 将单组输入 (c, h, w) 展开为 (c*kh*kw, oh*ow) 的矩阵，越界位置补 0
+func (g *convGeom) im2col(src, dst []float64) {
+	p := g.oh * g.ow
+	for c := 0; c < g.c; c++ {
+		for ki := 0; ki < g.kh; ki++ {
+			for kj := 0; kj < g.kw; kj++ {
+				row := dst[((c*g.kh+ki)*g.kw+kj)*p:][:p]
+				for oi := 0; oi < g.oh; oi++ {
+					i := oi*g.sh - g.ph + ki*g.dh
+					for oj := 0; oj < g.ow; oj++ {
+						j := oj*g.sw - g.pw + kj*g.dw
+						if i < 0 || i >= g.h || j < 0 || j >= g.w {
+							row[oi*g.ow+oj] = 0
+						} else {
+							row[oi*g.ow+oj] = src[(c*g.h+i)*g.w+j]
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// im2col 的逆操作：将矩阵中的值累加回 (c, h, w)
+func (g *convGeom) col2im(cols, dst []float64) {
+	p := g.oh * g.ow
+	for c := 0; c < g.c; c++ {
+		for ki := 0; ki < g.kh; ki++ {
+			for kj := 0; kj < g.kw; kj++ {
+				row := cols[((c*g.kh+ki)*g.kw+kj)*p:][:p]
+				for oi := 0; oi < g.oh; oi++ {
+					i := oi*g.sh - g.ph + ki*g.dh
+					if i < 0 || i >= g.h {
+						continue
+					}
+					for oj := 0; oj < g.ow; oj++ {
+						j := oj*g.sw - g.pw + kj*g.dw
+						if j >= 0 && j < g.w {
+							dst[(c*g.h+i)*g.w+j] += row[oi*g.ow+oj]
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// 计算 a(m×k) 与 b(k×n) 的乘积
+func matmul(a []float64, m, k int, b []float64, n int) []float64 {
+	c, _ := (&dubnp.Array{Data: a, Shape: []int{m, k}}).Multiply(&dubnp.Array{Data: b, Shape: []int{k, n}})
+	return c.Data
+}
+
+// 转置 m×n 矩阵
+func transposeData(a []float64, m, n int) []float64 {
+	t, _ := (&dubnp.Array{Data: a, Shape: []int{m, n}}).Transpose()
+	return t.Data
+}
+
+// 将 [0, n) 分给多个 goroutine 并行执行，用于按批次并行
+func parallelFor(n int, fn func(i int)) {
+	workers := min(runtime.NumCPU(), n)
+	var next int64 = -1
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// 按批次顺序将每个样本的部分梯度相加，保证结果与并行调度无关
+func sumParts(parts [][]float64, shape []int) *dubnp.Array {
+	out := dubnp.Zeros(shape...)
+	for _, part := range parts {
+		for i, v := range part {
+			out.Data[i] += v
+		}
+	}
+	return out
+}
+
+// 偏置的梯度：对批次与空间位置求和
+func biasGrad(g []float64, n, c, p int) *dubnp.Array {
+	gb := dubnp.Zeros(c)
+	for b := 0; b < n; b++ {
+		for ch := 0; ch < c; ch++ {
+			for _, v := range g[(b*c+ch)*p:][:p] {
+				gb.Data[ch] += v
+			}
+		}
+	}
+	return gb
+}
+
+// 二维卷积，x 为 (N, C, H, W)，weight 为 (O, C/groups, KH, KW)，bias 为 (O) 或 nil
+func conv2d(x, weight, bias *Tensor, p convParams) (*Tensor, error) {
+	xs, ws := x.Shape(), weight.Shape()
+	if len(xs) != 4 || len(ws) != 4 {
+		return nil, fmt.Errorf("卷积的输入与权重应为四维，实际为 %v 与 %v", xs, ws)
+	}
+	n, cin, h, w := xs[0], xs[1], xs[2], xs[3]
+	cout, cg, kh, kw := ws[0], ws[1], ws[2], ws[3]
+	groups := p.groups
+	if cin != cg*groups || cout%groups != 0 {
+		return nil, fmt.Errorf("通道数不匹配：输入 %d 通道，权重形状 %v，分组数 %d", cin, ws, groups)
+	}
+	if bias != nil && bias.Size() != cout {
+		return nil, fmt.Errorf("偏置应有 %d 个元素，实际为 %d", cout, bias.Size())
+	}
+	geom := convGeom{
+		c: cg, h: h, w: w, kh: kh, kw: kw,
+		sh: p.stride[0], sw: p.stride[1], ph: p.padding[0], pw: p.padding[1], dh: p.dilation[0], dw: p.dilation[1],
+	}
+	geom.oh = (h+2*geom.ph-geom.dh*(kh-1)-1)/geom.sh + 1
+	geom.ow = (w+2*geom.pw-geom.dw*(kw-1)-1)/geom.sw + 1
+	if geom.oh <= 0 || geom.ow <= 0 {
+		return nil, fmt.Errorf("输入尺寸 %v 小于卷积核覆盖的范围", xs[2:])
+	}
+
+	og, k, P := cout/groups, cg*kh*kw, geom.oh*geom.ow
+	xd, wd := x.Data.Data, weight.Data.Data
+	out := dubnp.Zeros(n, cout, geom.oh, geom.ow)
+	parallelFor(n, func(b int) {
+		cols := make([]float64, k*P)
+		for gi := 0; gi < groups; gi++ {
+			geom.im2col(xd[(b*cin+gi*cg)*h*w:][:cg*h*w], cols)
+			copy(out.Data[(b*cout+gi*og)*P:], matmul(wd[gi*og*k:][:og*k], og, k, cols, P))
+		}
+		if bias != nil {
+			for ch := 0; ch < cout; ch++ {
+				row := out.Data[(b*cout+ch)*P:][:P]
+				for i := range row {
+					row[i] += bias.Data.Data[ch]
+				}
+			}
+		}
+	})
+
+	inputs := []*Tensor{x, weight}
+	if bias != nil {
+		inputs = append(inputs, bias)
+	}
+	return newResult("Conv2d", out, inputs, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grads := make([]*dubnp.Array, len(inputs))
+		if x.RequiresGrad {
+			grads[0] = dubnp.Zeros(xs...)
+		}
+		var parts [][]float64
+		if weight.RequiresGrad {
+			parts = make([][]float64, n)
+		}
+		parallelFor(n, func(b int) {
+			cols := make([]float64, k*P)
+			var gw []float64
+			if parts != nil {
+				gw = make([]float64, len(wd))
+			}
+			for gi := 0; gi < groups; gi++ {
+				gg := g.Data[(b*cout+gi*og)*P:][:og*P]
+				if gw != nil {
+					// dW = G · cols^T
+					geom.im2col(xd[(b*cin+gi*cg)*h*w:][:cg*h*w], cols)
+					copy(gw[gi*og*k:], matmul(gg, og, P, transposeData(cols, k, P), k))
+				}
+				if grads[0] != nil {
+					// dcols = W^T · G，再累加回输入
+					dcols := matmul(transposeData(wd[gi*og*k:][:og*k], og, k), k, og, gg, P)
+					geom.col2im(dcols, grads[0].Data[(b*cin+gi*cg)*h*w:][:cg*h*w])
+				}
+			}
+			if parts != nil {
+				parts[b] = gw
+			}
+		})
+		if parts != nil {
+			grads[1] = sumParts(parts, ws)
+		}
+		if bias != nil && bias.RequiresGrad {
+			grads[2] = biasGrad(g.Data, n, cout, P)
+		}
+		return grads, nil
+	}), nil
+}
+
+// 二维转置卷积，x 为 (N, C, H, W)，weight 为 (C, O/groups, KH, KW)，bias 为 (O) 或 nil
+// 前向计算等价于卷积对输入求梯度
+func convTranspose2d(x, weight, bias *Tensor, p convParams) (*Tensor, error) {
+	xs, ws := x.Shape(), weight.Shape()
+	if len(xs) != 4 || len(ws) != 4 {
+		return nil, fmt.Errorf("转置卷积的输入与权重应为四维，实际为 %v 与 %v", xs, ws)
+	}
+	n, cin, h, w := xs[0], xs[1], xs[2], xs[3]
+	groups := p.groups
+	if ws[0] != cin || cin%groups != 0 {
+		return nil, fmt.Errorf("通道数不匹配：输入 %d 通道，权重形状 %v，分组数 %d", cin, ws, groups)
+	}
+	cg, og, kh, kw := cin/groups, ws[1], ws[2], ws[3]
+	cout := og * groups
+	if bias != nil && bias.Size() != cout {
+		return nil, fmt.Errorf("偏置应有 %d 个元素，实际为 %d", cout, bias.Size())
+	}
+	for i := 0; i < 2; i++ {
+		if p.outputPadding[i] >= p.stride[i] && p.outputPadding[i] >= p.dilation[i] {
+			return nil, errors.New("OutputPadding 必须小于 Stride 或 Dilation")
+		}
+	}
+	// 卷积几何以转置卷积的输出为输入、以转置卷积的输入为输出
+	geom := convGeom{
+		c: og, h: h, w: w, kh: kh, kw: kw,
+		sh: p.stride[0], sw: p.stride[1], ph: p.padding[0], pw: p.padding[1], dh: p.dilation[0], dw: p.dilation[1],
+		oh: h, ow: w,
+	}
+	geom.h = (h-1)*geom.sh - 2*geom.ph + geom.dh*(kh-1) + p.outputPadding[0] + 1
+	geom.w = (w-1)*geom.sw - 2*geom.pw + geom.dw*(kw-1) + p.outputPadding[1] + 1
+	if geom.h <= 0 || geom.w <= 0 {
+		return nil, fmt.Errorf("转置卷积的输出尺寸无效: (%d, %d)", geom.h, geom.w)
+	}
+
+	k, P, outP := og*kh*kw, h*w, geom.h*geom.w
+	xd, wd := x.Data.Data, weight.Data.Data
+	out := dubnp.Zeros(n, cout, geom.h, geom.w)
+	parallelFor(n, func(b int) {
+		for gi := 0; gi < groups; gi++ {
+			// cols = W^T · x，再累加到输出
+			wg := wd[gi*cg*k:][:cg*k]
+			cols := matmul(transposeData(wg, cg, k), k, cg, xd[(b*cin+gi*cg)*P:][:cg*P], P)
+			geom.col2im(cols, out.Data[(b*cout+gi*og)*outP:][:og*outP])
+		}
+		if bias != nil {
+			for ch := 0; ch < cout; ch++ {
+				row := out.Data[(b*cout+ch)*outP:][:outP]
+				for i := range row {
+					row[i] += bias.Data.Data[ch]
+				}
+			}
+		}
+	})
+
+	inputs := []*Tensor{x, weight}
+	if bias != nil {
+		inputs = append(inputs, bias)
+	}
+	return newResult("ConvTranspose2d", out, inputs, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grads := make([]*dubnp.Array, len(inputs))
+		if x.RequiresGrad {
+			grads[0] = dubnp.Zeros(xs...)
+		}
+		var parts [][]float64
+		if weight.RequiresGrad {
+			parts = make([][]float64, n)
+		}
+		parallelFor(n, func(b int) {
+			cols := make([]float64, k*P)
+			var gw []float64
+			if parts != nil {
+				gw = make([]float64, len(wd))
+			}
+			for gi := 0; gi < groups; gi++ {
+				geom.im2col(g.Data[(b*cout+gi*og)*outP:][:og*outP], cols)
+				wg := wd[gi*cg*k:][:cg*k]
+				if grads[0] != nil {
+					// dx = W · cols
+					copy(grads[0].Data[(b*cin+gi*cg)*P:], matmul(wg, cg, k, cols, P))
+				}
+				if gw != nil {
+					// dW = x · cols^T
+					copy(gw[gi*cg*k:], matmul(xd[(b*cin+gi*cg)*P:][:cg*P], cg, P, transposeData(cols, k, P), k))
+				}
+			}
+			if parts != nil {
+				parts[b] = gw
+			}
+		})
+		if parts != nil {
+			grads[1] = sumParts(parts, ws)
+		}
+		if bias != nil && bias.RequiresGrad {
+			grads[2] = biasGrad(g.Data, n, cout, outP)
+		}
+		return grads, nil
+	}), nil
+}
+
+// 将一维卷积的张量在第 2 维插入高度 1
+func unsqueezeHeight(t *Tensor) (*Tensor, error) {
+	s := t.Shape()
+	if len(s) != 3 {
+		return nil, fmt.Errorf("一维卷积的输入与权重应为三维，实际为 %v", s)
+	}
+	return t.Reshape(s[0], s[1], 1, s[2])
+}
+
+// 去掉高度维
+func squeezeHeight(t *Tensor) (*Tensor, error) {
+	s := t.Shape()
+	return t.Reshape(s[0], s[1], s[3])
+}
+
+// 以一维参数调用二维卷积核
+func conv1dVia(f func(x, weight, bias *Tensor, p convParams) (*Tensor, error), x, weight, bias *Tensor, opts ConvOptions) (*Tensor, error) {
+	p, err := opts.resolve(1)
+	if err != nil {
+		return nil, err
+	}
+	x4, err := unsqueezeHeight(x)
+	if err != nil {
+		return nil, err
+	}
+	w4, err := unsqueezeHeight(weight)
+	if err != nil {
+		return nil, err
+	}
+	y, err := f(x4, w4, bias, p)
+	if err != nil {
+		return nil, err
+	}
+	return squeezeHeight(y)
+}
+
+// Conv2d 二维卷积，x 为 (N, C, H, W)，weight 为 (O, C/groups, KH, KW)，bias 为 (O) 或 nil
+func Conv2d(x, weight, bias *Tensor, opts ConvOptions) (*Tensor, error) {
+	p, err := opts.resolve(2)
+	if err != nil {
+		return nil, err
+	}
+	return conv2d(x, weight, bias, p)
+}
+
+// Conv1d 一维卷积，x 为 (N, C, L)，weight 为 (O, C/groups, K)，bias 为 (O) 或 nil
+func Conv1d(x, weight, bias *Tensor, opts ConvOptions) (*Tensor, error) {
+	return conv1dVia(conv2d, x, weight, bias, opts)
+}
+
+// ConvTranspose2d 二维转置卷积，x 为 (N, C, H, W)，weight 为 (C, O/groups, KH, KW)，bias 为 (O) 或 nil
+func ConvTranspose2d(x, weight, bias *Tensor, opts ConvOptions) (*Tensor, error) {
+	p, err := opts.resolve(2)
+	if err != nil {
+		return nil, err
+	}
+	return convTranspose2d(x, weight, bias, p)
+}
+
+// ConvTranspose1d 一维转置卷积，x 为 (N, C, L)，weight 为 (C, O/groups, K)，bias 为 (O) 或 nil
+func ConvTranspose1d(x, weight, bias *Tensor, opts ConvOptions) (*Tensor, error) {
+	return conv1dVia(convTranspose2d, x, weight, bias, opts)
+}
+
+// Conv 卷积层，Dims 为 1 或 2
+type Conv struct {
+	BaseModule
+	Dims                    int
+	InChannels, OutChannels int
+	KernelSize              []int
+	Options                 ConvOptions
+	Transposed              bool
+	Weight, Bias            *Tensor // 不使用偏置时 Bias 为 nil
+}
+
+func newConv(dims, in, out int, kernelSize []int, opts ConvOptions, bias, transposed bool) (*Conv, error) {
+	kernel, err := expandSpatial("KernelSize", kernelSize, dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	p, err := opts.resolve(dims)
+	if err != nil {
+		return nil, err
+	}
+	if in%p.groups != 0 || out%p.groups != 0 {
+		return nil, fmt.Errorf("输入通道数 %d 与输出通道数 %d 必须能被分组数 %d 整除", in, out, p.groups)
+	}
+	fanIn := 1
+	for _, k := range kernel {
+		if k <= 0 {
+			return nil, fmt.Errorf("卷积核尺寸必须为正数: %v", kernelSize)
+		}
+		fanIn *= k
+	}
+	// 权重形状：卷积为 (out, in/groups, k...)，转置卷积为 (in, out/groups, k...)
+	shape := []int{out, in / p.groups}
+	if transposed {
+		shape = []int{in, out / p.groups}
+	}
+	fanIn *= shape[1]
+	shape = append(shape, kernel...)
+
+	c := &Conv{Dims: dims, InChannels: in, OutChannels: out, KernelSize: kernel, Options: opts, Transposed: transposed}
+	bound := 1 / math.Sqrt(float64(fanIn))
+	c.Weight = c.RegisterParameter("weight", NewTensor(uniformArray(-bound, bound, shape...), true))
+	if bias {
+		c.Bias = c.RegisterParameter("bias", NewTensor(uniformArray(-bound, bound, out), true))
+	}
+	return c, nil
+}
+
+// NewConv1d 创建一维卷积层，输入形状为 (N, C, L)
+func NewConv1d(in, out, kernelSize int, opts ConvOptions, bias bool) (*Conv, error) {
+	return newConv(1, in, out, []int{kernelSize}, opts, bias, false)
+}
+
+// NewConv2d 创建二维卷积层，输入形状为 (N, C, H, W)，kernelSize 为一个或两个值
+func NewConv2d(in, out int, kernelSize []int, opts ConvOptions, bias bool) (*Conv, error) {
+	return newConv(2, in, out, kernelSize, opts, bias, false)
+}
+
+// NewConvTranspose1d 创建一维转置卷积层
+func NewConvTranspose1d(in, out, kernelSize int, opts ConvOptions, bias bool) (*Conv, error) {
+	return newConv(1, in, out, []int{kernelSize}, opts, bias, true)
+}
+
+// NewConvTranspose2d 创建二维转置卷积层
+func NewConvTranspose2d(in, out int, kernelSize []int, opts ConvOptions, bias bool) (*Conv, error) {
+	return newConv(2, in, out, kernelSize, opts, bias, true)
+}
+
+// Forward 执行卷积
+func (c *Conv) Forward(x *Tensor) (*Tensor, error) {
+	switch {
+	case c.Dims == 1 && !c.Transposed:
+		return Conv1d(x, c.Weight, c.Bias, c.Options)
+	case c.Dims == 1:
+		return ConvTranspose1d(x, c.Weight, c.Bias, c.Options)
+	case !c.Transposed:
+		return Conv2d(x, c.Weight, c.Bias, c.Options)
+	}
+	return ConvTranspose2d(x, c.Weight, c.Bias, c.Options)
+}
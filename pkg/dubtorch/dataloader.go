@@ -0,0 +1,352 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Batch 一个批次，每个字段为对应样本字段沿新的第 0 维堆叠后的数组
+type Batch []*dubnp.Array
+
+// CollateFunc 将若干样本组合为一个批次
+type CollateFunc func(samples []Sample) (Batch, error)
+
+// DefaultCollate 将每个字段沿新的第 0 维堆叠，标量字段堆叠为一维数组
+func DefaultCollate(samples []Sample) (Batch, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("批次中没有样本")
+	}
+	batch := make(Batch, len(samples[0]))
+	fields := make([]*dubnp.Array, len(samples))
+	for k := range batch {
+		for i, s := range samples {
+			if len(s) != len(batch) {
+				return nil, fmt.Errorf("样本的字段个数不一致: %d 与 %d", len(s), len(batch))
+			}
+			fields[i] = s[k]
+		}
+		stacked, err := dubnp.Stack(fields...)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 个字段: %v", k, err)
+		}
+		batch[k] = stacked
+	}
+	return batch, nil
+}
+
+// DataLoaderOptions 数据加载选项
+type DataLoaderOptions struct {
+	BatchSize  int         // 每个批次的样本数，默认 1
+	Shuffle    bool        // 每轮打乱样本顺序，仅支持可随机访问的数据集
+	Seed       int64       // 打乱顺序的随机种子，第 e 轮使用 Seed+e，保证可复现
+	DropLast   bool        // 丢弃最后不足 BatchSize 的批次
+	Collate    CollateFunc // 组合批次的函数，默认 DefaultCollate
+	NumWorkers int         // 并行加载批次的 goroutine 数，为 0 时在调用方的 goroutine 中加载
+	Prefetch   int         // 预取的批次数，默认 2*NumWorkers
+}
+
+// EpochSetter 由需要感知轮次的数据集实现（例如随机数据增强），DataLoader 在每轮开始时调用
+type EpochSetter interface {
+	SetEpoch(epoch int)
+}
+
+// DataLoader 按批次读取数据集，支持打乱、丢弃尾批次、自定义组合与多 goroutine 预取
+type DataLoader struct {
+	dataset  Dataset
+	iterable IterableDataset
+	opts     DataLoaderOptions
+	epoch    int
+}
+
+// NewDataLoader 为可随机访问的数据集创建 DataLoader
+func NewDataLoader(ds Dataset, opts DataLoaderOptions) (*DataLoader, error) {
+	opts, err := normalizeLoaderOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &DataLoader{dataset: ds, opts: opts}, nil
+}
+
+// NewIterableDataLoader 为只能顺序读取的数据集创建 DataLoader，不支持 Shuffle
+func NewIterableDataLoader(ds IterableDataset, opts DataLoaderOptions) (*DataLoader, error) {
+	if opts.Shuffle {
+		return nil, errors.New("可迭代数据集不支持 Shuffle")
+	}
+	opts, err := normalizeLoaderOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return &DataLoader{iterable: ds, opts: opts}, nil
+}
+
+func normalizeLoaderOptions(opts DataLoaderOptions) (DataLoaderOptions, error) {
+	if opts.BatchSize < 0 || opts.NumWorkers < 0 || opts.Prefetch < 0 {
+		return opts, errors.New("BatchSize、NumWorkers 与 Prefetch 不能为负")
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = 1
+	}
+	if opts.Collate == nil {
+		opts.Collate = DefaultCollate
+	}
+	if opts.Prefetch == 0 {
+		opts.Prefetch = 2 * opts.NumWorkers
+	}
+	return opts, nil
+}
+
+// Len 返回每轮的批次数，可迭代数据集返回 -1
+func (l *DataLoader) Len() int {
+	if l.dataset == nil {
+		return -1
+	}
+	n := l.dataset.Len()
+	if l.opts.DropLast {
+		return n / l.opts.BatchSize
+	}
+	return (n + l.opts.BatchSize - 1) / l.opts.BatchSize
+}
+
+// Epoch 返回下一次 Iter 对应的轮次
+func (l *DataLoader) Epoch() int {
+	return l.epoch
+}
+
+// SetEpoch 设置下一次 Iter 的轮次，用于从检查点恢复时复现打乱顺序
+func (l *DataLoader) SetEpoch(epoch int) {
+	l.epoch = epoch
+}
+
+// Iter 开始新的一轮遍历，使用完毕后应调用 Close 释放后台 goroutine
+func (l *DataLoader) Iter() *BatchIterator {
+	epoch := l.epoch
+	l.epoch++
+
+	if setter, ok := l.dataset.(EpochSetter); ok {
+		setter.SetEpoch(epoch)
+	} else if setter, ok := l.iterable.(EpochSetter); ok {
+		setter.SetEpoch(epoch)
+	}
+
+	it := &BatchIterator{loader: l, done: make(chan struct{})}
+	if l.dataset != nil {
+		it.batches = l.batchIndices(epoch)
+	}
+	if l.opts.NumWorkers > 0 {
+		it.startWorkers()
+	}
+	return it
+}
+
+// 计算本轮每个批次包含的样本下标
+func (l *DataLoader) batchIndices(epoch int) [][]int {
+	n := l.dataset.Len()
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	if l.opts.Shuffle {
+		r := rand.New(rand.NewSource(l.opts.Seed + int64(epoch)))
+		r.Shuffle(n, func(i, j int) { order[i], order[j] = order[j], order[i] })
+	}
+
+	var batches [][]int
+	for start := 0; start < n; start += l.opts.BatchSize {
+		end := min(start+l.opts.BatchSize, n)
+		if end-start < l.opts.BatchSize && l.opts.DropLast {
+			break
+		}
+		batches = append(batches, order[start:end])
+	}
+	return batches
+}
+
+// 读取一组下标对应的样本并组合为批次
+func (l *DataLoader) loadBatch(indices []int) (Batch, error) {
+	samples := make([]Sample, len(indices))
+	for i, idx := range indices {
+		s, err := l.dataset.Get(idx)
+		if err != nil {
+			return nil, fmt.Errorf("读取样本 %d 失败: %v", idx, err)
+		}
+		samples[i] = s
+	}
+	return l.opts.Collate(samples)
+}
+
+type batchResult struct {
+	batch Batch
+	err   error
+}
+
+// BatchIterator 一轮遍历中的批次迭代器
+type BatchIterator struct {
+	loader *DataLoader
+
+	// 可随机访问数据集：本轮的批次划分与下一个批次的位置
+	batches [][]int
+	next    int
+
+	// 可迭代数据集：样本迭代器
+	samples SampleIterator
+	eof     bool
+
+	// 多 goroutine 模式：按批次顺序排列的结果通道
+	pending   chan chan batchResult
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Next 返回下一个批次，本轮结束时返回 io.EOF
+func (it *BatchIterator) Next() (Batch, error) {
+	if it.pending != nil {
+		slot, ok := <-it.pending
+		if !ok {
+			return nil, io.EOF
+		}
+		result := <-slot
+		return result.batch, result.err
+	}
+
+	if it.loader.dataset != nil {
+		if it.next >= len(it.batches) {
+			return nil, io.EOF
+		}
+		it.next++
+		return it.loader.loadBatch(it.batches[it.next-1])
+	}
+
+	samples, err := it.nextSamples()
+	if err != nil {
+		return nil, err
+	}
+	return it.loader.opts.Collate(samples)
+}
+
+// 从可迭代数据集中读取下一组样本
+func (it *BatchIterator) nextSamples() ([]Sample, error) {
+	if it.eof {
+		return nil, io.EOF
+	}
+	if it.samples == nil {
+		samples, err := it.loader.iterable.Iter()
+		if err != nil {
+			return nil, err
+		}
+		it.samples = samples
+	}
+
+	opts := it.loader.opts
+	var samples []Sample
+	for len(samples) < opts.BatchSize {
+		s, err := it.samples.Next()
+		if err == io.EOF {
+			it.eof = true
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+	if len(samples) == 0 || (opts.DropLast && len(samples) < opts.BatchSize) {
+		return nil, io.EOF
+	}
+	return samples, nil
+}
+
+// 启动后台 goroutine：一个调度者按顺序分配批次，NumWorkers 个工作者并行加载
+func (it *BatchIterator) startWorkers() {
+	opts := it.loader.opts
+	it.pending = make(chan chan batchResult, opts.Prefetch)
+
+	type job struct {
+		indices []int    // 可随机访问数据集的样本下标
+		samples []Sample // 可迭代数据集已读出的样本
+		slot    chan batchResult
+	}
+	jobs := make(chan job)
+
+	it.wg.Add(opts.NumWorkers)
+	for w := 0; w < opts.NumWorkers; w++ {
+		go func() {
+			defer it.wg.Done()
+			for j := range jobs {
+				var result batchResult
+				if j.samples != nil {
+					result.batch, result.err = opts.Collate(j.samples)
+				} else {
+					result.batch, result.err = it.loader.loadBatch(j.indices)
+				}
+				j.slot <- result
+			}
+		}()
+	}
+
+	it.wg.Add(1)
+	go func() {
+		defer it.wg.Done()
+		defer close(jobs)
+		defer close(it.pending)
+		for {
+			var j job
+			if it.loader.dataset != nil {
+				if it.next >= len(it.batches) {
+					return
+				}
+				j.indices = it.batches[it.next]
+				it.next++
+			} else {
+				samples, err := it.nextSamples()
+				if err == io.EOF {
+					return
+				}
+				if err != nil {
+					// 读取失败时把错误按顺序交给调用方，然后结束本轮
+					slot := make(chan batchResult, 1)
+					slot <- batchResult{err: err}
+					select {
+					case it.pending <- slot:
+					case <-it.done:
+					}
+					return
+				}
+				j.samples = samples
+			}
+			j.slot = make(chan batchResult, 1)
+
+			// 先占住顺序位置，再交给工作者
+			select {
+			case it.pending <- j.slot:
+			case <-it.done:
+				return
+			}
+			select {
+			case jobs <- j:
+			case <-it.done:
+				return
+			}
+		}
+	}()
+}
+
+// Close 停止后台 goroutine，可以在本轮结束前调用，多次调用是安全的，Close 之后不应再调用 Next
+func (it *BatchIterator) Close() {
+	it.closeOnce.Do(func() {
+		close(it.done)
+		if it.pending != nil {
+			// 排空已排队的结果，使阻塞的调度者能够退出
+			go func() {
+				for range it.pending {
+				}
+			}()
+		}
+		it.wg.Wait()
+	})
+}
@@ -1,5 +1,115 @@
 package dubtorch
 
-func DataSet() {
+import (
+	"errors"
+	"fmt"
+	"io"
 
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Sample 一个样本，由若干字段组成，例如 [输入, 标签]
+type Sample []*dubnp.Array
+
+// Dataset 可按下标随机访问的数据集
+type Dataset interface {
+	Len() int
+	Get(i int) (Sample, error)
+}
+
+// SampleIterator 顺序读取样本，读完时返回 io.EOF
+type SampleIterator interface {
+	Next() (Sample, error)
+}
+
+// IterableDataset 只能顺序读取的数据集（例如数据流），每次 Iter 从头开始
+type IterableDataset interface {
+	Iter() (SampleIterator, error)
+}
+
+// TensorDataset 由若干第 0 维长度相同的数组组成的数据集，第 i 个样本为各数组的第 i 行
+type TensorDataset struct {
+	arrays []*dubnp.Array
+}
+
+// NewTensorDataset 创建 TensorDataset
+func NewTensorDataset(arrays ...*dubnp.Array) (*TensorDataset, error) {
+	if len(arrays) == 0 {
+		return nil, errors.New("至少需要一个数组")
+	}
+	for _, a := range arrays {
+		if len(a.Shape) == 0 || a.Shape[0] != arrays[0].Shape[0] {
+			return nil, fmt.Errorf("数组的第 0 维长度不一致: %v 与 %v", arrays[0].Shape, a.Shape)
+		}
+	}
+	return &TensorDataset{arrays: arrays}, nil
+}
+
+// Len 返回样本个数
+func (d *TensorDataset) Len() int {
+	return d.arrays[0].Shape[0]
+}
+
+// Get 返回第 i 个样本，与原数组共享数据
+func (d *TensorDataset) Get(i int) (Sample, error) {
+	sample := make(Sample, len(d.arrays))
+	for k, a := range d.arrays {
+		row, err := a.Index(i)
+		if err != nil {
+			return nil, err
+		}
+		sample[k] = row
+	}
+	return sample, nil
+}
+
+// Subset 数据集中由 indices 指定的子集
+type Subset struct {
+	Dataset Dataset
+	Indices []int
+}
+
+// NewSubset 创建子集
+func NewSubset(ds Dataset, indices []int) (*Subset, error) {
+	for _, i := range indices {
+		if i < 0 || i >= ds.Len() {
+			return nil, fmt.Errorf("下标 %d 超出数据集范围 [0, %d)", i, ds.Len())
+		}
+	}
+	return &Subset{Dataset: ds, Indices: indices}, nil
+}
+
+// Len 返回子集的样本个数
+func (s *Subset) Len() int {
+	return len(s.Indices)
+}
+
+// Get 返回子集中的第 i 个样本
+func (s *Subset) Get(i int) (Sample, error) {
+	if i < 0 || i >= len(s.Indices) {
+		return nil, fmt.Errorf("下标 %d 超出子集范围 [0, %d)", i, len(s.Indices))
+	}
+	return s.Dataset.Get(s.Indices[i])
+}
+
+// sliceIterator 在切片上顺序读取样本
+type sliceIterator struct {
+	samples []Sample
+	pos     int
+}
+
+func (it *sliceIterator) Next() (Sample, error) {
+	if it.pos >= len(it.samples) {
+		return nil, io.EOF
+	}
+	it.pos++
+	return it.samples[it.pos-1], nil
+}
+
+// SliceDataset 由内存中的样本组成的可迭代数据集
+type SliceDataset []Sample
+
+// Iter 从头开始读取样本
+func (s SliceDataset) Iter() (SampleIterator, error) {
+	return &sliceIterator{samples: s}, nil
 }
@@ -0,0 +1,91 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// 每对进程之间缓冲的消息数
+const memoryQueueSize = 64
+
+// 同一进程内的一组 MemoryTransport 共享的信道
+type memoryGroup struct {
+	queues [][]chan *Message // queues[from][to]
+	done   chan struct{}
+	once   sync.Once
+}
+
+// MemoryTransport 单进程内用信道模拟的 Transport，用于测试与单机多副本训练
+type MemoryTransport struct {
+	group *memoryGroup
+	rank  int
+}
+
+// NewMemoryGroup 创建 size 个互相连通的 MemoryTransport，关闭其中任意一个会关闭整组
+func NewMemoryGroup(size int) ([]*MemoryTransport, error) {
+	if size <= 0 {
+		return nil, errors.New("进程数必须为正数")
+	}
+	g := &memoryGroup{queues: make([][]chan *Message, size), done: make(chan struct{})}
+	for i := range g.queues {
+		g.queues[i] = make([]chan *Message, size)
+		for j := range g.queues[i] {
+			if i != j {
+				g.queues[i][j] = make(chan *Message, memoryQueueSize)
+			}
+		}
+	}
+	out := make([]*MemoryTransport, size)
+	for i := range out {
+		out[i] = &MemoryTransport{group: g, rank: i}
+	}
+	return out, nil
+}
+
+// Rank 返回本进程的编号
+func (t *MemoryTransport) Rank() int {
+	return t.rank
+}
+
+// Size 返回进程总数
+func (t *MemoryTransport) Size() int {
+	return len(t.group.queues)
+}
+
+// Send 发送消息的副本，接收方修改数据不会影响发送方
+func (t *MemoryTransport) Send(ctx context.Context, to int, msg *Message) error {
+	if err := checkPeer(t, to); err != nil {
+		return err
+	}
+	cp := &Message{Kind: msg.Kind, Seq: msg.Seq, Chunk: msg.Chunk, Data: append([]float64(nil), msg.Data...)}
+	select {
+	case t.group.queues[t.rank][to] <- cp:
+		return nil
+	case <-t.group.done:
+		return errors.New("传输已关闭")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Recv 接收来自 from 的下一条消息
+func (t *MemoryTransport) Recv(ctx context.Context, from int) (*Message, error) {
+	if err := checkPeer(t, from); err != nil {
+		return nil, err
+	}
+	select {
+	case msg := <-t.group.queues[from][t.rank]:
+		return msg, nil
+	case <-t.group.done:
+		return nil, errors.New("传输已关闭")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Close 关闭整组传输，阻塞中的 Send 与 Recv 返回错误
+func (t *MemoryTransport) Close() error {
+	t.group.once.Do(func() { close(t.group.done) })
+	return nil
+}
@@ -0,0 +1,157 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// Shard 按编号交错划分数据集，进程 rank 取下标 rank, rank+size, ...。
+// 样本数不能整除时从头补齐，保证各进程的批次数相同，否则 all-reduce 会因步数不一致而阻塞
+func Shard(ds dubtorch.Dataset, rank, size int) (*dubtorch.Subset, error) {
+	if size <= 0 || rank < 0 || rank >= size {
+		return nil, fmt.Errorf("无效的编号 %d（共 %d 个进程）", rank, size)
+	}
+	n := ds.Len()
+	if n == 0 {
+		return nil, errors.New("数据集为空")
+	}
+	per := (n + size - 1) / size
+	indices := make([]int, per)
+	for i := range indices {
+		indices[i] = (rank + i*size) % n
+	}
+	return dubtorch.NewSubset(ds, indices)
+}
+
+// 参数的元素总数
+func numel(params []*dubtorch.Tensor) int {
+	n := 0
+	for _, p := range params {
+		n += len(p.Data.Data)
+	}
+	return n
+}
+
+// 将参数拼接为一个连续的缓冲区，get 取出每个参数对应的数组，为 nil 时按 0 处理
+func flatten(params []*dubtorch.Tensor, get func(p *dubtorch.Tensor) *dubnp.Array) []float64 {
+	buf := make([]float64, 0, numel(params))
+	for _, p := range params {
+		if a := get(p); a != nil {
+			buf = append(buf, a.Data...)
+		} else {
+			buf = append(buf, make([]float64, len(p.Data.Data))...)
+		}
+	}
+	return buf
+}
+
+// 拼接各参数的梯度，末尾附带每个参数是否有梯度的标记（1 或 0），没有梯度的参数按 0 拼接
+func flattenGrads(params []*dubtorch.Tensor) []float64 {
+	buf := flatten(params, func(p *dubtorch.Tensor) *dubnp.Array { return p.Grad })
+	for _, p := range params {
+		present := 0.0
+		if p.Grad != nil {
+			present = 1
+		}
+		buf = append(buf, present)
+	}
+	return buf
+}
+
+// 按 flattenGrads 的布局把 buf 乘以 scale 后写回梯度，标记为 0 的参数梯度为 nil
+func unflattenGrads(params []*dubtorch.Tensor, buf []float64, scale float64) {
+	present := buf[len(buf)-len(params):]
+	for i, p := range params {
+		n := len(p.Data.Data)
+		if present[i] == 0 {
+			p.Grad = nil
+			buf = buf[n:]
+			continue
+		}
+		grad := dubnp.Zeros(p.Data.Shape...)
+		for j := range grad.Data {
+			grad.Data[j] = buf[j] * scale
+		}
+		p.Grad = grad
+		buf = buf[n:]
+	}
+}
+
+// BroadcastParameters 用 root 进程的参数覆盖其余进程，训练开始前调用可保证各副本的初始值一致
+func BroadcastParameters(ctx context.Context, ring *Ring, root int, params []*dubtorch.Tensor) error {
+	buf := flatten(params, func(p *dubtorch.Tensor) *dubnp.Array { return p.Data })
+	if err := ring.Broadcast(ctx, root, buf); err != nil {
+		return err
+	}
+	for _, p := range params {
+		n := copy(p.Data.Data, buf)
+		buf = buf[n:]
+	}
+	return nil
+}
+
+// AllReduceGradients 把各进程的梯度替换为所有进程梯度的平均值，所有进程必须以相同的顺序传入相同形状的参数。
+// 缓冲区末尾附带每个参数是否有梯度的标记，一起参与归约：所有进程都没有梯度的参数（冻结或未使用）保持 nil，
+// 与单进程训练一样不会被优化器更新；只在部分进程有梯度的参数，其余进程按 0 参与平均
+func AllReduceGradients(ctx context.Context, ring *Ring, params []*dubtorch.Tensor) error {
+	buf := flattenGrads(params)
+	if err := ring.AllReduce(ctx, buf); err != nil {
+		return err
+	}
+	unflattenGrads(params, buf, 1/float64(ring.Size()))
+	return nil
+}
+
+// DataParallel 包装优化器：每次 Step 前先用 all-reduce 平均各副本的梯度，
+// 再由内部优化器更新参数，可直接交给 dubtorch.Trainer 使用，Trainer 以 Fit 的 ctx 调用 StepCtx
+type DataParallel struct {
+	Ring      *Ring
+	Params    []*dubtorch.Tensor
+	Optimizer dubtorch.Optimizer
+}
+
+// NewDataParallel 创建数据并行优化器，并以 0 号进程的参数初始化所有副本，ctx 只用于这次广播
+func NewDataParallel(ctx context.Context, ring *Ring, params []*dubtorch.Tensor, opt dubtorch.Optimizer) (*DataParallel, error) {
+	if ring == nil || opt == nil {
+		return nil, errors.New("通信器与优化器不能为空")
+	}
+	if len(params) == 0 {
+		return nil, errors.New("没有需要同步的参数")
+	}
+	if err := BroadcastParameters(ctx, ring, 0, params); err != nil {
+		return nil, fmt.Errorf("广播初始参数失败: %v", err)
+	}
+	return &DataParallel{Ring: ring, Params: params, Optimizer: opt}, nil
+}
+
+// Step 同步梯度后更新参数，等同于 StepCtx(context.Background())
+func (d *DataParallel) Step() error {
+	return d.StepCtx(context.Background())
+}
+
+// StepCtx 在 ctx 下同步梯度后更新参数
+func (d *DataParallel) StepCtx(ctx context.Context) error {
+	if err := AllReduceGradients(ctx, d.Ring, d.Params); err != nil {
+		return fmt.Errorf("梯度同步失败: %v", err)
+	}
+	return d.Optimizer.Step()
+}
+
+// ZeroGrad 清空梯度
+func (d *DataParallel) ZeroGrad() {
+	d.Optimizer.ZeroGrad()
+}
+
+// StateDict 返回内部优化器的状态，各副本的状态相同
+func (d *DataParallel) StateDict() map[string]*dubnp.Array {
+	return d.Optimizer.StateDict()
+}
+
+// LoadStateDict 加载内部优化器的状态
+func (d *DataParallel) LoadStateDict(state map[string]*dubnp.Array) error {
+	return d.Optimizer.LoadStateDict(state)
+}
@@ -0,0 +1,200 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// SplitSequential 按 sizes 把模型依次切分为若干阶段，sizes[i] 为第 i 个阶段的子模块个数，
+// 之和必须等于模型的子模块个数。各阶段与原模型共享子模块，并保留原来的子模块名
+func SplitSequential(model *dubtorch.Sequential, sizes []int) ([]*dubtorch.Sequential, error) {
+	total := 0
+	for _, n := range sizes {
+		if n <= 0 {
+			return nil, fmt.Errorf("每个阶段至少包含一个子模块: %v", sizes)
+		}
+		total += n
+	}
+	if total != model.Len() {
+		return nil, fmt.Errorf("各阶段的子模块数之和 %d 与模型的 %d 不一致", total, model.Len())
+	}
+	stages := make([]*dubtorch.Sequential, len(sizes))
+	start := 0
+	for i, n := range sizes {
+		var err error
+		if stages[i], err = model.Slice(start, start+n); err != nil {
+			return nil, err
+		}
+		start += n
+	}
+	return stages, nil
+}
+
+// PipelineOptions 流水线并行的选项，零值表示默认值
+type PipelineOptions struct {
+	MicroBatches int // 每个批次切分的微批次个数，默认 4；批大小更小时按批大小切分
+	ChunkSize    int // 每条消息最多携带的元素个数，默认 8192
+}
+
+// PipelineStage 流水线中的一个阶段，阶段编号即 Transport 中的进程编号。
+// 第 i 个阶段从第 i-1 个阶段接收激活值、向第 i+1 个阶段发送输出，反向时方向相反
+type PipelineStage struct {
+	transport Transport
+	Module    dubtorch.Module
+	Optimizer dubtorch.Optimizer
+	Loss      dubtorch.LossFunc // 只在最后一个阶段使用
+	opts      PipelineOptions
+	step      uint64
+}
+
+// NewPipelineStage 创建本进程负责的阶段，opt 只更新本阶段的参数
+func NewPipelineStage(t Transport, module dubtorch.Module, opt dubtorch.Optimizer, loss dubtorch.LossFunc, opts PipelineOptions) (*PipelineStage, error) {
+	if t == nil || module == nil || opt == nil {
+		return nil, errors.New("传输、模块与优化器不能为空")
+	}
+	if t.Rank() == t.Size()-1 && loss == nil {
+		return nil, errors.New("最后一个阶段需要损失函数")
+	}
+	if opts.MicroBatches < 0 || opts.ChunkSize < 0 {
+		return nil, fmt.Errorf("无效的选项 MicroBatches=%d ChunkSize=%d", opts.MicroBatches, opts.ChunkSize)
+	}
+	if opts.MicroBatches == 0 {
+		opts.MicroBatches = 4
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	return &PipelineStage{transport: t, Module: module, Optimizer: opt, Loss: loss, opts: opts}, nil
+}
+
+// Stage 返回本阶段的编号
+func (p *PipelineStage) Stage() int {
+	return p.transport.Rank()
+}
+
+// NumStages 返回阶段总数
+func (p *PipelineStage) NumStages() int {
+	return p.transport.Size()
+}
+
+// 取数组第一维的 [start, end) 行
+func rows(a *dubnp.Array, start, end int) *dubnp.Array {
+	width := 1
+	for _, d := range a.Shape[1:] {
+		width *= d
+	}
+	shape := append([]int{end - start}, a.Shape[1:]...)
+	return &dubnp.Array{Data: append([]float64(nil), a.Data[start*width:end*width]...), Shape: shape}
+}
+
+// Step 按 GPipe 的调度执行一个训练步：先依次前向所有微批次，再逆序反向，
+// 梯度在各微批次间累加，最后各阶段用自己的优化器更新参数。
+// 第一个阶段传入 input，最后一个阶段传入 target，其余阶段传 nil；
+// 返回整个批次的平均损失，只在最后一个阶段有效
+func (p *PipelineStage) Step(ctx context.Context, input, target *dubnp.Array) (float64, error) {
+	stage, last := p.Stage(), p.NumStages()-1
+	step := p.step
+	p.step++
+
+	// 第一个阶段决定微批次个数，并沿流水线向后传递
+	var micro int
+	if stage == 0 {
+		if input == nil || len(input.Shape) == 0 || input.Shape[0] == 0 {
+			return 0, errors.New("第一个阶段需要非空的批次输入")
+		}
+		micro = min(p.opts.MicroBatches, input.Shape[0])
+	} else {
+		msg, err := p.transport.Recv(ctx, stage-1)
+		if err != nil {
+			return 0, err
+		}
+		if msg.Kind != KindSchedule || msg.Seq != step || len(msg.Data) != 1 {
+			return 0, fmt.Errorf("阶段 %d 期望第 %d 步的调度消息，收到类型 %d 序号 %d", stage, step, msg.Kind, msg.Seq)
+		}
+		micro = int(msg.Data[0])
+	}
+	if stage < last {
+		if err := p.transport.Send(ctx, stage+1, &Message{Kind: KindSchedule, Seq: step, Data: []float64{float64(micro)}}); err != nil {
+			return 0, err
+		}
+	}
+	if stage == last && (target == nil || len(target.Shape) == 0) {
+		return 0, errors.New("最后一个阶段需要批次的目标")
+	}
+
+	inputs := make([]*dubtorch.Tensor, micro)
+	outputs := make([]*dubtorch.Tensor, micro)
+	total := 0.0
+	for m := 0; m < micro; m++ {
+		var x *dubnp.Array
+		var err error
+		if stage == 0 {
+			start, end := segment(input.Shape[0], micro, m)
+			x = rows(input, start, end)
+		} else if x, err = recvArray(ctx, p.transport, stage-1, KindActivation, uint64(m)); err != nil {
+			return 0, fmt.Errorf("阶段 %d 接收微批次 %d 的激活值: %v", stage, m, err)
+		}
+		// 中间阶段需要输入的梯度传回上一阶段
+		inputs[m] = dubtorch.NewTensor(x, stage > 0)
+		out, err := p.Module.Forward(inputs[m])
+		if err != nil {
+			return 0, fmt.Errorf("阶段 %d 微批次 %d: %v", stage, m, err)
+		}
+		if stage < last {
+			if err := sendArray(ctx, p.transport, stage+1, KindActivation, uint64(m), out.Data, p.opts.ChunkSize); err != nil {
+				return 0, err
+			}
+			outputs[m] = out
+			continue
+		}
+		// 各微批次的平均损失按样本数加权，使梯度之和等于整个批次的平均损失的梯度
+		n := out.Data.Shape[0]
+		start, end := segment(target.Shape[0], micro, m)
+		if end-start != n {
+			return 0, fmt.Errorf("微批次 %d 的输出有 %d 行，目标有 %d 行", m, n, end-start)
+		}
+		loss, err := p.Loss(out, dubtorch.NewTensor(rows(target, start, end), false))
+		if err != nil {
+			return 0, err
+		}
+		weight := float64(n) / float64(target.Shape[0])
+		total += loss.Data.Data[0] * weight
+		outputs[m] = loss.MulScalar(weight)
+	}
+
+	for m := micro - 1; m >= 0; m-- {
+		var grad *dubnp.Array
+		if stage == last {
+			grad = dubnp.Ones(outputs[m].Data.Shape...)
+		} else {
+			var err error
+			if grad, err = recvArray(ctx, p.transport, stage+1, KindGradient, uint64(m)); err != nil {
+				return 0, fmt.Errorf("阶段 %d 接收微批次 %d 的梯度: %v", stage, m, err)
+			}
+		}
+		if outputs[m].RequiresGrad {
+			if err := outputs[m].BackwardWithGrad(grad); err != nil {
+				return 0, fmt.Errorf("阶段 %d 微批次 %d 反向传播: %v", stage, m, err)
+			}
+		}
+		if stage > 0 {
+			g := inputs[m].Grad
+			if g == nil {
+				g = dubnp.Zeros(inputs[m].Data.Shape...)
+			}
+			if err := sendArray(ctx, p.transport, stage-1, KindGradient, uint64(m), g, p.opts.ChunkSize); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if err := p.Optimizer.Step(); err != nil {
+		return 0, err
+	}
+	p.Optimizer.ZeroGrad()
+	return total, nil
+}
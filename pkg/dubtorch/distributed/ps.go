@@ -0,0 +1,293 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// ParameterServerOptions 参数服务器的选项，零值表示默认值
+type ParameterServerOptions struct {
+	// MaxStaleness 允许的最大延迟：梯度所用参数的版本落后当前版本超过该值时丢弃。
+	// 为 0 时只采用基于最新参数的梯度（同步），为负数时不限制
+	MaxStaleness int
+	ChunkSize    int // 每条消息最多携带的元素个数，默认 8192
+}
+
+// ParameterServer 保存全局参数并用优化器应用工作者推送的梯度，实现异步 SGD。
+// 每应用一次梯度，参数版本加一
+type ParameterServer struct {
+	transport Transport
+	params    []*dubtorch.Tensor
+	optimizer dubtorch.Optimizer
+	opts      ParameterServerOptions
+
+	mu       sync.Mutex
+	version  uint64
+	accepted int
+	rejected int
+}
+
+// NewParameterServer 创建参数服务器，params 为服务器持有的模型参数，optimizer 负责更新它们
+func NewParameterServer(t Transport, params []*dubtorch.Tensor, optimizer dubtorch.Optimizer, opts ParameterServerOptions) (*ParameterServer, error) {
+	if t == nil || optimizer == nil {
+		return nil, errors.New("传输与优化器不能为空")
+	}
+	if len(params) == 0 {
+		return nil, errors.New("没有需要服务的参数")
+	}
+	if opts.ChunkSize < 0 {
+		return nil, fmt.Errorf("无效的块大小 %d", opts.ChunkSize)
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	return &ParameterServer{transport: t, params: params, optimizer: optimizer, opts: opts}, nil
+}
+
+// Version 返回当前的参数版本
+func (s *ParameterServer) Version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// Stats 返回被采用与因过期被丢弃的梯度个数
+func (s *ParameterServer) Stats() (accepted, rejected int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.accepted, s.rejected
+}
+
+// Serve 为 workers 中的每个工作者提供服务，所有工作者发送停止消息后返回
+func (s *ParameterServer) Serve(ctx context.Context, workers []int) error {
+	if len(workers) == 0 {
+		return errors.New("没有工作者")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(workers))
+	for _, w := range workers {
+		if err := checkPeer(s.transport, w); err != nil {
+			return err
+		}
+		go func(w int) {
+			err := s.serveWorker(ctx, w)
+			if err != nil {
+				// 任一工作者出错时停止整个服务
+				cancel()
+				err = fmt.Errorf("工作者 %d: %v", w, err)
+			}
+			errs <- err
+		}(w)
+	}
+	var first error
+	for range workers {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// 依次处理一个工作者的请求
+func (s *ParameterServer) serveWorker(ctx context.Context, w int) error {
+	for {
+		msg, err := s.transport.Recv(ctx, w)
+		if err != nil {
+			return err
+		}
+		switch msg.Kind {
+		case KindPull:
+			s.mu.Lock()
+			version := s.version
+			buf := flatten(s.params, func(p *dubtorch.Tensor) *dubnp.Array { return p.Data })
+			s.mu.Unlock()
+			if err := sendChunks(ctx, s.transport, w, KindParams, version, buf, s.opts.ChunkSize); err != nil {
+				return err
+			}
+		case KindPush:
+			// 梯度之后附带每个参数是否有梯度的标记，见 flattenGrads
+			grad := make([]float64, numel(s.params)+len(s.params))
+			base, err := recvChunks(ctx, s.transport, w, msg, grad)
+			if err != nil {
+				return err
+			}
+			accepted, version, err := s.apply(base, grad)
+			if err != nil {
+				return err
+			}
+			flag := 0.0
+			if accepted {
+				flag = 1
+			}
+			if err := s.transport.Send(ctx, w, &Message{Kind: KindAck, Seq: version, Data: []float64{flag}}); err != nil {
+				return err
+			}
+		case KindStop:
+			return nil
+		default:
+			return fmt.Errorf("无法处理类型为 %d 的消息", msg.Kind)
+		}
+	}
+}
+
+// 检查梯度的延迟，未过期时用优化器更新参数
+func (s *ParameterServer) apply(base uint64, grad []float64) (bool, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if base > s.version {
+		return false, s.version, fmt.Errorf("梯度的版本 %d 超过当前版本 %d", base, s.version)
+	}
+	if s.opts.MaxStaleness >= 0 && s.version-base > uint64(s.opts.MaxStaleness) {
+		s.rejected++
+		return false, s.version, nil
+	}
+	// 工作者没有梯度的参数保持 nil，不被优化器更新
+	unflattenGrads(s.params, grad, 1)
+	if err := s.optimizer.Step(); err != nil {
+		return false, s.version, err
+	}
+	s.optimizer.ZeroGrad()
+	s.version++
+	s.accepted++
+	return true, s.version, nil
+}
+
+// WorkerOptions 工作者的选项，零值表示默认值
+type WorkerOptions struct {
+	ChunkSize int // 每条消息最多携带的元素个数，默认 8192
+}
+
+// Worker 参数服务器模式下的工作者：从服务器拉取参数，在本地计算梯度后推送回去。
+// Worker 实现 dubtorch.Optimizer，可直接交给 Trainer 使用，Trainer 以 Fit 的 ctx 调用 StepCtx；
+// 优化器状态保存在服务器上
+type Worker struct {
+	transport Transport
+	server    int
+	params    []*dubtorch.Tensor
+	opts      WorkerOptions
+	version   uint64
+	rejected  int
+}
+
+// NewWorker 创建工作者并拉取一次参数，ctx 只用于这次拉取
+func NewWorker(ctx context.Context, t Transport, server int, params []*dubtorch.Tensor, opts WorkerOptions) (*Worker, error) {
+	if t == nil {
+		return nil, errors.New("传输不能为空")
+	}
+	if err := checkPeer(t, server); err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return nil, errors.New("没有需要同步的参数")
+	}
+	if opts.ChunkSize < 0 {
+		return nil, fmt.Errorf("无效的块大小 %d", opts.ChunkSize)
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	w := &Worker{transport: t, server: server, params: params, opts: opts}
+	if err := w.Pull(ctx); err != nil {
+		return nil, fmt.Errorf("拉取初始参数失败: %v", err)
+	}
+	return w, nil
+}
+
+// Version 返回本地参数的版本
+func (w *Worker) Version() uint64 {
+	return w.version
+}
+
+// Rejected 返回因过期被服务器丢弃的梯度个数
+func (w *Worker) Rejected() int {
+	return w.rejected
+}
+
+// Pull 用服务器上的最新参数覆盖本地参数
+func (w *Worker) Pull(ctx context.Context) error {
+	if err := w.transport.Send(ctx, w.server, &Message{Kind: KindPull}); err != nil {
+		return err
+	}
+	first, err := w.transport.Recv(ctx, w.server)
+	if err != nil {
+		return err
+	}
+	if first.Kind != KindParams {
+		return fmt.Errorf("期望参数消息，收到类型 %d", first.Kind)
+	}
+	buf := make([]float64, numel(w.params))
+	version, err := recvChunks(ctx, w.transport, w.server, first, buf)
+	if err != nil {
+		return err
+	}
+	for _, p := range w.params {
+		n := copy(p.Data.Data, buf)
+		buf = buf[n:]
+	}
+	w.version = version
+	return nil
+}
+
+// Push 推送本地梯度，返回梯度是否被服务器采用；没有梯度的参数不会被服务器更新
+func (w *Worker) Push(ctx context.Context) (bool, error) {
+	grad := flattenGrads(w.params)
+	if err := sendChunks(ctx, w.transport, w.server, KindPush, w.version, grad, w.opts.ChunkSize); err != nil {
+		return false, err
+	}
+	ack, err := w.transport.Recv(ctx, w.server)
+	if err != nil {
+		return false, err
+	}
+	if ack.Kind != KindAck || len(ack.Data) != 1 {
+		return false, fmt.Errorf("期望推送的答复，收到类型 %d", ack.Kind)
+	}
+	if ack.Data[0] == 0 {
+		w.rejected++
+		return false, nil
+	}
+	return true, nil
+}
+
+// Step 等同于 StepCtx(context.Background())
+func (w *Worker) Step() error {
+	return w.StepCtx(context.Background())
+}
+
+// StepCtx 在 ctx 下推送梯度并拉取最新参数；梯度因过期被丢弃不算错误
+func (w *Worker) StepCtx(ctx context.Context) error {
+	if _, err := w.Push(ctx); err != nil {
+		return err
+	}
+	return w.Pull(ctx)
+}
+
+// ZeroGrad 清空本地梯度
+func (w *Worker) ZeroGrad() {
+	for _, p := range w.params {
+		p.ZeroGrad()
+	}
+}
+
+// StateDict 优化器状态保存在服务器上，工作者没有需要保存的状态
+func (w *Worker) StateDict() map[string]*dubnp.Array {
+	return map[string]*dubnp.Array{}
+}
+
+// LoadStateDict 工作者没有状态，只接受空的状态
+func (w *Worker) LoadStateDict(state map[string]*dubnp.Array) error {
+	if len(state) != 0 {
+		return errors.New("工作者没有优化器状态，请在参数服务器上加载")
+	}
+	return nil
+}
+
+// Stop 通知服务器本工作者已结束
+func (w *Worker) Stop(ctx context.Context) error {
+	return w.transport.Send(ctx, w.server, &Message{Kind: KindStop})
+}
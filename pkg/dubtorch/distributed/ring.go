@@ -0,0 +1,169 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 每条消息默认携带的元素个数
+const defaultChunkSize = 8192
+
+// RingOptions 环形集合通信的选项，零值表示默认值
+type RingOptions struct {
+	ChunkSize int // 每条消息最多携带的元素个数，默认 8192
+}
+
+// Ring 按编号 0 -> 1 -> ... -> Size-1 -> 0 组成环的集合通信器。
+// 所有进程必须以相同的顺序调用相同的集合操作，且数据长度一致
+type Ring struct {
+	transport Transport
+	opts      RingOptions
+	seq       uint64
+}
+
+// NewRing 在 transport 上创建环形通信器
+func NewRing(t Transport, opts RingOptions) (*Ring, error) {
+	if t == nil {
+		return nil, errors.New("传输不能为空")
+	}
+	if opts.ChunkSize < 0 {
+		return nil, fmt.Errorf("无效的块大小 %d", opts.ChunkSize)
+	}
+	if opts.ChunkSize == 0 {
+		opts.ChunkSize = defaultChunkSize
+	}
+	return &Ring{transport: t, opts: opts}, nil
+}
+
+// Rank 返回本进程的编号
+func (r *Ring) Rank() int {
+	return r.transport.Rank()
+}
+
+// Size 返回进程总数
+func (r *Ring) Size() int {
+	return r.transport.Size()
+}
+
+// 将长度为 n 的数据均分为 size 段，返回第 i 段的起止位置；前 n%size 段多一个元素
+func segment(n, size, i int) (int, int) {
+	q, rem := n/size, n%size
+	start := i*q + min(i, rem)
+	end := start + q
+	if i < rem {
+		end++
+	}
+	return start, end
+}
+
+// 向后继发送 send 段的同时从前驱接收 recv 段，收到的每块交给 apply 处理
+func (r *Ring) exchange(ctx context.Context, seq uint64, send, recv []float64, apply func(offset int, data []float64)) error {
+	size, rank := r.Size(), r.Rank()
+	next, prev := (rank+1)%size, (rank+size-1)%size
+	chunk := r.opts.ChunkSize
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 先启动发送，避免两端都阻塞在发送上
+	sent := make(chan error, 1)
+	go func() { sent <- sendChunks(ctx, r.transport, next, KindCollective, seq, send, chunk) }()
+
+	var err error
+	for i, off := uint32(0), 0; off < len(recv) && err == nil; i, off = i+1, off+chunk {
+		var msg *Message
+		if msg, err = r.transport.Recv(ctx, prev); err != nil {
+			break
+		}
+		want := min(chunk, len(recv)-off)
+		if msg.Kind != KindCollective || msg.Seq != seq || msg.Chunk != i || len(msg.Data) != want {
+			err = fmt.Errorf("进程 %d 收到意外的消息（类型 %d 序号 %d 块 %d 长度 %d），期望序号 %d 块 %d 长度 %d",
+				rank, msg.Kind, msg.Seq, msg.Chunk, len(msg.Data), seq, i, want)
+			break
+		}
+		apply(off, msg.Data)
+	}
+	if err != nil {
+		// 接收失败时中断仍在阻塞的发送
+		cancel()
+	}
+	if sendErr := <-sent; err == nil {
+		err = sendErr
+	}
+	return err
+}
+
+// AllReduce 原地求所有进程 data 的逐元素之和。
+//
+// 数据分为 Size 段，先经 Size-1 轮 reduce-scatter 让每段在某个进程上累加完整，
+// 再经 Size-1 轮 all-gather 把结果传遍整个环。第 j 段总是按 j, j+1, ... 的环上顺序累加，
+// 与消息到达的时机无关，因此结果可以逐位复现，且所有进程得到完全相同的值
+func (r *Ring) AllReduce(ctx context.Context, data []float64) error {
+	size, rank := r.Size(), r.Rank()
+	if size == 1 {
+		return nil
+	}
+	seq := r.seq
+	r.seq++
+	part := func(i int) []float64 {
+		start, end := segment(len(data), size, ((i%size)+size)%size)
+		return data[start:end]
+	}
+	for s := 0; s < size-1; s++ {
+		dst := part(rank - s - 1)
+		err := r.exchange(ctx, seq, part(rank-s), dst, func(off int, chunk []float64) {
+			for k, v := range chunk {
+				// 前驱传来的是更早进程的部分和，放在加号左侧以固定累加顺序
+				dst[off+k] = v + dst[off+k]
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("reduce-scatter 第 %d 轮: %v", s, err)
+		}
+	}
+	for s := 0; s < size-1; s++ {
+		dst := part(rank - s)
+		err := r.exchange(ctx, seq, part(rank-s+1), dst, func(off int, chunk []float64) {
+			copy(dst[off:], chunk)
+		})
+		if err != nil {
+			return fmt.Errorf("all-gather 第 %d 轮: %v", s, err)
+		}
+	}
+	return nil
+}
+
+// Broadcast 将 root 上的 data 沿环依次转发，覆盖其余进程的 data
+func (r *Ring) Broadcast(ctx context.Context, root int, data []float64) error {
+	size, rank := r.Size(), r.Rank()
+	if root < 0 || root >= size {
+		return fmt.Errorf("无效的根进程 %d", root)
+	}
+	if size == 1 {
+		return nil
+	}
+	seq := r.seq
+	r.seq++
+	next, prev := (rank+1)%size, (rank+size-1)%size
+	chunk := r.opts.ChunkSize
+	// 每块收到后立即转发，长数据在环上流水传输
+	for i, off := uint32(0), 0; off < len(data); i, off = i+1, off+chunk {
+		block := data[off:min(off+chunk, len(data))]
+		if rank != root {
+			msg, err := r.transport.Recv(ctx, prev)
+			if err != nil {
+				return err
+			}
+			if msg.Kind != KindCollective || msg.Seq != seq || msg.Chunk != i || len(msg.Data) != len(block) {
+				return fmt.Errorf("进程 %d 收到意外的广播消息（序号 %d 块 %d）", rank, msg.Seq, msg.Chunk)
+			}
+			copy(block, msg.Data)
+		}
+		if next != root {
+			if err := r.transport.Send(ctx, next, &Message{Seq: seq, Chunk: i, Data: block}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package distributed
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// 单条消息的最大字节数，超过时认为数据流已损坏
+const maxFrameSize = 1 << 30
+
+// 连接对端失败后的重试间隔
+const dialRetry = 100 * time.Millisecond
+
+// cell 之间的一条单向连接
+type tcpPeer struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// TCPTransport 基于 TCP 的 Transport。cell 的 UDP 消息可能丢失或乱序，
+// 梯度同步需要可靠有序的通道，因此每对进程之间建立两条 TCP 连接，各负责一个方向
+type TCPTransport struct {
+	rank     int
+	listener net.Listener
+	out, in  []*tcpPeer
+}
+
+// NewTCPTransport 在 addrs[rank] 上监听并连接其余所有进程，
+// 各进程可以先后启动，未就绪的对端会一直重试直到 ctx 结束
+func NewTCPTransport(ctx context.Context, rank int, addrs []string) (*TCPTransport, error) {
+	if rank < 0 || rank >= len(addrs) {
+		return nil, fmt.Errorf("编号 %d 超出地址列表范围 [0, %d)", rank, len(addrs))
+	}
+	listener, err := net.Listen("tcp", addrs[rank])
+	if err != nil {
+		return nil, err
+	}
+	t := &TCPTransport{rank: rank, listener: listener, out: make([]*tcpPeer, len(addrs)), in: make([]*tcpPeer, len(addrs))}
+
+	accepted := make(chan error, 1)
+	go func() { accepted <- t.accept(len(addrs) - 1) }()
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+
+	for peer, addr := range addrs {
+		if peer == rank {
+			continue
+		}
+		conn, err := dial(ctx, addr)
+		if err == nil {
+			err = binary.Write(conn, binary.LittleEndian, uint32(rank))
+		}
+		if err != nil {
+			listener.Close()
+			<-accepted
+			t.Close()
+			return nil, fmt.Errorf("连接进程 %d (%s) 失败: %v", peer, addr, err)
+		}
+		t.out[peer] = &tcpPeer{conn: conn}
+	}
+	if err := <-accepted; err != nil {
+		t.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// 不断重试直到连接成功或 ctx 结束
+func dial(ctx context.Context, addr string) (net.Conn, error) {
+	var d net.Dialer
+	for {
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err == nil {
+			return conn, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(dialRetry):
+		}
+	}
+}
+
+// 接受 n 条入站连接，连接建立后对端先发送自己的编号
+func (t *TCPTransport) accept(n int) error {
+	for i := 0; i < n; i++ {
+		conn, err := t.listener.Accept()
+		if err != nil {
+			return err
+		}
+		var peer uint32
+		if err := binary.Read(conn, binary.LittleEndian, &peer); err != nil {
+			conn.Close()
+			return err
+		}
+		if int(peer) >= len(t.in) || int(peer) == t.rank || t.in[peer] != nil {
+			conn.Close()
+			return fmt.Errorf("无效的对端编号 %d", peer)
+		}
+		t.in[peer] = &tcpPeer{conn: conn, reader: bufio.NewReader(conn)}
+	}
+	return nil
+}
+
+// Rank 返回本进程的编号
+func (t *TCPTransport) Rank() int {
+	return t.rank
+}
+
+// Size 返回进程总数
+func (t *TCPTransport) Size() int {
+	return len(t.out)
+}
+
+// 在 ctx 结束时中断 conn 上阻塞的读写，返回的函数用于解除
+func watch(ctx context.Context, conn net.Conn) func() bool {
+	conn.SetDeadline(time.Time{})
+	return context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+}
+
+// Send 以 4 字节长度前缀加消息体的帧格式发送
+func (t *TCPTransport) Send(ctx context.Context, to int, msg *Message) error {
+	if err := checkPeer(t, to); err != nil {
+		return err
+	}
+	body, err := msg.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	frame := make([]byte, 4+len(body))
+	binary.LittleEndian.PutUint32(frame, uint32(len(body)))
+	copy(frame[4:], body)
+
+	p := t.out[to]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stop := watch(ctx, p.conn)
+	_, err = p.conn.Write(frame)
+	if !stop() && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// Recv 读取来自 from 的下一帧
+func (t *TCPTransport) Recv(ctx context.Context, from int) (*Message, error) {
+	if err := checkPeer(t, from); err != nil {
+		return nil, err
+	}
+	p := t.in[from]
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stop := watch(ctx, p.conn)
+	msg, err := readFrame(p.reader)
+	if !stop() && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("进程 %d 已断开连接", from)
+	}
+	return msg, err
+}
+
+func readFrame(r io.Reader) (*Message, error) {
+	var size uint32
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > maxFrameSize {
+		return nil, fmt.Errorf("消息过大: %d 字节", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	msg := &Message{}
+	if err := msg.UnmarshalBinary(body); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Close 关闭监听与所有连接
+func (t *TCPTransport) Close() error {
+	err := t.listener.Close()
+	for _, peers := range [][]*tcpPeer{t.out, t.in} {
+		for _, p := range peers {
+			if p != nil {
+				p.conn.Close()
+			}
+		}
+	}
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,173 @@
+// Package distributed 提供多个进程（cell）之间的集合通信与数据并行训练。
+// 每个进程持有一个模型副本，通过 Transport 交换分块的张量消息，
+// 用环形 all-reduce 同步梯度，使所有副本在每一步之后保持一致
+package distributed
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// MessageKind 消息的类型
+type MessageKind uint32
+
+const (
+	// KindCollective 集合通信（all-reduce、广播）的数据块
+	KindCollective MessageKind = iota
+	// KindPull 工作者请求最新参数
+	KindPull
+	// KindParams 参数服务器返回的参数，Seq 为参数版本
+	KindParams
+	// KindPush 工作者推送的梯度，Seq 为计算梯度时所用参数的版本
+	KindPush
+	// KindAck 参数服务器对推送的答复，Seq 为更新后的版本，Data[0] 为 1 表示梯度被采用
+	KindAck
+	// KindStop 工作者通知参数服务器训练结束
+	KindStop
+	// KindSchedule 流水线第一个阶段公布本步的微批次个数，Seq 为步数，Data[0] 为个数
+	KindSchedule
+	// KindActivation 流水线前向传给下一阶段的激活值，Seq 为微批次编号
+	KindActivation
+	// KindGradient 流水线反向传给上一阶段的梯度，Seq 为微批次编号
+	KindGradient
+)
+
+// Message 进程之间传递的一块张量数据
+type Message struct {
+	Kind  MessageKind
+	Seq   uint64    // 集合通信的序号或参数版本，含义取决于 Kind
+	Chunk uint32    // 块在本次通信中的编号
+	Data  []float64 // 块的数据
+}
+
+// 消息头：Kind、Seq、Chunk 与元素个数
+const headerSize = 4 + 8 + 4 + 4
+
+// MarshalBinary 以小端序编码消息
+func (m *Message) MarshalBinary() ([]byte, error) {
+	if len(m.Data) > math.MaxUint32 {
+		return nil, fmt.Errorf("消息过大: %d 个元素", len(m.Data))
+	}
+	buf := make([]byte, headerSize+8*len(m.Data))
+	binary.LittleEndian.PutUint32(buf[0:], uint32(m.Kind))
+	binary.LittleEndian.PutUint64(buf[4:], m.Seq)
+	binary.LittleEndian.PutUint32(buf[12:], m.Chunk)
+	binary.LittleEndian.PutUint32(buf[16:], uint32(len(m.Data)))
+	for i, v := range m.Data {
+		binary.LittleEndian.PutUint64(buf[headerSize+8*i:], math.Float64bits(v))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary 解码 MarshalBinary 的结果
+func (m *Message) UnmarshalBinary(buf []byte) error {
+	if len(buf) < headerSize {
+		return fmt.Errorf("消息不完整: %d 字节", len(buf))
+	}
+	n := int(binary.LittleEndian.Uint32(buf[16:]))
+	if len(buf) != headerSize+8*n {
+		return fmt.Errorf("消息长度 %d 与元素个数 %d 不符", len(buf), n)
+	}
+	m.Kind = MessageKind(binary.LittleEndian.Uint32(buf[0:]))
+	m.Seq = binary.LittleEndian.Uint64(buf[4:])
+	m.Chunk = binary.LittleEndian.Uint32(buf[12:])
+	m.Data = make([]float64, n)
+	for i := range m.Data {
+		m.Data[i] = math.Float64frombits(binary.LittleEndian.Uint64(buf[headerSize+8*i:]))
+	}
+	return nil
+}
+
+// Transport 点对点的消息通道，进程编号为 [0, Size)。
+// 同一对进程之间的消息按发送顺序到达；Send 与 Recv 可以在不同的 goroutine 中并发调用
+type Transport interface {
+	Rank() int
+	Size() int
+	Send(ctx context.Context, to int, msg *Message) error
+	Recv(ctx context.Context, from int) (*Message, error)
+	Close() error
+}
+
+// 检查对端编号
+func checkPeer(t Transport, peer int) error {
+	if peer < 0 || peer >= t.Size() || peer == t.Rank() {
+		return fmt.Errorf("进程 %d 无法与 %d 通信（共 %d 个进程）", t.Rank(), peer, t.Size())
+	}
+	return nil
+}
+
+// 将 data 分块发送，每块的 Kind 与 Seq 相同
+func sendChunks(ctx context.Context, t Transport, to int, kind MessageKind, seq uint64, data []float64, chunk int) error {
+	for i, off := uint32(0), 0; off < len(data); i, off = i+1, off+chunk {
+		if err := t.Send(ctx, to, &Message{Kind: kind, Seq: seq, Chunk: i, Data: data[off:min(off+chunk, len(data))]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 接收分块的数据填满 dst，first 为已经收到的第一块，返回各块共同的 Seq
+func recvChunks(ctx context.Context, t Transport, from int, first *Message, dst []float64) (uint64, error) {
+	msg := first
+	for i, off := uint32(0), 0; off < len(dst); i, off = i+1, off+len(msg.Data) {
+		if i > 0 {
+			var err error
+			if msg, err = t.Recv(ctx, from); err != nil {
+				return 0, err
+			}
+		}
+		if msg.Kind != first.Kind || msg.Seq != first.Seq || msg.Chunk != i || len(msg.Data) == 0 || off+len(msg.Data) > len(dst) {
+			return 0, fmt.Errorf("来自进程 %d 的第 %d 块消息无效（类型 %d 块 %d 长度 %d）", from, i, msg.Kind, msg.Chunk, len(msg.Data))
+		}
+		copy(dst[off:], msg.Data)
+	}
+	return first.Seq, nil
+}
+
+// 发送带形状的数组：先发送一条以形状为数据的消息，再分块发送数组的数据
+func sendArray(ctx context.Context, t Transport, to int, kind MessageKind, seq uint64, a *dubnp.Array, chunk int) error {
+	shape := make([]float64, len(a.Shape))
+	for i, d := range a.Shape {
+		shape[i] = float64(d)
+	}
+	if err := t.Send(ctx, to, &Message{Kind: kind, Seq: seq, Data: shape}); err != nil {
+		return err
+	}
+	return sendChunks(ctx, t, to, kind, seq, a.Data, chunk)
+}
+
+// 接收 sendArray 发送的数组，并检查类型与序号
+func recvArray(ctx context.Context, t Transport, from int, kind MessageKind, seq uint64) (*dubnp.Array, error) {
+	header, err := t.Recv(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if header.Kind != kind || header.Seq != seq {
+		return nil, fmt.Errorf("来自进程 %d 的消息类型 %d 序号 %d，期望类型 %d 序号 %d", from, header.Kind, header.Seq, kind, seq)
+	}
+	shape := make([]int, len(header.Data))
+	for i, d := range header.Data {
+		if shape[i] = int(d); float64(shape[i]) != d || shape[i] < 0 {
+			return nil, fmt.Errorf("无效的形状 %v", header.Data)
+		}
+	}
+	a := dubnp.Zeros(shape...)
+	if len(a.Data) == 0 {
+		return a, nil
+	}
+	first, err := t.Recv(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if first.Kind != kind || first.Seq != seq {
+		return nil, fmt.Errorf("来自进程 %d 的数据块类型 %d 序号 %d，期望类型 %d 序号 %d", from, first.Kind, first.Seq, kind, seq)
+	}
+	if _, err := recvChunks(ctx, t, from, first, a.Data); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
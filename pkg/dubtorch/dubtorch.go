@@ -1,3 +1,31 @@
+// Package dubtorch 基于 dubnp.Array 的深度学习工具包，提供反向自动求导的 Tensor
 package dubtorch
 
-// "github.com/duringbug/go-web-net/pkg/dubnp"
+import (
+	"sync/atomic"
+)
+
+// 进行中的 NoGrad 调用个数，为 0 时不必查找调用栈
+var noGradActive int32
+
+// NoGrad 的帧，见 scope.go
+//
+//go:noinline
+func noGradFrame(fn func()) {
+	fn()
+}
+
+var noGradPC = frameReturnPC(noGradFrame)
+
+// IsGradEnabled 返回当前 goroutine 是否记录计算图
+func IsGradEnabled() bool {
+	return atomic.LoadInt32(&noGradActive) == 0 || !onStack(noGradPC)
+}
+
+// NoGrad 在 fn 执行期间不记录计算图，包括只由参数参与的运算，常用于推理。
+// 该模式只作用于调用它的 goroutine，其他 goroutine 可以同时训练；可以嵌套
+func NoGrad(fn func()) {
+	atomic.AddInt32(&noGradActive, 1)
+	defer atomic.AddInt32(&noGradActive, -1)
+	noGradFrame(fn)
+}
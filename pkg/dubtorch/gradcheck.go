@@ -0,0 +1,123 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// GradCheckError 自动求导得到的梯度与有限差分不一致的位置
+type GradCheckError struct {
+	Input    int // 输入的下标
+	Index    int // 输入中元素的下标
+	Output   int // 输出中元素的下标
+	Analytic float64
+	Numeric  float64
+}
+
+func (e *GradCheckError) Error() string {
+	return fmt.Sprintf("输出第 %d 个元素对输入 %d 第 %d 个元素的梯度不一致: 解析 %v，数值 %v", e.Output, e.Input, e.Index, e.Analytic, e.Numeric)
+}
+
+// GradCheck 用中心差分 (f(x+eps) - f(x-eps)) / 2eps 检查 fn 关于 inputs 的雅可比矩阵。
+// 只检查需要梯度的输入，它们必须是叶子张量；输出可以是任意形状，对每个输出元素各反向传播一次。
+// 解析值与数值之差超过 tol*max(1, |数值|) 时返回 *GradCheckError。
+// fn 必须是确定性的（例如不使用训练模式的 Dropout），检查结束后输入的数据与 Grad 保持原样
+func GradCheck(fn func(inputs []*Tensor) (*Tensor, error), inputs []*Tensor, eps, tol float64) error {
+	if !(eps > 0) || !(tol > 0) {
+		return fmt.Errorf("eps 与 tol 必须为正数: %v, %v", eps, tol)
+	}
+	var checked []int
+	for i, in := range inputs {
+		if !in.RequiresGrad {
+			continue
+		}
+		if !in.IsLeaf() {
+			return fmt.Errorf("第 %d 个输入不是叶子张量", i)
+		}
+		checked = append(checked, i)
+	}
+	if len(checked) == 0 {
+		return errors.New("没有需要梯度的输入")
+	}
+	saved := make([]*dubnp.Array, len(inputs))
+	for _, i := range checked {
+		saved[i] = inputs[i].Grad
+	}
+	defer func() {
+		for _, i := range checked {
+			inputs[i].Grad = saved[i]
+		}
+	}()
+
+	out, err := fn(inputs)
+	if err != nil {
+		return fmt.Errorf("前向计算失败: %v", err)
+	}
+	m := out.Size()
+	// 解析雅可比矩阵，analytic[i][j*n+k] 为输出 j 对输入 i 第 k 个元素的导数；输出与输入无关时为 0
+	analytic := make([][]float64, len(inputs))
+	for _, i := range checked {
+		analytic[i] = make([]float64, m*inputs[i].Size())
+	}
+	if out.RequiresGrad {
+		for j := 0; j < m; j++ {
+			for _, i := range checked {
+				inputs[i].Grad = nil
+			}
+			seed := dubnp.Zeros(out.Shape()...)
+			seed.Data[j] = 1
+			if err := out.BackwardWithGrad(seed); err != nil {
+				return fmt.Errorf("反向传播失败: %v", err)
+			}
+			for _, i := range checked {
+				if g := inputs[i].Grad; g != nil {
+					copy(analytic[i][j*len(g.Data):], g.Data)
+				}
+			}
+		}
+	}
+
+	eval := func() ([]float64, error) {
+		var y *Tensor
+		var err error
+		NoGrad(func() {
+			y, err = fn(inputs)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if y.Size() != m {
+			return nil, fmt.Errorf("输出的元素个数从 %d 变为 %d", m, y.Size())
+		}
+		// 输出可能与输入共享数据，复制一份以免被下一次扰动修改
+		return append([]float64(nil), y.Data.Data...), nil
+	}
+	for _, i := range checked {
+		x := inputs[i].Data.Data
+		for k := range x {
+			orig := x[k]
+			x[k] = orig + eps
+			plus, err := eval()
+			var minus []float64
+			if err == nil {
+				x[k] = orig - eps
+				minus, err = eval()
+			}
+			x[k] = orig
+			if err != nil {
+				return fmt.Errorf("扰动输入 %d 第 %d 个元素后前向计算失败: %v", i, k, err)
+			}
+			for j := 0; j < m; j++ {
+				numeric := (plus[j] - minus[j]) / (2 * eps)
+				a := analytic[i][j*len(x)+k]
+				if !(math.Abs(a-numeric) <= tol*math.Max(1, math.Abs(numeric))) {
+					return &GradCheckError{Input: i, Index: k, Output: j, Analytic: a, Numeric: numeric}
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,294 @@
+package dubtorch
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// 逐元素并行时每段的元素个数与全连接层每段的行数
+const (
+	graphChunk     = 1 << 14
+	graphLinearRow = 8
+)
+
+// Run 执行静态图，x 的形状必须与 Trace 的示例输入相同。返回的数组不与图的缓冲区共享内存
+func (g *Graph) Run(x *dubnp.Array) (*dubnp.Array, error) {
+	if x == nil || !sameShape(x.Shape, g.values[g.input].shape) || len(x.Data) != g.size(g.input) {
+		var shape []int
+		if x != nil {
+			shape = x.Shape
+		}
+		return nil, fmt.Errorf("静态图的输入形状为 %v，实际为 %v", g.values[g.input].shape, shape)
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, v := range g.bound {
+		g.data[v] = x.Data
+	}
+	defer func() {
+		for _, v := range g.bound {
+			g.data[v] = nil
+		}
+	}()
+	for i := range g.ops {
+		if err := g.runOp(&g.ops[i]); err != nil {
+			return nil, fmt.Errorf("%s %s: %v", g.ops[i].name, g.ops[i].kind, err)
+		}
+	}
+	return &dubnp.Array{Data: append([]float64(nil), g.data[g.output]...), Shape: append([]int(nil), g.values[g.output].shape...)}, nil
+}
+
+// Forward 以 Tensor 为输入执行静态图，输出不参与求导
+func (g *Graph) Forward(x *Tensor) (*Tensor, error) {
+	out, err := g.Run(x.Data)
+	if err != nil {
+		return nil, err
+	}
+	return NewTensor(out, false), nil
+}
+
+// 执行一个算子，结果写入该算子输出的缓冲区
+func (g *Graph) runOp(op *graphOp) error {
+	dst, src := g.data[op.out], g.data[op.in[0]]
+	switch op.kind {
+	case "linear":
+		g.runLinear(op, dst, src)
+		return nil
+	case "conv":
+		g.runConv(op, dst, src)
+		return nil
+	case "transpose":
+		s := g.values[op.in[0]].shape
+		copy(dst, transposeData(src, s[0], s[1]))
+	case "affine":
+		scale, shift := g.data[op.in[1]], g.data[op.in[2]]
+		inner := len(dst) / g.values[op.out].shape[0] / len(scale)
+		parallelBlocks(len(dst), graphChunk, func(start, end int) {
+			for i := start; i < end; i++ {
+				c := (i / inner) % len(scale)
+				dst[i] = src[i]*scale[c] + shift[c]
+			}
+		})
+	case "softmax":
+		softmaxInto(dst, src, g.values[op.out].shape, op.axis)
+	case "maxpool", "avgpool":
+		poolInto(dst, src, &op.pool, op.kind == "maxpool")
+	case "act", "reshape", "identity":
+		// reshape 与原地执行的激活和输入共享存储，此时无需复制
+		if len(dst) > 0 && &dst[0] != &src[0] {
+			copy(dst, src)
+		}
+	case "module":
+		var y *Tensor
+		var err error
+		x := NewTensor(&dubnp.Array{Data: src, Shape: g.values[op.in[0]].shape}, false)
+		NoGrad(func() {
+			y, err = op.module.Forward(x)
+		})
+		if err != nil {
+			return err
+		}
+		if !sameShape(y.Shape(), g.values[op.out].shape) {
+			return fmt.Errorf("输出形状为 %v，与追踪时的 %v 不同", y.Shape(), g.values[op.out].shape)
+		}
+		copy(dst, y.Data.Data)
+	default:
+		return fmt.Errorf("未知的算子 %s", op.kind)
+	}
+	if len(op.acts) > 0 {
+		parallelBlocks(len(dst), graphChunk, func(start, end int) {
+			applyActs(dst[start:end], op.acts)
+		})
+	}
+	return nil
+}
+
+// 将 [0, n) 按 block 分段并行处理
+func parallelBlocks(n, block int, fn func(start, end int)) {
+	if n <= block {
+		fn(0, n)
+		return
+	}
+	parallelFor((n+block-1)/block, func(b int) {
+		fn(b*block, min((b+1)*block, n))
+	})
+}
+
+// 依次原地计算逐元素激活，公式与 Tensor 上的同名方法一致
+func applyActs(x []float64, acts []string) {
+	for _, act := range acts {
+		switch act {
+		case "relu":
+			for i, v := range x {
+				x[i] = math.Max(v, 0)
+			}
+		case "tanh":
+			for i, v := range x {
+				x[i] = math.Tanh(v)
+			}
+		case "sigmoid":
+			for i, v := range x {
+				x[i] = sigmoid(v)
+			}
+		case "gelu":
+			for i, v := range x {
+				x[i] = 0.5 * v * (1 + math.Erf(v/math.Sqrt2))
+			}
+		}
+	}
+}
+
+// dst(m×n) = a(m×k) · b(k×n)，每次同时累加 4 行，b 的每一行读入一次用于 4 行结果
+func gemm(dst, a []float64, m, k int, b []float64, n int) {
+	clear(dst[:m*n])
+	i := 0
+	for ; i+4 <= m; i += 4 {
+		r0, r1, r2, r3 := dst[i*n:][:n], dst[(i+1)*n:][:n], dst[(i+2)*n:][:n], dst[(i+3)*n:][:n]
+		for p := 0; p < k; p++ {
+			a0, a1, a2, a3 := a[i*k+p], a[(i+1)*k+p], a[(i+2)*k+p], a[(i+3)*k+p]
+			for j, bv := range b[p*n:][:n] {
+				r0[j] += a0 * bv
+				r1[j] += a1 * bv
+				r2[j] += a2 * bv
+				r3[j] += a3 * bv
+			}
+		}
+	}
+	for ; i < m; i++ {
+		row := dst[i*n:][:n]
+		for p, av := range a[i*k:][:k] {
+			for j, bv := range b[p*n:][:n] {
+				row[j] += av * bv
+			}
+		}
+	}
+}
+
+// 全连接层，权重已转置为 (in, out)，偏置与激活在每段行计算完后立即处理
+func (g *Graph) runLinear(op *graphOp, dst, src []float64) {
+	wt := g.data[op.in[1]]
+	in, out := g.values[op.in[1]].shape[0], g.values[op.in[1]].shape[1]
+	var bias []float64
+	if len(op.in) > 2 {
+		bias = g.data[op.in[2]]
+	}
+	parallelBlocks(len(src)/in, graphLinearRow, func(start, end int) {
+		block := dst[start*out : end*out]
+		gemm(block, src[start*in:end*in], end-start, in, wt, out)
+		if bias != nil {
+			for i := 0; i < len(block); i += out {
+				for j, b := range bias {
+					block[i+j] += b
+				}
+			}
+		}
+		applyActs(block, op.acts)
+	})
+}
+
+// 卷积并行使用的 goroutine 个数，每个 goroutine 在展开区中有自己的一段
+func convWorkers(batch int) int {
+	return max(min(runtime.NumCPU(), batch), 1)
+}
+
+// 卷积：每个 goroutine 处理一部分样本，im2col 使用预先分配的展开区；
+// 1×1、步长为 1 且不补零的卷积直接以输入作为展开后的矩阵
+func (g *Graph) runConv(op *graphOp, dst, src []float64) {
+	geo := op.conv
+	xs := g.values[op.in[0]].shape
+	n, cin, cout := xs[0], xs[1], g.values[op.in[1]].shape[0]
+	w := g.data[op.in[1]]
+	var bias []float64
+	if len(op.in) > 2 {
+		bias = g.data[op.in[2]]
+	}
+	og, k, P, hw := cout/op.groups, geo.c*geo.kh*geo.kw, geo.oh*geo.ow, geo.h*geo.w
+	pointwise := geo.kh == 1 && geo.kw == 1 && geo.sh == 1 && geo.sw == 1 && geo.ph == 0 && geo.pw == 0
+	workers := convWorkers(n)
+	if len(g.scratch) < workers*k*P {
+		g.scratch = make([]float64, workers*k*P)
+	}
+	parallelFor(workers, func(wi int) {
+		cols := g.scratch[wi*k*P:][:k*P]
+		for b := wi; b < n; b += workers {
+			for gi := 0; gi < op.groups; gi++ {
+				in := src[(b*cin+gi*geo.c)*hw:][:geo.c*hw]
+				if pointwise {
+					cols = in
+				} else {
+					geo.im2col(in, cols)
+				}
+				gemm(dst[(b*cout+gi*og)*P:][:og*P], w[gi*og*k:][:og*k], og, k, cols, P)
+			}
+			out := dst[b*cout*P:][:cout*P]
+			if bias != nil {
+				for ch, v := range bias {
+					row := out[ch*P:][:P]
+					for i := range row {
+						row[i] += v
+					}
+				}
+			}
+			applyActs(out, op.acts)
+		}
+	})
+}
+
+// 沿 axis 计算 softmax，每个元素先读后写，因此 dst 可以与 src 相同
+func softmaxInto(dst, src []float64, shape []int, axis int) {
+	outer, n, inner, _ := laneGeometry(shape, axis)
+	forEachLane(outer, n, inner, func(base int) {
+		m := math.Inf(-1)
+		for j := 0; j < n; j++ {
+			m = math.Max(m, src[base+j*inner])
+		}
+		if math.IsInf(m, -1) {
+			for j := 0; j < n; j++ {
+				dst[base+j*inner] = 0
+			}
+			return
+		}
+		sum := 0.0
+		for j := 0; j < n; j++ {
+			sum += math.Exp(src[base+j*inner] - m)
+		}
+		for j := 0; j < n; j++ {
+			dst[base+j*inner] = math.Exp(src[base+j*inner]-m) / sum
+		}
+	})
+}
+
+// 最大池化或平均池化（填充位置计入窗口大小），与 maxPool、avgPool 的前向一致
+func poolInto(dst, src []float64, g *poolGeom, isMax bool) {
+	inP, outP := g.h*g.w, g.oh*g.ow
+	scale := 1 / float64(g.kh*g.kw)
+	parallelFor(len(src)/inP, func(p int) {
+		plane := src[p*inP:][:inP]
+		for oi := 0; oi < g.oh; oi++ {
+			for oj := 0; oj < g.ow; oj++ {
+				i0, i1, j0, j1 := g.window(oi, oj)
+				acc := 0.0
+				if isMax {
+					acc = math.Inf(-1)
+				}
+				for i := i0; i < i1; i++ {
+					for j := j0; j < j1; j++ {
+						v := plane[i*g.w+j]
+						if !isMax {
+							acc += v
+						} else if v > acc || math.IsNaN(v) {
+							acc = v
+						}
+					}
+				}
+				if !isMax {
+					acc *= scale
+				}
+				dst[p*outP+oi*g.ow+oj] = acc
+			}
+		}
+	})
+}
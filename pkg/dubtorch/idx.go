@@ -0,0 +1,226 @@
+package dubtorch
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// IDXType IDX 文件中元素的类型码，数据均为大端序
+type IDXType byte
+
+const (
+	IDXUint8   IDXType = 0x08
+	IDXInt8    IDXType = 0x09
+	IDXInt16   IDXType = 0x0B
+	IDXInt32   IDXType = 0x0C
+	IDXFloat32 IDXType = 0x0D
+	IDXFloat64 IDXType = 0x0E
+)
+
+func (t IDXType) size() int {
+	switch t {
+	case IDXUint8, IDXInt8:
+		return 1
+	case IDXInt16:
+		return 2
+	case IDXInt32, IDXFloat32:
+		return 4
+	case IDXFloat64:
+		return 8
+	}
+	return 0
+}
+
+const (
+	maxIDXElements = 1 << 30   // IDX 文件元素个数的上限
+	idxChunk       = 64 * 1024 // 每次读取的元素个数
+)
+
+// LoadIDX 读取 IDX 格式（MNIST 使用的格式）：2 字节 0、1 字节类型码、1 字节维数、
+// 每维一个大端 uint32，之后是大端序的数据。以 gzip 魔数开头时自动解压
+func LoadIDX(r io.Reader) (*dubnp.Array, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, fmt.Errorf("读取 IDX 头部失败: %v", err)
+	}
+	dtype := IDXType(header[2])
+	if header[0] != 0 || header[1] != 0 || dtype.size() == 0 {
+		return nil, fmt.Errorf("无效的 IDX 魔数 % x", header)
+	}
+	shape := make([]int, header[3])
+	size := 1
+	for i := range shape {
+		var d uint32
+		if err := binary.Read(br, binary.BigEndian, &d); err != nil {
+			return nil, fmt.Errorf("读取 IDX 维度失败: %v", err)
+		}
+		shape[i] = int(d)
+		if shape[i] > 0 && size > maxIDXElements/shape[i] {
+			return nil, fmt.Errorf("IDX 形状 %v 的元素个数超过上限 %d", shape[:i+1], maxIDXElements)
+		}
+		size *= shape[i]
+	}
+
+	// 按块读取，数组随实际读到的数据增长，损坏的头部不会导致一次性分配过大的内存
+	data := make([]float64, 0, min(size, idxChunk))
+	buf := make([]byte, idxChunk*dtype.size())
+	for len(data) < size {
+		n := min(idxChunk, size-len(data)) * dtype.size()
+		if _, err := io.ReadFull(br, buf[:n]); err != nil {
+			return nil, fmt.Errorf("IDX 数据不完整: %v", err)
+		}
+		for k := 0; k < n; k += dtype.size() {
+			data = append(data, decodeIDX(dtype, buf[k:]))
+		}
+	}
+	return &dubnp.Array{Data: data, Shape: shape}, nil
+}
+
+func decodeIDX(t IDXType, b []byte) float64 {
+	switch t {
+	case IDXUint8:
+		return float64(b[0])
+	case IDXInt8:
+		return float64(int8(b[0]))
+	case IDXInt16:
+		return float64(int16(binary.BigEndian.Uint16(b)))
+	case IDXInt32:
+		return float64(int32(binary.BigEndian.Uint32(b)))
+	case IDXFloat32:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b)))
+	default:
+		return math.Float64frombits(binary.BigEndian.Uint64(b))
+	}
+}
+
+// SaveIDX 以指定类型写出 IDX 数据，整数类型要求取值为范围内的整数
+func SaveIDX(w io.Writer, a *dubnp.Array, dtype IDXType) error {
+	if dtype.size() == 0 {
+		return fmt.Errorf("未知的 IDX 类型码 0x%02x", byte(dtype))
+	}
+	if len(a.Shape) > 255 {
+		return errors.New("IDX 最多支持 255 维")
+	}
+	buf := []byte{0, 0, byte(dtype), byte(len(a.Shape))}
+	for _, d := range a.Shape {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(d))
+	}
+	for _, v := range a.Data {
+		if err := checkIDXValue(dtype, v); err != nil {
+			return err
+		}
+		switch dtype {
+		case IDXUint8, IDXInt8:
+			buf = append(buf, byte(int64(v)))
+		case IDXInt16:
+			buf = binary.BigEndian.AppendUint16(buf, uint16(int16(v)))
+		case IDXInt32:
+			buf = binary.BigEndian.AppendUint32(buf, uint32(int32(v)))
+		case IDXFloat32:
+			buf = binary.BigEndian.AppendUint32(buf, math.Float32bits(float32(v)))
+		case IDXFloat64:
+			buf = binary.BigEndian.AppendUint64(buf, math.Float64bits(v))
+		}
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+func checkIDXValue(t IDXType, v float64) error {
+	var low, high float64
+	switch t {
+	case IDXUint8:
+		low, high = 0, math.MaxUint8
+	case IDXInt8:
+		low, high = math.MinInt8, math.MaxInt8
+	case IDXInt16:
+		low, high = math.MinInt16, math.MaxInt16
+	case IDXInt32:
+		low, high = math.MinInt32, math.MaxInt32
+	default:
+		return nil
+	}
+	if v != math.Trunc(v) || v < low || v > high {
+		return fmt.Errorf("值 %v 无法以 IDX 类型码 0x%02x 保存", v, byte(t))
+	}
+	return nil
+}
+
+// LoadIDXFile 读取 IDX 文件，支持 gzip 压缩
+func LoadIDXFile(path string) (*dubnp.Array, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	a, err := LoadIDX(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return a, nil
+}
+
+// SaveIDXFile 写出 IDX 文件，路径以 ".gz" 结尾时使用 gzip 压缩
+func SaveIDXFile(path string, a *dubnp.Array, dtype IDXType) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	err = SaveIDX(w, a, dtype)
+	if gz != nil && err == nil {
+		err = gz.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// NewMNIST 由图像与标签两个 IDX 文件创建数据集（MNIST、Fashion-MNIST 等），
+// 样本为 [(1, H, W) 且缩放到 [0, 1] 的图像, 标量标签]
+func NewMNIST(imagesPath, labelsPath string) (*TensorDataset, error) {
+	images, err := LoadIDXFile(imagesPath)
+	if err != nil {
+		return nil, err
+	}
+	labels, err := LoadIDXFile(labelsPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(images.Shape) != 3 || len(labels.Shape) != 1 {
+		return nil, fmt.Errorf("期望图像为 (N, H, W)、标签为 (N)，实际为 %v 与 %v", images.Shape, labels.Shape)
+	}
+	n, h, w := images.Shape[0], images.Shape[1], images.Shape[2]
+	for i, v := range images.Data {
+		images.Data[i] = v / 255
+	}
+	if images, err = images.Reshape(n, 1, h, w); err != nil {
+		return nil, err
+	}
+	return NewTensorDataset(images, labels)
+}
@@ -0,0 +1,151 @@
+package dubtorch
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg" // 注册 JPEG 解码器
+	_ "image/png"  // 注册 PNG 解码器
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// ImageFolderOptions ImageFolder 的选项，零值表示 RGB 三通道且只缩放到 [0, 1]
+type ImageFolderOptions struct {
+	Grayscale  bool      // 转换为单通道灰度图
+	Mean, Std  []float64 // 每个通道的均值与标准差，设置后输出 (x - mean) / std
+	Extensions []string  // 识别的扩展名，默认 .png、.jpg、.jpeg（不区分大小写）
+}
+
+// ImageFolder 按 root/类别名/图片 组织的图像数据集，类别按名称排序后编号，
+// 样本为 [(C, H, W) 的图像, 标量标签]，图像在 Get 时才解码
+type ImageFolder struct {
+	Classes []string
+	opts    ImageFolderOptions
+	paths   []string
+	labels  []int
+}
+
+// NewImageFolder 扫描 root 下的子目录创建数据集
+func NewImageFolder(root string, opts ImageFolderOptions) (*ImageFolder, error) {
+	channels := 3
+	if opts.Grayscale {
+		channels = 1
+	}
+	if (opts.Mean != nil || opts.Std != nil) && (len(opts.Mean) != channels || len(opts.Std) != channels) {
+		return nil, fmt.Errorf("Mean 与 Std 的长度应为通道数 %d", channels)
+	}
+	for _, s := range opts.Std {
+		if s <= 0 {
+			return nil, fmt.Errorf("Std 必须为正数: %v", opts.Std)
+		}
+	}
+	if len(opts.Extensions) == 0 {
+		opts.Extensions = []string{".png", ".jpg", ".jpeg"}
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	d := &ImageFolder{opts: opts}
+	// os.ReadDir 的结果已按文件名排序
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(root, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		label := len(d.Classes)
+		d.Classes = append(d.Classes, e.Name())
+		for _, f := range files {
+			if !f.IsDir() && d.hasImageExt(f.Name()) {
+				d.paths = append(d.paths, filepath.Join(root, e.Name(), f.Name()))
+				d.labels = append(d.labels, label)
+			}
+		}
+	}
+	if len(d.paths) == 0 {
+		return nil, fmt.Errorf("%s 中没有找到图像", root)
+	}
+	return d, nil
+}
+
+func (d *ImageFolder) hasImageExt(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range d.opts.Extensions {
+		if strings.ToLower(e) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// Len 返回图像个数
+func (d *ImageFolder) Len() int {
+	return len(d.paths)
+}
+
+// Path 返回第 i 个样本的文件路径
+func (d *ImageFolder) Path(i int) string {
+	return d.paths[i]
+}
+
+// Get 解码第 i 张图像
+func (d *ImageFolder) Get(i int) (Sample, error) {
+	if i < 0 || i >= len(d.paths) {
+		return nil, fmt.Errorf("下标 %d 超出范围 [0, %d)", i, len(d.paths))
+	}
+	f, err := os.Open(d.paths[i])
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", d.paths[i], err)
+	}
+	x := ImageToArray(img, d.opts.Grayscale)
+	if d.opts.Mean != nil {
+		plane := len(x.Data) / x.Shape[0]
+		for c := 0; c < x.Shape[0]; c++ {
+			for k := c * plane; k < (c+1)*plane; k++ {
+				x.Data[k] = (x.Data[k] - d.opts.Mean[c]) / d.opts.Std[c]
+			}
+		}
+	}
+	return Sample{x, dubnp.Full(float64(d.labels[i]))}, nil
+}
+
+// ImageToArray 将图像转换为 (C, H, W) 且取值在 [0, 1] 的数组，C 为 3（RGB）或 1（灰度）
+func ImageToArray(img image.Image, grayscale bool) *dubnp.Array {
+	bounds := img.Bounds()
+	h, w := bounds.Dy(), bounds.Dx()
+	if grayscale {
+		out := dubnp.Zeros(1, h, w)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+				// ITU-R BT.601 亮度
+				out.Data[y*w+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+			}
+		}
+		return out
+	}
+	out := dubnp.Zeros(3, h, w)
+	plane := h * w
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			k := y*w + x
+			out.Data[k] = float64(r) / 0xffff
+			out.Data[plane+k] = float64(g) / 0xffff
+			out.Data[2*plane+k] = float64(b) / 0xffff
+		}
+	}
+	return out
+}
@@ -0,0 +1,204 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+)
+
+// 以下初始化函数原地改写参数的数据，使用包级随机数生成器，ManualSeed 可复现。
+// 扇入与扇出按 PyTorch 的约定计算：形状为 (out, in, k...) 时 fanIn = in*k...，fanOut = out*k...
+
+// 权重的扇入与扇出，至少需要二维
+func fans(t *Tensor) (fanIn, fanOut int, err error) {
+	shape := t.Shape()
+	if len(shape) < 2 {
+		return 0, 0, fmt.Errorf("计算扇入与扇出至少需要二维，实际形状为 %v", shape)
+	}
+	receptive := 1
+	for _, d := range shape[2:] {
+		receptive *= d
+	}
+	return shape[1] * receptive, shape[0] * receptive, nil
+}
+
+// CalculateGain 返回激活函数对应的推荐增益：linear、sigmoid 为 1，tanh 为 5/3，relu 为 √2，
+// leaky_relu 为 √(2/(1+a²))，a 为负半轴斜率，selu 为 3/4
+func CalculateGain(nonlinearity string, a float64) (float64, error) {
+	switch nonlinearity {
+	case "linear", "conv", "sigmoid":
+		return 1, nil
+	case "tanh":
+		return 5.0 / 3, nil
+	case "relu":
+		return math.Sqrt2, nil
+	case "leaky_relu":
+		return math.Sqrt(2 / (1 + a*a)), nil
+	case "selu":
+		return 0.75, nil
+	}
+	return 0, fmt.Errorf("未知的激活函数: %q", nonlinearity)
+}
+
+// Constant 将参数的所有元素设为 value
+func Constant(t *Tensor, value float64) {
+	for i := range t.Data.Data {
+		t.Data.Data[i] = value
+	}
+}
+
+// XavierUniform 按 U(-b, b) 初始化，b = gain*√(6/(fanIn+fanOut))
+func XavierUniform(t *Tensor, gain float64) error {
+	fanIn, fanOut, err := fans(t)
+	if err != nil {
+		return err
+	}
+	bound := gain * math.Sqrt(6/float64(fanIn+fanOut))
+	fillRandom(t.Data.Data, func(r *rand.Rand) float64 { return bound * (2*r.Float64() - 1) })
+	return nil
+}
+
+// XavierNormal 按 N(0, std²) 初始化，std = gain*√(2/(fanIn+fanOut))
+func XavierNormal(t *Tensor, gain float64) error {
+	fanIn, fanOut, err := fans(t)
+	if err != nil {
+		return err
+	}
+	std := gain * math.Sqrt(2/float64(fanIn+fanOut))
+	fillRandom(t.Data.Data, func(r *rand.Rand) float64 { return std * r.NormFloat64() })
+	return nil
+}
+
+// KaimingOptions Kaiming 初始化的选项，零值对应 PyTorch 的默认值：按扇入、relu 增益
+type KaimingOptions struct {
+	A            float64 // leaky_relu 负半轴的斜率
+	FanOut       bool    // 按扇出计算，保持反向传播时梯度的方差
+	Nonlinearity string  // 传给 CalculateGain 的激活函数名，默认 leaky_relu
+}
+
+// Kaiming 初始化的标准差 gain/√fan
+func kaimingStd(t *Tensor, opts KaimingOptions) (float64, error) {
+	fanIn, fanOut, err := fans(t)
+	if err != nil {
+		return 0, err
+	}
+	if opts.Nonlinearity == "" {
+		opts.Nonlinearity = "leaky_relu"
+	}
+	gain, err := CalculateGain(opts.Nonlinearity, opts.A)
+	if err != nil {
+		return 0, err
+	}
+	fan := fanIn
+	if opts.FanOut {
+		fan = fanOut
+	}
+	return gain / math.Sqrt(float64(fan)), nil
+}
+
+// KaimingUniform 按 U(-b, b) 初始化，b = √3*gain/√fan
+func KaimingUniform(t *Tensor, opts KaimingOptions) error {
+	std, err := kaimingStd(t, opts)
+	if err != nil {
+		return err
+	}
+	bound := math.Sqrt(3) * std
+	fillRandom(t.Data.Data, func(r *rand.Rand) float64 { return bound * (2*r.Float64() - 1) })
+	return nil
+}
+
+// KaimingNormal 按 N(0, std²) 初始化，std = gain/√fan
+func KaimingNormal(t *Tensor, opts KaimingOptions) error {
+	std, err := kaimingStd(t, opts)
+	if err != nil {
+		return err
+	}
+	fillRandom(t.Data.Data, func(r *rand.Rand) float64 { return std * r.NormFloat64() })
+	return nil
+}
+
+// TruncatedNormal 按截断到 [a, b] 的 N(mean, std²) 初始化，用逆累积分布函数采样，不需要拒绝重采样
+func TruncatedNormal(t *Tensor, mean, std, a, b float64) error {
+	if !(std > 0) || !(a < b) {
+		return fmt.Errorf("截断正态分布的参数无效: std=%v, [%v, %v]", std, a, b)
+	}
+	cdf := func(x float64) float64 { return 0.5 * (1 + math.Erf((x-mean)/(std*math.Sqrt2))) }
+	lo, hi := cdf(a), cdf(b)
+	if hi <= lo {
+		return fmt.Errorf("区间 [%v, %v] 离均值 %v 太远", a, b, mean)
+	}
+	fillRandom(t.Data.Data, func(r *rand.Rand) float64 {
+		p := lo + (hi-lo)*r.Float64()
+		x := mean + std*math.Sqrt2*math.Erfinv(2*p-1)
+		return math.Max(a, math.Min(b, x))
+	})
+	return nil
+}
+
+// Orthogonal 用（半）正交矩阵初始化：参数视为 (shape[0], 其余维度之积) 的矩阵，
+// 行数不超过列数时各行正交归一，否则各列正交归一，再乘以 gain。
+// 正交矩阵由正态随机矩阵做 QR 分解得到，R 的对角线取正，使结果在正交矩阵上均匀分布
+func Orthogonal(t *Tensor, gain float64) error {
+	shape := t.Shape()
+	if len(shape) < 2 {
+		return fmt.Errorf("正交初始化至少需要二维，实际形状为 %v", shape)
+	}
+	if t.Size() == 0 {
+		return fmt.Errorf("不能对空参数做正交初始化，形状为 %v", shape)
+	}
+	rows := shape[0]
+	cols := t.Size() / rows
+	// 对 n 个长度为 m 的随机向量正交化（m ≥ n）
+	n, m := min(rows, cols), max(rows, cols)
+	q := make([]float64, n*m)
+	fillRandom(q, func(r *rand.Rand) float64 { return r.NormFloat64() })
+	for j := 0; j < n; j++ {
+		v := q[j*m : (j+1)*m]
+		// 修正的 Gram-Schmidt 做两遍，弥补一遍时的正交性损失
+		for pass := 0; pass < 2; pass++ {
+			for i := 0; i < j; i++ {
+				u := q[i*m : (i+1)*m]
+				dot := 0.0
+				for k := range v {
+					dot += u[k] * v[k]
+				}
+				for k := range v {
+					v[k] -= dot * u[k]
+				}
+			}
+		}
+		norm := 0.0
+		for _, x := range v {
+			norm += x * x
+		}
+		if norm = math.Sqrt(norm); norm == 0 {
+			return errors.New("随机矩阵退化，无法正交化")
+		}
+		for k := range v {
+			v[k] /= norm
+		}
+	}
+	// q 的每一行是一个正交向量：行数不超过列数时直接作为参数的行，否则作为参数的列
+	out := t.Data.Data
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			if rows <= cols {
+				out[i*cols+j] = gain * q[i*m+j]
+			} else {
+				out[i*cols+j] = gain * q[j*m+i]
+			}
+		}
+	}
+	return nil
+}
+
+// InitParameters 对模块（含子模块）的每个参数调用 fn，例如权重用 XavierUniform、偏置设为 0
+func InitParameters(m Module, fn func(name string, p *Tensor) error) error {
+	for _, p := range m.NamedParameters() {
+		if err := fn(p.Name, p.Tensor); err != nil {
+			return fmt.Errorf("初始化参数 %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Linear 全连接层 y = x W^T + b，权重形状为 (out, in)，与 PyTorch 一致
+type Linear struct {
+	BaseModule
+	InFeatures, OutFeatures int
+	Weight, Bias            *Tensor // 不使用偏置时 Bias 为 nil
+}
+
+// NewLinear 创建全连接层，权重与偏置按 U(-1/sqrt(in), 1/sqrt(in)) 初始化
+func NewLinear(in, out int, bias bool) *Linear {
+	l := &Linear{InFeatures: in, OutFeatures: out}
+	bound := 1 / math.Sqrt(float64(in))
+	l.Weight = l.RegisterParameter("weight", NewTensor(uniformArray(-bound, bound, out, in), true))
+	if bias {
+		l.Bias = l.RegisterParameter("bias", NewTensor(uniformArray(-bound, bound, out), true))
+	}
+	return l
+}
+
+// Forward 输入形状为 (..., in)，输出形状为 (..., out)
+func (l *Linear) Forward(x *Tensor) (*Tensor, error) {
+	shape := x.Shape()
+	if len(shape) == 0 || shape[len(shape)-1] != l.InFeatures {
+		return nil, fmt.Errorf("Linear 期望最后一维为 %d，输入形状为 %v", l.InFeatures, shape)
+	}
+	return linear(x, l.Weight, l.Bias)
+}
+
+// 计算 x W^T + b，weight 形状为 (out, in)，bias 可为 nil
+func linear(x, weight, bias *Tensor) (*Tensor, error) {
+	shape := x.Shape()
+	out, in := weight.Data.Shape[0], weight.Data.Shape[1]
+	// 将前导维度合并，按二维矩阵计算
+	flat, err := x.Reshape(-1, in)
+	if err != nil {
+		return nil, err
+	}
+	wt, err := weight.Transpose()
+	if err != nil {
+		return nil, err
+	}
+	y, err := flat.MatMul(wt)
+	if err != nil {
+		return nil, err
+	}
+	if bias != nil {
+		if y, err = y.Add(bias); err != nil {
+			return nil, err
+		}
+	}
+	outShape := append(append([]int(nil), shape[:len(shape)-1]...), out)
+	return y.Reshape(outShape...)
+}
+
+// Sequential 按顺序串联的模块，子模块名为其下标
+type Sequential struct {
+	BaseModule
+	modules []Module
+}
+
+// NewSequential 创建顺序模块
+func NewSequential(modules ...Module) *Sequential {
+	s := &Sequential{}
+	for _, m := range modules {
+		s.Append(m)
+	}
+	return s
+}
+
+// Append 在末尾追加模块
+func (s *Sequential) Append(m Module) {
+	s.RegisterModule(strconv.Itoa(len(s.modules)), m)
+	s.modules = append(s.modules, m)
+}
+
+// Len 返回子模块个数
+func (s *Sequential) Len() int {
+	return len(s.modules)
+}
+
+// At 返回第 i 个子模块
+func (s *Sequential) At(i int) Module {
+	return s.modules[i]
+}
+
+// Slice 返回由第 [start, end) 个子模块组成的新 Sequential，与原模型共享子模块。
+// 子模块保留原来的名字，因此切片的 StateDict 键与原模型一致；切片不应再 Append
+func (s *Sequential) Slice(start, end int) (*Sequential, error) {
+	if start < 0 || end > len(s.modules) || start > end {
+		return nil, fmt.Errorf("无效的切片范围 [%d, %d)，共 %d 个子模块", start, end, len(s.modules))
+	}
+	out := &Sequential{}
+	for i := start; i < end; i++ {
+		out.RegisterModule(strconv.Itoa(i), s.modules[i])
+		out.modules = append(out.modules, s.modules[i])
+	}
+	return out, nil
+}
+
+// Forward 依次执行每个子模块
+func (s *Sequential) Forward(x *Tensor) (*Tensor, error) {
+	var err error
+	for i, m := range s.modules {
+		if x, err = m.Forward(x); err != nil {
+			return nil, fmt.Errorf("Sequential 第 %d 层: %v", i, err)
+		}
+	}
+	return x, nil
+}
+
+// Embedding 查表层，将整数下标映射为向量
+type Embedding struct {
+	BaseModule
+	NumEmbeddings, EmbeddingDim int
+	Weight                      *Tensor
+}
+
+// NewEmbedding 创建查表层，权重按标准正态分布初始化
+func NewEmbedding(numEmbeddings, embeddingDim int) *Embedding {
+	e := &Embedding{NumEmbeddings: numEmbeddings, EmbeddingDim: embeddingDim}
+	weight := Randn(numEmbeddings, embeddingDim)
+	e.Weight = e.RegisterParameter("weight", weight)
+	return e
+}
+
+// Forward 输入为任意形状的下标张量（以浮点数存储），输出形状为输入形状加上 (dim)
+func (e *Embedding) Forward(indices *Tensor) (*Tensor, error) {
+	dim := e.EmbeddingDim
+	idx := make([]int, indices.Size())
+	for i, v := range indices.Data.Data {
+		k := int(v)
+		if float64(k) != v || k < 0 || k >= e.NumEmbeddings {
+			return nil, fmt.Errorf("Embedding 下标 %v 超出范围 [0, %d)", v, e.NumEmbeddings)
+		}
+		idx[i] = k
+	}
+
+	data := make([]float64, len(idx)*dim)
+	for i, k := range idx {
+		copy(data[i*dim:(i+1)*dim], e.Weight.Data.Data[k*dim:(k+1)*dim])
+	}
+	shape := append(append([]int(nil), indices.Shape()...), dim)
+	out := &dubnp.Array{Data: data, Shape: shape}
+
+	weight := e.Weight
+	return newResult("Embedding", out, []*Tensor{weight, indices}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		// 将梯度按下标散射累加到权重上，下标本身不可导
+		gw := dubnp.Zeros(weight.Data.Shape...)
+		for i, k := range idx {
+			row := gw.Data[k*dim : (k+1)*dim]
+			for j := range row {
+				row[j] += g.Data[i*dim+j]
+			}
+		}
+		return []*dubnp.Array{gw, nil}, nil
+	}), nil
+}
+
+// Dropout 训练时以概率 P 将元素置 0，并将其余元素放大 1/(1-P)；推理时不做处理
+type Dropout struct {
+	BaseModule
+	P float64
+}
+
+// NewDropout 创建 Dropout 层
+func NewDropout(p float64) *Dropout {
+	return &Dropout{P: p}
+}
+
+// Forward 执行 Dropout
+func (d *Dropout) Forward(x *Tensor) (*Tensor, error) {
+	return dropout(x, d.P, d.IsTraining())
+}
+
+// 以概率 p 随机置零，training 为 false 时原样返回
+func dropout(x *Tensor, p float64, training bool) (*Tensor, error) {
+	if p < 0 || p > 1 {
+		return nil, errors.New("Dropout 的概率必须在 [0, 1] 之间")
+	}
+	if !training || p == 0 {
+		return x, nil
+	}
+	if p == 1 {
+		return x.MulScalar(0), nil
+	}
+	scale := 1 / (1 - p)
+	mask := dubnp.Zeros(x.Shape()...)
+	fillRandom(mask.Data, func(r *rand.Rand) float64 {
+		if r.Float64() < p {
+			return 0
+		}
+		return scale
+	})
+	return x.Mul(NewTensor(mask, false))
+}
@@ -0,0 +1,269 @@
+package dubtorch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Reduction 损失的归约方式
+type Reduction int
+
+const (
+	ReductionMean Reduction = iota // 对所有元素求平均（默认）
+	ReductionSum                   // 对所有元素求和
+	ReductionNone                  // 不归约，返回逐元素损失
+)
+
+// 按归约方式处理逐元素损失
+func reduceLoss(loss *Tensor, reduction Reduction) (*Tensor, error) {
+	switch reduction {
+	case ReductionMean:
+		return loss.Mean()
+	case ReductionSum:
+		return loss.Sum()
+	case ReductionNone:
+		return loss, nil
+	}
+	return nil, fmt.Errorf("未知的归约方式: %d", reduction)
+}
+
+// 损失函数要求输入与目标形状一致，避免广播造成的隐蔽错误
+func lossDiff(name string, input, target *Tensor) (*Tensor, error) {
+	if !sameShape(input.Shape(), target.Shape()) {
+		return nil, fmt.Errorf("%s 的输入形状 %v 与目标形状 %v 不一致", name, input.Shape(), target.Shape())
+	}
+	return input.Sub(target)
+}
+
+func sameShape(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// MSELoss 均方误差 (input - target)^2
+func MSELoss(input, target *Tensor, reduction Reduction) (*Tensor, error) {
+	diff, err := lossDiff("MSELoss", input, target)
+	if err != nil {
+		return nil, err
+	}
+	return reduceLoss(diff.Pow(2), reduction)
+}
+
+// L1Loss 绝对误差 |input - target|
+func L1Loss(input, target *Tensor, reduction Reduction) (*Tensor, error) {
+	diff, err := lossDiff("L1Loss", input, target)
+	if err != nil {
+		return nil, err
+	}
+	return reduceLoss(diff.Abs(), reduction)
+}
+
+// HuberLoss 误差绝对值不超过 delta 时为 0.5*d^2，否则为 delta*(|d| - 0.5*delta)
+func HuberLoss(input, target *Tensor, delta float64, reduction Reduction) (*Tensor, error) {
+	if delta <= 0 {
+		return nil, fmt.Errorf("HuberLoss 的 delta 必须为正数: %v", delta)
+	}
+	diff, err := lossDiff("HuberLoss", input, target)
+	if err != nil {
+		return nil, err
+	}
+	loss := diff.unary("Huber", func(d float64) float64 {
+		if math.Abs(d) <= delta {
+			return 0.5 * d * d
+		}
+		return delta * (math.Abs(d) - 0.5*delta)
+	}, func(d, y float64) float64 {
+		return math.Max(-delta, math.Min(delta, d))
+	})
+	return reduceLoss(loss, reduction)
+}
+
+// BCEWithLogitsLoss 以 logits 为输入的二分类交叉熵，按 max(x,0) - x*y + log(1+e^-|x|) 计算以避免溢出
+func BCEWithLogitsLoss(logits, target *Tensor, reduction Reduction) (*Tensor, error) {
+	if !sameShape(logits.Shape(), target.Shape()) {
+		return nil, fmt.Errorf("BCEWithLogitsLoss 的输入形状 %v 与目标形状 %v 不一致", logits.Shape(), target.Shape())
+	}
+	x, y := logits.Data.Data, target.Data.Data
+	data := make([]float64, len(x))
+	for i := range x {
+		data[i] = math.Max(x[i], 0) - x[i]*y[i] + math.Log1p(math.Exp(-math.Abs(x[i])))
+	}
+	out := &dubnp.Array{Data: data, Shape: append([]int(nil), logits.Shape()...)}
+	loss := newResult("BCEWithLogits", out, []*Tensor{logits, target}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		gx := make([]float64, len(x))
+		gy := make([]float64, len(x))
+		for i := range x {
+			gx[i] = g.Data[i] * (sigmoid(x[i]) - y[i])
+			gy[i] = -g.Data[i] * x[i]
+		}
+		return []*dubnp.Array{{Data: gx, Shape: out.Shape}, {Data: gy, Shape: out.Shape}}, nil
+	})
+	return reduceLoss(loss, reduction)
+}
+
+// ClassLossOptions CrossEntropyLoss 与 NLLLoss 的选项
+type ClassLossOptions struct {
+	Reduction      Reduction
+	Weight         *dubnp.Array // 每个类别的权重，形状为 (C)，为 nil 时权重均为 1
+	LabelSmoothing float64      // 标签平滑系数，目标分布为 (1-ε)·onehot + ε/C
+	UseIgnoreIndex bool         // 是否忽略目标为 IgnoreIndex 的样本
+	IgnoreIndex    int
+}
+
+// CrossEntropyLoss 输入为 (N, C) 或 (N, C, d...) 的 logits，目标为 (N) 或 (N, d...) 的类别下标
+// 内部使用数值稳定的 log-softmax，再计算负对数似然
+func CrossEntropyLoss(logits, target *Tensor, opts ClassLossOptions) (*Tensor, error) {
+	if len(logits.Shape()) < 2 {
+		return nil, fmt.Errorf("CrossEntropyLoss 的输入至少为二维，实际形状为 %v", logits.Shape())
+	}
+	logProbs, err := logits.LogSoftmax(1)
+	if err != nil {
+		return nil, err
+	}
+	return NLLLoss(logProbs, target, opts)
+}
+
+// NLLLoss 负对数似然，输入为 (N, C) 或 (N, C, d...) 的对数概率，目标为类别下标
+//
+// 加权平均时除以未被忽略样本的目标类别权重之和，与 PyTorch 一致
+func NLLLoss(logProbs, target *Tensor, opts ClassLossOptions) (*Tensor, error) {
+	shape := logProbs.Shape()
+	if len(shape) < 2 {
+		return nil, fmt.Errorf("NLLLoss 的输入至少为二维，实际形状为 %v", shape)
+	}
+	n, c := shape[0], shape[1]
+	inner := logProbs.Size() / (n * c)
+	wantTarget := append([]int{n}, shape[2:]...)
+	if !sameShape(target.Shape(), wantTarget) {
+		return nil, fmt.Errorf("目标形状应为 %v，实际为 %v", wantTarget, target.Shape())
+	}
+	weight := make([]float64, c)
+	for k := range weight {
+		weight[k] = 1
+	}
+	if opts.Weight != nil {
+		if len(opts.Weight.Data) != c {
+			return nil, fmt.Errorf("类别权重应有 %d 个元素，实际为 %d", c, len(opts.Weight.Data))
+		}
+		copy(weight, opts.Weight.Data)
+	}
+	eps := opts.LabelSmoothing
+	if eps < 0 || eps > 1 {
+		return nil, fmt.Errorf("LabelSmoothing 必须在 [0, 1] 之间: %v", eps)
+	}
+
+	// 逐样本的类别下标，-1 表示忽略
+	classes := make([]int, len(target.Data.Data))
+	for i, v := range target.Data.Data {
+		k := int(v)
+		if opts.UseIgnoreIndex && k == opts.IgnoreIndex {
+			classes[i] = -1
+			continue
+		}
+		if float64(k) != v || k < 0 || k >= c {
+			return nil, fmt.Errorf("类别下标 %v 超出范围 [0, %d)", v, c)
+		}
+		classes[i] = k
+	}
+
+	// 第 i 个样本的类别 k 位于 logp[(i/inner)*c*inner + k*inner + i%inner]
+	lp := logProbs.Data.Data
+	at := func(i, k int) int { return (i/inner)*c*inner + k*inner + i%inner }
+	losses := make([]float64, len(classes))
+	totalWeight := 0.0
+	for i, k := range classes {
+		if k < 0 {
+			continue
+		}
+		loss := -(1 - eps) * weight[k] * lp[at(i, k)]
+		if eps > 0 {
+			smooth := 0.0
+			for j := 0; j < c; j++ {
+				smooth += weight[j] * lp[at(i, j)]
+			}
+			loss -= eps / float64(c) * smooth
+		}
+		losses[i] = loss
+		totalWeight += weight[k]
+	}
+
+	// 归约后的输出及每个样本损失对应的梯度系数
+	var out *dubnp.Array
+	scale := 1.0
+	switch opts.Reduction {
+	case ReductionNone:
+		out = &dubnp.Array{Data: losses, Shape: wantTarget}
+	case ReductionSum, ReductionMean:
+		sum := 0.0
+		for _, l := range losses {
+			sum += l
+		}
+		if opts.Reduction == ReductionMean {
+			scale = 1 / totalWeight
+			sum *= scale
+		}
+		out = dubnp.Full(sum)
+	default:
+		return nil, fmt.Errorf("未知的归约方式: %d", opts.Reduction)
+	}
+
+	return newResult("NLLLoss", out, []*Tensor{logProbs, target}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grad := make([]float64, len(lp))
+		for i, k := range classes {
+			if k < 0 {
+				continue
+			}
+			gi := scale
+			if opts.Reduction == ReductionNone {
+				gi = g.Data[i]
+			} else {
+				gi *= g.Data[0]
+			}
+			grad[at(i, k)] -= gi * (1 - eps) * weight[k]
+			if eps > 0 {
+				for j := 0; j < c; j++ {
+					grad[at(i, j)] -= gi * eps / float64(c) * weight[j]
+				}
+			}
+		}
+		// 类别下标不可导
+		return []*dubnp.Array{{Data: grad, Shape: shape}, nil}, nil
+	}), nil
+}
+
+// KLDivLoss KL 散度 target·(log(target) - input)，input 为对数概率，target 为概率
+// target 为 0 的位置损失为 0
+func KLDivLoss(input, target *Tensor, reduction Reduction) (*Tensor, error) {
+	if !sameShape(input.Shape(), target.Shape()) {
+		return nil, fmt.Errorf("KLDivLoss 的输入形状 %v 与目标形状 %v 不一致", input.Shape(), target.Shape())
+	}
+	x, p := input.Data.Data, target.Data.Data
+	data := make([]float64, len(x))
+	for i := range x {
+		if p[i] > 0 {
+			data[i] = p[i] * (math.Log(p[i]) - x[i])
+		}
+	}
+	out := &dubnp.Array{Data: data, Shape: append([]int(nil), input.Shape()...)}
+	loss := newResult("KLDiv", out, []*Tensor{input, target}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		gx := make([]float64, len(x))
+		gp := make([]float64, len(x))
+		for i := range x {
+			gx[i] = -g.Data[i] * p[i]
+			if p[i] > 0 {
+				gp[i] = g.Data[i] * (math.Log(p[i]) + 1 - x[i])
+			}
+		}
+		return []*dubnp.Array{{Data: gx, Shape: out.Shape}, {Data: gp, Shape: out.Shape}}, nil
+	})
+	return reduceLoss(loss, reduction)
+}
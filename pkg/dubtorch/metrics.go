@@ -0,0 +1,179 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Metric 按批次累积的评估指标，Values 返回自上次 Reset 以来的结果
+type Metric interface {
+	Reset()
+	Update(output, target *dubnp.Array) error
+	Values() map[string]float64
+}
+
+// 将模型输出转换为类别：(N, C) 且 C > 1 时取 argmax，
+// (N) 或 (N, 1) 视为二分类的 logits，大于 threshold 为 1
+func predictClasses(output, target *dubnp.Array, threshold float64) ([]int, []int, error) {
+	n := len(target.Data)
+	if n == 0 {
+		return nil, nil, errors.New("目标为空，无法计算指标")
+	}
+	if len(output.Shape) == 0 || output.Shape[0] != n {
+		return nil, nil, fmt.Errorf("输出形状 %v 与目标形状 %v 不匹配", output.Shape, target.Shape)
+	}
+	c := len(output.Data) / n
+	if c*n != len(output.Data) {
+		return nil, nil, fmt.Errorf("输出形状 %v 与目标形状 %v 不匹配", output.Shape, target.Shape)
+	}
+	preds := make([]int, n)
+	labels := make([]int, n)
+	for i := 0; i < n; i++ {
+		row := output.Data[i*c : (i+1)*c]
+		if c == 1 {
+			if row[0] > threshold {
+				preds[i] = 1
+			}
+		} else {
+			for j, v := range row {
+				if v > row[preds[i]] {
+					preds[i] = j
+				}
+			}
+		}
+		labels[i] = int(target.Data[i])
+		if float64(labels[i]) != target.Data[i] || labels[i] < 0 {
+			return nil, nil, fmt.Errorf("类别标签必须为非负整数: %v", target.Data[i])
+		}
+	}
+	return preds, labels, nil
+}
+
+// Accuracy 分类准确率
+type Accuracy struct {
+	Threshold      float64 // 二分类输出的判定阈值，默认 0（对应 logits）
+	correct, total int
+}
+
+// NewAccuracy 创建准确率指标
+func NewAccuracy() *Accuracy {
+	return &Accuracy{}
+}
+
+// Reset 清空累计结果
+func (a *Accuracy) Reset() {
+	a.correct, a.total = 0, 0
+}
+
+// Update 累计一个批次
+func (a *Accuracy) Update(output, target *dubnp.Array) error {
+	preds, labels, err := predictClasses(output, target, a.Threshold)
+	if err != nil {
+		return err
+	}
+	for i, p := range preds {
+		if p == labels[i] {
+			a.correct++
+		}
+	}
+	a.total += len(preds)
+	return nil
+}
+
+// Values 返回 "accuracy"
+func (a *Accuracy) Values() map[string]float64 {
+	if a.total == 0 {
+		return map[string]float64{"accuracy": 0}
+	}
+	return map[string]float64{"accuracy": float64(a.correct) / float64(a.total)}
+}
+
+// ConfusionMatrix 混淆矩阵，Counts[i][j] 为真实类别 i 被预测为 j 的样本数
+type ConfusionMatrix struct {
+	NumClasses int
+	Threshold  float64 // 二分类输出的判定阈值，默认 0（对应 logits）
+	Counts     [][]int
+}
+
+// NewConfusionMatrix 创建 numClasses 个类别的混淆矩阵
+func NewConfusionMatrix(numClasses int) (*ConfusionMatrix, error) {
+	if numClasses <= 0 {
+		return nil, fmt.Errorf("类别数必须为正数: %d", numClasses)
+	}
+	m := &ConfusionMatrix{NumClasses: numClasses}
+	m.Reset()
+	return m, nil
+}
+
+// Reset 清空计数
+func (m *ConfusionMatrix) Reset() {
+	m.Counts = make([][]int, m.NumClasses)
+	for i := range m.Counts {
+		m.Counts[i] = make([]int, m.NumClasses)
+	}
+}
+
+// Update 累计一个批次
+func (m *ConfusionMatrix) Update(output, target *dubnp.Array) error {
+	if m.NumClasses <= 0 || len(m.Counts) != m.NumClasses {
+		return fmt.Errorf("混淆矩阵的类别数 %d 无效，请使用 NewConfusionMatrix 创建", m.NumClasses)
+	}
+	preds, labels, err := predictClasses(output, target, m.Threshold)
+	if err != nil {
+		return err
+	}
+	for i, p := range preds {
+		if p >= m.NumClasses || labels[i] >= m.NumClasses {
+			return fmt.Errorf("类别 %d 或 %d 超出范围 [0, %d)", labels[i], p, m.NumClasses)
+		}
+		m.Counts[labels[i]][p]++
+	}
+	return nil
+}
+
+// PrecisionRecallF1 返回某个类别的精确率、召回率与 F1，分母为 0 时对应值为 0
+func (m *ConfusionMatrix) PrecisionRecallF1(class int) (precision, recall, f1 float64) {
+	tp := m.Counts[class][class]
+	predicted, actual := 0, 0
+	for i := 0; i < m.NumClasses; i++ {
+		predicted += m.Counts[i][class]
+		actual += m.Counts[class][i]
+	}
+	if predicted > 0 {
+		precision = float64(tp) / float64(predicted)
+	}
+	if actual > 0 {
+		recall = float64(tp) / float64(actual)
+	}
+	if precision+recall > 0 {
+		f1 = 2 * precision * recall / (precision + recall)
+	}
+	return precision, recall, f1
+}
+
+// Values 返回准确率与各类别的宏平均精确率、召回率、F1，没有类别时均为 0
+func (m *ConfusionMatrix) Values() map[string]float64 {
+	if m.NumClasses <= 0 {
+		return map[string]float64{"precision": 0, "recall": 0, "f1": 0, "accuracy": 0}
+	}
+	correct, total := 0, 0
+	var precision, recall, f1 float64
+	for c := 0; c < m.NumClasses; c++ {
+		p, r, f := m.PrecisionRecallF1(c)
+		precision += p
+		recall += r
+		f1 += f
+		correct += m.Counts[c][c]
+		for _, v := range m.Counts[c] {
+			total += v
+		}
+	}
+	k := float64(m.NumClasses)
+	values := map[string]float64{"precision": precision / k, "recall": recall / k, "f1": f1 / k, "accuracy": 0}
+	if total > 0 {
+		values["accuracy"] = float64(correct) / float64(total)
+	}
+	return values
+}
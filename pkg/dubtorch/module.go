@@ -0,0 +1,146 @@
+package dubtorch
+
+import (
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Module 神经网络模块，参数与子模块按名称注册，名称用于优化器与检查点
+type Module interface {
+	Forward(x *Tensor) (*Tensor, error)
+
+	// NamedParameters 递归返回所有参数，子模块的参数名带有 "子模块名." 前缀
+	NamedParameters() []NamedTensor
+	Parameters() []*Tensor
+	// NamedBuffers 递归返回所有非参数的状态（例如 BatchNorm 的滑动平均）
+	NamedBuffers() []NamedArray
+	// NamedChildren 返回直接子模块
+	NamedChildren() []NamedModule
+
+	// StateDict 返回参数与缓冲区的副本，LoadStateDict 原地恢复，用于检查点
+	StateDict() map[string]*dubnp.Array
+	LoadStateDict(state map[string]*dubnp.Array, strict bool) (IncompatibleKeys, error)
+
+	Train()
+	Eval()
+	IsTraining() bool
+}
+
+// NamedTensor 带名称的参数
+type NamedTensor struct {
+	Name   string
+	Tensor *Tensor
+}
+
+// NamedArray 带名称的缓冲区
+type NamedArray struct {
+	Name  string
+	Array *dubnp.Array
+}
+
+// NamedModule 带名称的子模块
+type NamedModule struct {
+	Name   string
+	Module Module
+}
+
+// BaseModule 由各层嵌入，负责参数、缓冲区、子模块的注册以及训练/推理模式的切换
+type BaseModule struct {
+	eval     bool // 零值表示训练模式
+	params   []NamedTensor
+	buffers  []NamedArray
+	children []NamedModule
+}
+
+// RegisterParameter 注册参数并将其标记为需要梯度
+func (m *BaseModule) RegisterParameter(name string, t *Tensor) *Tensor {
+	t.RequiresGrad = true
+	m.params = append(m.params, NamedTensor{Name: name, Tensor: t})
+	return t
+}
+
+// RegisterBuffer 注册缓冲区，缓冲区保存在检查点中但不参与求导
+func (m *BaseModule) RegisterBuffer(name string, a *dubnp.Array) *dubnp.Array {
+	m.buffers = append(m.buffers, NamedArray{Name: name, Array: a})
+	return a
+}
+
+// RegisterModule 注册子模块，子模块的训练/推理模式随父模块切换
+func (m *BaseModule) RegisterModule(name string, child Module) Module {
+	m.children = append(m.children, NamedModule{Name: name, Module: child})
+	return child
+}
+
+// NamedParameters 递归返回所有参数
+func (m *BaseModule) NamedParameters() []NamedTensor {
+	params := append([]NamedTensor(nil), m.params...)
+	for _, child := range m.children {
+		for _, p := range child.Module.NamedParameters() {
+			params = append(params, NamedTensor{Name: child.Name + "." + p.Name, Tensor: p.Tensor})
+		}
+	}
+	return params
+}
+
+// Parameters 递归返回所有参数
+func (m *BaseModule) Parameters() []*Tensor {
+	named := m.NamedParameters()
+	params := make([]*Tensor, len(named))
+	for i, p := range named {
+		params[i] = p.Tensor
+	}
+	return params
+}
+
+// NamedBuffers 递归返回所有缓冲区
+func (m *BaseModule) NamedBuffers() []NamedArray {
+	buffers := append([]NamedArray(nil), m.buffers...)
+	for _, child := range m.children {
+		for _, b := range child.Module.NamedBuffers() {
+			buffers = append(buffers, NamedArray{Name: child.Name + "." + b.Name, Array: b.Array})
+		}
+	}
+	return buffers
+}
+
+// NamedChildren 返回直接子模块
+func (m *BaseModule) NamedChildren() []NamedModule {
+	return m.children
+}
+
+// LookupParameter 按完整名称查找参数
+func (m *BaseModule) LookupParameter(name string) (*Tensor, bool) {
+	for _, p := range m.NamedParameters() {
+		if p.Name == name {
+			return p.Tensor, true
+		}
+	}
+	return nil, false
+}
+
+// Train 切换到训练模式（递归）
+func (m *BaseModule) Train() {
+	m.eval = false
+	for _, child := range m.children {
+		child.Module.Train()
+	}
+}
+
+// Eval 切换到推理模式（递归）
+func (m *BaseModule) Eval() {
+	m.eval = true
+	for _, child := range m.children {
+		child.Module.Eval()
+	}
+}
+
+// IsTraining 是否处于训练模式
+func (m *BaseModule) IsTraining() bool {
+	return !m.eval
+}
+
+// ZeroGrad 清空所有参数的梯度
+func (m *BaseModule) ZeroGrad() {
+	for _, p := range m.NamedParameters() {
+		p.Tensor.ZeroGrad()
+	}
+}
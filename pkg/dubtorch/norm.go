@@ -0,0 +1,183 @@
+package dubtorch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// LayerNorm 对最后若干维做归一化，并逐元素缩放、平移
+type LayerNorm struct {
+	BaseModule
+	NormalizedShape []int
+	Eps             float64
+	Weight, Bias    *Tensor
+}
+
+// NewLayerNorm 创建层归一化，Weight 初始化为 1，Bias 初始化为 0
+func NewLayerNorm(normalizedShape ...int) *LayerNorm {
+	l := &LayerNorm{NormalizedShape: append([]int(nil), normalizedShape...), Eps: 1e-5}
+	l.Weight = l.RegisterParameter("weight", NewTensor(dubnp.Ones(normalizedShape...), true))
+	l.Bias = l.RegisterParameter("bias", NewTensor(dubnp.Zeros(normalizedShape...), true))
+	return l
+}
+
+// Forward 输入形状为 (..., NormalizedShape...)
+func (l *LayerNorm) Forward(x *Tensor) (*Tensor, error) {
+	shape := x.Shape()
+	k := len(l.NormalizedShape)
+	if len(shape) < k {
+		return nil, fmt.Errorf("LayerNorm 期望输入以 %v 结尾，输入形状为 %v", l.NormalizedShape, shape)
+	}
+	d := 1
+	for i, n := range l.NormalizedShape {
+		if shape[len(shape)-k+i] != n {
+			return nil, fmt.Errorf("LayerNorm 期望输入以 %v 结尾，输入形状为 %v", l.NormalizedShape, shape)
+		}
+		d *= n
+	}
+
+	// 合并为 (-1, d) 后沿第 1 维计算均值与方差
+	flat, err := x.Reshape(-1, d)
+	if err != nil {
+		return nil, err
+	}
+	normed, err := normalize(flat, []int{1}, l.Eps)
+	if err != nil {
+		return nil, err
+	}
+	if normed, err = normed.Reshape(shape...); err != nil {
+		return nil, err
+	}
+	if normed, err = normed.Mul(l.Weight); err != nil {
+		return nil, err
+	}
+	return normed.Add(l.Bias)
+}
+
+// 沿 axes 计算有偏方差并归一化，返回 (x-mean)/sqrt(var+eps)
+func normalize(x *Tensor, axes []int, eps float64) (*Tensor, error) {
+	mean, err := meanAxes(x, axes)
+	if err != nil {
+		return nil, err
+	}
+	centered, err := x.Sub(mean)
+	if err != nil {
+		return nil, err
+	}
+	variance, err := meanAxes(centered.Pow(2), axes)
+	if err != nil {
+		return nil, err
+	}
+	return centered.Div(variance.AddScalar(eps).Sqrt())
+}
+
+// 依次沿多个轴求平均值并保留维度
+func meanAxes(x *Tensor, axes []int) (*Tensor, error) {
+	var err error
+	for _, axis := range axes {
+		if x, err = x.MeanAxis(axis, true); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+// BatchNorm1d 对 (N, C) 或 (N, C, L) 输入的每个通道做批归一化
+// 训练时使用当前批次的统计量并更新滑动平均，推理时使用滑动平均
+type BatchNorm1d struct {
+	BaseModule
+	NumFeatures             int
+	Eps, Momentum           float64
+	Weight, Bias            *Tensor
+	RunningMean, RunningVar *dubnp.Array
+}
+
+// NewBatchNorm1d 创建批归一化层，Momentum 默认 0.1
+func NewBatchNorm1d(numFeatures int) *BatchNorm1d {
+	b := &BatchNorm1d{NumFeatures: numFeatures, Eps: 1e-5, Momentum: 0.1}
+	b.Weight = b.RegisterParameter("weight", NewTensor(dubnp.Ones(numFeatures), true))
+	b.Bias = b.RegisterParameter("bias", NewTensor(dubnp.Zeros(numFeatures), true))
+	b.RunningMean = b.RegisterBuffer("running_mean", dubnp.Zeros(numFeatures))
+	b.RunningVar = b.RegisterBuffer("running_var", dubnp.Ones(numFeatures))
+	return b
+}
+
+// Forward 执行批归一化
+func (b *BatchNorm1d) Forward(x *Tensor) (*Tensor, error) {
+	shape := x.Shape()
+	if (len(shape) != 2 && len(shape) != 3) || shape[1] != b.NumFeatures {
+		return nil, fmt.Errorf("BatchNorm1d 期望输入形状为 (N, %d) 或 (N, %d, L)，实际为 %v", b.NumFeatures, b.NumFeatures, shape)
+	}
+	// 通道参数的广播形状：(C) 或 (C, 1)
+	channelShape := []int{b.NumFeatures}
+	axes := []int{0}
+	if len(shape) == 3 {
+		channelShape = []int{b.NumFeatures, 1}
+		axes = []int{0, 2}
+	}
+
+	var normed *Tensor
+	var err error
+	if b.IsTraining() {
+		count := x.Size() / b.NumFeatures
+		if count < 2 {
+			return nil, fmt.Errorf("BatchNorm1d 训练时每个通道至少需要 2 个值，输入形状为 %v", shape)
+		}
+		if normed, err = normalize(x, axes, b.Eps); err != nil {
+			return nil, err
+		}
+		b.updateRunningStats(x.Data, axes, count)
+	} else {
+		mean, err := b.RunningMean.Reshape(channelShape...)
+		if err != nil {
+			return nil, err
+		}
+		std := b.RunningVar.Map(func(v float64) float64 { return v + b.Eps }).Map(math.Sqrt)
+		if std, err = std.Reshape(channelShape...); err != nil {
+			return nil, err
+		}
+		centered, err := x.Sub(NewTensor(mean, false))
+		if err != nil {
+			return nil, err
+		}
+		if normed, err = centered.Div(NewTensor(std, false)); err != nil {
+			return nil, err
+		}
+	}
+
+	weight, err := b.Weight.Reshape(channelShape...)
+	if err != nil {
+		return nil, err
+	}
+	bias, err := b.Bias.Reshape(channelShape...)
+	if err != nil {
+		return nil, err
+	}
+	if normed, err = normed.Mul(weight); err != nil {
+		return nil, err
+	}
+	return normed.Add(bias)
+}
+
+// 用当前批次的均值与无偏方差更新滑动平均
+func (b *BatchNorm1d) updateRunningStats(x *dubnp.Array, axes []int, count int) {
+	mean, sq := make([]float64, b.NumFeatures), make([]float64, b.NumFeatures)
+	inner := 1
+	if len(axes) == 2 {
+		inner = x.Shape[2]
+	}
+	for i, v := range x.Data {
+		c := (i / inner) % b.NumFeatures
+		mean[c] += v
+		sq[c] += v * v
+	}
+	n := float64(count)
+	for c := range mean {
+		m := mean[c] / n
+		unbiased := (sq[c] - n*m*m) / (n - 1)
+		b.RunningMean.Data[c] = (1-b.Momentum)*b.RunningMean.Data[c] + b.Momentum*m
+		b.RunningVar.Data[c] = (1-b.Momentum)*b.RunningVar.Data[c] + b.Momentum*unbiased
+	}
+}
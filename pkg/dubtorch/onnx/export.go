@@ -0,0 +1,308 @@
+package onnx
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// Opset 导出模型使用的默认算子集版本
+const Opset = 13
+
+// ExportOptions 导出选项，零值表示以 F32 导出、输入输出名为 "input" 与 "output"、批次维度固定
+type ExportOptions struct {
+	DType        string // 权重的数据类型，"F32" 或 "F64"
+	InputName    string
+	OutputName   string
+	DynamicBatch bool // 为 true 时输入输出的第 0 维导出为名为 "N" 的动态维度
+}
+
+// tracer 在示例输入上逐层执行模块，同时记录对应的 ONNX 节点
+type tracer struct {
+	graph *GraphProto
+	dtype int
+	count int
+}
+
+// 生成新的中间值名
+func (t *tracer) value() string {
+	t.count++
+	return fmt.Sprintf("t%d", t.count)
+}
+
+// 追加节点并返回其输出名
+func (t *tracer) node(name, op string, inputs []string, attrs ...*AttributeProto) string {
+	out := t.value()
+	t.graph.Node = append(t.graph.Node, &NodeProto{Name: name + op, OpType: op, Input: inputs, Output: []string{out}, Attribute: attrs})
+	return out
+}
+
+// 追加常量并返回其名字
+func (t *tracer) initializer(name string, a *dubnp.Array, dtype int) string {
+	dims := make([]int64, len(a.Shape))
+	for i, d := range a.Shape {
+		dims[i] = int64(d)
+	}
+	t.graph.Initializer = append(t.graph.Initializer, &TensorProto{Name: name, Dims: dims, DataType: dtype, Data: append([]float64(nil), a.Data...)})
+	return name
+}
+
+func intAttr(name string, v int64) *AttributeProto {
+	return &AttributeProto{Name: name, Type: AttrInt, I: v}
+}
+
+func intsAttr(name string, v []int64) *AttributeProto {
+	return &AttributeProto{Name: name, Type: AttrInts, Ints: v}
+}
+
+// 将按空间维度给出的选项展开为 dims 个值，规则与 dubtorch.ConvOptions 相同
+func spatial(v []int, dims, def int) ([]int64, error) {
+	out := make([]int64, dims)
+	switch len(v) {
+	case 0:
+		for i := range out {
+			out[i] = int64(def)
+		}
+	case 1, dims:
+		for i := range out {
+			out[i] = int64(v[min(i, len(v)-1)])
+		}
+	default:
+		return nil, fmt.Errorf("期望 1 或 %d 个值，实际为 %v", dims, v)
+	}
+	return out, nil
+}
+
+// ONNX 的 pads 依次给出各维度的起始与末尾填充，dubtorch 两侧填充相同
+func pads(p []int64) []int64 {
+	return append(append([]int64(nil), p...), p...)
+}
+
+// trace 在输入 x（图中名为 in）上执行模块 m 并记录节点，prefix 为参数名前缀
+func (t *tracer) trace(prefix string, m dubtorch.Module, x *dubtorch.Tensor, in string) (*dubtorch.Tensor, string, error) {
+	if s, ok := m.(*dubtorch.Sequential); ok {
+		children := s.NamedChildren()
+		var err error
+		for i := 0; i < s.Len(); i++ {
+			if x, in, err = t.trace(prefix+children[i].Name+".", s.At(i), x, in); err != nil {
+				return nil, "", err
+			}
+		}
+		return x, in, nil
+	}
+	y, err := m.Forward(x)
+	if err != nil {
+		return nil, "", err
+	}
+	var out string
+	switch l := m.(type) {
+	case *dubtorch.Linear:
+		// Gemm 只接受二维输入，计算 x W^T + b
+		if len(x.Shape()) != 2 {
+			return nil, "", fmt.Errorf("%s: Linear 只能导出二维输入，实际形状为 %v", prefix, x.Shape())
+		}
+		inputs := []string{in, t.initializer(prefix+"weight", l.Weight.Data, t.dtype)}
+		if l.Bias != nil {
+			inputs = append(inputs, t.initializer(prefix+"bias", l.Bias.Data, t.dtype))
+		}
+		out = t.node(prefix, "Gemm", inputs, intAttr("transB", 1))
+	case *dubtorch.Conv:
+		if l.Transposed {
+			return nil, "", fmt.Errorf("%s: 暂不支持导出转置卷积", prefix)
+		}
+		attrs, err := convAttrs(l)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %v", prefix, err)
+		}
+		inputs := []string{in, t.initializer(prefix+"weight", l.Weight.Data, t.dtype)}
+		if l.Bias != nil {
+			inputs = append(inputs, t.initializer(prefix+"bias", l.Bias.Data, t.dtype))
+		}
+		out = t.node(prefix, "Conv", inputs, attrs...)
+	case *dubtorch.MaxPool, *dubtorch.AvgPool:
+		op, dims, opts := "MaxPool", 0, dubtorch.PoolOptions{}
+		var extra []*AttributeProto
+		if p, ok := l.(*dubtorch.MaxPool); ok {
+			dims, opts = p.Dims, p.Options
+		} else {
+			p := l.(*dubtorch.AvgPool)
+			op, dims, opts = "AveragePool", p.Dims, p.Options
+			// dubtorch 的平均池化把填充位置计入窗口大小
+			extra = append(extra, intAttr("count_include_pad", 1))
+		}
+		attrs, err := poolAttrs(dims, opts)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %v", prefix, err)
+		}
+		out = t.node(prefix, op, []string{in}, append(attrs, extra...)...)
+	case *dubtorch.AdaptiveAvgPool:
+		for _, s := range l.OutputSize {
+			if s != 1 {
+				return nil, "", fmt.Errorf("%s: 自适应平均池化只能导出输出尺寸为 1 的情形，实际为 %v", prefix, l.OutputSize)
+			}
+		}
+		out = t.node(prefix, "GlobalAveragePool", []string{in})
+	case *dubtorch.ReLU:
+		out = t.node(prefix, "Relu", []string{in})
+	case *dubtorch.Tanh:
+		out = t.node(prefix, "Tanh", []string{in})
+	case *dubtorch.Sigmoid:
+		out = t.node(prefix, "Sigmoid", []string{in})
+	case *dubtorch.Softmax:
+		out = t.node(prefix, "Softmax", []string{in}, intAttr("axis", int64(l.Axis)))
+	case *dubtorch.Flatten:
+		// 0 表示沿用输入对应维度，使导出的图对批大小无关
+		shape := t.initializer(prefix+"shape", &dubnp.Array{Data: []float64{0, -1}, Shape: []int{2}}, DataTypeInt64)
+		out = t.node(prefix, "Reshape", []string{in, shape})
+	case *dubtorch.Dropout:
+		// 推理时为恒等映射
+		return y, in, nil
+	default:
+		return nil, "", fmt.Errorf("%s: 不支持导出模块 %T", prefix, m)
+	}
+	return y, out, nil
+}
+
+func convAttrs(c *dubtorch.Conv) ([]*AttributeProto, error) {
+	kernel, err := spatial(c.KernelSize, c.Dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	stride, err := spatial(c.Options.Stride, c.Dims, 1)
+	if err != nil {
+		return nil, err
+	}
+	padding, err := spatial(c.Options.Padding, c.Dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	dilation, err := spatial(c.Options.Dilation, c.Dims, 1)
+	if err != nil {
+		return nil, err
+	}
+	group := int64(max(c.Options.Groups, 1))
+	return []*AttributeProto{
+		intsAttr("kernel_shape", kernel), intsAttr("strides", stride), intsAttr("pads", pads(padding)),
+		intsAttr("dilations", dilation), intAttr("group", group),
+	}, nil
+}
+
+func poolAttrs(dims int, opts dubtorch.PoolOptions) ([]*AttributeProto, error) {
+	kernel, err := spatial(opts.KernelSize, dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	stride, err := spatial(opts.Stride, dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	// 步长默认等于窗口大小
+	for i := range stride {
+		if stride[i] == 0 {
+			stride[i] = kernel[i]
+		}
+	}
+	padding, err := spatial(opts.Padding, dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	return []*AttributeProto{intsAttr("kernel_shape", kernel), intsAttr("strides", stride), intsAttr("pads", pads(padding))}, nil
+}
+
+// 构造图的输入或输出描述
+func valueInfo(name string, shape []int, elem int, dynamic bool) *ValueInfoProto {
+	v := &ValueInfoProto{Name: name, ElemType: elem, Dims: make([]int64, len(shape)), DimParams: make([]string, len(shape))}
+	for i, d := range shape {
+		v.Dims[i] = int64(d)
+	}
+	if dynamic && len(shape) > 0 {
+		v.Dims[0], v.DimParams[0] = -1, "N"
+	}
+	return v
+}
+
+// Export 在示例输入上逐层执行模块，得到对应的 ONNX 模型。
+// 支持 Sequential 及其中的 Linear、Conv、池化、常用激活、Flatten 与 Dropout，遇到其他模块返回错误。
+// 导出期间模块处于推理模式，结束后恢复原来的模式；常量名与模块的参数名一致
+func Export(m dubtorch.Module, input *dubnp.Array, opts ExportOptions) (*ModelProto, error) {
+	if m == nil || input == nil {
+		return nil, errors.New("模块与示例输入不能为空")
+	}
+	dtype := DataTypeFloat
+	switch opts.DType {
+	case "", "F32":
+	case "F64":
+		dtype = DataTypeDouble
+	default:
+		return nil, fmt.Errorf("不支持的数据类型 %q", opts.DType)
+	}
+	if opts.InputName == "" {
+		opts.InputName = "input"
+	}
+	if opts.OutputName == "" {
+		opts.OutputName = "output"
+	}
+	if m.IsTraining() {
+		m.Eval()
+		defer m.Train()
+	}
+
+	t := &tracer{graph: &GraphProto{Name: "dubtorch"}, dtype: dtype}
+	var y *dubtorch.Tensor
+	var out string
+	var err error
+	dubtorch.NoGrad(func() {
+		y, out, err = t.trace("", m, dubtorch.NewTensor(input, false), opts.InputName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 把最后一个节点的输出改名为图的输出；整个模型是恒等映射时补一个 Identity
+	if out == opts.InputName {
+		t.graph.Node = append(t.graph.Node, &NodeProto{Name: "Identity", OpType: "Identity", Input: []string{out}, Output: []string{opts.OutputName}})
+	} else {
+		last := t.graph.Node[len(t.graph.Node)-1]
+		last.Output[0] = opts.OutputName
+	}
+	t.graph.Input = []*ValueInfoProto{valueInfo(opts.InputName, input.Shape, dtype, opts.DynamicBatch)}
+	t.graph.Output = []*ValueInfoProto{valueInfo(opts.OutputName, y.Shape(), dtype, opts.DynamicBatch)}
+	return &ModelProto{
+		IRVersion:    8,
+		ProducerName: "dubtorch",
+		OpsetImport:  []OperatorSetID{{Version: Opset}},
+		Graph:        t.graph,
+	}, nil
+}
+
+// Save 导出模块并写出 ONNX 的 protobuf 编码
+func Save(w io.Writer, m dubtorch.Module, input *dubnp.Array, opts ExportOptions) error {
+	model, err := Export(m, input, opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(model.Marshal())
+	return err
+}
+
+// SaveFile 导出模块到 .onnx 文件
+func SaveFile(path string, m dubtorch.Module, input *dubnp.Array, opts ExportOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	if err := Save(w, m, input, opts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
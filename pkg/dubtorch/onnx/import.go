@@ -0,0 +1,499 @@
+package onnx
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// 支持导入的算子及其最少输入个数
+var supportedOps = map[string]int{
+	"Gemm": 2, "MatMul": 2, "Add": 2, "Conv": 2, "Reshape": 2, "Flatten": 1,
+	"Relu": 1, "Tanh": 1, "Sigmoid": 1, "Softmax": 1, "Identity": 1, "Dropout": 1,
+	"MaxPool": 1, "AveragePool": 1, "GlobalAveragePool": 1,
+}
+
+// attrs 节点属性，按名称查找，缺省时使用 ONNX 规定的默认值
+type attrs map[string]*AttributeProto
+
+func (a attrs) int(name string, def int64) int64 {
+	if v, ok := a[name]; ok {
+		return v.I
+	}
+	return def
+}
+
+func (a attrs) float(name string, def float64) float64 {
+	if v, ok := a[name]; ok {
+		return float64(v.F)
+	}
+	return def
+}
+
+func (a attrs) ints(name string) []int64 {
+	if v, ok := a[name]; ok {
+		return v.Ints
+	}
+	return nil
+}
+
+func (a attrs) string(name, def string) string {
+	if v, ok := a[name]; ok {
+		return v.S
+	}
+	return def
+}
+
+// 预处理后的节点
+type node struct {
+	proto *NodeProto
+	attrs attrs
+}
+
+// Module 由 ONNX 计算图构建的可执行模块。浮点常量注册为参数（可以继续训练），
+// 整数常量（如 Reshape 的目标形状）只在图内使用；执行使用 dubtorch 的张量运算，支持自动求导
+type Module struct {
+	dubtorch.BaseModule
+	Inputs, Outputs []string // 图的输入与输出名，不含常量
+	opset           int64
+	nodes           []node
+	constants       map[string]*dubtorch.Tensor
+}
+
+// NewModule 从模型构建可执行模块，遇到不支持的算子返回错误
+func NewModule(model *ModelProto) (*Module, error) {
+	if model == nil || model.Graph == nil {
+		return nil, errors.New("模型中没有计算图")
+	}
+	m := &Module{opset: 1, constants: map[string]*dubtorch.Tensor{}}
+	for _, op := range model.OpsetImport {
+		if op.Domain == "" || op.Domain == "ai.onnx" {
+			m.opset = op.Version
+		}
+	}
+	g := model.Graph
+	for _, t := range g.Initializer {
+		if err := m.addConstant(t, true); err != nil {
+			return nil, err
+		}
+	}
+	for _, v := range g.Input {
+		// 旧版本的模型把常量也列为图的输入
+		if _, ok := m.constants[v.Name]; !ok {
+			m.Inputs = append(m.Inputs, v.Name)
+		}
+	}
+	for _, v := range g.Output {
+		m.Outputs = append(m.Outputs, v.Name)
+	}
+
+	for _, n := range g.Node {
+		if n.Domain != "" && n.Domain != "ai.onnx" {
+			return nil, fmt.Errorf("节点 %q 属于不支持的算子域 %q", n.Name, n.Domain)
+		}
+		a := attrs{}
+		for _, attr := range n.Attribute {
+			a[attr.Name] = attr
+		}
+		// Constant 节点在构建时求值
+		if n.OpType == "Constant" {
+			v, ok := a["value"]
+			if !ok || v.T == nil || len(n.Output) != 1 {
+				return nil, fmt.Errorf("Constant 节点 %q 只支持 value 属性", n.Name)
+			}
+			t := *v.T
+			t.Name = n.Output[0]
+			if err := m.addConstant(&t, false); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		need, ok := supportedOps[n.OpType]
+		if !ok {
+			return nil, fmt.Errorf("不支持的算子 %s（节点 %q）", n.OpType, n.Name)
+		}
+		if len(n.Input) < need || len(n.Output) == 0 {
+			return nil, fmt.Errorf("%s 节点 %q 的输入输出个数不正确", n.OpType, n.Name)
+		}
+		// 空名字表示省略的可选输入，必需的输入不能省略
+		for i, in := range n.Input[:need] {
+			if in == "" {
+				return nil, fmt.Errorf("%s 节点 %q 缺少第 %d 个必需的输入", n.OpType, n.Name, i)
+			}
+		}
+		// 只使用第一个输出，其余输出（如 MaxPool 的下标）不能被引用
+		for _, out := range n.Output[1:] {
+			if out != "" && n.OpType != "Dropout" {
+				return nil, fmt.Errorf("%s 节点 %q 只支持一个输出", n.OpType, n.Name)
+			}
+		}
+		m.nodes = append(m.nodes, node{proto: n, attrs: a})
+	}
+	return m, nil
+}
+
+// 注册常量，浮点常量作为参数（param 为 true 时）或不求导的张量，整数常量只在图内使用
+func (m *Module) addConstant(t *TensorProto, param bool) error {
+	shape, err := t.shape()
+	if err != nil {
+		return err
+	}
+	tensor := dubtorch.NewTensor(&dubnp.Array{Data: append([]float64(nil), t.Data...), Shape: shape}, false)
+	switch t.DataType {
+	case DataTypeFloat, DataTypeDouble:
+		if param {
+			m.RegisterParameter(t.Name, tensor)
+		}
+	case DataTypeInt32, DataTypeInt64:
+	default:
+		return fmt.Errorf("常量 %q 的数据类型 %d 不受支持", t.Name, t.DataType)
+	}
+	m.constants[t.Name] = tensor
+	return nil
+}
+
+// Opset 返回模型使用的默认算子集版本
+func (m *Module) Opset() int64 {
+	return m.opset
+}
+
+// Run 按拓扑序执行计算图，inputs 以图的输入名为键，返回所有图输出
+func (m *Module) Run(inputs map[string]*dubtorch.Tensor) (map[string]*dubtorch.Tensor, error) {
+	values := make(map[string]*dubtorch.Tensor, len(m.constants)+len(m.nodes))
+	for name, t := range m.constants {
+		values[name] = t
+	}
+	for _, name := range m.Inputs {
+		x, ok := inputs[name]
+		if !ok {
+			return nil, fmt.Errorf("缺少图的输入 %q", name)
+		}
+		values[name] = x
+	}
+	for _, n := range m.nodes {
+		in := make([]*dubtorch.Tensor, len(n.proto.Input))
+		for i, name := range n.proto.Input {
+			if name == "" {
+				continue
+			}
+			var ok bool
+			if in[i], ok = values[name]; !ok {
+				return nil, fmt.Errorf("%s 节点 %q 的输入 %q 尚未计算，节点可能不是拓扑序", n.proto.OpType, n.proto.Name, name)
+			}
+		}
+		y, err := m.apply(n, in)
+		if err != nil {
+			return nil, fmt.Errorf("%s 节点 %q: %v", n.proto.OpType, n.proto.Name, err)
+		}
+		values[n.proto.Output[0]] = y
+	}
+	outputs := make(map[string]*dubtorch.Tensor, len(m.Outputs))
+	for _, name := range m.Outputs {
+		y, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf("图的输出 %q 没有被计算", name)
+		}
+		outputs[name] = y
+	}
+	return outputs, nil
+}
+
+// Forward 执行单输入单输出的计算图
+func (m *Module) Forward(x *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	if len(m.Inputs) != 1 || len(m.Outputs) != 1 {
+		return nil, fmt.Errorf("Forward 只适用于单输入单输出的图，实际有 %d 个输入 %d 个输出，请使用 Run", len(m.Inputs), len(m.Outputs))
+	}
+	out, err := m.Run(map[string]*dubtorch.Tensor{m.Inputs[0]: x})
+	if err != nil {
+		return nil, err
+	}
+	return out[m.Outputs[0]], nil
+}
+
+// 执行单个节点
+func (m *Module) apply(n node, in []*dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	a, x := n.attrs, in[0]
+	switch n.proto.OpType {
+	case "Identity", "Dropout":
+		return x, nil
+	case "Relu":
+		return x.ReLU(), nil
+	case "Tanh":
+		return x.Tanh(), nil
+	case "Sigmoid":
+		return x.Sigmoid(), nil
+	case "Add":
+		return x.Add(in[1])
+	case "MatMul":
+		if len(x.Shape()) < 2 || len(in[1].Shape()) < 2 {
+			return nil, fmt.Errorf("MatMul 只支持至少二维的操作数，实际为 %v 与 %v", x.Shape(), in[1].Shape())
+		}
+		if len(x.Shape()) == 2 && len(in[1].Shape()) == 2 {
+			return x.MatMul(in[1])
+		}
+		return x.BatchMatMul(in[1])
+	case "Gemm":
+		return gemm(a, x, in[1], optional(in, 2))
+	case "Softmax":
+		return m.softmax(a, x)
+	case "Reshape":
+		return reshape(a, x, in[1])
+	case "Flatten":
+		shape := x.Shape()
+		axis := int(a.int("axis", 1))
+		if axis < 0 {
+			axis += len(shape)
+		}
+		if axis < 0 || axis > len(shape) {
+			return nil, fmt.Errorf("Flatten 的 axis %d 超出范围", a.int("axis", 1))
+		}
+		return x.Reshape(prod(shape[:axis]), prod(shape[axis:]))
+	case "Conv":
+		return conv(a, x, in[1], optional(in, 2))
+	case "MaxPool", "AveragePool":
+		return pool(n.proto.OpType, a, x)
+	case "GlobalAveragePool":
+		switch len(x.Shape()) {
+		case 3:
+			return dubtorch.AdaptiveAvgPool1d(x, 1)
+		case 4:
+			return dubtorch.AdaptiveAvgPool2d(x, []int{1, 1})
+		}
+		return nil, fmt.Errorf("GlobalAveragePool 只支持三维或四维输入，实际形状为 %v", x.Shape())
+	}
+	return nil, fmt.Errorf("不支持的算子 %s", n.proto.OpType)
+}
+
+// 取可选输入，省略时为 nil
+func optional(in []*dubtorch.Tensor, i int) *dubtorch.Tensor {
+	if i < len(in) {
+		return in[i]
+	}
+	return nil
+}
+
+func prod(shape []int) int {
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
+
+// 张量中保存的整数列表，如 Reshape 的目标形状
+func intValues(t *dubtorch.Tensor) []int {
+	out := make([]int, len(t.Data.Data))
+	for i, v := range t.Data.Data {
+		out[i] = int(v)
+	}
+	return out
+}
+
+// Y = alpha * A' B' + beta * C，A' 与 B' 按 transA、transB 转置，C 可广播
+func gemm(a attrs, x, w, c *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	var err error
+	if a.int("transA", 0) != 0 {
+		if x, err = x.Transpose(); err != nil {
+			return nil, err
+		}
+	}
+	if a.int("transB", 0) != 0 {
+		if w, err = w.Transpose(); err != nil {
+			return nil, err
+		}
+	}
+	y, err := x.MatMul(w)
+	if err != nil {
+		return nil, err
+	}
+	if alpha := a.float("alpha", 1); alpha != 1 {
+		y = y.MulScalar(alpha)
+	}
+	if c == nil {
+		return y, nil
+	}
+	if beta := a.float("beta", 1); beta != 1 {
+		c = c.MulScalar(beta)
+	}
+	return y.Add(c)
+}
+
+// opset 13 起 Softmax 沿单个轴计算，默认最后一维；更早的版本把 axis 之后的维度展平后计算，默认 axis 为 1
+func (m *Module) softmax(a attrs, x *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	shape := x.Shape()
+	if m.opset >= 13 {
+		return x.Softmax(int(a.int("axis", -1)))
+	}
+	axis := int(a.int("axis", 1))
+	if axis < 0 {
+		axis += len(shape)
+	}
+	if axis < 0 || axis > len(shape) {
+		return nil, fmt.Errorf("Softmax 的 axis %d 超出范围", a.int("axis", 1))
+	}
+	flat, err := x.Reshape(prod(shape[:axis]), prod(shape[axis:]))
+	if err != nil {
+		return nil, err
+	}
+	y, err := flat.Softmax(1)
+	if err != nil {
+		return nil, err
+	}
+	return y.Reshape(shape...)
+}
+
+// 目标形状中的 0 表示沿用输入对应维度（allowzero 为 0 时），-1 表示由其余维度推断
+func reshape(a attrs, x, shape *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	target := intValues(shape)
+	if a.int("allowzero", 0) == 0 {
+		for i, d := range target {
+			if d == 0 {
+				if i >= len(x.Shape()) {
+					return nil, fmt.Errorf("目标形状 %v 的第 %d 维为 0，但输入只有 %d 维", target, i, len(x.Shape()))
+				}
+				target[i] = x.Shape()[i]
+			}
+		}
+	}
+	return x.Reshape(target...)
+}
+
+// 把 ONNX 的 pads（各维起始填充后接各维末尾填充）转为两侧相同的填充
+func symmetricPads(p []int64, dims int) ([]int, error) {
+	out := make([]int, dims)
+	if len(p) == 0 {
+		return out, nil
+	}
+	if len(p) != 2*dims {
+		return nil, fmt.Errorf("pads 应有 %d 个值，实际为 %v", 2*dims, p)
+	}
+	for i := range out {
+		if p[i] != p[i+dims] {
+			return nil, fmt.Errorf("只支持两侧相同的填充，实际为 %v", p)
+		}
+		out[i] = int(p[i])
+	}
+	return out, nil
+}
+
+func toInts(v []int64) []int {
+	out := make([]int, len(v))
+	for i, x := range v {
+		out[i] = int(x)
+	}
+	return out
+}
+
+func checkAutoPad(a attrs) error {
+	if p := a.string("auto_pad", "NOTSET"); p != "NOTSET" && p != "VALID" {
+		return fmt.Errorf("不支持 auto_pad=%s", p)
+	}
+	return nil
+}
+
+// 卷积的权重形状为 (M, C/group, k...)，空间维度为 1 或 2
+func conv(a attrs, x, w, b *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	if err := checkAutoPad(a); err != nil {
+		return nil, err
+	}
+	dims := len(w.Shape()) - 2
+	if dims != 1 && dims != 2 {
+		return nil, fmt.Errorf("只支持一维或二维卷积，权重形状为 %v", w.Shape())
+	}
+	padding, err := symmetricPads(a.ints("pads"), dims)
+	if err != nil {
+		return nil, err
+	}
+	opts := dubtorch.ConvOptions{
+		Stride:   toInts(a.ints("strides")),
+		Padding:  padding,
+		Dilation: toInts(a.ints("dilations")),
+		Groups:   int(a.int("group", 1)),
+	}
+	if dims == 1 {
+		return dubtorch.Conv1d(x, w, b, opts)
+	}
+	return dubtorch.Conv2d(x, w, b, opts)
+}
+
+func pool(op string, a attrs, x *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	if err := checkAutoPad(a); err != nil {
+		return nil, err
+	}
+	if a.int("ceil_mode", 0) != 0 {
+		return nil, errors.New("不支持 ceil_mode=1")
+	}
+	for _, d := range a.ints("dilations") {
+		if d != 1 {
+			return nil, fmt.Errorf("不支持 dilations=%v", a.ints("dilations"))
+		}
+	}
+	kernel := toInts(a.ints("kernel_shape"))
+	dims := len(kernel)
+	if dims != 1 && dims != 2 {
+		return nil, fmt.Errorf("只支持一维或二维池化，kernel_shape 为 %v", kernel)
+	}
+	padding, err := symmetricPads(a.ints("pads"), dims)
+	if err != nil {
+		return nil, err
+	}
+	stride := toInts(a.ints("strides"))
+	if len(stride) == 0 {
+		// ONNX 的步长默认为 1，而 dubtorch 默认等于窗口大小
+		stride = make([]int, dims)
+		for i := range stride {
+			stride[i] = 1
+		}
+	}
+	opts := dubtorch.PoolOptions{KernelSize: kernel, Stride: stride, Padding: padding}
+	if op == "MaxPool" {
+		if dims == 1 {
+			return dubtorch.MaxPool1d(x, opts)
+		}
+		return dubtorch.MaxPool2d(x, opts)
+	}
+	// dubtorch 的平均池化把填充位置计入窗口大小
+	if a.int("count_include_pad", 0) == 0 && anyPositive(padding) {
+		return nil, errors.New("带填充的 AveragePool 只支持 count_include_pad=1")
+	}
+	if dims == 1 {
+		return dubtorch.AvgPool1d(x, opts)
+	}
+	return dubtorch.AvgPool2d(x, opts)
+}
+
+func anyPositive(v []int) bool {
+	for _, x := range v {
+		if x > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Load 读取 ONNX 模型并构建可执行模块
+func Load(r io.Reader) (*Module, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	model, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return NewModule(model)
+}
+
+// LoadFile 从 .onnx 文件构建可执行模块
+func LoadFile(path string) (*Module, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
@@ -0,0 +1,611 @@
+// Package onnx 在 dubtorch 模块与 ONNX 模型之间转换。
+// 只实现 ONNX protobuf 中推理所需的字段，编解码由包内的小型 protobuf 实现完成，不依赖 protoc 生成的代码
+package onnx
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// TensorProto 的元素类型，取值与 onnx.proto 一致
+const (
+	DataTypeFloat  = 1
+	DataTypeInt32  = 6
+	DataTypeInt64  = 7
+	DataTypeDouble = 11
+)
+
+// AttributeProto 的类型，取值与 onnx.proto 一致
+const (
+	AttrFloat   = 1
+	AttrInt     = 2
+	AttrString  = 3
+	AttrTensor  = 4
+	AttrFloats  = 6
+	AttrInts    = 7
+	AttrStrings = 8
+)
+
+// ModelProto ONNX 模型
+type ModelProto struct {
+	IRVersion       int64
+	ProducerName    string
+	ProducerVersion string
+	OpsetImport     []OperatorSetID
+	Graph           *GraphProto
+}
+
+// OperatorSetID 算子集版本，Domain 为空表示默认的 ai.onnx
+type OperatorSetID struct {
+	Domain  string
+	Version int64
+}
+
+// GraphProto 计算图，Node 按拓扑序排列
+type GraphProto struct {
+	Name        string
+	Node        []*NodeProto
+	Initializer []*TensorProto
+	Input       []*ValueInfoProto
+	Output      []*ValueInfoProto
+	ValueInfo   []*ValueInfoProto
+}
+
+// NodeProto 图中的一个算子，Input 中的空串表示省略的可选输入
+type NodeProto struct {
+	Input     []string
+	Output    []string
+	Name      string
+	OpType    string
+	Domain    string
+	Attribute []*AttributeProto
+}
+
+// AttributeProto 算子属性，按 Type 使用对应的字段
+type AttributeProto struct {
+	Name   string
+	Type   int
+	F      float32
+	I      int64
+	S      string
+	T      *TensorProto
+	Floats []float32
+	Ints   []int64
+}
+
+// TensorProto 常量张量，数据统一以 float64 保存，编码时按 DataType 转换
+type TensorProto struct {
+	Name     string
+	Dims     []int64
+	DataType int
+	Data     []float64
+}
+
+// 常量张量元素个数的上限
+const maxTensorElements = 1 << 30
+
+// 检查 Dims 并返回形状：维度不能为负，元素个数不能超过上限且必须与 Data 的长度一致
+func (t *TensorProto) shape() ([]int, error) {
+	shape := make([]int, len(t.Dims))
+	size := 1
+	for i, d := range t.Dims {
+		if d < 0 || d > maxTensorElements {
+			return nil, fmt.Errorf("张量 %q 的第 %d 维 %d 无效", t.Name, i, d)
+		}
+		shape[i] = int(d)
+		if shape[i] > 0 && size > maxTensorElements/shape[i] {
+			return nil, fmt.Errorf("张量 %q 的形状 %v 的元素个数超过上限 %d", t.Name, t.Dims, maxTensorElements)
+		}
+		size *= shape[i]
+	}
+	if len(t.Data) != size {
+		return nil, fmt.Errorf("张量 %q 的形状 %v 需要 %d 个元素，实际为 %d", t.Name, t.Dims, size, len(t.Data))
+	}
+	return shape, nil
+}
+
+// ValueInfoProto 图的输入输出描述，Dims 中的 -1 表示由 DimParams 对应项命名的动态维度
+type ValueInfoProto struct {
+	Name      string
+	ElemType  int
+	Dims      []int64
+	DimParams []string
+}
+
+// Marshal 按 protobuf 线路格式编码模型
+func (m *ModelProto) Marshal() []byte {
+	var e encoder
+	e.int(1, m.IRVersion)
+	e.string(2, m.ProducerName)
+	e.string(3, m.ProducerVersion)
+	if m.Graph != nil {
+		e.message(7, m.Graph.encode)
+	}
+	for _, op := range m.OpsetImport {
+		e.message(8, func(e *encoder) {
+			e.string(1, op.Domain)
+			e.int(2, op.Version)
+		})
+	}
+	return e.buf
+}
+
+func (g *GraphProto) encode(e *encoder) {
+	for _, n := range g.Node {
+		e.message(1, n.encode)
+	}
+	e.string(2, g.Name)
+	for _, t := range g.Initializer {
+		e.message(5, t.encode)
+	}
+	for _, v := range g.Input {
+		e.message(11, v.encode)
+	}
+	for _, v := range g.Output {
+		e.message(12, v.encode)
+	}
+	for _, v := range g.ValueInfo {
+		e.message(13, v.encode)
+	}
+}
+
+func (n *NodeProto) encode(e *encoder) {
+	e.strings(1, n.Input)
+	e.strings(2, n.Output)
+	e.string(3, n.Name)
+	e.string(4, n.OpType)
+	for _, a := range n.Attribute {
+		e.message(5, a.encode)
+	}
+	e.string(7, n.Domain)
+}
+
+func (a *AttributeProto) encode(e *encoder) {
+	e.string(1, a.Name)
+	switch a.Type {
+	case AttrFloat:
+		e.float32(2, a.F)
+	case AttrInt:
+		// 值为 0 时也必须写出，否则读取方无法区分
+		e.tag(3, wireVarint)
+		e.buf = binary.AppendUvarint(e.buf, uint64(a.I))
+	case AttrString:
+		e.bytes(4, []byte(a.S))
+	case AttrTensor:
+		if a.T != nil {
+			e.message(5, a.T.encode)
+		}
+	case AttrFloats:
+		e.packedFloats(7, a.Floats)
+	case AttrInts:
+		e.packedInts(8, a.Ints)
+	}
+	e.int(20, int64(a.Type))
+}
+
+func (t *TensorProto) encode(e *encoder) {
+	e.packedInts(1, t.Dims)
+	e.int(2, int64(t.DataType))
+	e.string(8, t.Name)
+	// 数据一律写入 raw_data，按小端存储
+	var raw []byte
+	switch t.DataType {
+	case DataTypeFloat:
+		raw = make([]byte, 0, 4*len(t.Data))
+		for _, v := range t.Data {
+			raw = binary.LittleEndian.AppendUint32(raw, math.Float32bits(float32(v)))
+		}
+	case DataTypeDouble:
+		raw = make([]byte, 0, 8*len(t.Data))
+		for _, v := range t.Data {
+			raw = binary.LittleEndian.AppendUint64(raw, math.Float64bits(v))
+		}
+	case DataTypeInt32:
+		raw = make([]byte, 0, 4*len(t.Data))
+		for _, v := range t.Data {
+			raw = binary.LittleEndian.AppendUint32(raw, uint32(int32(v)))
+		}
+	case DataTypeInt64:
+		raw = make([]byte, 0, 8*len(t.Data))
+		for _, v := range t.Data {
+			raw = binary.LittleEndian.AppendUint64(raw, uint64(int64(v)))
+		}
+	}
+	if len(raw) > 0 {
+		e.bytes(9, raw)
+	}
+}
+
+func (v *ValueInfoProto) encode(e *encoder) {
+	e.string(1, v.Name)
+	// type { tensor_type { elem_type shape { dim { dim_value | dim_param } } } }
+	e.message(2, func(e *encoder) {
+		e.message(1, func(e *encoder) {
+			e.int(1, int64(v.ElemType))
+			e.message(2, func(e *encoder) {
+				for i, d := range v.Dims {
+					e.message(1, func(e *encoder) {
+						if d < 0 && i < len(v.DimParams) && v.DimParams[i] != "" {
+							e.string(2, v.DimParams[i])
+						} else if d >= 0 {
+							e.tag(1, wireVarint)
+							e.buf = binary.AppendUvarint(e.buf, uint64(d))
+						}
+					})
+				}
+			})
+		})
+	})
+}
+
+// Unmarshal 解码 protobuf 格式的模型，未知字段被忽略
+func Unmarshal(data []byte) (*ModelProto, error) {
+	m := &ModelProto{}
+	d := decoder{data}
+	for !d.done() {
+		field, wire, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case 1:
+			m.IRVersion, err = d.int(wire)
+		case 2:
+			m.ProducerName, err = d.string(wire)
+		case 3:
+			m.ProducerVersion, err = d.string(wire)
+		case 7:
+			var body []byte
+			if body, err = d.bytes(wire); err == nil {
+				m.Graph, err = decodeGraph(body)
+			}
+		case 8:
+			var body []byte
+			if body, err = d.bytes(wire); err == nil {
+				var op OperatorSetID
+				op, err = decodeOpset(body)
+				m.OpsetImport = append(m.OpsetImport, op)
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 ModelProto 字段 %d: %v", field, err)
+		}
+	}
+	if m.Graph == nil {
+		return nil, fmt.Errorf("模型中没有计算图")
+	}
+	return m, nil
+}
+
+func decodeOpset(data []byte) (OperatorSetID, error) {
+	var op OperatorSetID
+	d := decoder{data}
+	for !d.done() {
+		field, wire, err := d.next()
+		if err != nil {
+			return op, err
+		}
+		switch field {
+		case 1:
+			op.Domain, err = d.string(wire)
+		case 2:
+			op.Version, err = d.int(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return op, err
+		}
+	}
+	return op, nil
+}
+
+func decodeGraph(data []byte) (*GraphProto, error) {
+	g := &GraphProto{}
+	d := decoder{data}
+	for !d.done() {
+		field, wire, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		if field == 2 {
+			if g.Name, err = d.string(wire); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if field != 1 && field != 5 && field != 11 && field != 12 && field != 13 {
+			if err := d.skip(wire); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		body, err := d.bytes(wire)
+		if err != nil {
+			return nil, err
+		}
+		switch field {
+		case 1:
+			var n *NodeProto
+			if n, err = decodeNode(body); err == nil {
+				g.Node = append(g.Node, n)
+			}
+		case 5:
+			var t *TensorProto
+			if t, err = decodeTensor(body); err == nil {
+				g.Initializer = append(g.Initializer, t)
+			}
+		default:
+			var v *ValueInfoProto
+			if v, err = decodeValueInfo(body); err == nil {
+				switch field {
+				case 11:
+					g.Input = append(g.Input, v)
+				case 12:
+					g.Output = append(g.Output, v)
+				default:
+					g.ValueInfo = append(g.ValueInfo, v)
+				}
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 GraphProto 字段 %d: %v", field, err)
+		}
+	}
+	return g, nil
+}
+
+func decodeNode(data []byte) (*NodeProto, error) {
+	n := &NodeProto{}
+	d := decoder{data}
+	for !d.done() {
+		field, wire, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		var s string
+		switch field {
+		case 1:
+			if s, err = d.string(wire); err == nil {
+				n.Input = append(n.Input, s)
+			}
+		case 2:
+			if s, err = d.string(wire); err == nil {
+				n.Output = append(n.Output, s)
+			}
+		case 3:
+			n.Name, err = d.string(wire)
+		case 4:
+			n.OpType, err = d.string(wire)
+		case 5:
+			var body []byte
+			if body, err = d.bytes(wire); err == nil {
+				var a *AttributeProto
+				if a, err = decodeAttribute(body); err == nil {
+					n.Attribute = append(n.Attribute, a)
+				}
+			}
+		case 7:
+			n.Domain, err = d.string(wire)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func decodeAttribute(data []byte) (*AttributeProto, error) {
+	a := &AttributeProto{}
+	d := decoder{data}
+	for !d.done() {
+		field, wire, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		var v int64
+		switch field {
+		case 1:
+			a.Name, err = d.string(wire)
+		case 2:
+			a.F, err = d.float32(wire)
+		case 3:
+			a.I, err = d.int(wire)
+		case 4:
+			a.S, err = d.string(wire)
+		case 5:
+			var body []byte
+			if body, err = d.bytes(wire); err == nil {
+				a.T, err = decodeTensor(body)
+			}
+		case 7:
+			var fs []float64
+			if fs, err = d.floats(wire, nil); err == nil {
+				for _, f := range fs {
+					a.Floats = append(a.Floats, float32(f))
+				}
+			}
+		case 8:
+			a.Ints, err = d.ints(wire, a.Ints)
+		case 20:
+			v, err = d.int(wire)
+			a.Type = int(v)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func decodeTensor(data []byte) (*TensorProto, error) {
+	t := &TensorProto{}
+	d := decoder{data}
+	var raw []byte
+	for !d.done() {
+		field, wire, err := d.next()
+		if err != nil {
+			return nil, err
+		}
+		var v int64
+		var ints []int64
+		switch field {
+		case 1:
+			t.Dims, err = d.ints(wire, t.Dims)
+		case 2:
+			v, err = d.int(wire)
+			t.DataType = int(v)
+		case 4:
+			t.Data, err = d.floats(wire, t.Data)
+		case 5, 7:
+			// int32_data 与 int64_data 都以 varint 编码
+			if ints, err = d.ints(wire, nil); err == nil {
+				for _, x := range ints {
+					if field == 5 {
+						x = int64(int32(x))
+					}
+					t.Data = append(t.Data, float64(x))
+				}
+			}
+		case 8:
+			t.Name, err = d.string(wire)
+		case 9:
+			raw, err = d.bytes(wire)
+		case 10:
+			t.Data, err = d.doubles(wire, t.Data)
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析张量 %q: %v", t.Name, err)
+		}
+	}
+	if raw != nil {
+		if err := t.decodeRaw(raw); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := t.shape(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// 按 DataType 解码 raw_data
+func (t *TensorProto) decodeRaw(raw []byte) error {
+	size := map[int]int{DataTypeFloat: 4, DataTypeInt32: 4, DataTypeInt64: 8, DataTypeDouble: 8}[t.DataType]
+	if size == 0 {
+		return fmt.Errorf("张量 %q 的数据类型 %d 不受支持", t.Name, t.DataType)
+	}
+	if len(raw)%size != 0 {
+		return fmt.Errorf("张量 %q 的 raw_data 长度 %d 不是 %d 的倍数", t.Name, len(raw), size)
+	}
+	t.Data = make([]float64, len(raw)/size)
+	for i := range t.Data {
+		b := raw[i*size:]
+		switch t.DataType {
+		case DataTypeFloat:
+			t.Data[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case DataTypeInt32:
+			t.Data[i] = float64(int32(binary.LittleEndian.Uint32(b)))
+		case DataTypeInt64:
+			t.Data[i] = float64(int64(binary.LittleEndian.Uint64(b)))
+		case DataTypeDouble:
+			t.Data[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+	}
+	return nil
+}
+
+func decodeValueInfo(data []byte) (*ValueInfoProto, error) {
+	v := &ValueInfoProto{}
+	// 依次进入 type -> tensor_type -> shape -> dim，逐层只关心需要的字段
+	err := walk(data, func(field, wire int, d *decoder) error {
+		var err error
+		switch field {
+		case 1:
+			v.Name, err = d.string(wire)
+		case 2:
+			var body []byte
+			if body, err = d.bytes(wire); err != nil {
+				return err
+			}
+			err = walk(body, func(field, wire int, d *decoder) error {
+				if field != 1 {
+					return d.skip(wire)
+				}
+				body, err := d.bytes(wire)
+				if err != nil {
+					return err
+				}
+				return walk(body, func(field, wire int, d *decoder) error {
+					switch field {
+					case 1:
+						t, err := d.int(wire)
+						v.ElemType = int(t)
+						return err
+					case 2:
+						body, err := d.bytes(wire)
+						if err != nil {
+							return err
+						}
+						return walk(body, func(field, wire int, d *decoder) error {
+							if field != 1 {
+								return d.skip(wire)
+							}
+							body, err := d.bytes(wire)
+							if err != nil {
+								return err
+							}
+							dim, param := int64(-1), ""
+							if err := walk(body, func(field, wire int, d *decoder) error {
+								var err error
+								switch field {
+								case 1:
+									dim, err = d.int(wire)
+								case 2:
+									param, err = d.string(wire)
+								default:
+									err = d.skip(wire)
+								}
+								return err
+							}); err != nil {
+								return err
+							}
+							v.Dims = append(v.Dims, dim)
+							v.DimParams = append(v.DimParams, param)
+							return nil
+						})
+					}
+					return d.skip(wire)
+				})
+			})
+		default:
+			err = d.skip(wire)
+		}
+		return err
+	})
+	return v, err
+}
+
+// walk 依次对消息中的每个字段调用 fn，fn 负责读取或跳过字段值
+func walk(data []byte, fn func(field, wire int, d *decoder) error) error {
+	d := decoder{data}
+	for !d.done() {
+		field, wire, err := d.next()
+		if err != nil {
+			return err
+		}
+		if err := fn(field, wire, &d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,256 @@
+package onnx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// protobuf 的线路类型
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// encoder 按 protobuf 线路格式追加字段，只实现 ONNX 用到的类型
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field, wire int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(field)<<3|uint64(wire))
+}
+
+// int 写入 varint 字段，零值按 proto3 的约定省略
+func (e *encoder) int(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = binary.AppendUvarint(e.buf, uint64(v))
+}
+
+func (e *encoder) bytes(field int, b []byte) {
+	e.tag(field, wireBytes)
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// string 写入字符串字段，空串省略
+func (e *encoder) string(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.bytes(field, []byte(s))
+}
+
+// strings 写入 repeated string，空串也要保留以维持位置（如 ONNX 中省略的可选输入）
+func (e *encoder) strings(field int, ss []string) {
+	for _, s := range ss {
+		e.bytes(field, []byte(s))
+	}
+}
+
+func (e *encoder) float32(field int, v float32) {
+	e.tag(field, wireFixed32)
+	e.buf = binary.LittleEndian.AppendUint32(e.buf, math.Float32bits(v))
+}
+
+// message 写入嵌套消息，fn 负责编码消息体
+func (e *encoder) message(field int, fn func(e *encoder)) {
+	var sub encoder
+	fn(&sub)
+	e.bytes(field, sub.buf)
+}
+
+// packedInts 以 packed 方式写入 repeated int64
+func (e *encoder) packedInts(field int, vs []int64) {
+	if len(vs) == 0 {
+		return
+	}
+	var body []byte
+	for _, v := range vs {
+		body = binary.AppendUvarint(body, uint64(v))
+	}
+	e.bytes(field, body)
+}
+
+// packedFloats 以 packed 方式写入 repeated float
+func (e *encoder) packedFloats(field int, vs []float32) {
+	if len(vs) == 0 {
+		return
+	}
+	body := make([]byte, 0, 4*len(vs))
+	for _, v := range vs {
+		body = binary.LittleEndian.AppendUint32(body, math.Float32bits(v))
+	}
+	e.bytes(field, body)
+}
+
+// decoder 顺序读取 protobuf 字段
+type decoder struct {
+	buf []byte
+}
+
+var errTruncated = errors.New("protobuf 数据不完整")
+
+func (d *decoder) done() bool {
+	return len(d.buf) == 0
+}
+
+func (d *decoder) uvarint() (uint64, error) {
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		return 0, errTruncated
+	}
+	d.buf = d.buf[n:]
+	return v, nil
+}
+
+// next 读取下一个字段的编号与线路类型
+func (d *decoder) next() (field, wire int, err error) {
+	key, err := d.uvarint()
+	if err != nil {
+		return 0, 0, err
+	}
+	field, wire = int(key>>3), int(key&7)
+	if field == 0 {
+		return 0, 0, errors.New("protobuf 字段编号不能为 0")
+	}
+	return field, wire, nil
+}
+
+func (d *decoder) int(wire int) (int64, error) {
+	if wire != wireVarint {
+		return 0, fmt.Errorf("期望 varint，线路类型为 %d", wire)
+	}
+	v, err := d.uvarint()
+	return int64(v), err
+}
+
+func (d *decoder) bytes(wire int) ([]byte, error) {
+	if wire != wireBytes {
+		return nil, fmt.Errorf("期望长度前缀字段，线路类型为 %d", wire)
+	}
+	n, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.buf)) {
+		return nil, errTruncated
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+func (d *decoder) string(wire int) (string, error) {
+	b, err := d.bytes(wire)
+	return string(b), err
+}
+
+func (d *decoder) fixed32() (uint32, error) {
+	if len(d.buf) < 4 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint32(d.buf)
+	d.buf = d.buf[4:]
+	return v, nil
+}
+
+func (d *decoder) fixed64() (uint64, error) {
+	if len(d.buf) < 8 {
+		return 0, errTruncated
+	}
+	v := binary.LittleEndian.Uint64(d.buf)
+	d.buf = d.buf[8:]
+	return v, nil
+}
+
+func (d *decoder) float32(wire int) (float32, error) {
+	if wire != wireFixed32 {
+		return 0, fmt.Errorf("期望 fixed32，线路类型为 %d", wire)
+	}
+	v, err := d.fixed32()
+	return math.Float32frombits(v), err
+}
+
+// ints 读取 repeated int64 的一个字段，兼容 packed 与非 packed 两种编码
+func (d *decoder) ints(wire int, dst []int64) ([]int64, error) {
+	if wire == wireVarint {
+		v, err := d.uvarint()
+		return append(dst, int64(v)), err
+	}
+	body, err := d.bytes(wire)
+	if err != nil {
+		return nil, err
+	}
+	sub := decoder{body}
+	for !sub.done() {
+		v, err := sub.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		dst = append(dst, int64(v))
+	}
+	return dst, nil
+}
+
+// floats 读取 repeated float 的一个字段，兼容 packed 与非 packed 两种编码
+func (d *decoder) floats(wire int, dst []float64) ([]float64, error) {
+	if wire == wireFixed32 {
+		v, err := d.fixed32()
+		return append(dst, float64(math.Float32frombits(v))), err
+	}
+	body, err := d.bytes(wire)
+	if err != nil {
+		return nil, err
+	}
+	if len(body)%4 != 0 {
+		return nil, errTruncated
+	}
+	for i := 0; i < len(body); i += 4 {
+		dst = append(dst, float64(math.Float32frombits(binary.LittleEndian.Uint32(body[i:]))))
+	}
+	return dst, nil
+}
+
+// doubles 读取 repeated double 的一个字段，兼容 packed 与非 packed 两种编码
+func (d *decoder) doubles(wire int, dst []float64) ([]float64, error) {
+	if wire == wireFixed64 {
+		v, err := d.fixed64()
+		return append(dst, math.Float64frombits(v)), err
+	}
+	body, err := d.bytes(wire)
+	if err != nil {
+		return nil, err
+	}
+	if len(body)%8 != 0 {
+		return nil, errTruncated
+	}
+	for i := 0; i < len(body); i += 8 {
+		dst = append(dst, math.Float64frombits(binary.LittleEndian.Uint64(body[i:])))
+	}
+	return dst, nil
+}
+
+// skip 跳过不关心的字段
+func (d *decoder) skip(wire int) error {
+	var err error
+	switch wire {
+	case wireVarint:
+		_, err = d.uvarint()
+	case wireFixed64:
+		_, err = d.fixed64()
+	case wireBytes:
+		_, err = d.bytes(wire)
+	case wireFixed32:
+		_, err = d.fixed32()
+	default:
+		err = fmt.Errorf("不支持的线路类型 %d", wire)
+	}
+	return err
+}
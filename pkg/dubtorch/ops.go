@@ -0,0 +1,494 @@
+package dubtorch
+
+import (
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// 将广播后的梯度求和归约回输入的形状
+func reduceGrad(g *dubnp.Array, shape []int) (*dubnp.Array, error) {
+	return g.SumTo(shape...)
+}
+
+// Add 逐元素相加，支持广播
+func (a *Tensor) Add(b *Tensor) (*Tensor, error) {
+	data, err := a.Data.Add(b.Data)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("Add", data, []*Tensor{a, b}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		ga, err := reduceGrad(g, a.Data.Shape)
+		if err != nil {
+			return nil, err
+		}
+		gb, err := reduceGrad(g, b.Data.Shape)
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga, gb}, nil
+	}), nil
+}
+
+// Sub 逐元素相减，支持广播
+func (a *Tensor) Sub(b *Tensor) (*Tensor, error) {
+	data, err := a.Data.Sub(b.Data)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("Sub", data, []*Tensor{a, b}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		ga, err := reduceGrad(g, a.Data.Shape)
+		if err != nil {
+			return nil, err
+		}
+		gb, err := reduceGrad(g.Scale(-1), b.Data.Shape)
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga, gb}, nil
+	}), nil
+}
+
+// Mul 逐元素相乘，支持广播
+func (a *Tensor) Mul(b *Tensor) (*Tensor, error) {
+	data, err := a.Data.Mul(b.Data)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("Mul", data, []*Tensor{a, b}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grads := make([]*dubnp.Array, 2)
+		if a.RequiresGrad {
+			ga, err := g.Mul(b.Data)
+			if err != nil {
+				return nil, err
+			}
+			if grads[0], err = reduceGrad(ga, a.Data.Shape); err != nil {
+				return nil, err
+			}
+		}
+		if b.RequiresGrad {
+			gb, err := g.Mul(a.Data)
+			if err != nil {
+				return nil, err
+			}
+			if grads[1], err = reduceGrad(gb, b.Data.Shape); err != nil {
+				return nil, err
+			}
+		}
+		return grads, nil
+	}), nil
+}
+
+// Div 逐元素相除，支持广播
+func (a *Tensor) Div(b *Tensor) (*Tensor, error) {
+	data, err := a.Data.Div(b.Data)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("Div", data, []*Tensor{a, b}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grads := make([]*dubnp.Array, 2)
+		if a.RequiresGrad {
+			ga, err := g.Div(b.Data)
+			if err != nil {
+				return nil, err
+			}
+			if grads[0], err = reduceGrad(ga, a.Data.Shape); err != nil {
+				return nil, err
+			}
+		}
+		if b.RequiresGrad {
+			// d(a/b)/db = -a/b^2 = -out/b
+			gb, err := dubnp.MapN(func(xs []float64) float64 {
+				return -xs[0] * xs[1] / xs[2]
+			}, g, data, b.Data)
+			if err != nil {
+				return nil, err
+			}
+			if grads[1], err = reduceGrad(gb, b.Data.Shape); err != nil {
+				return nil, err
+			}
+		}
+		return grads, nil
+	}), nil
+}
+
+// AddScalar 所有元素加上 s
+func (a *Tensor) AddScalar(s float64) *Tensor {
+	data := a.Data.Map(func(x float64) float64 { return x + s })
+	return newResult("AddScalar", data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		return []*dubnp.Array{g}, nil
+	})
+}
+
+// MulScalar 所有元素乘以 s
+func (a *Tensor) MulScalar(s float64) *Tensor {
+	return newResult("MulScalar", a.Data.Scale(s), []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		return []*dubnp.Array{g.Scale(s)}, nil
+	})
+}
+
+// Neg 取相反数
+func (a *Tensor) Neg() *Tensor {
+	return newResult("Neg", a.Data.Scale(-1), []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		return []*dubnp.Array{g.Scale(-1)}, nil
+	})
+}
+
+// 逐元素一元运算，df 根据输入 x 与输出 y 计算导数
+func (a *Tensor) unary(op string, f func(x float64) float64, df func(x, y float64) float64) *Tensor {
+	data := a.Data.Map(f)
+	return newResult(op, data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		ga, err := dubnp.MapN(func(xs []float64) float64 {
+			return xs[0] * df(xs[1], xs[2])
+		}, g, a.Data, data)
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga}, nil
+	})
+}
+
+// Exp 逐元素求 e^x
+func (a *Tensor) Exp() *Tensor {
+	return a.unary("Exp", math.Exp, func(x, y float64) float64 { return y })
+}
+
+// Log 逐元素求自然对数
+func (a *Tensor) Log() *Tensor {
+	return a.unary("Log", math.Log, func(x, y float64) float64 { return 1 / x })
+}
+
+// Pow 逐元素求 x^p
+func (a *Tensor) Pow(p float64) *Tensor {
+	return a.unary("Pow", func(x float64) float64 { return math.Pow(x, p) }, func(x, y float64) float64 {
+		return p * math.Pow(x, p-1)
+	})
+}
+
+// Sqrt 逐元素求平方根
+func (a *Tensor) Sqrt() *Tensor {
+	return a.unary("Sqrt", math.Sqrt, func(x, y float64) float64 { return 0.5 / y })
+}
+
+// Abs 逐元素求绝对值，0 处的导数取 0
+func (a *Tensor) Abs() *Tensor {
+	return a.unary("Abs", math.Abs, func(x, y float64) float64 {
+		switch {
+		case x > 0:
+			return 1
+		case x < 0:
+			return -1
+		}
+		return 0
+	})
+}
+
+// Tanh 逐元素求双曲正切
+func (a *Tensor) Tanh() *Tensor {
+	return a.unary("Tanh", math.Tanh, func(x, y float64) float64 { return 1 - y*y })
+}
+
+// Sigmoid 逐元素求 1/(1+e^-x)
+func (a *Tensor) Sigmoid() *Tensor {
+	return a.unary("Sigmoid", sigmoid, func(x, y float64) float64 { return y * (1 - y) })
+}
+
+// 数值稳定的 sigmoid
+func sigmoid(x float64) float64 {
+	if x >= 0 {
+		return 1 / (1 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1 + e)
+}
+
+// ReLU 逐元素求 max(x, 0)
+func (a *Tensor) ReLU() *Tensor {
+	return a.unary("ReLU", func(x float64) float64 { return math.Max(x, 0) }, func(x, y float64) float64 {
+		if x > 0 {
+			return 1
+		}
+		return 0
+	})
+}
+
+// Map 逐元素执行 f，df 为 f 的导数，用于自定义可求导的逐元素运算
+func (a *Tensor) Map(f, df func(x float64) float64) *Tensor {
+	return a.unary("Map", f, func(x, y float64) float64 { return df(x) })
+}
+
+// MatMul 二维矩阵乘法
+func (a *Tensor) MatMul(b *Tensor) (*Tensor, error) {
+	data, err := a.Data.Multiply(b.Data)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("MatMul", data, []*Tensor{a, b}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grads := make([]*dubnp.Array, 2)
+		if a.RequiresGrad {
+			// dA = G * B^T
+			bt, err := b.Data.Transpose()
+			if err != nil {
+				return nil, err
+			}
+			if grads[0], err = g.Multiply(bt); err != nil {
+				return nil, err
+			}
+		}
+		if b.RequiresGrad {
+			// dB = A^T * G
+			at, err := a.Data.Transpose()
+			if err != nil {
+				return nil, err
+			}
+			if grads[1], err = at.Multiply(g); err != nil {
+				return nil, err
+			}
+		}
+		return grads, nil
+	}), nil
+}
+
+// BatchMatMul 批量矩阵乘法：(..., M, K) x (..., K, N)，批次维度可广播
+func (a *Tensor) BatchMatMul(b *Tensor) (*Tensor, error) {
+	data, err := a.Data.BatchMultiply(b.Data)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("BatchMatMul", data, []*Tensor{a, b}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grads := make([]*dubnp.Array, 2)
+		if a.RequiresGrad {
+			// dA = G * B^T，再归约掉广播出的批次维度
+			bt, err := b.Data.SwapAxes(-1, -2)
+			if err != nil {
+				return nil, err
+			}
+			ga, err := g.BatchMultiply(bt)
+			if err != nil {
+				return nil, err
+			}
+			if grads[0], err = reduceGrad(ga, a.Data.Shape); err != nil {
+				return nil, err
+			}
+		}
+		if b.RequiresGrad {
+			// dB = A^T * G
+			at, err := a.Data.SwapAxes(-1, -2)
+			if err != nil {
+				return nil, err
+			}
+			gb, err := at.BatchMultiply(g)
+			if err != nil {
+				return nil, err
+			}
+			if grads[1], err = reduceGrad(gb, b.Data.Shape); err != nil {
+				return nil, err
+			}
+		}
+		return grads, nil
+	}), nil
+}
+
+// Transpose 二维矩阵转置
+func (a *Tensor) Transpose() (*Tensor, error) {
+	data, err := a.Data.Transpose()
+	if err != nil {
+		return nil, err
+	}
+	return newResult("Transpose", data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		gt, err := g.Transpose()
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{gt}, nil
+	}), nil
+}
+
+// Reshape 改变形状，至多一个维度可以为 -1
+func (a *Tensor) Reshape(shape ...int) (*Tensor, error) {
+	data, err := a.Data.Reshape(shape...)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("Reshape", data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		ga, err := g.Reshape(a.Data.Shape...)
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga}, nil
+	}), nil
+}
+
+// Sum 求所有元素之和，结果为标量张量
+func (a *Tensor) Sum() (*Tensor, error) {
+	sum, err := a.Data.Sum()
+	if err != nil {
+		return nil, err
+	}
+	data := &dubnp.Array{Data: []float64{sum}, Shape: []int{}}
+	return newResult("Sum", data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		return []*dubnp.Array{dubnp.Full(g.Data[0], a.Data.Shape...)}, nil
+	}), nil
+}
+
+// Mean 求所有元素的平均值，结果为标量张量
+func (a *Tensor) Mean() (*Tensor, error) {
+	sum, err := a.Sum()
+	if err != nil {
+		return nil, err
+	}
+	return sum.MulScalar(1 / float64(a.Size())), nil
+}
+
+// 将沿 axis 归约后的梯度恢复为输入形状
+func expandReduced(g *dubnp.Array, shape []int, axis int) (*dubnp.Array, error) {
+	keep := append([]int(nil), shape...)
+	keep[axis] = 1
+	g, err := g.Reshape(keep...)
+	if err != nil {
+		return nil, err
+	}
+	return g.BroadcastTo(shape...)
+}
+
+// 规范化轴编号，支持负数
+func normalizeAxis(axis, ndim int) int {
+	if axis < 0 {
+		axis += ndim
+	}
+	return axis
+}
+
+// SumAxis 沿 axis 求和
+func (a *Tensor) SumAxis(axis int, keepDims bool) (*Tensor, error) {
+	data, err := a.Data.SumAxis(axis, keepDims)
+	if err != nil {
+		return nil, err
+	}
+	axis = normalizeAxis(axis, len(a.Data.Shape))
+	return newResult("SumAxis", data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		ga, err := expandReduced(g, a.Data.Shape, axis)
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga}, nil
+	}), nil
+}
+
+// MeanAxis 沿 axis 求平均值
+func (a *Tensor) MeanAxis(axis int, keepDims bool) (*Tensor, error) {
+	sum, err := a.SumAxis(axis, keepDims)
+	if err != nil {
+		return nil, err
+	}
+	axis = normalizeAxis(axis, len(a.Data.Shape))
+	return sum.MulScalar(1 / float64(a.Data.Shape[axis])), nil
+}
+
+// 取到极值的位置为 1，其余为 0；最大值/最小值的梯度平均分给这些位置
+func extremumMask(x, extremum *dubnp.Array) (*dubnp.Array, error) {
+	return x.Map2(extremum, func(v, e float64) float64 {
+		if v == e {
+			return 1
+		}
+		return 0
+	})
+}
+
+// 整体最大值/最小值
+func (a *Tensor) extremum(op string, reduce func() (float64, error)) (*Tensor, error) {
+	value, err := reduce()
+	if err != nil {
+		return nil, err
+	}
+	data := &dubnp.Array{Data: []float64{value}, Shape: []int{}}
+	return newResult(op, data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		mask, err := extremumMask(a.Data, data)
+		if err != nil {
+			return nil, err
+		}
+		count, _ := mask.Sum()
+		return []*dubnp.Array{mask.Scale(g.Data[0] / count)}, nil
+	}), nil
+}
+
+// 沿 axis 的最大值/最小值
+func (a *Tensor) extremumAxis(op string, axis int, keepDims bool, reduce func(int, bool) (*dubnp.Array, error)) (*Tensor, error) {
+	data, err := reduce(axis, keepDims)
+	if err != nil {
+		return nil, err
+	}
+	axis = normalizeAxis(axis, len(a.Data.Shape))
+	return newResult(op, data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		kept, err := reduce(axis, true)
+		if err != nil {
+			return nil, err
+		}
+		mask, err := extremumMask(a.Data, kept)
+		if err != nil {
+			return nil, err
+		}
+		count, err := mask.SumAxis(axis, true)
+		if err != nil {
+			return nil, err
+		}
+		keepShape := append([]int(nil), kept.Shape...)
+		gk, err := g.Reshape(keepShape...)
+		if err != nil {
+			return nil, err
+		}
+		share, err := gk.Div(count)
+		if err != nil {
+			return nil, err
+		}
+		ga, err := mask.Mul(share)
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga}, nil
+	}), nil
+}
+
+// Max 求所有元素的最大值
+func (a *Tensor) Max() (*Tensor, error) {
+	return a.extremum("Max", a.Data.Max)
+}
+
+// Min 求所有元素的最小值
+func (a *Tensor) Min() (*Tensor, error) {
+	return a.extremum("Min", a.Data.Min)
+}
+
+// MaxAxis 沿 axis 求最大值
+func (a *Tensor) MaxAxis(axis int, keepDims bool) (*Tensor, error) {
+	return a.extremumAxis("MaxAxis", axis, keepDims, a.Data.MaxAxis)
+}
+
+// MinAxis 沿 axis 求最小值
+func (a *Tensor) MinAxis(axis int, keepDims bool) (*Tensor, error) {
+	return a.extremumAxis("MinAxis", axis, keepDims, a.Data.MinAxis)
+}
+
+// CumSum 沿 axis 求累加和
+func (a *Tensor) CumSum(axis int) (*Tensor, error) {
+	data, err := a.Data.CumSum(axis)
+	if err != nil {
+		return nil, err
+	}
+	return newResult("CumSum", data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		// 累加和的梯度是梯度的反向累加和
+		ga, err := g.Apply(axis, func(lane []float64) []float64 {
+			out := make([]float64, len(lane))
+			acc := 0.0
+			for i := len(lane) - 1; i >= 0; i-- {
+				acc += lane[i]
+				out[i] = acc
+			}
+			return out
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga}, nil
+	}), nil
+}
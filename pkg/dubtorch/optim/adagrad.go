@@ -0,0 +1,58 @@
+package optim
+
+import (
+	"errors"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// AdagradOptions Adagrad 的选项，为 0 的字段使用默认值
+type AdagradOptions struct {
+	LR          float64 // 学习率，默认 0.01
+	Eps         float64 // 分母中的小量，默认 1e-10
+	WeightDecay float64 // L2 权重衰减系数
+}
+
+// Adagrad 按历史平方梯度之和缩放学习率
+type Adagrad struct {
+	base
+	opts AdagradOptions
+}
+
+// NewAdagrad 创建 Adagrad 优化器
+func NewAdagrad(params []dubtorch.NamedTensor, opts AdagradOptions) (*Adagrad, error) {
+	if opts.LR == 0 {
+		opts.LR = 0.01
+	}
+	if opts.Eps == 0 {
+		opts.Eps = 1e-10
+	}
+	if opts.WeightDecay < 0 {
+		return nil, errors.New("WeightDecay 不能为负")
+	}
+	b, err := newBase(params, opts.LR, "sum")
+	if err != nil {
+		return nil, err
+	}
+	return &Adagrad{base: b, opts: opts}, nil
+}
+
+// Step 执行一步更新，没有梯度的参数会被跳过
+func (o *Adagrad) Step() error {
+	for _, p := range o.params {
+		if p.Tensor.Grad == nil {
+			continue
+		}
+		data := p.Tensor.Data.Data
+		g := gradWithDecay(p.Tensor, o.opts.WeightDecay)
+		sum := o.buffer(p.Name, "sum", p.Tensor.Shape()...).Data
+		for i := range data {
+			sum[i] += g[i] * g[i]
+			data[i] -= o.lr * g[i] / (math.Sqrt(sum[i]) + o.opts.Eps)
+		}
+	}
+	return nil
+}
+
+var _ Optimizer = (*Adagrad)(nil)
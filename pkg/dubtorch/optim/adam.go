@@ -0,0 +1,101 @@
+package optim
+
+import (
+	"errors"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// AdamOptions Adam 与 AdamW 的选项，为 0 的字段使用默认值
+type AdamOptions struct {
+	LR          float64 // 学习率，默认 0.001
+	Beta1       float64 // 一阶矩的衰减系数，默认 0.9
+	Beta2       float64 // 二阶矩的衰减系数，默认 0.999
+	Eps         float64 // 分母中的小量，默认 1e-8
+	WeightDecay float64 // Adam 中为 L2 权重衰减，AdamW 中为解耦的权重衰减（默认 0.01）
+}
+
+// Adam 自适应矩估计优化器
+type Adam struct {
+	base
+	opts      AdamOptions
+	decoupled bool // AdamW：权重衰减直接作用于参数而不是梯度
+}
+
+// NewAdam 创建 Adam 优化器
+func NewAdam(params []dubtorch.NamedTensor, opts AdamOptions) (*Adam, error) {
+	return newAdam(params, opts, false)
+}
+
+// NewAdamW 创建 AdamW 优化器，权重衰减与梯度的自适应缩放解耦
+func NewAdamW(params []dubtorch.NamedTensor, opts AdamOptions) (*Adam, error) {
+	if opts.WeightDecay == 0 {
+		opts.WeightDecay = 0.01
+	}
+	return newAdam(params, opts, true)
+}
+
+func newAdam(params []dubtorch.NamedTensor, opts AdamOptions, decoupled bool) (*Adam, error) {
+	if opts.LR == 0 {
+		opts.LR = 1e-3
+	}
+	if opts.Beta1 == 0 {
+		opts.Beta1 = 0.9
+	}
+	if opts.Beta2 == 0 {
+		opts.Beta2 = 0.999
+	}
+	if opts.Eps == 0 {
+		opts.Eps = 1e-8
+	}
+	if opts.Beta1 < 0 || opts.Beta1 >= 1 || opts.Beta2 < 0 || opts.Beta2 >= 1 {
+		return nil, errors.New("Beta1 与 Beta2 必须在 [0, 1) 之间")
+	}
+	if opts.WeightDecay < 0 {
+		return nil, errors.New("WeightDecay 不能为负")
+	}
+	b, err := newBase(params, opts.LR, "step", "exp_avg", "exp_avg_sq")
+	if err != nil {
+		return nil, err
+	}
+	return &Adam{base: b, opts: opts, decoupled: decoupled}, nil
+}
+
+// Step 执行一步更新，没有梯度的参数会被跳过
+func (o *Adam) Step() error {
+	for _, p := range o.params {
+		if p.Tensor.Grad == nil {
+			continue
+		}
+		data := p.Tensor.Data.Data
+		var g []float64
+		if o.decoupled {
+			g = p.Tensor.Grad.Data
+			for i := range data {
+				data[i] *= 1 - o.lr*o.opts.WeightDecay
+			}
+		} else {
+			g = gradWithDecay(p.Tensor, o.opts.WeightDecay)
+		}
+
+		// 步数按参数记录，未参与某次更新的参数的偏差修正不受影响
+		step := o.buffer(p.Name, "step")
+		step.Data[0]++
+		t := step.Data[0]
+		m := o.buffer(p.Name, "exp_avg", p.Tensor.Shape()...).Data
+		v := o.buffer(p.Name, "exp_avg_sq", p.Tensor.Shape()...).Data
+		b1, b2 := o.opts.Beta1, o.opts.Beta2
+		correction1 := 1 - math.Pow(b1, t)
+		correction2 := 1 - math.Pow(b2, t)
+		for i := range data {
+			m[i] = b1*m[i] + (1-b1)*g[i]
+			v[i] = b2*v[i] + (1-b2)*g[i]*g[i]
+			denom := math.Sqrt(v[i]/correction2) + o.opts.Eps
+			data[i] -= o.lr * (m[i] / correction1) / denom
+		}
+	}
+	return nil
+}
+
+var _ Optimizer = (*Adam)(nil)
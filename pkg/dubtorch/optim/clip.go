@@ -0,0 +1,45 @@
+package optim
+
+import (
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// ClipGradNorm 当所有梯度拼接后的 L2 范数超过 maxNorm 时按比例缩小梯度，返回裁剪前的范数
+func ClipGradNorm(params []*dubtorch.Tensor, maxNorm float64) float64 {
+	sq := 0.0
+	for _, p := range params {
+		if p.Grad == nil {
+			continue
+		}
+		for _, g := range p.Grad.Data {
+			sq += g * g
+		}
+	}
+	norm := math.Sqrt(sq)
+	if norm > maxNorm {
+		scale := maxNorm / (norm + 1e-6)
+		for _, p := range params {
+			if p.Grad == nil {
+				continue
+			}
+			for i := range p.Grad.Data {
+				p.Grad.Data[i] *= scale
+			}
+		}
+	}
+	return norm
+}
+
+// ClipGradValue 将每个梯度元素截断到 [-clip, clip]
+func ClipGradValue(params []*dubtorch.Tensor, clip float64) {
+	for _, p := range params {
+		if p.Grad == nil {
+			continue
+		}
+		for i, g := range p.Grad.Data {
+			p.Grad.Data[i] = math.Max(-clip, math.Min(clip, g))
+		}
+	}
+}
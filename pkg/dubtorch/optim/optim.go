@@ -0,0 +1,166 @@
+// Package optim 提供基于 dubtorch 参数的优化器、学习率调度器与梯度裁剪
+package optim
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// Optimizer 优化器，根据参数的梯度原地更新参数
+//
+// StateDict 返回的状态以 "lr" 和 "参数名.状态名" 为键，标量保存为 0 维数组，
+// 可直接用 encoding/gob 编码后保存，用于断点续训
+type Optimizer interface {
+	Step() error
+	ZeroGrad()
+	LR() float64
+	SetLR(lr float64)
+	StateDict() map[string]*dubnp.Array
+	LoadStateDict(state map[string]*dubnp.Array) error
+}
+
+// 各优化器共用的参数、学习率与逐参数状态
+type base struct {
+	params      []dubtorch.NamedTensor
+	lr          float64
+	bufferNames []string                           // 每个参数的状态名，决定 StateDict 的键
+	state       map[string]map[string]*dubnp.Array // 参数名 -> 状态名 -> 状态
+}
+
+func newBase(params []dubtorch.NamedTensor, lr float64, bufferNames ...string) (base, error) {
+	if lr <= 0 {
+		return base{}, fmt.Errorf("学习率必须为正数: %v", lr)
+	}
+	if len(params) == 0 {
+		return base{}, errors.New("没有需要优化的参数")
+	}
+	seen := make(map[string]bool, len(params))
+	for _, p := range params {
+		if seen[p.Name] {
+			return base{}, fmt.Errorf("参数名重复: %s", p.Name)
+		}
+		seen[p.Name] = true
+	}
+	return base{
+		params:      params,
+		lr:          lr,
+		bufferNames: bufferNames,
+		state:       make(map[string]map[string]*dubnp.Array),
+	}, nil
+}
+
+// LR 返回当前学习率
+func (b *base) LR() float64 {
+	return b.lr
+}
+
+// SetLR 设置学习率，供学习率调度器调用
+func (b *base) SetLR(lr float64) {
+	b.lr = lr
+}
+
+// ZeroGrad 清空所有参数的梯度
+func (b *base) ZeroGrad() {
+	for _, p := range b.params {
+		p.Tensor.ZeroGrad()
+	}
+}
+
+// 返回参数的某个状态，不存在时创建为指定形状的零数组
+func (b *base) buffer(param, name string, shape ...int) *dubnp.Array {
+	s, ok := b.state[param]
+	if !ok {
+		s = make(map[string]*dubnp.Array)
+		b.state[param] = s
+	}
+	a, ok := s[name]
+	if !ok {
+		a = dubnp.Zeros(shape...)
+		s[name] = a
+	}
+	return a
+}
+
+// 返回加上 L2 权重衰减后的梯度，不修改参数的 Grad
+func gradWithDecay(p *dubtorch.Tensor, weightDecay float64) []float64 {
+	if weightDecay == 0 {
+		return p.Grad.Data
+	}
+	g := make([]float64, len(p.Grad.Data))
+	for i, v := range p.Grad.Data {
+		g[i] = v + weightDecay*p.Data.Data[i]
+	}
+	return g
+}
+
+// StateDict 返回学习率与各参数状态的副本
+func (b *base) StateDict() map[string]*dubnp.Array {
+	state := map[string]*dubnp.Array{"lr": dubnp.Full(b.lr)}
+	for _, p := range b.params {
+		for _, name := range b.bufferNames {
+			if a, ok := b.state[p.Name][name]; ok {
+				state[p.Name+"."+name] = a.Copy()
+			}
+		}
+	}
+	return state
+}
+
+// LoadStateDict 恢复学习率与各参数状态，未知的键或形状不一致时返回错误
+func (b *base) LoadStateDict(state map[string]*dubnp.Array) error {
+	// 参数名可能包含 "."，因此按已知的键反查参数与状态名
+	type target struct {
+		param *dubtorch.Tensor
+		name  string
+	}
+	known := make(map[string]target)
+	for _, p := range b.params {
+		for _, name := range b.bufferNames {
+			known[p.Name+"."+name] = target{p.Tensor, name}
+		}
+	}
+
+	loaded := make(map[string]map[string]*dubnp.Array)
+	lr := b.lr
+	for key, a := range state {
+		if key == "lr" {
+			if len(a.Data) != 1 {
+				return fmt.Errorf("lr 应为标量，实际形状为 %v", a.Shape)
+			}
+			lr = a.Data[0]
+			continue
+		}
+		t, ok := known[key]
+		if !ok {
+			return fmt.Errorf("未知的优化器状态: %s", key)
+		}
+		// 逐参数的步数为标量，其余状态与参数形状一致
+		if (t.name == "step" && len(a.Data) != 1) || (t.name != "step" && !sameShape(a.Shape, t.param.Shape())) {
+			return fmt.Errorf("状态 %s 的形状 %v 与参数形状 %v 不一致", key, a.Shape, t.param.Shape())
+		}
+		param := key[:len(key)-len(t.name)-1]
+		if loaded[param] == nil {
+			loaded[param] = make(map[string]*dubnp.Array)
+		}
+		loaded[param][t.name] = a.Copy()
+	}
+
+	b.lr = lr
+	b.state = loaded
+	return nil
+}
+
+func sameShape(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
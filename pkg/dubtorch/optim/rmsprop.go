@@ -0,0 +1,72 @@
+package optim
+
+import (
+	"errors"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// RMSPropOptions RMSProp 的选项，为 0 的字段使用默认值
+type RMSPropOptions struct {
+	LR          float64 // 学习率，默认 0.01
+	Alpha       float64 // 平方梯度的衰减系数，默认 0.99
+	Eps         float64 // 分母中的小量，默认 1e-8
+	Momentum    float64 // 动量系数，为 0 时不使用动量
+	WeightDecay float64 // L2 权重衰减系数
+}
+
+// RMSProp 按平方梯度的滑动平均缩放学习率
+type RMSProp struct {
+	base
+	opts RMSPropOptions
+}
+
+// NewRMSProp 创建 RMSProp 优化器
+func NewRMSProp(params []dubtorch.NamedTensor, opts RMSPropOptions) (*RMSProp, error) {
+	if opts.LR == 0 {
+		opts.LR = 0.01
+	}
+	if opts.Alpha == 0 {
+		opts.Alpha = 0.99
+	}
+	if opts.Eps == 0 {
+		opts.Eps = 1e-8
+	}
+	if opts.Alpha < 0 || opts.Alpha >= 1 || opts.Momentum < 0 || opts.WeightDecay < 0 {
+		return nil, errors.New("Alpha 必须在 [0, 1) 之间，Momentum 与 WeightDecay 不能为负")
+	}
+	b, err := newBase(params, opts.LR, "square_avg", "momentum_buffer")
+	if err != nil {
+		return nil, err
+	}
+	return &RMSProp{base: b, opts: opts}, nil
+}
+
+// Step 执行一步更新，没有梯度的参数会被跳过
+func (o *RMSProp) Step() error {
+	for _, p := range o.params {
+		if p.Tensor.Grad == nil {
+			continue
+		}
+		data := p.Tensor.Data.Data
+		g := gradWithDecay(p.Tensor, o.opts.WeightDecay)
+		sq := o.buffer(p.Name, "square_avg", p.Tensor.Shape()...).Data
+		var buf []float64
+		if o.opts.Momentum > 0 {
+			buf = o.buffer(p.Name, "momentum_buffer", p.Tensor.Shape()...).Data
+		}
+		for i := range data {
+			sq[i] = o.opts.Alpha*sq[i] + (1-o.opts.Alpha)*g[i]*g[i]
+			update := g[i] / (math.Sqrt(sq[i]) + o.opts.Eps)
+			if buf != nil {
+				buf[i] = o.opts.Momentum*buf[i] + update
+				update = buf[i]
+			}
+			data[i] -= o.lr * update
+		}
+	}
+	return nil
+}
+
+var _ Optimizer = (*RMSProp)(nil)
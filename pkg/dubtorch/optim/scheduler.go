@@ -0,0 +1,223 @@
+package optim
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Scheduler 学习率调度器，每轮（或每步）调用一次 Step
+type Scheduler interface {
+	Step()
+	LastEpoch() int
+	StateDict() map[string]*dubnp.Array
+	LoadStateDict(state map[string]*dubnp.Array) error
+}
+
+// LambdaLR 学习率为初始学习率乘以 Factor(epoch)
+type LambdaLR struct {
+	opt    Optimizer
+	baseLR float64
+	epoch  int
+	factor func(epoch int) float64
+}
+
+// NewLambdaLR 创建按自定义系数调整学习率的调度器，创建时立即应用 factor(0)
+func NewLambdaLR(opt Optimizer, factor func(epoch int) float64) *LambdaLR {
+	s := &LambdaLR{opt: opt, baseLR: opt.LR(), factor: factor}
+	opt.SetLR(s.baseLR * factor(0))
+	return s
+}
+
+// NewStepLR 每 stepSize 轮将学习率乘以 gamma
+func NewStepLR(opt Optimizer, stepSize int, gamma float64) (*LambdaLR, error) {
+	if stepSize <= 0 {
+		return nil, fmt.Errorf("stepSize 必须为正数: %d", stepSize)
+	}
+	return NewLambdaLR(opt, func(epoch int) float64 {
+		return math.Pow(gamma, float64(epoch/stepSize))
+	}), nil
+}
+
+// NewCosineAnnealingLR 在 tMax 轮内按余弦曲线将学习率从初始值降到 etaMin，之后保持 etaMin
+func NewCosineAnnealingLR(opt Optimizer, tMax int, etaMin float64) (*LambdaLR, error) {
+	if tMax <= 0 {
+		return nil, fmt.Errorf("tMax 必须为正数: %d", tMax)
+	}
+	// 系数依赖初始学习率，从调度器读取，LoadStateDict 恢复的 base_lr 同样生效
+	s := &LambdaLR{opt: opt, baseLR: opt.LR()}
+	s.factor = func(epoch int) float64 {
+		progress := math.Min(float64(epoch)/float64(tMax), 1)
+		lr := etaMin + (s.baseLR-etaMin)*(1+math.Cos(math.Pi*progress))/2
+		return lr / s.baseLR
+	}
+	opt.SetLR(s.baseLR * s.factor(0))
+	return s, nil
+}
+
+// NewLinearWarmupLR 在 warmupSteps 步内将学习率从 startFactor 倍线性增加到初始值，之后保持不变
+func NewLinearWarmupLR(opt Optimizer, warmupSteps int, startFactor float64) (*LambdaLR, error) {
+	if warmupSteps <= 0 || startFactor < 0 || startFactor > 1 {
+		return nil, errors.New("warmupSteps 必须为正数，startFactor 必须在 [0, 1] 之间")
+	}
+	return NewLambdaLR(opt, func(epoch int) float64 {
+		if epoch >= warmupSteps {
+			return 1
+		}
+		return startFactor + (1-startFactor)*float64(epoch)/float64(warmupSteps)
+	}), nil
+}
+
+// Step 进入下一轮并更新学习率
+func (s *LambdaLR) Step() {
+	s.epoch++
+	s.opt.SetLR(s.baseLR * s.factor(s.epoch))
+}
+
+// LastEpoch 返回已经执行 Step 的次数
+func (s *LambdaLR) LastEpoch() int {
+	return s.epoch
+}
+
+// StateDict 返回轮次与初始学习率
+func (s *LambdaLR) StateDict() map[string]*dubnp.Array {
+	return map[string]*dubnp.Array{
+		"last_epoch": dubnp.Full(float64(s.epoch)),
+		"base_lr":    dubnp.Full(s.baseLR),
+	}
+}
+
+// LoadStateDict 恢复轮次与初始学习率，并将优化器的学习率设为对应轮次的值
+func (s *LambdaLR) LoadStateDict(state map[string]*dubnp.Array) error {
+	values, err := scalarState(state, "last_epoch", "base_lr")
+	if err != nil {
+		return err
+	}
+	s.epoch, s.baseLR = int(values[0]), values[1]
+	s.opt.SetLR(s.baseLR * s.factor(s.epoch))
+	return nil
+}
+
+// 按顺序读取若干标量状态，缺少或多出键时返回错误
+func scalarState(state map[string]*dubnp.Array, keys ...string) ([]float64, error) {
+	if len(state) != len(keys) {
+		return nil, fmt.Errorf("调度器状态应包含 %v，实际有 %d 个键", keys, len(state))
+	}
+	values := make([]float64, len(keys))
+	for i, key := range keys {
+		a, ok := state[key]
+		if !ok || len(a.Data) != 1 {
+			return nil, fmt.Errorf("调度器状态缺少标量 %s", key)
+		}
+		values[i] = a.Data[0]
+	}
+	return values, nil
+}
+
+// PlateauOptions ReduceLROnPlateau 的选项
+type PlateauOptions struct {
+	Maximize  bool    // 指标越大越好（例如准确率），默认越小越好（例如损失）
+	Factor    float64 // 学习率的衰减系数，默认 0.1
+	Patience  int     // 指标连续多少轮没有改善后衰减学习率
+	Threshold float64 // 相对改善的阈值，默认 1e-4
+	Cooldown  int     // 衰减后暂停计数的轮数
+	MinLR     float64 // 学习率下限
+}
+
+// ReduceLROnPlateau 在指标停止改善时衰减学习率，每轮先用 Observe 记录指标再调用 Step
+type ReduceLROnPlateau struct {
+	opt      Optimizer
+	opts     PlateauOptions
+	metric   float64 // Observe 记录、尚未被 Step 使用的指标，没有时为 NaN
+	best     float64
+	numBad   int
+	cooldown int
+	epoch    int
+}
+
+// NewReduceLROnPlateau 创建按指标衰减学习率的调度器
+func NewReduceLROnPlateau(opt Optimizer, opts PlateauOptions) (*ReduceLROnPlateau, error) {
+	if opts.Factor == 0 {
+		opts.Factor = 0.1
+	}
+	if opts.Threshold == 0 {
+		opts.Threshold = 1e-4
+	}
+	if opts.Factor < 0 || opts.Factor >= 1 || opts.Patience < 0 || opts.Cooldown < 0 {
+		return nil, errors.New("Factor 必须在 (0, 1) 之间，Patience 与 Cooldown 不能为负")
+	}
+	s := &ReduceLROnPlateau{opt: opt, opts: opts, metric: math.NaN(), best: math.Inf(1)}
+	if opts.Maximize {
+		s.best = math.Inf(-1)
+	}
+	return s, nil
+}
+
+// Observe 记录本轮的指标，由随后的 Step 使用
+func (s *ReduceLROnPlateau) Observe(metric float64) {
+	s.metric = metric
+}
+
+// Step 按 Observe 记录的指标进入下一轮，必要时衰减学习率；本轮没有记录指标时视为没有改善
+func (s *ReduceLROnPlateau) Step() {
+	metric := s.metric
+	s.metric = math.NaN()
+	s.epoch++
+	if s.improved(metric) {
+		s.best = metric
+		s.numBad = 0
+	} else {
+		s.numBad++
+	}
+	if s.cooldown > 0 {
+		s.cooldown--
+		s.numBad = 0
+	}
+	if s.numBad > s.opts.Patience {
+		s.opt.SetLR(math.Max(s.opt.LR()*s.opts.Factor, s.opts.MinLR))
+		s.cooldown = s.opts.Cooldown
+		s.numBad = 0
+	}
+}
+
+func (s *ReduceLROnPlateau) improved(metric float64) bool {
+	if math.IsNaN(metric) {
+		return false
+	}
+	if s.opts.Maximize {
+		return metric > s.best*(1+s.opts.Threshold) || math.IsInf(s.best, -1)
+	}
+	return metric < s.best*(1-s.opts.Threshold) || math.IsInf(s.best, 1)
+}
+
+// LastEpoch 返回已经执行 Step 的次数
+func (s *ReduceLROnPlateau) LastEpoch() int {
+	return s.epoch
+}
+
+// StateDict 返回最佳指标与计数器
+func (s *ReduceLROnPlateau) StateDict() map[string]*dubnp.Array {
+	return map[string]*dubnp.Array{
+		"last_epoch":       dubnp.Full(float64(s.epoch)),
+		"best":             dubnp.Full(s.best),
+		"num_bad_epochs":   dubnp.Full(float64(s.numBad)),
+		"cooldown_counter": dubnp.Full(float64(s.cooldown)),
+	}
+}
+
+// LoadStateDict 恢复最佳指标与计数器
+func (s *ReduceLROnPlateau) LoadStateDict(state map[string]*dubnp.Array) error {
+	values, err := scalarState(state, "last_epoch", "best", "num_bad_epochs", "cooldown_counter")
+	if err != nil {
+		return err
+	}
+	s.epoch, s.best, s.numBad, s.cooldown = int(values[0]), values[1], int(values[2]), int(values[3])
+	return nil
+}
+
+var (
+	_ Scheduler = (*LambdaLR)(nil)
+	_ Scheduler = (*ReduceLROnPlateau)(nil)
+)
@@ -0,0 +1,76 @@
+package optim
+
+import (
+	"errors"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// SGDOptions 随机梯度下降的选项
+type SGDOptions struct {
+	LR          float64 // 学习率，默认 0.01
+	Momentum    float64 // 动量系数，为 0 时不使用动量
+	Dampening   float64 // 动量的阻尼系数
+	Nesterov    bool    // 使用 Nesterov 动量，要求 Momentum > 0 且 Dampening = 0
+	WeightDecay float64 // L2 权重衰减系数
+}
+
+// SGD 随机梯度下降，支持动量、Nesterov 动量与权重衰减
+type SGD struct {
+	base
+	opts SGDOptions
+}
+
+// NewSGD 创建 SGD 优化器
+func NewSGD(params []dubtorch.NamedTensor, opts SGDOptions) (*SGD, error) {
+	if opts.LR == 0 {
+		opts.LR = 0.01
+	}
+	if opts.Momentum < 0 || opts.WeightDecay < 0 {
+		return nil, errors.New("Momentum 与 WeightDecay 不能为负")
+	}
+	if opts.Nesterov && (opts.Momentum <= 0 || opts.Dampening != 0) {
+		return nil, errors.New("Nesterov 动量要求 Momentum > 0 且 Dampening = 0")
+	}
+	b, err := newBase(params, opts.LR, "momentum_buffer")
+	if err != nil {
+		return nil, err
+	}
+	return &SGD{base: b, opts: opts}, nil
+}
+
+// Step 执行一步更新，没有梯度的参数会被跳过
+func (o *SGD) Step() error {
+	for _, p := range o.params {
+		if p.Tensor.Grad == nil {
+			continue
+		}
+		data := p.Tensor.Data.Data
+		g := gradWithDecay(p.Tensor, o.opts.WeightDecay)
+		if o.opts.Momentum == 0 {
+			for i := range data {
+				data[i] -= o.lr * g[i]
+			}
+			continue
+		}
+
+		// 第一次更新时动量直接取梯度
+		_, started := o.state[p.Name]["momentum_buffer"]
+		buf := o.buffer(p.Name, "momentum_buffer", p.Tensor.Shape()...).Data
+		for i := range data {
+			if started {
+				buf[i] = o.opts.Momentum*buf[i] + (1-o.opts.Dampening)*g[i]
+			} else {
+				buf[i] = g[i]
+			}
+			step := buf[i]
+			if o.opts.Nesterov {
+				step = g[i] + o.opts.Momentum*buf[i]
+			}
+			data[i] -= o.lr * step
+		}
+	}
+	return nil
+}
+
+var _ Optimizer = (*SGD)(nil)
@@ -0,0 +1,308 @@
+package dubtorch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// PoolOptions 池化选项，各字段按空间维度给出，只给一个值时用于所有维度
+type PoolOptions struct {
+	KernelSize []int // 窗口大小
+	Stride     []int // 步长，默认等于 KernelSize
+	Padding    []int // 两侧填充的个数，默认 0，不能超过窗口的一半
+}
+
+// 二维池化的几何参数，一维池化视为高为 1 的二维池化
+type poolGeom struct {
+	h, w, kh, kw, sh, sw, ph, pw, oh, ow int
+}
+
+func (o PoolOptions) resolve(dims int, shape []int) (poolGeom, error) {
+	if len(shape) != dims+2 {
+		return poolGeom{}, fmt.Errorf("%d 维池化的输入应为 %d 维，实际形状为 %v", dims, dims+2, shape)
+	}
+	kernel, err := expandSpatial("KernelSize", o.KernelSize, dims, 0)
+	if err != nil {
+		return poolGeom{}, err
+	}
+	stride, err := expandSpatial("Stride", o.Stride, dims, 0)
+	if err != nil {
+		return poolGeom{}, err
+	}
+	padding, err := expandSpatial("Padding", o.Padding, dims, 0)
+	if err != nil {
+		return poolGeom{}, err
+	}
+	// 一维时在前面补上高度方向的参数
+	size := shape[2:]
+	if dims == 1 {
+		kernel, stride, padding, size = append([]int{1}, kernel...), append([]int{1}, stride...), append([]int{0}, padding...), append([]int{1}, size...)
+	}
+	for i := range kernel {
+		if stride[i] == 0 {
+			stride[i] = kernel[i]
+		}
+		if kernel[i] <= 0 || stride[i] <= 0 || padding[i] < 0 || 2*padding[i] > kernel[i] {
+			return poolGeom{}, fmt.Errorf("池化参数无效: %+v", o)
+		}
+	}
+	g := poolGeom{
+		h: size[0], w: size[1], kh: kernel[0], kw: kernel[1],
+		sh: stride[0], sw: stride[1], ph: padding[0], pw: padding[1],
+	}
+	g.oh = (g.h+2*g.ph-g.kh)/g.sh + 1
+	g.ow = (g.w+2*g.pw-g.kw)/g.sw + 1
+	if g.oh <= 0 || g.ow <= 0 {
+		return poolGeom{}, fmt.Errorf("输入尺寸 %v 小于池化窗口 %v", shape[2:], o.KernelSize)
+	}
+	return g, nil
+}
+
+// 池化输出形状：保留 (N, C)，空间维度替换为输出尺寸
+func poolOutShape(shape []int, oh, ow int) []int {
+	if len(shape) == 3 {
+		return []int{shape[0], shape[1], ow}
+	}
+	return []int{shape[0], shape[1], oh, ow}
+}
+
+// 返回输出位置 (oi, oj) 的窗口与输入的交集 [i0, i1) × [j0, j1)
+func (g *poolGeom) window(oi, oj int) (i0, i1, j0, j1 int) {
+	i0, j0 = oi*g.sh-g.ph, oj*g.sw-g.pw
+	i1, j1 = min(i0+g.kh, g.h), min(j0+g.kw, g.w)
+	return max(i0, 0), i1, max(j0, 0), j1
+}
+
+func maxPool(dims int, x *Tensor, opts PoolOptions) (*Tensor, error) {
+	g, err := opts.resolve(dims, x.Shape())
+	if err != nil {
+		return nil, err
+	}
+	planes, inP, outP := x.Size()/(g.h*g.w), g.h*g.w, g.oh*g.ow
+	xd := x.Data.Data
+	out := dubnp.Zeros(poolOutShape(x.Shape(), g.oh, g.ow)...)
+	argmax := make([]int, len(out.Data))
+	parallelFor(planes, func(p int) {
+		src := xd[p*inP:][:inP]
+		for oi := 0; oi < g.oh; oi++ {
+			for oj := 0; oj < g.ow; oj++ {
+				i0, i1, j0, j1 := g.window(oi, oj)
+				best, arg := math.Inf(-1), i0*g.w+j0
+				for i := i0; i < i1; i++ {
+					for j := j0; j < j1; j++ {
+						if v := src[i*g.w+j]; v > best || math.IsNaN(v) {
+							best, arg = v, i*g.w+j
+						}
+					}
+				}
+				k := p*outP + oi*g.ow + oj
+				out.Data[k], argmax[k] = best, p*inP+arg
+			}
+		}
+	})
+	return newResult("MaxPool", out, []*Tensor{x}, func(grad *dubnp.Array) ([]*dubnp.Array, error) {
+		gx := dubnp.Zeros(x.Shape()...)
+		for k, idx := range argmax {
+			gx.Data[idx] += grad.Data[k]
+		}
+		return []*dubnp.Array{gx}, nil
+	}), nil
+}
+
+func avgPool(dims int, x *Tensor, opts PoolOptions) (*Tensor, error) {
+	g, err := opts.resolve(dims, x.Shape())
+	if err != nil {
+		return nil, err
+	}
+	planes, inP, outP := x.Size()/(g.h*g.w), g.h*g.w, g.oh*g.ow
+	// 填充位置计入窗口大小，与 PyTorch 默认的 count_include_pad 一致
+	scale := 1 / float64(g.kh*g.kw)
+	xd := x.Data.Data
+	out := dubnp.Zeros(poolOutShape(x.Shape(), g.oh, g.ow)...)
+	parallelFor(planes, func(p int) {
+		src := xd[p*inP:][:inP]
+		for oi := 0; oi < g.oh; oi++ {
+			for oj := 0; oj < g.ow; oj++ {
+				i0, i1, j0, j1 := g.window(oi, oj)
+				sum := 0.0
+				for i := i0; i < i1; i++ {
+					for j := j0; j < j1; j++ {
+						sum += src[i*g.w+j]
+					}
+				}
+				out.Data[p*outP+oi*g.ow+oj] = sum * scale
+			}
+		}
+	})
+	return newResult("AvgPool", out, []*Tensor{x}, func(grad *dubnp.Array) ([]*dubnp.Array, error) {
+		gx := dubnp.Zeros(x.Shape()...)
+		parallelFor(planes, func(p int) {
+			dst := gx.Data[p*inP:][:inP]
+			for oi := 0; oi < g.oh; oi++ {
+				for oj := 0; oj < g.ow; oj++ {
+					i0, i1, j0, j1 := g.window(oi, oj)
+					v := grad.Data[p*outP+oi*g.ow+oj] * scale
+					for i := i0; i < i1; i++ {
+						for j := j0; j < j1; j++ {
+							dst[i*g.w+j] += v
+						}
+					}
+				}
+			}
+		})
+		return []*dubnp.Array{gx}, nil
+	}), nil
+}
+
+func adaptiveAvgPool(dims int, x *Tensor, outputSize []int) (*Tensor, error) {
+	shape := x.Shape()
+	if len(shape) != dims+2 {
+		return nil, fmt.Errorf("%d 维池化的输入应为 %d 维，实际形状为 %v", dims, dims+2, shape)
+	}
+	size, err := expandSpatial("OutputSize", outputSize, dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	in := shape[2:]
+	if dims == 1 {
+		size, in = []int{1, size[0]}, []int{1, in[0]}
+	}
+	h, w, oh, ow := in[0], in[1], size[0], size[1]
+	if oh <= 0 || ow <= 0 {
+		return nil, fmt.Errorf("输出尺寸必须为正数: %v", outputSize)
+	}
+	// 第 o 个输出覆盖输入的 [floor(o*n/on), ceil((o+1)*n/on))
+	bounds := func(o, n, on int) (int, int) {
+		return o * n / on, ((o+1)*n + on - 1) / on
+	}
+	planes, inP, outP := x.Size()/(h*w), h*w, oh*ow
+	xd := x.Data.Data
+	out := dubnp.Zeros(poolOutShape(shape, oh, ow)...)
+	parallelFor(planes, func(p int) {
+		src := xd[p*inP:][:inP]
+		for oi := 0; oi < oh; oi++ {
+			i0, i1 := bounds(oi, h, oh)
+			for oj := 0; oj < ow; oj++ {
+				j0, j1 := bounds(oj, w, ow)
+				sum := 0.0
+				for i := i0; i < i1; i++ {
+					for j := j0; j < j1; j++ {
+						sum += src[i*w+j]
+					}
+				}
+				out.Data[p*outP+oi*ow+oj] = sum / float64((i1-i0)*(j1-j0))
+			}
+		}
+	})
+	return newResult("AdaptiveAvgPool", out, []*Tensor{x}, func(grad *dubnp.Array) ([]*dubnp.Array, error) {
+		gx := dubnp.Zeros(shape...)
+		parallelFor(planes, func(p int) {
+			dst := gx.Data[p*inP:][:inP]
+			for oi := 0; oi < oh; oi++ {
+				i0, i1 := bounds(oi, h, oh)
+				for oj := 0; oj < ow; oj++ {
+					j0, j1 := bounds(oj, w, ow)
+					v := grad.Data[p*outP+oi*ow+oj] / float64((i1-i0)*(j1-j0))
+					for i := i0; i < i1; i++ {
+						for j := j0; j < j1; j++ {
+							dst[i*w+j] += v
+						}
+					}
+				}
+			}
+		})
+		return []*dubnp.Array{gx}, nil
+	}), nil
+}
+
+// MaxPool1d 一维最大池化，x 为 (N, C, L)
+func MaxPool1d(x *Tensor, opts PoolOptions) (*Tensor, error) { return maxPool(1, x, opts) }
+
+// MaxPool2d 二维最大池化，x 为 (N, C, H, W)
+func MaxPool2d(x *Tensor, opts PoolOptions) (*Tensor, error) { return maxPool(2, x, opts) }
+
+// AvgPool1d 一维平均池化，x 为 (N, C, L)
+func AvgPool1d(x *Tensor, opts PoolOptions) (*Tensor, error) { return avgPool(1, x, opts) }
+
+// AvgPool2d 二维平均池化，x 为 (N, C, H, W)
+func AvgPool2d(x *Tensor, opts PoolOptions) (*Tensor, error) { return avgPool(2, x, opts) }
+
+// AdaptiveAvgPool1d 一维自适应平均池化，输出长度为 outputSize
+func AdaptiveAvgPool1d(x *Tensor, outputSize int) (*Tensor, error) {
+	return adaptiveAvgPool(1, x, []int{outputSize})
+}
+
+// AdaptiveAvgPool2d 二维自适应平均池化，outputSize 为一个或两个值
+func AdaptiveAvgPool2d(x *Tensor, outputSize []int) (*Tensor, error) {
+	return adaptiveAvgPool(2, x, outputSize)
+}
+
+// MaxPool 最大池化层，Dims 为 1 或 2
+type MaxPool struct {
+	BaseModule
+	Dims    int
+	Options PoolOptions
+}
+
+// NewMaxPool1d 创建一维最大池化层
+func NewMaxPool1d(opts PoolOptions) *MaxPool { return &MaxPool{Dims: 1, Options: opts} }
+
+// NewMaxPool2d 创建二维最大池化层
+func NewMaxPool2d(opts PoolOptions) *MaxPool { return &MaxPool{Dims: 2, Options: opts} }
+
+// Forward 执行最大池化
+func (m *MaxPool) Forward(x *Tensor) (*Tensor, error) { return maxPool(m.Dims, x, m.Options) }
+
+// AvgPool 平均池化层，Dims 为 1 或 2
+type AvgPool struct {
+	BaseModule
+	Dims    int
+	Options PoolOptions
+}
+
+// NewAvgPool1d 创建一维平均池化层
+func NewAvgPool1d(opts PoolOptions) *AvgPool { return &AvgPool{Dims: 1, Options: opts} }
+
+// NewAvgPool2d 创建二维平均池化层
+func NewAvgPool2d(opts PoolOptions) *AvgPool { return &AvgPool{Dims: 2, Options: opts} }
+
+// Forward 执行平均池化
+func (m *AvgPool) Forward(x *Tensor) (*Tensor, error) { return avgPool(m.Dims, x, m.Options) }
+
+// AdaptiveAvgPool 自适应平均池化层，Dims 为 1 或 2
+type AdaptiveAvgPool struct {
+	BaseModule
+	Dims       int
+	OutputSize []int
+}
+
+// NewAdaptiveAvgPool1d 创建一维自适应平均池化层
+func NewAdaptiveAvgPool1d(outputSize int) *AdaptiveAvgPool {
+	return &AdaptiveAvgPool{Dims: 1, OutputSize: []int{outputSize}}
+}
+
+// NewAdaptiveAvgPool2d 创建二维自适应平均池化层
+func NewAdaptiveAvgPool2d(outputSize ...int) *AdaptiveAvgPool {
+	return &AdaptiveAvgPool{Dims: 2, OutputSize: outputSize}
+}
+
+// Forward 执行自适应平均池化
+func (m *AdaptiveAvgPool) Forward(x *Tensor) (*Tensor, error) {
+	return adaptiveAvgPool(m.Dims, x, m.OutputSize)
+}
+
+// Flatten 将第 1 维及之后的维度展平，输出形状为 (N, -1)
+type Flatten struct{ BaseModule }
+
+// NewFlatten 创建展平层
+func NewFlatten() *Flatten { return &Flatten{} }
+
+// Forward 执行展平
+func (m *Flatten) Forward(x *Tensor) (*Tensor, error) {
+	if len(x.Shape()) == 0 {
+		return nil, fmt.Errorf("Flatten 的输入至少为一维")
+	}
+	return x.Reshape(x.Shape()[0], -1)
+}
@@ -0,0 +1,133 @@
+package quant
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// observed 校准时包装 Linear 与 Conv，在前向前记录输入
+type observed struct {
+	dubtorch.BaseModule
+	inner dubtorch.Module
+	obs   Observer
+}
+
+func (o *observed) Forward(x *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	if err := o.obs.Observe(x.Data); err != nil {
+		return nil, err
+	}
+	return o.inner.Forward(x)
+}
+
+// 重建模型，Sequential（含嵌套）中的每个非容器模块由 fn 替换
+func rewrite(m dubtorch.Module, fn func(dubtorch.Module) (dubtorch.Module, error)) (dubtorch.Module, error) {
+	s, ok := m.(*dubtorch.Sequential)
+	if !ok {
+		return fn(m)
+	}
+	out := dubtorch.NewSequential()
+	for i := 0; i < s.Len(); i++ {
+		child, err := rewrite(s.At(i), fn)
+		if err != nil {
+			return nil, fmt.Errorf("Sequential 第 %d 层: %v", i, err)
+		}
+		out.Append(child)
+	}
+	return out, nil
+}
+
+// 可以量化的模块
+func quantizable(m dubtorch.Module) bool {
+	switch l := m.(type) {
+	case *dubtorch.Linear:
+		return true
+	case *dubtorch.Conv:
+		return !l.Transposed
+	}
+	return false
+}
+
+// QuantizeModel 返回模型的 int8 副本：Sequential（含嵌套）中的 Linear 与 Conv 被替换为 QuantLinear 与 QuantConv，
+// 其余模块与原模型共享，因此切换副本的训练/推理模式也会影响原模型中的这些模块。
+// calib 不为 nil 时先在其每个批次的第一个数组上以推理模式执行模型，用观察器确定各层输入的量化参数（静态量化）；
+// 为 nil 时在推理时按批次计算（动态量化）
+func QuantizeModel(model dubtorch.Module, calib *dubtorch.DataLoader, cfg Config) (dubtorch.Module, error) {
+	if model == nil {
+		return nil, errors.New("模型不能为空")
+	}
+	if cfg.NewObserver == nil {
+		cfg.NewObserver = func() Observer { return NewMinMaxObserver(-1) }
+	}
+	observers := map[dubtorch.Module]Observer{}
+	if calib != nil {
+		wrapped, err := rewrite(model, func(m dubtorch.Module) (dubtorch.Module, error) {
+			if !quantizable(m) {
+				return m, nil
+			}
+			o := &observed{inner: m, obs: cfg.NewObserver()}
+			observers[m] = o.obs
+			return o, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := calibrate(model, wrapped, calib); err != nil {
+			return nil, err
+		}
+	}
+	return rewrite(model, func(m dubtorch.Module) (dubtorch.Module, error) {
+		if !quantizable(m) {
+			return m, nil
+		}
+		var input *QParams
+		if obs, ok := observers[m]; ok {
+			p, err := obs.QParams(cfg.ActivationScheme)
+			if err != nil {
+				return nil, err
+			}
+			if p.Axis >= 0 {
+				return nil, errors.New("激活观察器必须逐张量统计")
+			}
+			input = &p
+		}
+		if l, ok := m.(*dubtorch.Linear); ok {
+			return NewQuantLinear(l, input, cfg)
+		}
+		return NewQuantConv(m.(*dubtorch.Conv), input, cfg)
+	})
+}
+
+// 以推理模式在校准数据上执行包装后的模型，结束后恢复原模型的模式
+func calibrate(model, wrapped dubtorch.Module, calib *dubtorch.DataLoader) error {
+	training := model.IsTraining()
+	wrapped.Eval()
+	if training {
+		defer model.Train()
+	}
+	it := calib.Iter()
+	defer it.Close()
+	batches := 0
+	for {
+		b, err := it.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		dubtorch.NoGrad(func() {
+			_, err = wrapped.Forward(dubtorch.NewTensor(b[0], false))
+		})
+		if err != nil {
+			return fmt.Errorf("校准第 %d 个批次: %v", batches, err)
+		}
+		batches++
+	}
+	if batches == 0 {
+		return errors.New("校准数据为空")
+	}
+	return nil
+}
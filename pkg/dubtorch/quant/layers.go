@@ -0,0 +1,291 @@
+package quant
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// Config 量化配置，零值表示权重与激活均逐张量对称量化，激活使用 min/max 观察器
+type Config struct {
+	WeightScheme     Scheme
+	ActivationScheme Scheme
+	PerChannel       bool            // 权重按输出通道量化
+	NewObserver      func() Observer // 激活观察器的构造函数，须逐张量统计，默认 NewMinMaxObserver(-1)
+}
+
+// 量化权重，逐通道时沿第 0 维（输出通道）
+func quantizeWeight(w *dubnp.Array, cfg Config) (*dubnp.Int8Array, QParams, error) {
+	axis := -1
+	if cfg.PerChannel {
+		axis = 0
+	}
+	obs := NewMinMaxObserver(axis)
+	if err := obs.Observe(w); err != nil {
+		return nil, QParams{}, err
+	}
+	p, err := obs.QParams(cfg.WeightScheme)
+	if err != nil {
+		return nil, QParams{}, err
+	}
+	q, err := Quantize(w, p)
+	return q, p, err
+}
+
+// 输入的量化参数：校准得到的固定参数，或按当前批次的 min/max 动态计算
+func inputParams(x *dubnp.Array, fixed *QParams, scheme Scheme) (QParams, error) {
+	if fixed != nil {
+		if fixed.Axis >= 0 || len(fixed.Scale) != 1 {
+			return QParams{}, errors.New("输入只支持逐张量量化")
+		}
+		return *fixed, nil
+	}
+	obs := NewMinMaxObserver(-1)
+	if err := obs.Observe(x); err != nil {
+		return QParams{}, err
+	}
+	return obs.QParams(scheme)
+}
+
+// 第 c 个输出通道的 scale 或零点，逐张量时所有通道共用
+func channel[T any](v []T, c int) T {
+	return v[min(c, len(v)-1)]
+}
+
+// 量化权重与其参数占用的字节数
+func weightBytes(w *dubnp.Int8Array, p QParams, bias *dubnp.Array) int {
+	n := len(w.Data) + 8*len(p.Scale) + 4*len(p.ZeroPoint)
+	if bias != nil {
+		n += 8 * len(bias.Data)
+	}
+	return n
+}
+
+// QuantLinear int8 全连接层，只用于推理，输出不参与求导
+type QuantLinear struct {
+	dubtorch.BaseModule
+	InFeatures, OutFeatures int
+	Weight                  *dubnp.Int8Array // 形状为 (out, in)
+	WeightParams            QParams          // 逐通道时 Axis 为 0
+	Bias                    *dubnp.Array     // 以 float64 保存，可为 nil
+	Input                   *QParams         // 输入的逐张量量化参数，nil 时按批次动态计算
+	InputScheme             Scheme           // 动态计算输入参数时使用的量化方式
+}
+
+// NewQuantLinear 量化全连接层的权重，input 为校准得到的输入参数，可为 nil
+func NewQuantLinear(l *dubtorch.Linear, input *QParams, cfg Config) (*QuantLinear, error) {
+	w, p, err := quantizeWeight(l.Weight.Data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	q := &QuantLinear{InFeatures: l.InFeatures, OutFeatures: l.OutFeatures, Weight: w, WeightParams: p, Input: input, InputScheme: cfg.ActivationScheme}
+	if l.Bias != nil {
+		q.Bias = l.Bias.Data.Copy()
+	}
+	return q, nil
+}
+
+// Forward 输入形状为 (..., in)，量化后与 int8 权重相乘，按 y = sx * sw * acc + b 还原为浮点数
+func (l *QuantLinear) Forward(x *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	shape := x.Shape()
+	if len(shape) == 0 || shape[len(shape)-1] != l.InFeatures {
+		return nil, fmt.Errorf("QuantLinear 期望最后一维为 %d，输入形状为 %v", l.InFeatures, shape)
+	}
+	flat := &dubnp.Array{Data: x.Data.Data, Shape: []int{x.Size() / l.InFeatures, l.InFeatures}}
+	px, err := inputParams(flat, l.Input, l.InputScheme)
+	if err != nil {
+		return nil, err
+	}
+	qx, err := Quantize(flat, px)
+	if err != nil {
+		return nil, err
+	}
+	acc, err := dubnp.Int8MatMulTransB(qx, l.Weight, px.ZeroPoint, l.WeightParams.ZeroPoint)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]float64, len(acc.Data))
+	for i, v := range acc.Data {
+		j := i % l.OutFeatures
+		out[i] = px.Scale[0] * channel(l.WeightParams.Scale, j) * float64(v)
+		if l.Bias != nil {
+			out[i] += l.Bias.Data[j]
+		}
+	}
+	outShape := append(append([]int(nil), shape[:len(shape)-1]...), l.OutFeatures)
+	return dubtorch.NewTensor(&dubnp.Array{Data: out, Shape: outShape}, false), nil
+}
+
+// Bytes 返回量化权重、量化参数与偏置占用的字节数
+func (l *QuantLinear) Bytes() int {
+	return weightBytes(l.Weight, l.WeightParams, l.Bias)
+}
+
+// QuantConv int8 卷积层，Dims 为 1 或 2，只用于推理
+type QuantConv struct {
+	dubtorch.BaseModule
+	Dims                    int
+	InChannels, OutChannels int
+	Options                 dubtorch.ConvOptions
+	Weight                  *dubnp.Int8Array // 形状为 (out, in/groups, k...)
+	WeightParams            QParams
+	Bias                    *dubnp.Array
+	Input                   *QParams
+	InputScheme             Scheme
+}
+
+// NewQuantConv 量化卷积层的权重，暂不支持转置卷积
+func NewQuantConv(c *dubtorch.Conv, input *QParams, cfg Config) (*QuantConv, error) {
+	if c.Transposed {
+		return nil, errors.New("暂不支持量化转置卷积")
+	}
+	w, p, err := quantizeWeight(c.Weight.Data, cfg)
+	if err != nil {
+		return nil, err
+	}
+	q := &QuantConv{
+		Dims: c.Dims, InChannels: c.InChannels, OutChannels: c.OutChannels, Options: c.Options,
+		Weight: w, WeightParams: p, Input: input, InputScheme: cfg.ActivationScheme,
+	}
+	if c.Bias != nil {
+		q.Bias = c.Bias.Data.Copy()
+	}
+	return q, nil
+}
+
+// 将按空间维度给出的选项展开为二维，一维时高度方向取 def，规则与 dubtorch.ConvOptions 相同
+func expand2(name string, v []int, dims, def int) ([2]int, error) {
+	out := [2]int{def, def}
+	switch len(v) {
+	case 0:
+	case 1:
+		out[1] = v[0]
+		if dims == 2 {
+			out[0] = v[0]
+		}
+	case dims:
+		copy(out[2-dims:], v)
+	default:
+		return out, fmt.Errorf("%s 应有 1 或 %d 个值，实际为 %v", name, dims, v)
+	}
+	return out, nil
+}
+
+// 单组 int8 卷积的几何参数
+type geom struct {
+	c, h, w, kh, kw, sh, sw, ph, pw, dh, dw, oh, ow int
+}
+
+// 将单组输入 (c, h, w) 展开为 (oh*ow, c*kh*kw) 的矩阵，越界位置填入输入的零点（即实数 0）
+func (g *geom) im2col(src, dst []int8, zero int8) {
+	k := g.c * g.kh * g.kw
+	for oi := 0; oi < g.oh; oi++ {
+		for oj := 0; oj < g.ow; oj++ {
+			row := dst[(oi*g.ow+oj)*k:][:k]
+			for c := 0; c < g.c; c++ {
+				for ki := 0; ki < g.kh; ki++ {
+					i := oi*g.sh - g.ph + ki*g.dh
+					for kj := 0; kj < g.kw; kj++ {
+						j := oj*g.sw - g.pw + kj*g.dw
+						v := zero
+						if i >= 0 && i < g.h && j >= 0 && j < g.w {
+							v = src[(c*g.h+i)*g.w+j]
+						}
+						row[(c*g.kh+ki)*g.kw+kj] = v
+					}
+				}
+			}
+		}
+	}
+}
+
+// Forward 输入形状为 (N, C, L) 或 (N, C, H, W)
+func (c *QuantConv) Forward(x *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	xs, ws := x.Shape(), c.Weight.Shape
+	if c.Dims != 1 && c.Dims != 2 {
+		return nil, fmt.Errorf("QuantConv 的维数必须为 1 或 2: %d", c.Dims)
+	}
+	if len(xs) != c.Dims+2 {
+		return nil, fmt.Errorf("%d 维卷积的输入应为 %d 维，实际形状为 %v", c.Dims, c.Dims+2, xs)
+	}
+	n, cin := xs[0], xs[1]
+	g := geom{h: 1, w: xs[len(xs)-1], kh: 1, kw: ws[len(ws)-1]}
+	if c.Dims == 2 {
+		g.h, g.kh = xs[2], ws[2]
+	}
+	groups := max(c.Options.Groups, 1)
+	cout := ws[0]
+	g.c = ws[1]
+	if cin != g.c*groups || cout%groups != 0 {
+		return nil, fmt.Errorf("通道数不匹配：输入 %d 通道，权重形状 %v，分组数 %d", cin, ws, groups)
+	}
+	stride, err := expand2("Stride", c.Options.Stride, c.Dims, 1)
+	if err != nil {
+		return nil, err
+	}
+	padding, err := expand2("Padding", c.Options.Padding, c.Dims, 0)
+	if err != nil {
+		return nil, err
+	}
+	dilation, err := expand2("Dilation", c.Options.Dilation, c.Dims, 1)
+	if err != nil {
+		return nil, err
+	}
+	g.sh, g.sw, g.ph, g.pw, g.dh, g.dw = stride[0], stride[1], padding[0], padding[1], dilation[0], dilation[1]
+	g.oh = (g.h+2*g.ph-g.dh*(g.kh-1)-1)/g.sh + 1
+	g.ow = (g.w+2*g.pw-g.dw*(g.kw-1)-1)/g.sw + 1
+	if g.oh <= 0 || g.ow <= 0 {
+		return nil, fmt.Errorf("输入尺寸 %v 小于卷积核覆盖的范围", xs[2:])
+	}
+
+	px, err := inputParams(x.Data, c.Input, c.InputScheme)
+	if err != nil {
+		return nil, err
+	}
+	qx, err := Quantize(x.Data, px)
+	if err != nil {
+		return nil, err
+	}
+	og, k, P := cout/groups, g.c*g.kh*g.kw, g.oh*g.ow
+	out := make([]float64, n*cout*P)
+	cols := &dubnp.Int8Array{Data: make([]int8, P*k), Shape: []int{P, k}}
+	for b := 0; b < n; b++ {
+		for gi := 0; gi < groups; gi++ {
+			g.im2col(qx.Data[(b*cin+gi*g.c)*g.h*g.w:][:g.c*g.h*g.w], cols.Data, int8(px.ZeroPoint[0]))
+			w := &dubnp.Int8Array{Data: c.Weight.Data[gi*og*k:][:og*k], Shape: []int{og, k}}
+			zw := c.WeightParams.ZeroPoint
+			if len(zw) > 1 {
+				zw = zw[gi*og : (gi+1)*og]
+			}
+			// (og, k) 与 (P, k) 的转置相乘得到 (og, P)，正好是输出的通道优先布局
+			acc, err := dubnp.Int8MatMulTransB(w, cols, zw, px.ZeroPoint)
+			if err != nil {
+				return nil, err
+			}
+			for o := 0; o < og; o++ {
+				ch := gi*og + o
+				scale := px.Scale[0] * channel(c.WeightParams.Scale, ch)
+				bias := 0.0
+				if c.Bias != nil {
+					bias = c.Bias.Data[ch]
+				}
+				dst := out[(b*cout+ch)*P:][:P]
+				for p, v := range acc.Data[o*P : (o+1)*P] {
+					dst[p] = scale*float64(v) + bias
+				}
+			}
+		}
+	}
+	shape := []int{n, cout, g.ow}
+	if c.Dims == 2 {
+		shape = []int{n, cout, g.oh, g.ow}
+	}
+	return dubtorch.NewTensor(&dubnp.Array{Data: out, Shape: shape}, false), nil
+}
+
+// Bytes 返回量化权重、量化参数与偏置占用的字节数
+func (c *QuantConv) Bytes() int {
+	return weightBytes(c.Weight, c.WeightParams, c.Bias)
+}
@@ -0,0 +1,203 @@
+// Package quant 实现训练后 int8 量化：用观察器在校准数据上统计激活的范围，
+// 将 Linear 与 Conv 的权重量化为 int8，并以 int32 累加的 int8 矩阵乘法执行推理
+package quant
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Scheme 量化方式
+type Scheme int
+
+const (
+	// Symmetric 对称量化，零点为 0，范围为 [-127, 127]
+	Symmetric Scheme = iota
+	// Asymmetric 非对称量化，用零点平移使 [min, max] 映射到 [-128, 127]
+	Asymmetric
+)
+
+func (s Scheme) String() string {
+	if s == Asymmetric {
+		return "asymmetric"
+	}
+	return "symmetric"
+}
+
+// QParams 量化参数，实际值为 Scale * (q - ZeroPoint)。
+// Axis 为 -1 时逐张量量化，Scale 与 ZeroPoint 各一个值；否则沿 Axis 逐通道量化
+type QParams struct {
+	Scale     []float64
+	ZeroPoint []int32
+	Axis      int
+}
+
+// 由范围 [lo, hi] 计算一组量化参数，范围总是扩展到包含 0，使 0 能被精确表示（用于补零）
+func rangeParams(lo, hi float64, scheme Scheme) (float64, int32) {
+	lo, hi = math.Min(lo, 0), math.Max(hi, 0)
+	if scheme == Symmetric {
+		amax := math.Max(-lo, hi)
+		if amax == 0 {
+			return 1, 0
+		}
+		return amax / 127, 0
+	}
+	if hi == lo {
+		return 1, 0
+	}
+	scale := (hi - lo) / 255
+	zero := math.RoundToEven(math.MinInt8 - lo/scale)
+	return scale, int32(math.Max(math.MinInt8, math.Min(math.MaxInt8, zero)))
+}
+
+// ComputeQParams 由各通道的范围计算量化参数，lo 与 hi 长度相同，axis 含义与 QParams.Axis 相同
+func ComputeQParams(lo, hi []float64, axis int, scheme Scheme) (QParams, error) {
+	if len(lo) == 0 || len(lo) != len(hi) {
+		return QParams{}, fmt.Errorf("范围的个数不一致: %d 与 %d", len(lo), len(hi))
+	}
+	if axis < 0 && len(lo) != 1 {
+		return QParams{}, fmt.Errorf("逐张量量化只能有一个范围，实际为 %d 个", len(lo))
+	}
+	p := QParams{Scale: make([]float64, len(lo)), ZeroPoint: make([]int32, len(lo)), Axis: axis}
+	for i := range lo {
+		if math.IsNaN(lo[i]) || math.IsNaN(hi[i]) || math.IsInf(lo[i], 0) || math.IsInf(hi[i], 0) {
+			return QParams{}, fmt.Errorf("第 %d 个范围无效: [%v, %v]", i, lo[i], hi[i])
+		}
+		p.Scale[i], p.ZeroPoint[i] = rangeParams(lo[i], hi[i], scheme)
+	}
+	return p, nil
+}
+
+// Quantize 按参数量化数组
+func Quantize(a *dubnp.Array, p QParams) (*dubnp.Int8Array, error) {
+	return a.QuantizeInt8(p.Scale, p.ZeroPoint, p.Axis)
+}
+
+// Dequantize 按参数还原数组
+func Dequantize(q *dubnp.Int8Array, p QParams) (*dubnp.Array, error) {
+	return q.Dequantize(p.Scale, p.ZeroPoint, p.Axis)
+}
+
+// Observer 在校准数据上统计取值范围，并据此给出量化参数
+type Observer interface {
+	Observe(a *dubnp.Array) error
+	QParams(scheme Scheme) (QParams, error)
+}
+
+// MinMaxObserver 记录观察到的最小值与最大值，Axis 为 -1 时逐张量统计，否则沿 Axis 逐通道统计
+type MinMaxObserver struct {
+	Axis     int
+	Min, Max []float64
+}
+
+// NewMinMaxObserver 创建 min/max 观察器
+func NewMinMaxObserver(axis int) *MinMaxObserver {
+	return &MinMaxObserver{Axis: axis}
+}
+
+// Observe 用一个批次更新范围
+func (o *MinMaxObserver) Observe(a *dubnp.Array) error {
+	channels, inner := 1, len(a.Data)
+	if o.Axis >= 0 {
+		if o.Axis >= len(a.Shape) {
+			return fmt.Errorf("观察轴 %d 超出形状 %v 的范围", o.Axis, a.Shape)
+		}
+		channels, inner = a.Shape[o.Axis], 1
+		for _, d := range a.Shape[o.Axis+1:] {
+			inner *= d
+		}
+	}
+	if o.Min == nil {
+		o.Min, o.Max = make([]float64, channels), make([]float64, channels)
+		for c := range o.Min {
+			o.Min[c], o.Max[c] = math.Inf(1), math.Inf(-1)
+		}
+	} else if len(o.Min) != channels {
+		return fmt.Errorf("通道数从 %d 变为 %d", len(o.Min), channels)
+	}
+	for i, v := range a.Data {
+		if math.IsNaN(v) {
+			return errors.New("观察到 NaN")
+		}
+		c := (i / inner) % channels
+		o.Min[c] = math.Min(o.Min[c], v)
+		o.Max[c] = math.Max(o.Max[c], v)
+	}
+	return nil
+}
+
+// QParams 按观察到的范围计算量化参数
+func (o *MinMaxObserver) QParams(scheme Scheme) (QParams, error) {
+	if o.Min == nil {
+		return QParams{}, errors.New("观察器尚未观察到数据")
+	}
+	return ComputeQParams(o.Min, o.Max, o.Axis, scheme)
+}
+
+// PercentileObserver 以百分位数作为范围，忽略少量离群值。
+// 样本以固定种子的蓄水池抽样保存，内存占用不超过 Capacity 个元素，结果可复现
+type PercentileObserver struct {
+	Percentile float64 // 例如 99.99，范围取 [100-P, P] 分位
+	Capacity   int     // 蓄水池大小，默认 65536
+	samples    []float64
+	seen       int
+	rng        *rand.Rand
+}
+
+// NewPercentileObserver 创建逐张量的百分位观察器
+func NewPercentileObserver(percentile float64) *PercentileObserver {
+	return &PercentileObserver{Percentile: percentile}
+}
+
+// Observe 将一个批次加入蓄水池
+func (o *PercentileObserver) Observe(a *dubnp.Array) error {
+	if o.Percentile <= 50 || o.Percentile > 100 {
+		return fmt.Errorf("百分位必须在 (50, 100] 之间: %v", o.Percentile)
+	}
+	if o.Capacity <= 0 {
+		o.Capacity = 1 << 16
+	}
+	if o.rng == nil {
+		o.rng = rand.New(rand.NewSource(1))
+	}
+	for _, v := range a.Data {
+		if math.IsNaN(v) {
+			return errors.New("观察到 NaN")
+		}
+		o.seen++
+		if len(o.samples) < o.Capacity {
+			o.samples = append(o.samples, v)
+		} else if j := o.rng.Intn(o.seen); j < o.Capacity {
+			o.samples[j] = v
+		}
+	}
+	return nil
+}
+
+// QParams 按分位数计算逐张量的量化参数
+func (o *PercentileObserver) QParams(scheme Scheme) (QParams, error) {
+	if len(o.samples) == 0 {
+		return QParams{}, errors.New("观察器尚未观察到数据")
+	}
+	sorted := append([]float64(nil), o.samples...)
+	sort.Float64s(sorted)
+	lo := quantile(sorted, (100-o.Percentile)/100)
+	hi := quantile(sorted, o.Percentile/100)
+	return ComputeQParams([]float64{lo}, []float64{hi}, -1, scheme)
+}
+
+// 有序数据的分位数，在相邻样本间线性插值
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(pos)
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(i)
+	return sorted[i]*(1-frac) + sorted[i+1]*frac
+}
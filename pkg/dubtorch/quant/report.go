@@ -0,0 +1,136 @@
+package quant
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// ModelBytes 估算模型权重占用的字节数：浮点参数与缓冲区按 float64 计，量化层按 Bytes 计
+func ModelBytes(m dubtorch.Module) int {
+	n := 0
+	for _, p := range m.NamedParameters() {
+		n += 8 * p.Tensor.Size()
+	}
+	for _, b := range m.NamedBuffers() {
+		n += 8 * len(b.Array.Data)
+	}
+	var walk func(dubtorch.Module)
+	walk = func(m dubtorch.Module) {
+		if q, ok := m.(interface{ Bytes() int }); ok {
+			n += q.Bytes()
+		}
+		for _, c := range m.NamedChildren() {
+			walk(c.Module)
+		}
+	}
+	walk(m)
+	return n
+}
+
+// Report 浮点模型与量化模型在同一数据上的对比
+type Report struct {
+	Samples                      int
+	FloatAccuracy, QuantAccuracy float64
+	Agreement                    float64 // 两个模型预测类别相同的比例
+	MaxAbsError, MeanAbsError    float64 // 输出之差的绝对值
+	FloatBytes, QuantBytes       int
+}
+
+// AccuracyDelta 量化后准确率的变化，为负表示下降
+func (r *Report) AccuracyDelta() float64 {
+	return r.QuantAccuracy - r.FloatAccuracy
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "样本 %d，准确率 %.4f -> %.4f（%+.4f），预测一致 %.4f，", r.Samples, r.FloatAccuracy, r.QuantAccuracy, r.AccuracyDelta(), r.Agreement)
+	fmt.Fprintf(&b, "输出误差 max=%.3g mean=%.3g，权重 %d -> %d 字节", r.MaxAbsError, r.MeanAbsError, r.FloatBytes, r.QuantBytes)
+	return b.String()
+}
+
+// 每行的最大值下标
+func argmax(a *dubnp.Array) []float64 {
+	n := a.Shape[0]
+	c := len(a.Data) / n
+	out := make([]float64, n)
+	for i := range out {
+		row := a.Data[i*c : (i+1)*c]
+		for j, v := range row {
+			if v > row[int(out[i])] {
+				out[i] = float64(j)
+			}
+		}
+	}
+	return out
+}
+
+// Evaluate 以推理模式在 loader 上执行两个模型，比较分类准确率与输出误差。
+// 每个批次为 (输入, 类别标签)，模型输出为 (N, C) 的 logits
+func Evaluate(float, quantized dubtorch.Module, loader *dubtorch.DataLoader) (*Report, error) {
+	for _, m := range []dubtorch.Module{float, quantized} {
+		if m.IsTraining() {
+			m.Eval()
+			defer m.Train()
+		}
+	}
+	fa, qa, agree := dubtorch.NewAccuracy(), dubtorch.NewAccuracy(), dubtorch.NewAccuracy()
+	r := &Report{FloatBytes: ModelBytes(float), QuantBytes: ModelBytes(quantized)}
+	sum, elements := 0.0, 0
+	it := loader.Iter()
+	defer it.Close()
+	for {
+		b, err := it.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var fy, qy *dubtorch.Tensor
+		dubtorch.NoGrad(func() {
+			x := dubtorch.NewTensor(b[0], false)
+			if fy, err = float.Forward(x); err == nil {
+				qy, err = quantized.Forward(x)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(fy.Data.Data) != len(qy.Data.Data) {
+			return nil, fmt.Errorf("两个模型的输出形状不同: %v 与 %v", fy.Shape(), qy.Shape())
+		}
+		if err := fa.Update(fy.Data, b[1]); err != nil {
+			return nil, err
+		}
+		if err := qa.Update(qy.Data, b[1]); err != nil {
+			return nil, err
+		}
+		preds := argmax(fy.Data)
+		if err := agree.Update(qy.Data, &dubnp.Array{Data: preds, Shape: []int{len(preds)}}); err != nil {
+			return nil, err
+		}
+		for i, v := range fy.Data.Data {
+			d := v - qy.Data.Data[i]
+			if d < 0 {
+				d = -d
+			}
+			r.MaxAbsError = max(r.MaxAbsError, d)
+			sum += d
+		}
+		r.Samples += fy.Data.Shape[0]
+		elements += len(fy.Data.Data)
+	}
+	if r.Samples == 0 {
+		return nil, errors.New("评估数据为空")
+	}
+	r.MeanAbsError = sum / float64(elements)
+	r.FloatAccuracy = fa.Values()["accuracy"]
+	r.QuantAccuracy = qa.Values()["accuracy"]
+	r.Agreement = agree.Values()["accuracy"]
+	return r, nil
+}
@@ -0,0 +1,51 @@
+package dubtorch
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// 包级随机数生成器，用于参数初始化与 Dropout，ManualSeed 可复现
+var (
+	rngMu sync.Mutex
+	rng   = rand.New(rand.NewSource(1))
+)
+
+// ManualSeed 设置包级随机数生成器的种子
+func ManualSeed(seed int64) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	rng = rand.New(rand.NewSource(seed))
+}
+
+// 在持有锁的情况下用包级随机数生成器填充数据
+func fillRandom(data []float64, sample func(r *rand.Rand) float64) {
+	rngMu.Lock()
+	defer rngMu.Unlock()
+	for i := range data {
+		data[i] = sample(rng)
+	}
+}
+
+// Rand 创建 [0, 1) 均匀分布的叶子张量
+func Rand(shape ...int) *Tensor {
+	a := dubnp.Zeros(shape...)
+	fillRandom(a.Data, func(r *rand.Rand) float64 { return r.Float64() })
+	return NewTensor(a, false)
+}
+
+// Randn 创建标准正态分布的叶子张量
+func Randn(shape ...int) *Tensor {
+	a := dubnp.Zeros(shape...)
+	fillRandom(a.Data, func(r *rand.Rand) float64 { return r.NormFloat64() })
+	return NewTensor(a, false)
+}
+
+// 创建 [low, high) 均匀分布的数组
+func uniformArray(low, high float64, shape ...int) *dubnp.Array {
+	a := dubnp.Zeros(shape...)
+	fillRandom(a.Data, func(r *rand.Rand) float64 { return low + (high-low)*r.Float64() })
+	return a
+}
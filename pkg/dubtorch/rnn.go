@@ -0,0 +1,502 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// RecurrentMode 循环层的类型
+type RecurrentMode int
+
+const (
+	ModeRNNTanh RecurrentMode = iota // h' = tanh(W_ih x + W_hh h + b)
+	ModeRNNReLU                      // h' = relu(W_ih x + W_hh h + b)
+	ModeGRU
+	ModeLSTM
+)
+
+// 每种循环层的门数
+func (m RecurrentMode) gates() int {
+	switch m {
+	case ModeGRU:
+		return 3
+	case ModeLSTM:
+		return 4
+	}
+	return 1
+}
+
+// RecurrentOptions 循环层选项
+type RecurrentOptions struct {
+	NumLayers     int     // 层数，默认 1
+	Bidirectional bool    // 是否双向
+	BatchFirst    bool    // 输入输出形状为 (N, T, F)，默认为 (T, N, F)
+	Dropout       float64 // 训练时在相邻两层之间使用的 Dropout 概率
+	NoBias        bool    // 不使用偏置
+}
+
+// RecurrentState 隐状态，形状均为 (层数*方向数, N, HiddenSize)，只有 LSTM 使用 C
+type RecurrentState struct {
+	H, C *Tensor
+}
+
+// PackedSequence 变长序列组成的批次：Data 按最长序列在末尾补零，形状为 (T, N, F)，
+// Lengths 记录每个序列的有效长度，补零的位置在前向与反向传播中都会被屏蔽
+type PackedSequence struct {
+	Data    *Tensor
+	Lengths []int
+}
+
+// PackSequences 将形状为 (T_i, F) 的若干序列补零后组成 PackedSequence，补零操作可求导
+func PackSequences(seqs ...*Tensor) (*PackedSequence, error) {
+	if len(seqs) == 0 {
+		return nil, errors.New("至少需要一个序列")
+	}
+	maxLen := 0
+	for _, s := range seqs {
+		if len(s.Shape()) != 2 || s.Shape()[1] != seqs[0].Shape()[1] {
+			return nil, fmt.Errorf("序列形状应为 (T, %d)，实际为 %v", seqs[0].Shape()[1], s.Shape())
+		}
+		maxLen = max(maxLen, s.Shape()[0])
+	}
+	features := seqs[0].Shape()[1]
+	padded := make([]*Tensor, len(seqs))
+	lengths := make([]int, len(seqs))
+	for i, s := range seqs {
+		lengths[i] = s.Shape()[0]
+		padded[i] = s
+		if lengths[i] < maxLen {
+			var err error
+			zeros := NewTensor(dubnp.Zeros(maxLen-lengths[i], features), false)
+			if padded[i], err = Concat(0, s, zeros); err != nil {
+				return nil, err
+			}
+		}
+	}
+	data, err := Stack(1, padded...)
+	if err != nil {
+		return nil, err
+	}
+	return &PackedSequence{Data: data, Lengths: lengths}, nil
+}
+
+// Unpack 按有效长度拆分为形状为 (T_i, F) 的若干序列
+func (p *PackedSequence) Unpack() ([]*Tensor, error) {
+	features := p.Data.Shape()[2]
+	seqs := make([]*Tensor, len(p.Lengths))
+	for i, n := range p.Lengths {
+		column, err := p.Data.Narrow(1, i, 1)
+		if err != nil {
+			return nil, err
+		}
+		if column, err = column.Narrow(0, 0, n); err != nil {
+			return nil, err
+		}
+		if seqs[i], err = column.Reshape(n, features); err != nil {
+			return nil, err
+		}
+	}
+	return seqs, nil
+}
+
+// 一层一个方向的参数
+type recurrentCell struct {
+	wih, whh, bih, bhh *Tensor
+}
+
+// Recurrent 多层、可双向的循环层（RNN/GRU/LSTM），参数名与 PyTorch 一致
+type Recurrent struct {
+	BaseModule
+	Mode                  RecurrentMode
+	InputSize, HiddenSize int
+	Options               RecurrentOptions
+	cells                 []recurrentCell // 按 层*方向数+方向 排列
+	dropout               *Dropout
+}
+
+// NewRecurrent 创建循环层，参数按 U(-1/sqrt(hidden), 1/sqrt(hidden)) 初始化
+func NewRecurrent(mode RecurrentMode, inputSize, hiddenSize int, opts RecurrentOptions) (*Recurrent, error) {
+	if opts.NumLayers == 0 {
+		opts.NumLayers = 1
+	}
+	if inputSize <= 0 || hiddenSize <= 0 || opts.NumLayers < 0 {
+		return nil, errors.New("InputSize、HiddenSize 与 NumLayers 必须为正数")
+	}
+	if opts.Dropout < 0 || opts.Dropout >= 1 {
+		return nil, fmt.Errorf("Dropout 的概率必须在 [0, 1) 之间: %v", opts.Dropout)
+	}
+	r := &Recurrent{Mode: mode, InputSize: inputSize, HiddenSize: hiddenSize, Options: opts}
+	dirs := r.directions()
+	gh := mode.gates() * hiddenSize
+	bound := 1 / math.Sqrt(float64(hiddenSize))
+	for l := 0; l < opts.NumLayers; l++ {
+		in := inputSize
+		if l > 0 {
+			in = hiddenSize * dirs
+		}
+		for d := 0; d < dirs; d++ {
+			suffix := fmt.Sprintf("_l%d", l)
+			if d == 1 {
+				suffix += "_reverse"
+			}
+			var c recurrentCell
+			c.wih = r.RegisterParameter("weight_ih"+suffix, NewTensor(uniformArray(-bound, bound, gh, in), true))
+			c.whh = r.RegisterParameter("weight_hh"+suffix, NewTensor(uniformArray(-bound, bound, gh, hiddenSize), true))
+			if !opts.NoBias {
+				c.bih = r.RegisterParameter("bias_ih"+suffix, NewTensor(uniformArray(-bound, bound, gh), true))
+				c.bhh = r.RegisterParameter("bias_hh"+suffix, NewTensor(uniformArray(-bound, bound, gh), true))
+			}
+			r.cells = append(r.cells, c)
+		}
+	}
+	if opts.Dropout > 0 {
+		r.dropout = NewDropout(opts.Dropout)
+		r.RegisterModule("dropout", r.dropout)
+	}
+	return r, nil
+}
+
+// NewRNN 创建以 tanh 为激活函数的循环层
+func NewRNN(inputSize, hiddenSize int, opts RecurrentOptions) (*Recurrent, error) {
+	return NewRecurrent(ModeRNNTanh, inputSize, hiddenSize, opts)
+}
+
+// NewGRU 创建 GRU 层
+func NewGRU(inputSize, hiddenSize int, opts RecurrentOptions) (*Recurrent, error) {
+	return NewRecurrent(ModeGRU, inputSize, hiddenSize, opts)
+}
+
+// NewLSTM 创建 LSTM 层
+func NewLSTM(inputSize, hiddenSize int, opts RecurrentOptions) (*Recurrent, error) {
+	return NewRecurrent(ModeLSTM, inputSize, hiddenSize, opts)
+}
+
+func (r *Recurrent) directions() int {
+	if r.Options.Bidirectional {
+		return 2
+	}
+	return 1
+}
+
+// Forward 使用零初始状态，只返回输出序列
+func (r *Recurrent) Forward(x *Tensor) (*Tensor, error) {
+	out, _, err := r.ForwardState(x, nil)
+	return out, err
+}
+
+// ForwardState 从给定的初始状态开始计算（为 nil 时使用零状态），返回输出序列与最终状态
+// 输出形状为 (T, N, 方向数*HiddenSize)，BatchFirst 时为 (N, T, 方向数*HiddenSize)
+func (r *Recurrent) ForwardState(x *Tensor, state *RecurrentState) (*Tensor, *RecurrentState, error) {
+	if len(x.Shape()) != 3 {
+		return nil, nil, fmt.Errorf("循环层的输入应为三维，实际形状为 %v", x.Shape())
+	}
+	var err error
+	if r.Options.BatchFirst {
+		if x, err = x.TransposeAxes(0, 1); err != nil {
+			return nil, nil, err
+		}
+	}
+	out, state, err := r.run(x, nil, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.Options.BatchFirst {
+		if out, err = out.TransposeAxes(0, 1); err != nil {
+			return nil, nil, err
+		}
+	}
+	return out, state, nil
+}
+
+// ForwardPacked 处理变长序列，补零位置的输出为 0，且不改变隐状态；
+// 最终状态为每个序列最后一个有效时间步的状态（反向为第一个时间步）
+func (r *Recurrent) ForwardPacked(p *PackedSequence, state *RecurrentState) (*PackedSequence, *RecurrentState, error) {
+	shape := p.Data.Shape()
+	if len(shape) != 3 || len(p.Lengths) != shape[1] {
+		return nil, nil, fmt.Errorf("PackedSequence 的数据形状 %v 与 %d 个长度不一致", shape, len(p.Lengths))
+	}
+	for _, n := range p.Lengths {
+		if n < 0 || n > shape[0] {
+			return nil, nil, fmt.Errorf("序列长度 %d 超出范围 [0, %d]", n, shape[0])
+		}
+	}
+	out, state, err := r.run(p.Data, p.Lengths, state)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &PackedSequence{Data: out, Lengths: append([]int(nil), p.Lengths...)}, state, nil
+}
+
+// x 形状为 (T, N, F)；lengths 为 nil 时所有序列长度均为 T
+func (r *Recurrent) run(x *Tensor, lengths []int, state *RecurrentState) (*Tensor, *RecurrentState, error) {
+	T, N := x.Shape()[0], x.Shape()[1]
+	if x.Shape()[2] != r.InputSize {
+		return nil, nil, fmt.Errorf("循环层期望输入特征数为 %d，实际形状为 %v", r.InputSize, x.Shape())
+	}
+	dirs := r.directions()
+	stateShape := []int{r.Options.NumLayers * dirs, N, r.HiddenSize}
+	if state == nil {
+		state = &RecurrentState{}
+	}
+	for _, s := range []*Tensor{state.H, state.C} {
+		if s != nil && !sameShape(s.Shape(), stateShape) {
+			return nil, nil, fmt.Errorf("初始状态形状应为 %v，实际为 %v", stateShape, s.Shape())
+		}
+	}
+
+	// 每个时间步的掩码 (N, 1)，所有序列都有效的时间步为 nil
+	masks := make([]*Tensor, T)
+	for t := 0; t < T && lengths != nil; t++ {
+		m := dubnp.Zeros(N, 1)
+		full := true
+		for b, n := range lengths {
+			if t < n {
+				m.Data[b] = 1
+			} else {
+				full = false
+			}
+		}
+		if !full {
+			masks[t] = NewTensor(m, false)
+		}
+	}
+
+	// 取第 k 个（层*方向数+方向）初始状态，未给出时为零
+	initial := func(s *Tensor, k int) (*Tensor, error) {
+		if s == nil {
+			return NewTensor(dubnp.Zeros(N, r.HiddenSize), false), nil
+		}
+		h, err := s.Narrow(0, k, 1)
+		if err != nil {
+			return nil, err
+		}
+		return h.Reshape(N, r.HiddenSize)
+	}
+
+	input := x
+	var hs, cs []*Tensor
+	for l := 0; l < r.Options.NumLayers; l++ {
+		outputs := make([]*Tensor, dirs)
+		for d := 0; d < dirs; d++ {
+			k := l*dirs + d
+			h0, err := initial(state.H, k)
+			if err != nil {
+				return nil, nil, err
+			}
+			c0, err := initial(state.C, k)
+			if err != nil {
+				return nil, nil, err
+			}
+			seq, h, c, err := r.runDirection(r.cells[k], input, masks, d == 1, h0, c0)
+			if err != nil {
+				return nil, nil, err
+			}
+			outputs[d] = seq
+			hs = append(hs, h)
+			cs = append(cs, c)
+		}
+		var err error
+		if input, err = Concat(2, outputs...); err != nil {
+			return nil, nil, err
+		}
+		if r.dropout != nil && l < r.Options.NumLayers-1 {
+			if input, err = r.dropout.Forward(input); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	h, err := Stack(0, hs...)
+	if err != nil {
+		return nil, nil, err
+	}
+	final := &RecurrentState{H: h}
+	if r.Mode == ModeLSTM {
+		if final.C, err = Stack(0, cs...); err != nil {
+			return nil, nil, err
+		}
+	}
+	return input, final, nil
+}
+
+// 按时间顺序（reverse 时倒序）运行一层一个方向，返回 (T, N, H) 的输出与最终状态
+func (r *Recurrent) runDirection(cell recurrentCell, x *Tensor, masks []*Tensor, reverse bool, h, c *Tensor) (*Tensor, *Tensor, *Tensor, error) {
+	T, N, F := x.Shape()[0], x.Shape()[1], x.Shape()[2]
+
+	// 一次性计算所有时间步的输入投影 (T*N, 门数*H)
+	flat, err := x.Reshape(T*N, F)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	wihT, err := cell.wih.Transpose()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	xp, err := flat.MatMul(wihT)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if cell.bih != nil {
+		if xp, err = xp.Add(cell.bih); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	whhT, err := cell.whh.Transpose()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	outs := make([]*Tensor, T)
+	for step := 0; step < T; step++ {
+		t := step
+		if reverse {
+			t = T - 1 - step
+		}
+		xt, err := xp.Narrow(0, t*N, N)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		hp, err := h.MatMul(whhT)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if cell.bhh != nil {
+			if hp, err = hp.Add(cell.bhh); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		hNew, cNew, err := r.cellStep(xt, hp, h, c)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if m := masks[t]; m != nil {
+			// 补零位置保持原状态，输出为 0
+			if outs[t], err = hNew.Mul(m); err != nil {
+				return nil, nil, nil, err
+			}
+			if hNew, err = maskState(h, hNew, m); err != nil {
+				return nil, nil, nil, err
+			}
+			if cNew != nil {
+				if cNew, err = maskState(c, cNew, m); err != nil {
+					return nil, nil, nil, err
+				}
+			}
+		} else {
+			outs[t] = hNew
+		}
+		h, c = hNew, cNew
+	}
+	out, err := Stack(0, outs...)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return out, h, c, nil
+}
+
+// 一个时间步的状态更新，xt 与 hp 为当前输入与上一隐状态的投影（均已加偏置），只有 LSTM 返回新的 c
+func (r *Recurrent) cellStep(xt, hp, h, c *Tensor) (*Tensor, *Tensor, error) {
+	switch r.Mode {
+	case ModeRNNTanh, ModeRNNReLU:
+		sum, err := xt.Add(hp)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r.Mode == ModeRNNReLU {
+			return sum.ReLU(), nil, nil
+		}
+		return sum.Tanh(), nil, nil
+	case ModeGRU:
+		// r、z 为重置门与更新门，n 为候选状态：h' = n + z*(h - n)
+		xg, err := splitGates(xt, 3, r.HiddenSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		hg, err := splitGates(hp, 3, r.HiddenSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		rs, err := xg[0].Add(hg[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		zs, err := xg[1].Add(hg[1])
+		if err != nil {
+			return nil, nil, err
+		}
+		rn, err := rs.Sigmoid().Mul(hg[2])
+		if err != nil {
+			return nil, nil, err
+		}
+		ns, err := xg[2].Add(rn)
+		if err != nil {
+			return nil, nil, err
+		}
+		ng := ns.Tanh()
+		diff, err := h.Sub(ng)
+		if err != nil {
+			return nil, nil, err
+		}
+		if diff, err = zs.Sigmoid().Mul(diff); err != nil {
+			return nil, nil, err
+		}
+		hNew, err := ng.Add(diff)
+		return hNew, nil, err
+	case ModeLSTM:
+		sum, err := xt.Add(hp)
+		if err != nil {
+			return nil, nil, err
+		}
+		g, err := splitGates(sum, 4, r.HiddenSize)
+		if err != nil {
+			return nil, nil, err
+		}
+		ig, fg, gg, og := g[0].Sigmoid(), g[1].Sigmoid(), g[2].Tanh(), g[3].Sigmoid()
+		fc, err := fg.Mul(c)
+		if err != nil {
+			return nil, nil, err
+		}
+		igg, err := ig.Mul(gg)
+		if err != nil {
+			return nil, nil, err
+		}
+		cNew, err := fc.Add(igg)
+		if err != nil {
+			return nil, nil, err
+		}
+		hNew, err := og.Mul(cNew.Tanh())
+		if err != nil {
+			return nil, nil, err
+		}
+		return hNew, cNew, nil
+	}
+	return nil, nil, fmt.Errorf("未知的循环层类型: %d", r.Mode)
+}
+
+// 将 (N, n*size) 按列拆分为 n 个门
+func splitGates(t *Tensor, n, size int) ([]*Tensor, error) {
+	gates := make([]*Tensor, n)
+	for i := range gates {
+		var err error
+		if gates[i], err = t.Narrow(1, i*size, size); err != nil {
+			return nil, err
+		}
+	}
+	return gates, nil
+}
+
+// 按掩码合并状态：m 为 1 的位置取 next，为 0 的位置保持 prev，即 prev + (next - prev)*m
+func maskState(prev, next, m *Tensor) (*Tensor, error) {
+	diff, err := next.Sub(prev)
+	if err != nil {
+		return nil, err
+	}
+	if diff, err = diff.Mul(m); err != nil {
+		return nil, err
+	}
+	return prev.Add(diff)
+}
@@ -0,0 +1,227 @@
+package dubtorch
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// safetensors 布局：8 字节小端 uint64 头部长度 + JSON 头部 + 按偏移排列的小端原始数据。
+// 头部中每个张量记录 dtype、shape 与 data_offsets，可选的 "__metadata__" 为字符串映射
+
+// 头部最大长度，防止损坏的文件导致巨大的内存分配
+const maxSafetensorsHeader = 100 << 20
+
+// SafetensorsOptions 保存选项，零值表示以 F64 保存且没有元数据
+type SafetensorsOptions struct {
+	DType    string            // "F64"、"F32"、"F16" 或 "BF16"
+	Metadata map[string]string // 写入 "__metadata__"
+}
+
+type safetensorsEntry struct {
+	DType       string `json:"dtype"`
+	Shape       []int  `json:"shape"`
+	DataOffsets [2]int `json:"data_offsets"`
+}
+
+// 每种 dtype 的元素字节数
+var safetensorsSizes = map[string]int{
+	"F64": 8, "F32": 4, "F16": 2, "BF16": 2,
+	"I64": 8, "I32": 4, "I16": 2, "I8": 1, "U8": 1, "BOOL": 1,
+}
+
+// SaveSafetensors 将 state 按 safetensors 格式写出，张量按名称排序以保证输出确定
+func SaveSafetensors(w io.Writer, state map[string]*dubnp.Array, opts SafetensorsOptions) error {
+	dtype := opts.DType
+	if dtype == "" {
+		dtype = "F64"
+	}
+	if dtype != "F64" && dtype != "F32" && dtype != "F16" && dtype != "BF16" {
+		return fmt.Errorf("不支持以 %s 保存", dtype)
+	}
+	size := safetensorsSizes[dtype]
+
+	names := make([]string, 0, len(state))
+	for name := range state {
+		if name == "__metadata__" {
+			return errors.New("张量名称不能为 __metadata__")
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := make(map[string]any, len(names)+1)
+	if len(opts.Metadata) > 0 {
+		header["__metadata__"] = opts.Metadata
+	}
+	offset := 0
+	for _, name := range names {
+		a := state[name]
+		n := len(a.Data) * size
+		shape := append([]int{}, a.Shape...)
+		header[name] = safetensorsEntry{DType: dtype, Shape: shape, DataOffsets: [2]int{offset, offset + n}}
+		offset += n
+	}
+	// json.Marshal 对 map 的键排序，头部按 8 字节对齐并以空格填充
+	raw, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	for len(raw)%8 != 0 {
+		raw = append(raw, ' ')
+	}
+
+	buf := make([]byte, 8, 8+len(raw)+offset)
+	binary.LittleEndian.PutUint64(buf, uint64(len(raw)))
+	buf = append(buf, raw...)
+	for _, name := range names {
+		buf = appendEncoded(buf, state[name].Data, dtype)
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// 按 dtype 编码并追加到 buf
+func appendEncoded(buf []byte, data []float64, dtype string) []byte {
+	for _, v := range data {
+		switch dtype {
+		case "F64":
+			buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(v))
+		case "F32":
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(float32(v)))
+		case "F16":
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(dubnp.Float16FromFloat64(v)))
+		case "BF16":
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(dubnp.BFloat16FromFloat64(v)))
+		}
+	}
+	return buf
+}
+
+// LoadSafetensors 读取 safetensors 数据，所有 dtype 均转换为 float64，同时返回元数据
+func LoadSafetensors(r io.Reader) (map[string]*dubnp.Array, map[string]string, error) {
+	var prefix [8]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, nil, fmt.Errorf("读取 safetensors 头部长度失败: %v", err)
+	}
+	n := binary.LittleEndian.Uint64(prefix[:])
+	if n > maxSafetensorsHeader {
+		return nil, nil, fmt.Errorf("safetensors 头部过大: %d 字节", n)
+	}
+	raw := make([]byte, n)
+	if _, err := io.ReadFull(r, raw); err != nil {
+		return nil, nil, fmt.Errorf("读取 safetensors 头部失败: %v", err)
+	}
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &header); err != nil {
+		return nil, nil, fmt.Errorf("解析 safetensors 头部失败: %v", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var metadata map[string]string
+	state := make(map[string]*dubnp.Array, len(header))
+	for name, msg := range header {
+		if name == "__metadata__" {
+			if err := json.Unmarshal(msg, &metadata); err != nil {
+				return nil, nil, fmt.Errorf("解析 __metadata__ 失败: %v", err)
+			}
+			continue
+		}
+		var e safetensorsEntry
+		if err := json.Unmarshal(msg, &e); err != nil {
+			return nil, nil, fmt.Errorf("解析张量 %s 的头部失败: %v", name, err)
+		}
+		a, err := decodeEntry(e, data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("张量 %s: %v", name, err)
+		}
+		state[name] = a
+	}
+	return state, metadata, nil
+}
+
+// 按头部描述解码一个张量
+func decodeEntry(e safetensorsEntry, data []byte) (*dubnp.Array, error) {
+	size, ok := safetensorsSizes[e.DType]
+	if !ok {
+		return nil, fmt.Errorf("不支持的 dtype %s", e.DType)
+	}
+	// 元素个数不可能超过数据能容纳的个数，逐维检查以免乘积溢出
+	count := 1
+	for _, s := range e.Shape {
+		if s < 0 {
+			return nil, fmt.Errorf("无效的形状 %v", e.Shape)
+		}
+		if s > 0 && count > (len(data)/size)/s {
+			return nil, fmt.Errorf("形状 %v 超出数据长度 %d", e.Shape, len(data))
+		}
+		count *= s
+	}
+	begin, end := e.DataOffsets[0], e.DataOffsets[1]
+	if begin < 0 || end < begin || end > len(data) {
+		return nil, fmt.Errorf("数据偏移 [%d, %d) 超出数据长度 %d", begin, end, len(data))
+	}
+	if end-begin != count*size {
+		return nil, fmt.Errorf("数据长度 %d 与形状 %v、dtype %s 不匹配", end-begin, e.Shape, e.DType)
+	}
+
+	buf := data[begin:end]
+	out := make([]float64, count)
+	for i := range out {
+		b := buf[i*size:]
+		switch e.DType {
+		case "F64":
+			out[i] = math.Float64frombits(binary.LittleEndian.Uint64(b))
+		case "F32":
+			out[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+		case "F16":
+			out[i] = dubnp.Float16(binary.LittleEndian.Uint16(b)).Float64()
+		case "BF16":
+			out[i] = dubnp.BFloat16(binary.LittleEndian.Uint16(b)).Float64()
+		case "I64":
+			out[i] = float64(int64(binary.LittleEndian.Uint64(b)))
+		case "I32":
+			out[i] = float64(int32(binary.LittleEndian.Uint32(b)))
+		case "I16":
+			out[i] = float64(int16(binary.LittleEndian.Uint16(b)))
+		case "I8":
+			out[i] = float64(int8(b[0]))
+		case "U8", "BOOL":
+			out[i] = float64(b[0])
+		}
+	}
+	return &dubnp.Array{Data: out, Shape: append([]int{}, e.Shape...)}, nil
+}
+
+// SaveSafetensorsFile 将 state 保存到文件
+func SaveSafetensorsFile(path string, state map[string]*dubnp.Array, opts SafetensorsOptions) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := SaveSafetensors(f, state, opts); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// LoadSafetensorsFile 从文件读取 state 与元数据
+func LoadSafetensorsFile(path string) (map[string]*dubnp.Array, map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	return LoadSafetensors(f)
+}
@@ -0,0 +1,46 @@
+package dubtorch
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+)
+
+// 按调用划分的模式（NoGrad、DetectAnomaly）用一个不内联的帧函数调用 fn，
+// 记录运算时在当前 goroutine 的调用栈中查找该帧，因此模式只作用于这次调用，
+// 不影响其他 goroutine，也不包括 fn 中新启动的 goroutine
+
+// 帧函数调用 fn 时的返回地址，调用栈中出现该地址即表示处于这个帧之内
+func frameReturnPC(frame func(fn func())) uintptr {
+	var pc [1]uintptr
+	frame(func() { runtime.Callers(2, pc[:]) })
+	return pc[0]
+}
+
+// 当前 goroutine 的调用栈中是否出现 pc（不含本函数与调用者）
+func onStack(pc uintptr) bool {
+	var pcs [64]uintptr
+	for skip := 3; ; skip += len(pcs) {
+		n := runtime.Callers(skip, pcs[:])
+		for _, p := range pcs[:n] {
+			if p == pc {
+				return true
+			}
+		}
+		if n < len(pcs) {
+			return false
+		}
+	}
+}
+
+// 当前 goroutine 的编号，只在需要区分同时进行的多个调用时使用
+func goroutineID() uint64 {
+	var buf [64]byte
+	line := buf[:runtime.Stack(buf[:], false)]
+	line = bytes.TrimPrefix(line, []byte("goroutine "))
+	if i := bytes.IndexByte(line, ' '); i >= 0 {
+		line = line[:i]
+	}
+	id, _ := strconv.ParseUint(string(line), 10, 64)
+	return id
+}
@@ -0,0 +1,10 @@
+package dubtorch
+
+// 形状对应的元素个数
+func shapeSize(shape []int) int {
+	n := 1
+	for _, d := range shape {
+		n *= d
+	}
+	return n
+}
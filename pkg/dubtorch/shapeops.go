@@ -0,0 +1,147 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Narrow 沿 axis 取 [start, start+length) 的切片，结果为新的数组
+func (a *Tensor) Narrow(axis, start, length int) (*Tensor, error) {
+	outer, n, inner, err := laneGeometry(a.Data.Shape, axis)
+	if err != nil {
+		return nil, err
+	}
+	if start < 0 || length < 0 || start+length > n {
+		return nil, fmt.Errorf("切片 [%d, %d) 超出轴长度 %d", start, start+length, n)
+	}
+	axis = normalizeAxis(axis, len(a.Data.Shape))
+	shape := append([]int(nil), a.Data.Shape...)
+	shape[axis] = length
+	out := dubnp.Zeros(shape...)
+	block := length * inner
+	for o := 0; o < outer; o++ {
+		copy(out.Data[o*block:(o+1)*block], a.Data.Data[(o*n+start)*inner:])
+	}
+	return newResult("Narrow", out, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		ga := dubnp.Zeros(a.Data.Shape...)
+		for o := 0; o < outer; o++ {
+			copy(ga.Data[(o*n+start)*inner:], g.Data[o*block:(o+1)*block])
+		}
+		return []*dubnp.Array{ga}, nil
+	}), nil
+}
+
+// Concat 沿 axis 拼接张量，其余维度必须一致
+func Concat(axis int, tensors ...*Tensor) (*Tensor, error) {
+	if len(tensors) == 0 {
+		return nil, errors.New("至少需要一个张量")
+	}
+	first := tensors[0].Data.Shape
+	outer, _, inner, err := laneGeometry(first, axis)
+	if err != nil {
+		return nil, err
+	}
+	axis = normalizeAxis(axis, len(first))
+	lengths := make([]int, len(tensors))
+	total := 0
+	for i, t := range tensors {
+		s := t.Data.Shape
+		if len(s) != len(first) {
+			return nil, fmt.Errorf("无法拼接形状 %v 与 %v", first, s)
+		}
+		for d := range s {
+			if d != axis && s[d] != first[d] {
+				return nil, fmt.Errorf("无法拼接形状 %v 与 %v", first, s)
+			}
+		}
+		lengths[i] = s[axis]
+		total += s[axis]
+	}
+
+	shape := append([]int(nil), first...)
+	shape[axis] = total
+	out := dubnp.Zeros(shape...)
+	offset := 0
+	for i, t := range tensors {
+		block := lengths[i] * inner
+		for o := 0; o < outer; o++ {
+			copy(out.Data[(o*total+offset)*inner:], t.Data.Data[o*block:(o+1)*block])
+		}
+		offset += lengths[i]
+	}
+	return newResult("Concat", out, tensors, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		grads := make([]*dubnp.Array, len(tensors))
+		offset := 0
+		for i, t := range tensors {
+			block := lengths[i] * inner
+			if t.RequiresGrad {
+				grads[i] = dubnp.Zeros(t.Data.Shape...)
+				for o := 0; o < outer; o++ {
+					copy(grads[i].Data[o*block:(o+1)*block], g.Data[(o*total+offset)*inner:])
+				}
+			}
+			offset += lengths[i]
+		}
+		return grads, nil
+	}), nil
+}
+
+// Stack 沿新的第 axis 维堆叠形状相同的张量
+func Stack(axis int, tensors ...*Tensor) (*Tensor, error) {
+	if len(tensors) == 0 {
+		return nil, errors.New("至少需要一个张量")
+	}
+	ndim := len(tensors[0].Data.Shape)
+	if axis < 0 {
+		axis += ndim + 1
+	}
+	if axis < 0 || axis > ndim {
+		return nil, fmt.Errorf("轴 %d 超出范围，张量维度为 %d", axis, ndim)
+	}
+	expanded := make([]*Tensor, len(tensors))
+	for i, t := range tensors {
+		shape := append(append(append([]int(nil), t.Data.Shape[:axis]...), 1), t.Data.Shape[axis:]...)
+		r, err := t.Reshape(shape...)
+		if err != nil {
+			return nil, err
+		}
+		expanded[i] = r
+	}
+	return Concat(axis, expanded...)
+}
+
+// Permute 按 axes 重新排列维度，结果为新的连续数组
+func (a *Tensor) Permute(axes ...int) (*Tensor, error) {
+	data, err := a.Data.Permute(axes...)
+	if err != nil {
+		return nil, err
+	}
+	inverse := make([]int, len(axes))
+	for i, ax := range axes {
+		inverse[ax] = i
+	}
+	return newResult("Permute", data, []*Tensor{a}, func(g *dubnp.Array) ([]*dubnp.Array, error) {
+		ga, err := g.Permute(inverse...)
+		if err != nil {
+			return nil, err
+		}
+		return []*dubnp.Array{ga}, nil
+	}), nil
+}
+
+// TransposeAxes 交换两个维度
+func (a *Tensor) TransposeAxes(i, j int) (*Tensor, error) {
+	ndim := len(a.Data.Shape)
+	axes := make([]int, ndim)
+	for k := range axes {
+		axes[k] = k
+	}
+	i, j = normalizeAxis(i, ndim), normalizeAxis(j, ndim)
+	if i < 0 || i >= ndim || j < 0 || j >= ndim {
+		return nil, fmt.Errorf("轴 (%d, %d) 超出范围，张量维度为 %d", i, j, ndim)
+	}
+	axes[i], axes[j] = axes[j], axes[i]
+	return a.Permute(axes...)
+}
@@ -0,0 +1,94 @@
+package dubtorch
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// IncompatibleKeys 非严格加载时缺失与多余的键（均已排序）
+type IncompatibleKeys struct {
+	Missing    []string // 模块中存在但 state 中没有
+	Unexpected []string // state 中存在但模块中没有
+}
+
+// StateDict 返回所有参数与缓冲区的副本，键为其完整名称
+func (m *BaseModule) StateDict() map[string]*dubnp.Array {
+	state := make(map[string]*dubnp.Array)
+	for _, p := range m.NamedParameters() {
+		state[p.Name] = p.Tensor.Data.Copy()
+	}
+	for _, b := range m.NamedBuffers() {
+		state[b.Name] = b.Array.Copy()
+	}
+	return state
+}
+
+// LoadStateDict 将 state 原地复制到参数与缓冲区中。
+// strict 为 true 时键必须完全一致；任何形状不一致都会返回错误，且出错时不修改模块
+func (m *BaseModule) LoadStateDict(state map[string]*dubnp.Array, strict bool) (IncompatibleKeys, error) {
+	targets := make(map[string]*dubnp.Array)
+	for _, p := range m.NamedParameters() {
+		targets[p.Name] = p.Tensor.Data
+	}
+	for _, b := range m.NamedBuffers() {
+		targets[b.Name] = b.Array
+	}
+
+	var keys IncompatibleKeys
+	for name := range targets {
+		if _, ok := state[name]; !ok {
+			keys.Missing = append(keys.Missing, name)
+		}
+	}
+	for name := range state {
+		if _, ok := targets[name]; !ok {
+			keys.Unexpected = append(keys.Unexpected, name)
+		}
+	}
+	sort.Strings(keys.Missing)
+	sort.Strings(keys.Unexpected)
+	if strict && (len(keys.Missing) > 0 || len(keys.Unexpected) > 0) {
+		return keys, fmt.Errorf("state dict 的键不匹配，缺失 %v，多余 %v", keys.Missing, keys.Unexpected)
+	}
+
+	// 先检查全部形状，再统一复制
+	names := make([]string, 0, len(state))
+	for name := range state {
+		if _, ok := targets[name]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if dst, src := targets[name], state[name]; !sameShape(dst.Shape, src.Shape) {
+			return keys, fmt.Errorf("%s 的形状不匹配: 模块中为 %v，state dict 中为 %v", name, dst.Shape, src.Shape)
+		}
+	}
+	for _, name := range names {
+		copy(targets[name].Data, state[name].Data)
+	}
+	return keys, nil
+}
+
+// WithPrefix 为所有键加上 "prefix."，用于将多个 state dict 合并保存
+func WithPrefix(prefix string, state map[string]*dubnp.Array) map[string]*dubnp.Array {
+	out := make(map[string]*dubnp.Array, len(state))
+	for k, v := range state {
+		out[prefix+"."+k] = v
+	}
+	return out
+}
+
+// StripPrefix 取出以 "prefix." 开头的键并去掉前缀，其余键被忽略
+func StripPrefix(prefix string, state map[string]*dubnp.Array) map[string]*dubnp.Array {
+	out := make(map[string]*dubnp.Array)
+	for k, v := range state {
+		if rest, ok := strings.CutPrefix(k, prefix+"."); ok {
+			out[rest] = v
+		}
+	}
+	return out
+}
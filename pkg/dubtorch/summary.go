@@ -0,0 +1,164 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// LayerSummary 模型中一层的输出形状与参数
+type LayerSummary struct {
+	Name        string // 在模型中的路径，Sequential 的子模块为其下标
+	Type        string
+	OutputShape []int
+	Params      int // 该层（含子模块）的参数个数
+	Trainable   int // 其中需要梯度的个数
+}
+
+// ModelSummary Summary 的结果，字节数均按 float64 计算
+type ModelSummary struct {
+	InputShape      []int
+	Layers          []LayerSummary
+	TotalParams     int // 共享的参数只计一次
+	TrainableParams int
+	InputBytes      int
+	ActivationBytes int // 各层输出之和，反向传播时梯度再占用同样大小
+	ParamBytes      int
+}
+
+// TotalBytes 训练一步的内存估计：输入、前向与反向的激活以及参数
+func (s *ModelSummary) TotalBytes() int {
+	return s.InputBytes + 2*s.ActivationBytes + s.ParamBytes
+}
+
+// Summary 以推理模式在形状为 inputShape 的全零输入上执行模型，返回每层的输出形状、参数个数与内存估计，
+// 用 String 或 Fprint 输出表格。Sequential（含嵌套）逐层展开，其余模块作为一层。结束后恢复模型原来的训练/推理模式
+func Summary(m Module, inputShape []int) (*ModelSummary, error) {
+	if m == nil {
+		return nil, errors.New("模型不能为空")
+	}
+	training := m.IsTraining()
+	m.Eval()
+	if training {
+		defer m.Train()
+	}
+	s := &ModelSummary{InputShape: append([]int(nil), inputShape...)}
+	x := NewTensor(dubnp.Zeros(inputShape...), false)
+	s.InputBytes = 8 * x.Size()
+	var err error
+	NoGrad(func() {
+		_, err = s.walk("", m, x)
+	})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[*Tensor]bool{}
+	for _, p := range m.Parameters() {
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		s.TotalParams += p.Size()
+		if p.RequiresGrad {
+			s.TrainableParams += p.Size()
+		}
+	}
+	s.ParamBytes = 8 * s.TotalParams
+	return s, nil
+}
+
+// 逐层执行，记录每个非 Sequential 模块的输出
+func (s *ModelSummary) walk(name string, m Module, x *Tensor) (*Tensor, error) {
+	if seq, ok := m.(*Sequential); ok {
+		for _, c := range seq.NamedChildren() {
+			var err error
+			if x, err = s.walk(strings.TrimPrefix(name+"."+c.Name, "."), c.Module, x); err != nil {
+				return nil, err
+			}
+		}
+		return x, nil
+	}
+	y, err := m.Forward(x)
+	if err != nil {
+		return nil, fmt.Errorf("%s %T: %v", name, m, err)
+	}
+	typ := fmt.Sprintf("%T", m)
+	layer := LayerSummary{Name: name, Type: typ[strings.LastIndex(typ, ".")+1:], OutputShape: append([]int(nil), y.Shape()...)}
+	for _, p := range m.Parameters() {
+		layer.Params += p.Size()
+		if p.RequiresGrad {
+			layer.Trainable += p.Size()
+		}
+	}
+	s.Layers = append(s.Layers, layer)
+	s.ActivationBytes += 8 * y.Size()
+	return y, nil
+}
+
+// 字节数换算为 KB 或 MB
+func formatBytes(n int) string {
+	if n >= 1<<20 {
+		return fmt.Sprintf("%.2f MB", float64(n)/(1<<20))
+	}
+	return fmt.Sprintf("%.2f KB", float64(n)/(1<<10))
+}
+
+// Fprint 将摘要写成表格，数值列由 dubnp.Array.FormatMatrix 对齐
+func (s *ModelSummary) Fprint(w io.Writer) error {
+	_, err := io.WriteString(w, s.String())
+	return err
+}
+
+// String 返回摘要的表格与合计
+func (s *ModelSummary) String() string {
+	n := len(s.Layers)
+	params, outKB := dubnp.Zeros(n, 1), dubnp.Zeros(n, 1)
+	names, types, shapes := make([]string, n), make([]string, n), make([]string, n)
+	for i, l := range s.Layers {
+		params.Data[i] = float64(l.Params)
+		outKB.Data[i] = float64(8*shapeSize(l.OutputShape)) / 1024
+		names[i], types[i], shapes[i] = l.Name, l.Type, fmt.Sprint(l.OutputShape)
+	}
+	columns := [][]string{
+		append([]string{"Layer"}, names...),
+		append([]string{"Type"}, types...),
+		append([]string{"Output Shape"}, shapes...),
+		append([]string{"Param #"}, params.FormatMatrix(0)...),
+		append([]string{"Output KB"}, outKB.FormatMatrix(2)...),
+	}
+	widths := make([]int, len(columns))
+	total := 2 * (len(columns) - 1)
+	for c, col := range columns {
+		for _, cell := range col {
+			widths[c] = max(widths[c], len(cell))
+		}
+		total += widths[c]
+	}
+	var sb strings.Builder
+	line := strings.Repeat("-", total) + "\n"
+	for r := 0; r <= n; r++ {
+		for c, col := range columns {
+			if c > 0 {
+				sb.WriteString("  ")
+			}
+			// 文本列左对齐，数值列右对齐
+			if c < 3 {
+				fmt.Fprintf(&sb, "%-*s", widths[c], col[r])
+			} else {
+				fmt.Fprintf(&sb, "%*s", widths[c], col[r])
+			}
+		}
+		sb.WriteString("\n")
+		if r == 0 {
+			sb.WriteString(line)
+		}
+	}
+	sb.WriteString(line)
+	fmt.Fprintf(&sb, "参数总量 %d，可训练 %d，不可训练 %d\n", s.TotalParams, s.TrainableParams, s.TotalParams-s.TrainableParams)
+	fmt.Fprintf(&sb, "输入 %v: %s，前向/反向激活: %s，参数: %s，估计总计: %s\n",
+		s.InputShape, formatBytes(s.InputBytes), formatBytes(2*s.ActivationBytes), formatBytes(s.ParamBytes), formatBytes(s.TotalBytes()))
+	return sb.String()
+}
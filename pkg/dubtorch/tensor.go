@@ -0,0 +1,243 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// Tensor 带自动求导的张量，Data 为前向的值，Grad 为累加的梯度（仅叶子张量）
+type Tensor struct {
+	Data         *dubnp.Array
+	Grad         *dubnp.Array
+	RequiresGrad bool
+
+	node *node // 产生该张量的运算，叶子张量为 nil
+}
+
+// backwardFunc 根据输出的梯度计算每个输入的梯度，不需要梯度的输入可以返回 nil
+type backwardFunc func(grad *dubnp.Array) ([]*dubnp.Array, error)
+
+// node 是计算带（tape）上的一条记录
+type node struct {
+	op       string
+	seq      int64 // 记录的先后顺序，反向传播按 seq 从大到小执行
+	inputs   []*Tensor
+	backward backwardFunc
+	stack    string // 异常检测模式下记录的创建位置，为空时反向传播不检查
+}
+
+// 全局递增的记录序号
+var tapeSeq int64
+
+// NewTensor 用数组创建叶子张量
+func NewTensor(data *dubnp.Array, requiresGrad bool) *Tensor {
+	return &Tensor{Data: data, RequiresGrad: requiresGrad}
+}
+
+// FromSlice 用数据与形状创建叶子张量
+func FromSlice(data []float64, shape []int, requiresGrad bool) (*Tensor, error) {
+	array, err := dubnp.NewArray(data, shape)
+	if err != nil {
+		return nil, err
+	}
+	return NewTensor(array, requiresGrad), nil
+}
+
+// Scalar 创建标量（0 维）张量
+func Scalar(value float64) *Tensor {
+	return NewTensor(&dubnp.Array{Data: []float64{value}, Shape: []int{}}, false)
+}
+
+// 记录一次运算：只要有输入需要梯度且梯度记录开启，结果就需要梯度并挂上反向函数
+func newResult(op string, data *dubnp.Array, inputs []*Tensor, backward backwardFunc) *Tensor {
+	t := &Tensor{Data: data}
+	var stack string
+	if IsAnomalyEnabled() {
+		stack = callerStack(1)
+		checkForward(op, data.Data, inputs, stack)
+	}
+	for _, in := range inputs {
+		if in.RequiresGrad {
+			t.RequiresGrad = true
+			break
+		}
+	}
+	// 查找 NoGrad 需要遍历调用栈，只在需要梯度时检查
+	if t.RequiresGrad && !IsGradEnabled() {
+		t.RequiresGrad = false
+	}
+	if t.RequiresGrad {
+		t.node = &node{
+			op:       op,
+			seq:      atomic.AddInt64(&tapeSeq, 1),
+			inputs:   inputs,
+			backward: backward,
+			stack:    stack,
+		}
+	}
+	return t
+}
+
+// Shape 返回张量的形状
+func (t *Tensor) Shape() []int {
+	return t.Data.Shape
+}
+
+// Size 返回元素总数
+func (t *Tensor) Size() int {
+	return len(t.Data.Data)
+}
+
+// Item 返回只有一个元素的张量的值
+func (t *Tensor) Item() (float64, error) {
+	if len(t.Data.Data) != 1 {
+		return 0, fmt.Errorf("形状为 %v 的张量不能转换为标量", t.Data.Shape)
+	}
+	return t.Data.Data[0], nil
+}
+
+// IsLeaf 是否为叶子张量（由用户创建而不是运算产生）
+func (t *Tensor) IsLeaf() bool {
+	return t.node == nil
+}
+
+// Op 返回产生该张量的运算名称，叶子张量返回空字符串
+func (t *Tensor) Op() string {
+	if t.node == nil {
+		return ""
+	}
+	return t.node.op
+}
+
+// Detach 返回共享数据、但脱离计算图且不需要梯度的张量
+func (t *Tensor) Detach() *Tensor {
+	return &Tensor{Data: t.Data}
+}
+
+// ZeroGrad 清空累加的梯度
+func (t *Tensor) ZeroGrad() {
+	t.Grad = nil
+}
+
+// String 返回形状与运算信息，便于调试
+func (t *Tensor) String() string {
+	if t.node != nil {
+		return fmt.Sprintf("Tensor(shape=%v, op=%s)", t.Data.Shape, t.node.op)
+	}
+	return fmt.Sprintf("Tensor(shape=%v, requiresGrad=%v)", t.Data.Shape, t.RequiresGrad)
+}
+
+// Backward 从标量张量开始反向传播，梯度累加到需要梯度的叶子张量的 Grad 上
+func (t *Tensor) Backward() error {
+	if len(t.Data.Data) != 1 {
+		return fmt.Errorf("只能对标量调用 Backward，当前形状为 %v，请使用 BackwardWithGrad", t.Data.Shape)
+	}
+	return t.BackwardWithGrad(dubnp.Ones(t.Data.Shape...))
+}
+
+// BackwardWithGrad 以 grad 作为输出的梯度开始反向传播
+func (t *Tensor) BackwardWithGrad(grad *dubnp.Array) error {
+	if !t.RequiresGrad {
+		return errors.New("张量不需要梯度，无法反向传播")
+	}
+	if !sameShape(grad.Shape, t.Data.Shape) || len(grad.Data) != len(t.Data.Data) {
+		return fmt.Errorf("梯度形状 %v 与张量形状 %v 不一致", grad.Shape, t.Data.Shape)
+	}
+
+	// 收集计算带上所有可达的记录
+	var tensors []*Tensor
+	visited := map[*Tensor]bool{}
+	stack := []*Tensor{t}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if visited[cur] {
+			continue
+		}
+		visited[cur] = true
+		tensors = append(tensors, cur)
+		if cur.node != nil {
+			for _, in := range cur.node.inputs {
+				if in.RequiresGrad && !visited[in] {
+					stack = append(stack, in)
+				}
+			}
+		}
+	}
+	// 按记录顺序倒序执行，保证每个张量的梯度在使用前已累加完毕
+	sort.Slice(tensors, func(i, j int) bool {
+		return seqOf(tensors[i]) > seqOf(tensors[j])
+	})
+
+	grads := map[*Tensor]*dubnp.Array{t: grad}
+	// 反向函数只在 dubnp.Array 上计算，不产生新的记录，因此不需要关闭梯度记录
+	for _, cur := range tensors {
+		g := grads[cur]
+		if g == nil {
+			continue
+		}
+		delete(grads, cur)
+
+		var err error
+		if cur.node == nil {
+			err = accumulateGrad(cur, g)
+		} else {
+			err = propagate(cur, g, grads)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 叶子张量的记录序号视为 0，最后处理
+func seqOf(t *Tensor) int64 {
+	if t.node == nil {
+		return 0
+	}
+	return t.node.seq
+}
+
+// 执行一条记录的反向函数，并把梯度加到各输入上
+func propagate(t *Tensor, g *dubnp.Array, grads map[*Tensor]*dubnp.Array) error {
+	inputGrads, err := t.node.backward(g)
+	if err != nil {
+		return fmt.Errorf("%s 反向传播失败: %v", t.node.op, err)
+	}
+	if t.node.stack != "" {
+		if err := checkBackward(t.node, g.Data, inputGrads); err != nil {
+			return err
+		}
+	}
+	for i, in := range t.node.inputs {
+		if !in.RequiresGrad || inputGrads[i] == nil {
+			continue
+		}
+		ig := inputGrads[i]
+		if len(ig.Data) != len(in.Data.Data) {
+			return fmt.Errorf("%s 的第 %d 个输入梯度形状 %v 与输入形状 %v 不一致", t.node.op, i, ig.Shape, in.Data.Shape)
+		}
+		if prev, ok := grads[in]; ok {
+			// 不原地累加，反向函数返回的数组可能与其他梯度共享数据
+			if ig, err = prev.Add(ig); err != nil {
+				return err
+			}
+		}
+		grads[in] = ig
+	}
+	return nil
+}
+
+// 将梯度累加到叶子张量的 Grad 上
+func accumulateGrad(t *Tensor, g *dubnp.Array) error {
+	if t.Grad == nil {
+		t.Grad = &dubnp.Array{Data: append([]float64(nil), g.Data...), Shape: append([]int(nil), t.Data.Shape...)}
+		return nil
+	}
+	return t.Grad.AddInPlace(g)
+}
@@ -0,0 +1,500 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// 静态图中的值，data 不为 nil 时为常量
+type graphValue struct {
+	shape []int
+	data  []float64
+}
+
+// 静态图中的算子。kind 为 linear、conv、transpose、act、affine、softmax、maxpool、avgpool、
+// reshape、identity 或 module（无法编译、运行时直接调用 Forward 的模块）
+type graphOp struct {
+	kind   string
+	name   string // 模块在模型中的路径
+	in     []int  // 数据输入在前，常量参数在后
+	out    int
+	acts   []string // 依次作用在输出上的逐元素激活，act 算子与融合进来的激活都记录在这里
+	axis   int
+	groups int
+	conv   convGeom
+	pool   poolGeom
+	module Module
+}
+
+// GraphStats 静态图的优化统计
+type GraphStats struct {
+	Ops         int // 优化后的算子个数
+	Folded      int // 常量折叠在编译时执行的算子个数
+	Eliminated  int // 删除的恒等与无用算子个数
+	Fused       int // 融合进前一个算子的激活个数
+	Buffers     int // 复用后的中间缓冲区个数
+	BufferBytes int // 中间缓冲区与卷积展开区占用的字节数
+	NaiveBytes  int // 每个中间结果与卷积展开区单独分配时占用的字节数
+}
+
+// Graph 由 Trace 得到的静态计算图，只用于推理。
+// 图保存参数的副本，中间结果使用编译时规划好的缓冲区，Run 不在算子之间分配内存。
+// 同一个 Graph 可被多个 goroutine 使用，Run 之间互斥执行
+type Graph struct {
+	mu             sync.Mutex
+	values         []graphValue
+	ops            []graphOp
+	input, output  int
+	data           [][]float64 // 运行时每个值的数据：常量、缓冲区中的切片或输入
+	bound          []int       // 与输入共享数据的值
+	scratch        []float64   // 卷积 im2col 的展开区，所有卷积共用
+	stats          GraphStats
+	uses, producer []int
+}
+
+// Trace 以推理模式在示例输入上逐层执行模型，记录为静态图，并依次做常量折叠、删除无用算子、
+// 激活融合与缓冲区规划。Sequential（含嵌套）中的 Linear、Conv（非转置）、激活、Softmax、
+// 池化、Flatten、Dropout 与 BatchNorm1d 被编译为图中的算子，其余模块在运行时直接调用 Forward。
+// 图的输入形状固定为示例的形状，结束后恢复模型原来的训练/推理模式
+func Trace(m Module, example *Tensor) (*Graph, error) {
+	if m == nil || example == nil {
+		return nil, errors.New("模型与示例输入不能为空")
+	}
+	training := m.IsTraining()
+	m.Eval()
+	if training {
+		defer m.Train()
+	}
+	g := &Graph{}
+	g.input = g.value(example.Shape(), nil)
+	var err error
+	NoGrad(func() {
+		g.output, _, err = g.trace("", m, g.input, example)
+	})
+	if err != nil {
+		return nil, err
+	}
+	g.foldConstants()
+	g.foldAffine()
+	g.eliminate()
+	g.fuse()
+	g.plan()
+	return g, nil
+}
+
+func (g *Graph) value(shape []int, data []float64) int {
+	g.values = append(g.values, graphValue{shape: append([]int(nil), shape...), data: data})
+	return len(g.values) - 1
+}
+
+// 保存参数的副本作为常量
+func (g *Graph) constant(a *dubnp.Array) int {
+	return g.value(a.Shape, append([]float64(nil), a.Data...))
+}
+
+func (g *Graph) isConstant(v int) bool {
+	return g.values[v].data != nil
+}
+
+func (g *Graph) size(v int) int {
+	return shapeSize(g.values[v].shape)
+}
+
+// 逐层执行模块并记录算子，返回输出值的编号与示例输出
+func (g *Graph) trace(name string, m Module, x int, ex *Tensor) (int, *Tensor, error) {
+	if s, ok := m.(*Sequential); ok {
+		for _, c := range s.NamedChildren() {
+			var err error
+			if x, ex, err = g.trace(strings.TrimPrefix(name+"."+c.Name, "."), c.Module, x, ex); err != nil {
+				return 0, nil, err
+			}
+		}
+		return x, ex, nil
+	}
+	y, err := m.Forward(ex)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s %T: %v", name, m, err)
+	}
+	op := graphOp{kind: "module", name: name, in: []int{x}, out: g.value(y.Shape(), nil), module: m}
+	switch l := m.(type) {
+	case *Linear:
+		// 权重转置为 (in, out) 后逐行连续访问，转置由常量折叠在编译时完成
+		wt := g.value([]int{l.InFeatures, l.OutFeatures}, nil)
+		g.ops = append(g.ops, graphOp{kind: "transpose", name: name, in: []int{g.constant(l.Weight.Data)}, out: wt})
+		op.kind, op.in = "linear", append(op.in, wt)
+		if l.Bias != nil {
+			op.in = append(op.in, g.constant(l.Bias.Data))
+		}
+	case *Conv:
+		if l.Transposed {
+			break
+		}
+		p, err := l.Options.resolve(l.Dims)
+		if err != nil {
+			return 0, nil, err
+		}
+		xs, ws, ys := ex.Shape(), l.Weight.Shape(), y.Shape()
+		geom := convGeom{
+			c: ws[1], h: 1, w: xs[len(xs)-1], kh: 1, kw: ws[len(ws)-1],
+			sh: p.stride[0], sw: p.stride[1], ph: p.padding[0], pw: p.padding[1], dh: p.dilation[0], dw: p.dilation[1],
+			oh: 1, ow: ys[len(ys)-1],
+		}
+		if l.Dims == 2 {
+			geom.h, geom.kh, geom.oh = xs[2], ws[2], ys[2]
+		}
+		op.kind, op.conv, op.groups = "conv", geom, p.groups
+		op.in = append(op.in, g.constant(l.Weight.Data))
+		if l.Bias != nil {
+			op.in = append(op.in, g.constant(l.Bias.Data))
+		}
+	case *ReLU:
+		op.kind, op.acts = "act", []string{"relu"}
+	case *Tanh:
+		op.kind, op.acts = "act", []string{"tanh"}
+	case *Sigmoid:
+		op.kind, op.acts = "act", []string{"sigmoid"}
+	case *GELU:
+		op.kind, op.acts = "act", []string{"gelu"}
+	case *Softmax:
+		op.kind, op.axis = "softmax", l.Axis
+		if op.axis < 0 {
+			op.axis += len(y.Shape())
+		}
+	case *Flatten:
+		op.kind = "reshape"
+	case *Dropout:
+		op.kind = "identity"
+	case *MaxPool, *AvgPool:
+		opts, dims, kind := PoolOptions{}, 0, "maxpool"
+		if mp, ok := l.(*MaxPool); ok {
+			opts, dims = mp.Options, mp.Dims
+		} else {
+			ap := l.(*AvgPool)
+			opts, dims, kind = ap.Options, ap.Dims, "avgpool"
+		}
+		if op.pool, err = opts.resolve(dims, ex.Shape()); err != nil {
+			return 0, nil, err
+		}
+		op.kind = kind
+	case *BatchNorm1d:
+		// 推理时的批归一化是逐通道的 y = x*scale + shift
+		scale, shift := dubnp.Zeros(l.NumFeatures), dubnp.Zeros(l.NumFeatures)
+		for c := range scale.Data {
+			scale.Data[c] = l.Weight.Data.Data[c] / math.Sqrt(l.RunningVar.Data[c]+l.Eps)
+			shift.Data[c] = l.Bias.Data.Data[c] - l.RunningMean.Data[c]*scale.Data[c]
+		}
+		op.kind, op.in = "affine", append(op.in, g.constant(scale), g.constant(shift))
+	}
+	g.ops = append(g.ops, op)
+	return op.out, y, nil
+}
+
+// 统计每个值被使用的次数（图的输出算一次）与产生它的算子下标
+func (g *Graph) index() {
+	g.uses, g.producer = make([]int, len(g.values)), make([]int, len(g.values))
+	for v := range g.producer {
+		g.producer[v] = -1
+	}
+	for i, op := range g.ops {
+		for _, v := range op.in {
+			g.uses[v]++
+		}
+		g.producer[op.out] = i
+	}
+	g.uses[g.output]++
+}
+
+// 常量折叠：输入全为常量的算子在编译时执行，输出成为新的常量
+func (g *Graph) foldConstants() {
+	g.data = make([][]float64, len(g.values))
+	ops := g.ops[:0]
+	for _, op := range g.ops {
+		constant := op.kind != "module"
+		for _, v := range op.in {
+			constant = constant && g.isConstant(v)
+		}
+		if !constant {
+			ops = append(ops, op)
+			continue
+		}
+		for _, v := range op.in {
+			g.data[v] = g.values[v].data
+		}
+		g.data[op.out] = make([]float64, g.size(op.out))
+		// 常量上的算子只会是已知的核函数，不会出错
+		_ = g.runOp(&op)
+		g.values[op.out].data = g.data[op.out]
+		g.stats.Folded++
+	}
+	g.ops = ops
+}
+
+// 将 BatchNorm1d 的逐通道仿射变换折叠进前面只被它使用的 Linear 或 Conv 的权重与偏置
+func (g *Graph) foldAffine() {
+	g.index()
+	removed := make([]bool, len(g.ops))
+	for i := range g.ops {
+		a := &g.ops[i]
+		if a.kind != "affine" || g.producer[a.in[0]] < 0 {
+			continue
+		}
+		p := &g.ops[g.producer[a.in[0]]]
+		// Linear 只在通道位于最后一维，即输出为二维时可以折叠
+		linear := p.kind == "linear" && len(g.values[p.out].shape) == 2
+		if (!linear && p.kind != "conv") || len(p.acts) > 0 || g.uses[p.out] != 1 || !g.isConstant(p.in[1]) {
+			continue
+		}
+		scale, shift := g.values[a.in[1]].data, g.values[a.in[2]].data
+		w := g.values[p.in[1]].data
+		for j := range w {
+			if linear {
+				// 转置后的权重为 (in, out)
+				w[j] *= scale[j%len(scale)]
+			} else {
+				w[j] *= scale[j/(len(w)/len(scale))]
+			}
+		}
+		if len(p.in) > 2 {
+			b := g.values[p.in[2]].data
+			for c := range b {
+				b[c] = b[c]*scale[c] + shift[c]
+			}
+		} else {
+			p.in = append(p.in, a.in[2])
+		}
+		p.out = a.out
+		removed[i] = true
+		g.stats.Folded++
+	}
+	ops := g.ops[:0]
+	for i, op := range g.ops {
+		if !removed[i] {
+			ops = append(ops, op)
+		}
+	}
+	g.ops = ops
+}
+
+// 删除恒等算子、合并连续的 reshape，再删除输出不再被使用的算子
+func (g *Graph) eliminate() {
+	replace := make([]int, len(g.values))
+	for v := range replace {
+		replace[v] = v
+	}
+	source := map[int]int{} // reshape 输出 → 最初的输入
+	ops := g.ops[:0]
+	for _, op := range g.ops {
+		for j, v := range op.in {
+			op.in[j] = replace[v]
+		}
+		if op.kind == "reshape" {
+			if src, ok := source[op.in[0]]; ok {
+				op.in[0] = src
+			}
+			if sameShape(g.values[op.in[0]].shape, g.values[op.out].shape) {
+				op.kind = "identity"
+			} else {
+				source[op.out] = op.in[0]
+			}
+		}
+		if op.kind == "identity" {
+			replace[op.out] = op.in[0]
+			g.stats.Eliminated++
+			continue
+		}
+		ops = append(ops, op)
+	}
+	g.output = replace[g.output]
+
+	live := make([]bool, len(g.values))
+	live[g.output] = true
+	keep := make([]bool, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		if !live[ops[i].out] {
+			g.stats.Eliminated++
+			continue
+		}
+		keep[i] = true
+		for _, v := range ops[i].in {
+			live[v] = true
+		}
+	}
+	g.ops = g.ops[:0]
+	for i, op := range ops {
+		if keep[i] {
+			g.ops = append(g.ops, op)
+		}
+	}
+}
+
+// 可以在输出上附加逐元素激活的算子
+var fusible = map[string]bool{"linear": true, "conv": true, "affine": true, "act": true, "softmax": true, "maxpool": true, "avgpool": true}
+
+// 激活融合：只被激活使用的算子在写出结果时直接计算激活，省去一次读写与一个中间结果
+func (g *Graph) fuse() {
+	g.index()
+	removed := make([]bool, len(g.ops))
+	for i := range g.ops {
+		a := &g.ops[i]
+		pi := g.producer[a.in[0]]
+		if a.kind != "act" || pi < 0 {
+			continue
+		}
+		p := &g.ops[pi]
+		if !fusible[p.kind] || g.uses[p.out] != 1 {
+			continue
+		}
+		p.acts = append(p.acts, a.acts...)
+		p.out = a.out
+		g.producer[a.out] = pi
+		removed[i] = true
+		g.stats.Fused++
+	}
+	ops := g.ops[:0]
+	for i, op := range g.ops {
+		if !removed[i] {
+			ops = append(ops, op)
+		}
+	}
+	g.ops = ops
+}
+
+// 逐元素且可以原地执行的算子，输出可以复用此后不再使用的输入缓冲区
+var inPlace = map[string]bool{"act": true, "affine": true, "softmax": true}
+
+// 缓冲区规划：reshape 的输出与输入共享存储；按每个存储最后一次被使用的位置计算生存期，
+// 生存期结束的缓冲区放回空闲列表，之后的中间结果按最合适的大小复用
+func (g *Graph) plan() {
+	root := make([]int, len(g.values))
+	for v := range root {
+		root[v] = v
+	}
+	for _, op := range g.ops {
+		if op.kind == "reshape" {
+			root[op.out] = root[op.in[0]]
+		}
+	}
+	last := map[int]int{}
+	for i, op := range g.ops {
+		for _, v := range op.in {
+			last[root[v]] = i
+		}
+	}
+	last[root[g.output]] = len(g.ops)
+
+	var caps, free []int
+	assigned := map[int]int{} // 存储 → 缓冲区
+	active := map[int]int{}   // 仍在生存期内的存储 → 缓冲区
+	for i, op := range g.ops {
+		if op.kind == "reshape" {
+			continue
+		}
+		size := g.size(op.out)
+		g.stats.NaiveBytes += 8 * size
+		slot := -1
+		if r := root[op.in[0]]; inPlace[op.kind] && last[r] == i && g.size(r) == size {
+			if s, ok := active[r]; ok {
+				slot = s
+				delete(active, r)
+			}
+		}
+		if slot < 0 && len(free) > 0 {
+			// 优先选容量足够的最小缓冲区，都不够时扩大最大的一个
+			best := 0
+			for j, s := range free {
+				fits, bestFits := caps[s] >= size, caps[free[best]] >= size
+				if (fits && (!bestFits || caps[s] < caps[free[best]])) || (!fits && !bestFits && caps[s] > caps[free[best]]) {
+					best = j
+				}
+			}
+			slot = free[best]
+			free = append(free[:best], free[best+1:]...)
+		}
+		if slot < 0 {
+			slot = len(caps)
+			caps = append(caps, 0)
+		}
+		caps[slot] = max(caps[slot], size)
+		assigned[op.out], active[op.out] = slot, slot
+		for _, v := range op.in {
+			if s, ok := active[root[v]]; ok && last[root[v]] == i {
+				free = append(free, s)
+				delete(active, root[v])
+			}
+		}
+		if op.kind == "conv" {
+			// 逐层执行时每个样本都单独分配展开区
+			n, k, P := g.values[op.in[0]].shape[0], op.conv.c*op.conv.kh*op.conv.kw, op.conv.oh*op.conv.ow
+			g.scratch = make([]float64, max(len(g.scratch), convWorkers(n)*k*P))
+			g.stats.NaiveBytes += 8 * n * k * P
+		}
+	}
+
+	slots := make([][]float64, len(caps))
+	for s, c := range caps {
+		slots[s] = make([]float64, c)
+		g.stats.BufferBytes += 8 * c
+	}
+	g.stats.BufferBytes += 8 * len(g.scratch)
+	g.stats.Buffers, g.stats.Ops = len(slots), len(g.ops)
+	g.data = make([][]float64, len(g.values))
+	g.bound = nil
+	for v := range g.values {
+		switch r := root[v]; {
+		case g.isConstant(r):
+			g.data[v] = g.values[r].data
+		case r == g.input:
+			g.bound = append(g.bound, v)
+		default:
+			if s, ok := assigned[r]; ok {
+				g.data[v] = slots[s][:g.size(v)]
+			}
+		}
+	}
+}
+
+// Stats 返回优化统计
+func (g *Graph) Stats() GraphStats {
+	return g.stats
+}
+
+// InputShape 返回图的输入形状
+func (g *Graph) InputShape() []int {
+	return append([]int(nil), g.values[g.input].shape...)
+}
+
+// String 按执行顺序列出算子，v 为运行时的值，c 为常量
+func (g *Graph) String() string {
+	name := func(v int) string {
+		if g.isConstant(v) {
+			return fmt.Sprintf("c%d", v)
+		}
+		return fmt.Sprintf("v%d", v)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "graph(%s %v) -> %s %v\n", name(g.input), g.values[g.input].shape, name(g.output), g.values[g.output].shape)
+	for _, op := range g.ops {
+		args := make([]string, len(op.in))
+		for j, v := range op.in {
+			args[j] = name(v)
+		}
+		kind := strings.Join(append([]string{op.kind}, op.acts...), "+")
+		if op.kind == "act" {
+			kind = strings.Join(op.acts, "+")
+		}
+		fmt.Fprintf(&sb, "  %s %v = %s(%s)", name(op.out), g.values[op.out].shape, kind, strings.Join(args, ", "))
+		if op.name != "" {
+			fmt.Fprintf(&sb, "  # %s", op.name)
+		}
+		sb.WriteString("\n")
+	}
+	s := g.stats
+	fmt.Fprintf(&sb, "%d 个算子，折叠 %d、删除 %d、融合 %d；%d 个缓冲区共 %d 字节（不复用时 %d 字节）",
+		s.Ops, s.Folded, s.Eliminated, s.Fused, s.Buffers, s.BufferBytes, s.NaiveBytes)
+	return sb.String()
+}
@@ -0,0 +1,472 @@
+package dubtorch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/logger"
+)
+
+// Optimizer Trainer 所需的最小优化器接口，optim 包中的优化器均满足该接口
+type Optimizer interface {
+	Step() error
+	ZeroGrad()
+	StateDict() map[string]*dubnp.Array
+	LoadStateDict(state map[string]*dubnp.Array) error
+}
+
+// ContextOptimizer 更新参数时需要通信的优化器（例如分布式训练），Trainer 以 Fit 的 ctx 调用 StepCtx 代替 Step
+type ContextOptimizer interface {
+	Optimizer
+	StepCtx(ctx context.Context) error
+}
+
+// LossFunc 由模型输出与目标计算标量损失，批次只有一个字段时 target 为 nil
+type LossFunc func(output, target *Tensor) (*Tensor, error)
+
+// Callback 训练过程中的回调，返回错误会终止训练
+type Callback interface {
+	OnEpochStart(t *Trainer, epoch int) error
+	// OnEpochEnd 的 logs 包含 "loss"、各指标以及带 "val_" 前缀的验证结果
+	OnEpochEnd(t *Trainer, epoch int, logs map[string]float64) error
+	// OnBatchEnd 在每个训练批次之后调用，loss 为该批次未缩放的损失
+	OnBatchEnd(t *Trainer, batch int, loss float64) error
+}
+
+// CallbackFuncs 由函数组成的回调，未设置的函数不执行
+type CallbackFuncs struct {
+	EpochStart func(t *Trainer, epoch int) error
+	EpochEnd   func(t *Trainer, epoch int, logs map[string]float64) error
+	BatchEnd   func(t *Trainer, batch int, loss float64) error
+}
+
+// OnEpochStart 调用 EpochStart
+func (c CallbackFuncs) OnEpochStart(t *Trainer, epoch int) error {
+	if c.EpochStart == nil {
+		return nil
+	}
+	return c.EpochStart(t, epoch)
+}
+
+// OnEpochEnd 调用 EpochEnd
+func (c CallbackFuncs) OnEpochEnd(t *Trainer, epoch int, logs map[string]float64) error {
+	if c.EpochEnd == nil {
+		return nil
+	}
+	return c.EpochEnd(t, epoch, logs)
+}
+
+// OnBatchEnd 调用 BatchEnd
+func (c CallbackFuncs) OnBatchEnd(t *Trainer, batch int, loss float64) error {
+	if c.BatchEnd == nil {
+		return nil
+	}
+	return c.BatchEnd(t, batch, loss)
+}
+
+// TrainerOptions 训练选项，零值表示默认值
+type TrainerOptions struct {
+	Epochs            int         // 训练轮数，默认 1
+	AccumulationSteps int         // 每累积多少个批次的梯度更新一次参数，默认 1
+	Validation        *DataLoader // 验证集，为 nil 时不验证
+	ValidateEvery     int         // 每隔多少轮验证一次，默认 1
+	CheckpointDir     string      // 检查点目录，为空时不保存
+	CheckpointEvery   int         // 每隔多少轮保存一次检查点，默认 1
+	Metrics           []Metric    // 在训练与验证时累计的指标
+	Callbacks         []Callback
+	Logger            *logger.Logger // 为 nil 时不输出日志
+	LogEvery          int            // 每隔多少个批次输出一次损失，为 0 时只输出每轮的汇总
+}
+
+// Trainer 在 DataLoader 上按轮训练模型。批次的第 0 个字段为输入，第 1 个字段（如果有）为目标
+type Trainer struct {
+	Model     Module
+	Optimizer Optimizer
+	Loss      LossFunc
+	Data      *DataLoader
+	Options   TrainerOptions
+
+	epoch   int // 下一轮的轮次
+	stopped bool
+	history []map[string]float64
+}
+
+// NewTrainer 创建 Trainer
+func NewTrainer(model Module, opt Optimizer, loss LossFunc, data *DataLoader, opts TrainerOptions) (*Trainer, error) {
+	if model == nil || opt == nil || loss == nil || data == nil {
+		return nil, errors.New("Trainer 需要模型、优化器、损失函数与训练数据")
+	}
+	if opts.Epochs < 0 || opts.AccumulationSteps < 0 || opts.ValidateEvery < 0 || opts.CheckpointEvery < 0 || opts.LogEvery < 0 {
+		return nil, errors.New("Trainer 的选项不能为负数")
+	}
+	if opts.Epochs == 0 {
+		opts.Epochs = 1
+	}
+	if opts.AccumulationSteps == 0 {
+		opts.AccumulationSteps = 1
+	}
+	if opts.ValidateEvery == 0 {
+		opts.ValidateEvery = 1
+	}
+	if opts.CheckpointEvery == 0 {
+		opts.CheckpointEvery = 1
+	}
+	return &Trainer{Model: model, Optimizer: opt, Loss: loss, Data: data, Options: opts}, nil
+}
+
+// Epoch 返回下一轮的轮次（从 0 开始）
+func (t *Trainer) Epoch() int {
+	return t.epoch
+}
+
+// Stop 请求在当前轮结束后停止训练，通常由回调调用
+func (t *Trainer) Stop() {
+	t.stopped = true
+}
+
+// History 返回已完成各轮的日志
+func (t *Trainer) History() []map[string]float64 {
+	return t.history
+}
+
+// Fit 从当前轮次训练到 Options.Epochs，ctx 取消时在批次之间返回
+func (t *Trainer) Fit(ctx context.Context) error {
+	t.stopped = false
+	for t.epoch < t.Options.Epochs && !t.stopped {
+		epoch := t.epoch
+		for _, c := range t.Options.Callbacks {
+			if err := c.OnEpochStart(t, epoch); err != nil {
+				return err
+			}
+		}
+		logs, err := t.trainEpoch(ctx, epoch)
+		if err != nil {
+			return fmt.Errorf("第 %d 轮训练失败: %v", epoch, err)
+		}
+		if t.Options.Validation != nil && (epoch+1)%t.Options.ValidateEvery == 0 {
+			val, err := t.Evaluate(ctx, t.Options.Validation)
+			if err != nil {
+				return fmt.Errorf("第 %d 轮验证失败: %v", epoch, err)
+			}
+			for k, v := range val {
+				logs["val_"+k] = v
+			}
+		}
+		t.epoch++
+		t.history = append(t.history, logs)
+		t.logf("epoch %d/%d %s", epoch+1, t.Options.Epochs, formatLogs(logs))
+
+		if t.Options.CheckpointDir != "" && (epoch+1)%t.Options.CheckpointEvery == 0 {
+			path := filepath.Join(t.Options.CheckpointDir, fmt.Sprintf("epoch_%d.safetensors", epoch+1))
+			if err := t.SaveCheckpoint(path); err != nil {
+				return err
+			}
+		}
+		for _, c := range t.Options.Callbacks {
+			if err := c.OnEpochEnd(t, epoch, logs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// 训练一轮，返回平均损失与训练指标
+func (t *Trainer) trainEpoch(ctx context.Context, epoch int) (map[string]float64, error) {
+	t.Model.Train()
+	t.Data.SetEpoch(epoch)
+	resetMetrics(t.Options.Metrics)
+	it := t.Data.Iter()
+	defer it.Close()
+
+	accum := t.Options.AccumulationSteps
+	t.Optimizer.ZeroGrad()
+	total, count, pending := 0.0, 0, 0
+	for batch := 0; ; batch++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		b, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		output, target, loss, err := t.forward(b)
+		if err != nil {
+			return nil, err
+		}
+		value, err := loss.Item()
+		if err != nil {
+			return nil, err
+		}
+		// 累积梯度时按步数缩放，使更新量与大批次的平均损失一致
+		if accum > 1 {
+			loss = loss.MulScalar(1 / float64(accum))
+		}
+		if err := loss.Backward(); err != nil {
+			return nil, err
+		}
+		if pending++; pending == accum {
+			if err := t.step(ctx); err != nil {
+				return nil, err
+			}
+			pending = 0
+		}
+		if err := updateMetrics(t.Options.Metrics, output, target); err != nil {
+			return nil, err
+		}
+
+		total += value
+		count++
+		if t.Options.LogEvery > 0 && (batch+1)%t.Options.LogEvery == 0 {
+			t.logf("epoch %d batch %d loss=%.6g", epoch+1, batch+1, value)
+		}
+		for _, c := range t.Options.Callbacks {
+			if err := c.OnBatchEnd(t, batch, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	// 最后不足 accum 个批次的梯度也要应用
+	if pending > 0 {
+		if err := t.step(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if count == 0 {
+		return nil, errors.New("训练数据为空")
+	}
+	logs := metricValues(t.Options.Metrics)
+	logs["loss"] = total / float64(count)
+	return logs, nil
+}
+
+func (t *Trainer) step(ctx context.Context) error {
+	var err error
+	if opt, ok := t.Optimizer.(ContextOptimizer); ok {
+		err = opt.StepCtx(ctx)
+	} else {
+		err = t.Optimizer.Step()
+	}
+	if err != nil {
+		return err
+	}
+	t.Optimizer.ZeroGrad()
+	return nil
+}
+
+// 对一个批次执行前向计算与损失
+func (t *Trainer) forward(b Batch) (output *Tensor, target *dubnp.Array, loss *Tensor, err error) {
+	if len(b) == 0 {
+		return nil, nil, nil, errors.New("批次为空")
+	}
+	if output, err = t.Model.Forward(NewTensor(b[0], false)); err != nil {
+		return nil, nil, nil, err
+	}
+	var targetTensor *Tensor
+	if len(b) > 1 {
+		target = b[1]
+		targetTensor = NewTensor(target, false)
+	}
+	if loss, err = t.Loss(output, targetTensor); err != nil {
+		return nil, nil, nil, err
+	}
+	return output, target, loss, nil
+}
+
+// Evaluate 在推理模式下计算 loader 上的平均损失与指标，结束后恢复原来的模式
+func (t *Trainer) Evaluate(ctx context.Context, loader *DataLoader) (map[string]float64, error) {
+	if t.Model.IsTraining() {
+		t.Model.Eval()
+		defer t.Model.Train()
+	}
+	resetMetrics(t.Options.Metrics)
+	it := loader.Iter()
+	defer it.Close()
+
+	total, count := 0.0, 0
+	var err error
+	NoGrad(func() {
+		for {
+			if err = ctx.Err(); err != nil {
+				return
+			}
+			var b Batch
+			if b, err = it.Next(); err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				return
+			}
+			var output, loss *Tensor
+			var target *dubnp.Array
+			if output, target, loss, err = t.forward(b); err != nil {
+				return
+			}
+			var value float64
+			if value, err = loss.Item(); err != nil {
+				return
+			}
+			if err = updateMetrics(t.Options.Metrics, output, target); err != nil {
+				return
+			}
+			total += value
+			count++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("验证数据为空")
+	}
+	logs := metricValues(t.Options.Metrics)
+	logs["loss"] = total / float64(count)
+	return logs, nil
+}
+
+// SaveCheckpoint 以 safetensors 格式保存模型与优化器状态，元数据中记录已完成的轮数
+func (t *Trainer) SaveCheckpoint(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	state := WithPrefix("model", t.Model.StateDict())
+	for k, v := range WithPrefix("optim", t.Optimizer.StateDict()) {
+		state[k] = v
+	}
+	meta := map[string]string{"epoch": strconv.Itoa(t.epoch)}
+	if err := SaveSafetensorsFile(path, state, SafetensorsOptions{Metadata: meta}); err != nil {
+		return err
+	}
+	t.logf("保存检查点 %s", path)
+	return nil
+}
+
+// LoadCheckpoint 严格加载 SaveCheckpoint 保存的检查点，之后的 Fit 从检查点的下一轮继续
+func (t *Trainer) LoadCheckpoint(path string) error {
+	state, meta, err := LoadSafetensorsFile(path)
+	if err != nil {
+		return err
+	}
+	epoch, err := strconv.Atoi(meta["epoch"])
+	if err != nil {
+		return fmt.Errorf("检查点缺少有效的 epoch 元数据: %v", err)
+	}
+	if _, err := t.Model.LoadStateDict(StripPrefix("model", state), true); err != nil {
+		return err
+	}
+	if err := t.Optimizer.LoadStateDict(StripPrefix("optim", state)); err != nil {
+		return err
+	}
+	t.epoch = epoch
+	return nil
+}
+
+func (t *Trainer) logf(format string, args ...any) {
+	if t.Options.Logger != nil {
+		t.Options.Logger.Info(fmt.Sprintf(format, args...))
+	}
+}
+
+func resetMetrics(metrics []Metric) {
+	for _, m := range metrics {
+		m.Reset()
+	}
+}
+
+func updateMetrics(metrics []Metric, output *Tensor, target *dubnp.Array) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	if target == nil {
+		return errors.New("计算指标需要目标字段")
+	}
+	for _, m := range metrics {
+		if err := m.Update(output.Data, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func metricValues(metrics []Metric) map[string]float64 {
+	logs := make(map[string]float64)
+	for _, m := range metrics {
+		for k, v := range m.Values() {
+			logs[k] = v
+		}
+	}
+	return logs
+}
+
+// 按键排序格式化日志，保证输出稳定
+func formatLogs(logs map[string]float64) string {
+	keys := make([]string, 0, len(logs))
+	for k := range logs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%s=%.6g", k, logs[k])
+	}
+	return strings.Join(parts, " ")
+}
+
+// EarlyStopping 监控的指标连续 Patience 轮没有改善时停止训练
+type EarlyStopping struct {
+	Monitor  string  // 监控的日志键，默认 "val_loss"
+	Maximize bool    // 指标越大越好（例如准确率）
+	Patience int     // 连续没有改善的轮数达到该值时停止
+	MinDelta float64 // 视为改善的最小变化量
+
+	Best      float64
+	BestEpoch int
+	wait      int
+	seen      bool
+}
+
+// OnEpochStart 不做处理
+func (e *EarlyStopping) OnEpochStart(t *Trainer, epoch int) error { return nil }
+
+// OnBatchEnd 不做处理
+func (e *EarlyStopping) OnBatchEnd(t *Trainer, batch int, loss float64) error { return nil }
+
+// OnEpochEnd 检查指标是否改善，本轮没有验证时跳过
+func (e *EarlyStopping) OnEpochEnd(t *Trainer, epoch int, logs map[string]float64) error {
+	monitor := e.Monitor
+	if monitor == "" {
+		monitor = "val_loss"
+	}
+	v, ok := logs[monitor]
+	if !ok {
+		return nil
+	}
+	improved := !e.seen
+	if e.seen {
+		if e.Maximize {
+			improved = v > e.Best+e.MinDelta
+		} else {
+			improved = v < e.Best-e.MinDelta
+		}
+	}
+	if improved {
+		e.Best, e.BestEpoch, e.wait, e.seen = v, epoch, 0, true
+		return nil
+	}
+	if e.wait++; e.wait >= e.Patience {
+		t.logf("%s 已有 %d 轮没有改善，在第 %d 轮提前停止", monitor, e.wait, epoch+1)
+		t.Stop()
+	}
+	return nil
+}
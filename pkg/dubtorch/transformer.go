@@ -0,0 +1,196 @@
+package dubtorch
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TransformerOptions Transformer 层的配置，零值表示默认值
+type TransformerOptions struct {
+	DimFeedforward int     // 前馈层隐藏维度，默认为 4*dModel
+	Dropout        float64 // 默认为 0，即不使用 dropout
+	GELU           bool    // 前馈层使用 GELU，默认为 ReLU
+	NormFirst      bool    // 为 true 时采用 Pre-LN：x + f(norm(x))
+	LayerNormEps   float64 // 默认为 1e-5
+}
+
+func (o TransformerOptions) withDefaults(dModel int) TransformerOptions {
+	if o.DimFeedforward == 0 {
+		o.DimFeedforward = 4 * dModel
+	}
+	if o.LayerNormEps == 0 {
+		o.LayerNormEps = 1e-5
+	}
+	return o
+}
+
+// 两层前馈网络 linear2(dropout(act(linear1(x))))
+type feedforward struct {
+	linear1, linear2 *Linear
+	gelu             bool
+}
+
+func newFeedforward(m *BaseModule, dModel int, opts TransformerOptions) feedforward {
+	f := feedforward{
+		linear1: NewLinear(dModel, opts.DimFeedforward, true),
+		linear2: NewLinear(opts.DimFeedforward, dModel, true),
+		gelu:    opts.GELU,
+	}
+	m.RegisterModule("linear1", f.linear1)
+	m.RegisterModule("linear2", f.linear2)
+	return f
+}
+
+func (f feedforward) forward(x *Tensor, p float64, training bool) (*Tensor, error) {
+	h, err := f.linear1.Forward(x)
+	if err != nil {
+		return nil, err
+	}
+	if f.gelu {
+		h = h.GELU()
+	} else {
+		h = h.ReLU()
+	}
+	if h, err = dropout(h, p, training); err != nil {
+		return nil, err
+	}
+	return f.linear2.Forward(h)
+}
+
+// 残差连接：Pre-LN 为 x + dropout(f(norm(x)))，Post-LN 为 norm(x + dropout(f(x)))
+func residual(x *Tensor, norm *LayerNorm, normFirst bool, p float64, training bool, f func(*Tensor) (*Tensor, error)) (*Tensor, error) {
+	in := x
+	var err error
+	if normFirst {
+		if in, err = norm.Forward(x); err != nil {
+			return nil, err
+		}
+	}
+	y, err := f(in)
+	if err != nil {
+		return nil, err
+	}
+	if y, err = dropout(y, p, training); err != nil {
+		return nil, err
+	}
+	if y, err = x.Add(y); err != nil {
+		return nil, err
+	}
+	if normFirst {
+		return y, nil
+	}
+	return norm.Forward(y)
+}
+
+func newNorm(dModel int, eps float64) *LayerNorm {
+	n := NewLayerNorm(dModel)
+	n.Eps = eps
+	return n
+}
+
+// TransformerEncoderLayer 自注意力加前馈网络的编码器层，输入为 (N, L, dModel)
+type TransformerEncoderLayer struct {
+	BaseModule
+	Options      TransformerOptions
+	SelfAttn     *MultiheadAttention
+	Norm1, Norm2 *LayerNorm
+	ff           feedforward
+}
+
+// NewTransformerEncoderLayer 创建编码器层
+func NewTransformerEncoderLayer(dModel, numHeads int, opts TransformerOptions) (*TransformerEncoderLayer, error) {
+	opts = opts.withDefaults(dModel)
+	attn, err := NewMultiheadAttention(dModel, numHeads, opts.Dropout)
+	if err != nil {
+		return nil, err
+	}
+	l := &TransformerEncoderLayer{Options: opts, SelfAttn: attn}
+	l.RegisterModule("self_attn", attn)
+	l.ff = newFeedforward(&l.BaseModule, dModel, opts)
+	l.Norm1 = newNorm(dModel, opts.LayerNormEps)
+	l.Norm2 = newNorm(dModel, opts.LayerNormEps)
+	l.RegisterModule("norm1", l.Norm1)
+	l.RegisterModule("norm2", l.Norm2)
+	return l, nil
+}
+
+// Forward 不带掩码的编码
+func (l *TransformerEncoderLayer) Forward(x *Tensor) (*Tensor, error) {
+	return l.Encode(x, AttentionOptions{})
+}
+
+// Encode 使用给定掩码编码，mask 作用于自注意力
+func (l *TransformerEncoderLayer) Encode(x *Tensor, mask AttentionOptions) (*Tensor, error) {
+	p, training := l.Options.Dropout, l.IsTraining()
+	x, err := residual(x, l.Norm1, l.Options.NormFirst, p, training, func(h *Tensor) (*Tensor, error) {
+		out, _, err := l.SelfAttn.Attend(h, h, h, mask)
+		return out, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TransformerEncoderLayer 自注意力: %v", err)
+	}
+	return residual(x, l.Norm2, l.Options.NormFirst, p, training, func(h *Tensor) (*Tensor, error) {
+		return l.ff.forward(h, p, training)
+	})
+}
+
+// TransformerDecoderLayer 解码器层：带掩码的自注意力、对编码器输出的交叉注意力以及前馈网络
+type TransformerDecoderLayer struct {
+	BaseModule
+	Options             TransformerOptions
+	SelfAttn, CrossAttn *MultiheadAttention
+	Norm1, Norm2, Norm3 *LayerNorm
+	ff                  feedforward
+}
+
+// NewTransformerDecoderLayer 创建解码器层
+func NewTransformerDecoderLayer(dModel, numHeads int, opts TransformerOptions) (*TransformerDecoderLayer, error) {
+	opts = opts.withDefaults(dModel)
+	self, err := NewMultiheadAttention(dModel, numHeads, opts.Dropout)
+	if err != nil {
+		return nil, err
+	}
+	cross, err := NewMultiheadAttention(dModel, numHeads, opts.Dropout)
+	if err != nil {
+		return nil, err
+	}
+	l := &TransformerDecoderLayer{Options: opts, SelfAttn: self, CrossAttn: cross}
+	l.RegisterModule("self_attn", self)
+	l.RegisterModule("multihead_attn", cross)
+	l.ff = newFeedforward(&l.BaseModule, dModel, opts)
+	l.Norm1 = newNorm(dModel, opts.LayerNormEps)
+	l.Norm2 = newNorm(dModel, opts.LayerNormEps)
+	l.Norm3 = newNorm(dModel, opts.LayerNormEps)
+	l.RegisterModule("norm1", l.Norm1)
+	l.RegisterModule("norm2", l.Norm2)
+	l.RegisterModule("norm3", l.Norm3)
+	return l, nil
+}
+
+// Forward 解码器需要编码器输出，请使用 Decode
+func (l *TransformerDecoderLayer) Forward(x *Tensor) (*Tensor, error) {
+	return nil, errors.New("TransformerDecoderLayer 需要 memory，请使用 Decode")
+}
+
+// Decode tgt 为 (N, L, dModel)，memory 为编码器输出 (N, S, dModel)；
+// tgtMask 作用于自注意力（通常设置 Causal），memoryMask 作用于交叉注意力
+func (l *TransformerDecoderLayer) Decode(tgt, memory *Tensor, tgtMask, memoryMask AttentionOptions) (*Tensor, error) {
+	p, training, normFirst := l.Options.Dropout, l.IsTraining(), l.Options.NormFirst
+	x, err := residual(tgt, l.Norm1, normFirst, p, training, func(h *Tensor) (*Tensor, error) {
+		out, _, err := l.SelfAttn.Attend(h, h, h, tgtMask)
+		return out, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TransformerDecoderLayer 自注意力: %v", err)
+	}
+	x, err = residual(x, l.Norm2, normFirst, p, training, func(h *Tensor) (*Tensor, error) {
+		out, _, err := l.CrossAttn.Attend(h, memory, memory, memoryMask)
+		return out, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TransformerDecoderLayer 交叉注意力: %v", err)
+	}
+	return residual(x, l.Norm3, normFirst, p, training, func(h *Tensor) (*Tensor, error) {
+		return l.ff.forward(h, p, training)
+	})
+}
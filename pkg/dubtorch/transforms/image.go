@@ -0,0 +1,163 @@
+package transforms
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+)
+
+// 将形状拆分为前导维度的总大小与最后两维 (H, W)
+func spatial(x *dubnp.Array) (planes, h, w int, err error) {
+	n := len(x.Shape)
+	if n < 2 {
+		return 0, 0, 0, fmt.Errorf("图像变换期望输入至少为二维 (..., H, W)，输入形状为 %v", x.Shape)
+	}
+	h, w = x.Shape[n-2], x.Shape[n-1]
+	if h*w == 0 {
+		return 0, h, w, nil
+	}
+	return len(x.Data) / (h * w), h, w, nil
+}
+
+// 返回把最后两维替换为 (h, w) 的形状
+func withSpatial(shape []int, h, w int) []int {
+	out := append([]int(nil), shape...)
+	out[len(out)-2], out[len(out)-1] = h, w
+	return out
+}
+
+// RandomCrop 先在四周补 Padding 个 0，再随机裁剪出 Height x Width 的区域
+type RandomCrop struct {
+	Height, Width, Padding int
+}
+
+// Apply 执行随机裁剪
+func (c *RandomCrop) Apply(x *dubnp.Array, r *rand.Rand) (*dubnp.Array, error) {
+	planes, h, w, err := spatial(x)
+	if err != nil {
+		return nil, err
+	}
+	p := c.Padding
+	if c.Height <= 0 || c.Width <= 0 || p < 0 || c.Height > h+2*p || c.Width > w+2*p {
+		return nil, fmt.Errorf("无法从 %dx%d（补齐 %d）中裁剪 %dx%d", h, w, p, c.Height, c.Width)
+	}
+	top := r.Intn(h+2*p-c.Height+1) - p
+	left := r.Intn(w+2*p-c.Width+1) - p
+	out := dubnp.Zeros(withSpatial(x.Shape, c.Height, c.Width)...)
+	for k := 0; k < planes; k++ {
+		src := x.Data[k*h*w : (k+1)*h*w]
+		dst := out.Data[k*c.Height*c.Width : (k+1)*c.Height*c.Width]
+		for y := 0; y < c.Height; y++ {
+			sy := top + y
+			if sy < 0 || sy >= h {
+				continue
+			}
+			for xx := 0; xx < c.Width; xx++ {
+				if sx := left + xx; sx >= 0 && sx < w {
+					dst[y*c.Width+xx] = src[sy*w+sx]
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// RandomFlip 以概率 P 水平翻转（Vertical 为 true 时垂直翻转），P 必须显式设置，为 0 时从不翻转
+type RandomFlip struct {
+	P        float64
+	Vertical bool
+}
+
+// Apply 执行随机翻转
+func (f *RandomFlip) Apply(x *dubnp.Array, r *rand.Rand) (*dubnp.Array, error) {
+	planes, h, w, err := spatial(x)
+	if err != nil {
+		return nil, err
+	}
+	if f.P < 0 || f.P > 1 {
+		return nil, fmt.Errorf("翻转概率必须在 [0, 1] 之间: %v", f.P)
+	}
+	if r.Float64() >= f.P {
+		return x, nil
+	}
+	out := dubnp.Zeros(x.Shape...)
+	for k := 0; k < planes; k++ {
+		base := k * h * w
+		for y := 0; y < h; y++ {
+			for xx := 0; xx < w; xx++ {
+				sy, sx := y, w-1-xx
+				if f.Vertical {
+					sy, sx = h-1-y, xx
+				}
+				out.Data[base+y*w+xx] = x.Data[base+sy*w+sx]
+			}
+		}
+	}
+	return out, nil
+}
+
+// ResizeMode 缩放的插值方式
+type ResizeMode int
+
+const (
+	// ResizeBilinear 双线性插值，采用像素中心对齐（对应 PyTorch 的 align_corners=False）
+	ResizeBilinear ResizeMode = iota
+	// ResizeNearest 最近邻插值
+	ResizeNearest
+)
+
+// Resize 将最后两维缩放为 Height x Width
+type Resize struct {
+	Height, Width int
+	Mode          ResizeMode
+}
+
+// Apply 执行缩放
+func (s *Resize) Apply(x *dubnp.Array, _ *rand.Rand) (*dubnp.Array, error) {
+	planes, h, w, err := spatial(x)
+	if err != nil {
+		return nil, err
+	}
+	if s.Height <= 0 || s.Width <= 0 || h == 0 || w == 0 {
+		return nil, fmt.Errorf("无法将 %dx%d 缩放为 %dx%d", h, w, s.Height, s.Width)
+	}
+	ys := resizeAxis(h, s.Height, s.Mode)
+	xs := resizeAxis(w, s.Width, s.Mode)
+	out := dubnp.Zeros(withSpatial(x.Shape, s.Height, s.Width)...)
+	for k := 0; k < planes; k++ {
+		src := x.Data[k*h*w : (k+1)*h*w]
+		dst := out.Data[k*s.Height*s.Width : (k+1)*s.Height*s.Width]
+		for y, wy := range ys {
+			for xx, wx := range xs {
+				top := src[wy.i0*w+wx.i0]*(1-wx.t) + src[wy.i0*w+wx.i1]*wx.t
+				bottom := src[wy.i1*w+wx.i0]*(1-wx.t) + src[wy.i1*w+wx.i1]*wx.t
+				dst[y*s.Width+xx] = top*(1-wy.t) + bottom*wy.t
+			}
+		}
+	}
+	return out, nil
+}
+
+// 输出位置在输入中的两个相邻下标与插值权重
+type resizeWeight struct {
+	i0, i1 int
+	t      float64
+}
+
+func resizeAxis(in, out int, mode ResizeMode) []resizeWeight {
+	scale := float64(in) / float64(out)
+	weights := make([]resizeWeight, out)
+	for i := range weights {
+		if mode == ResizeNearest {
+			j := min(int(math.Floor(float64(i)*scale)), in-1)
+			weights[i] = resizeWeight{j, j, 0}
+			continue
+		}
+		src := math.Max((float64(i)+0.5)*scale-0.5, 0)
+		j := min(int(src), in-1)
+		weights[i] = resizeWeight{j, min(j+1, in-1), src - float64(j)}
+	}
+	return weights
+}
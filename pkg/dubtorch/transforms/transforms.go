@@ -0,0 +1,217 @@
+// Package transforms 提供样本级的数据变换与数据增强。
+// 随机变换从调用方传入的 *rand.Rand 取随机数，Dataset 按 (Seed, 轮次, 下标) 为每个样本派生随机数生成器，
+// 因此结果与 DataLoader 的工作者数量、加载顺序无关，固定种子即可复现
+package transforms
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync/atomic"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+)
+
+// Transform 对单个数组做变换，不应修改输入
+type Transform interface {
+	Apply(x *dubnp.Array, r *rand.Rand) (*dubnp.Array, error)
+}
+
+// Func 将函数包装为 Transform
+type Func func(x *dubnp.Array, r *rand.Rand) (*dubnp.Array, error)
+
+// Apply 调用函数本身
+func (f Func) Apply(x *dubnp.Array, r *rand.Rand) (*dubnp.Array, error) {
+	return f(x, r)
+}
+
+// Compose 依次执行的变换序列
+type Compose []Transform
+
+// Apply 依次执行每个变换
+func (c Compose) Apply(x *dubnp.Array, r *rand.Rand) (*dubnp.Array, error) {
+	var err error
+	for i, t := range c {
+		if x, err = t.Apply(x, r); err != nil {
+			return nil, fmt.Errorf("第 %d 个变换: %v", i, err)
+		}
+	}
+	return x, nil
+}
+
+// Options Dataset 的选项
+type Options struct {
+	Seed  int64 // 随机种子
+	Field int   // 被变换的样本字段，默认 0（输入）
+}
+
+// Dataset 在读取样本时对指定字段做变换，实现 dubtorch.EpochSetter，
+// 因此每轮的随机增强不同，但同一 (Seed, 轮次, 下标) 的结果始终相同
+type Dataset struct {
+	base      dubtorch.Dataset
+	transform Transform
+	opts      Options
+	epoch     atomic.Int64
+}
+
+// NewDataset 包装数据集
+func NewDataset(base dubtorch.Dataset, t Transform, opts Options) (*Dataset, error) {
+	if base == nil || t == nil {
+		return nil, errors.New("数据集与变换不能为空")
+	}
+	if opts.Field < 0 {
+		return nil, fmt.Errorf("无效的字段 %d", opts.Field)
+	}
+	return &Dataset{base: base, transform: t, opts: opts}, nil
+}
+
+// Len 返回样本个数
+func (d *Dataset) Len() int {
+	return d.base.Len()
+}
+
+// SetEpoch 由 DataLoader 在每轮开始时调用
+func (d *Dataset) SetEpoch(epoch int) {
+	d.epoch.Store(int64(epoch))
+}
+
+// Get 读取样本并变换，返回新的样本，不修改底层数据集
+func (d *Dataset) Get(i int) (dubtorch.Sample, error) {
+	sample, err := d.base.Get(i)
+	if err != nil {
+		return nil, err
+	}
+	if d.opts.Field >= len(sample) {
+		return nil, fmt.Errorf("样本只有 %d 个字段，无法变换第 %d 个", len(sample), d.opts.Field)
+	}
+	r := rand.New(rand.NewSource(sampleSeed(d.opts.Seed, d.epoch.Load(), int64(i))))
+	x, err := d.transform.Apply(sample[d.opts.Field], r)
+	if err != nil {
+		return nil, fmt.Errorf("样本 %d: %v", i, err)
+	}
+	out := append(dubtorch.Sample(nil), sample...)
+	out[d.opts.Field] = x
+	return out, nil
+}
+
+// 用 splitmix64 混合种子、轮次与下标，相邻的下标得到不相关的随机序列
+func sampleSeed(seed, epoch, index int64) int64 {
+	z := uint64(seed)
+	for _, v := range []int64{epoch, index} {
+		z += 0x9e3779b97f4a7c15 + uint64(v)
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		z ^= z >> 31
+	}
+	return int64(z)
+}
+
+// Normalize 按通道归一化 (x - mean[c]) / std[c]，输入形状为 (C, ...)
+type Normalize struct {
+	Mean, Std []float64
+}
+
+// NewNormalize 创建按通道归一化，std 必须为正数
+func NewNormalize(mean, std []float64) (*Normalize, error) {
+	if len(mean) == 0 || len(mean) != len(std) {
+		return nil, fmt.Errorf("mean 与 std 的长度必须相同且非零: %d 与 %d", len(mean), len(std))
+	}
+	for _, s := range std {
+		if s <= 0 {
+			return nil, fmt.Errorf("std 必须为正数: %v", std)
+		}
+	}
+	return &Normalize{Mean: mean, Std: std}, nil
+}
+
+// Apply 执行归一化
+func (n *Normalize) Apply(x *dubnp.Array, _ *rand.Rand) (*dubnp.Array, error) {
+	if len(x.Shape) == 0 || x.Shape[0] != len(n.Mean) {
+		return nil, fmt.Errorf("Normalize 期望 %d 个通道，输入形状为 %v", len(n.Mean), x.Shape)
+	}
+	out := x.Copy()
+	plane := len(x.Data) / len(n.Mean)
+	for c := range n.Mean {
+		for k := c * plane; k < (c+1)*plane; k++ {
+			out.Data[k] = (out.Data[k] - n.Mean[c]) / n.Std[c]
+		}
+	}
+	return out, nil
+}
+
+// GaussianNoise 以概率 P 加上 N(0, Std^2) 的噪声，P 必须显式设置，为 0 时从不加噪声
+type GaussianNoise struct {
+	Std, P float64
+}
+
+// Apply 注入噪声
+func (g *GaussianNoise) Apply(x *dubnp.Array, r *rand.Rand) (*dubnp.Array, error) {
+	if g.Std < 0 || g.P < 0 || g.P > 1 {
+		return nil, fmt.Errorf("无效的噪声参数 Std=%v P=%v", g.Std, g.P)
+	}
+	if r.Float64() >= g.P {
+		return x, nil
+	}
+	out := x.Copy()
+	for i := range out.Data {
+		out.Data[i] += r.NormFloat64() * g.Std
+	}
+	return out, nil
+}
+
+// Standardize 逐特征标准化 (x - Mean) / Std，Mean 与 Std 的形状与单个样本相同
+type Standardize struct {
+	Mean, Std *dubnp.Array
+}
+
+// FitStandardize 遍历数据集的第 field 个字段，计算逐特征的均值与总体标准差；
+// 标准差为 0 的特征按 1 处理，避免除以 0
+func FitStandardize(ds dubtorch.Dataset, field int) (*Standardize, error) {
+	n := ds.Len()
+	if n == 0 {
+		return nil, errors.New("数据集为空")
+	}
+	var mean, m2 *dubnp.Array
+	// Welford 算法，逐样本更新均值与平方和，数值稳定
+	for i := 0; i < n; i++ {
+		sample, err := ds.Get(i)
+		if err != nil {
+			return nil, err
+		}
+		if field < 0 || field >= len(sample) {
+			return nil, fmt.Errorf("样本只有 %d 个字段，无法读取第 %d 个", len(sample), field)
+		}
+		x := sample[field]
+		if mean == nil {
+			mean, m2 = dubnp.Zeros(x.Shape...), dubnp.Zeros(x.Shape...)
+		} else if len(x.Data) != len(mean.Data) {
+			return nil, fmt.Errorf("样本 %d 的形状 %v 与之前的样本 %v 不一致", i, x.Shape, mean.Shape)
+		}
+		for k, v := range x.Data {
+			delta := v - mean.Data[k]
+			mean.Data[k] += delta / float64(i+1)
+			m2.Data[k] += delta * (v - mean.Data[k])
+		}
+	}
+	for k, v := range m2.Data {
+		m2.Data[k] = math.Sqrt(v / float64(n))
+		if m2.Data[k] == 0 {
+			m2.Data[k] = 1
+		}
+	}
+	return &Standardize{Mean: mean, Std: m2}, nil
+}
+
+// Apply 执行标准化
+func (s *Standardize) Apply(x *dubnp.Array, _ *rand.Rand) (*dubnp.Array, error) {
+	if len(x.Data) != len(s.Mean.Data) {
+		return nil, fmt.Errorf("Standardize 期望形状 %v，输入形状为 %v", s.Mean.Shape, x.Shape)
+	}
+	out := x.Copy()
+	for k := range out.Data {
+		out.Data[k] = (out.Data[k] - s.Mean.Data[k]) / s.Std.Data[k]
+	}
+	return out, nil
+}
@@ -0,0 +1 @@
+go build -o ./build/mnist ./cmd/mnist
\ No newline at end of file
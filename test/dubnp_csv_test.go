@@ -0,0 +1,95 @@
+package test
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+const sensorCSV = `# 传感器原始数据
+time,temp,humidity,label
+0,21.5,40,1
+1,NA,41,0
+2,22.0,,1
+`
+
+// 测试表头、注释、列选择与缺失值
+func TestLoadCSV(t *testing.T) {
+	a, err := dubnp.LoadCSV(strings.NewReader(sensorCSV), dubnp.CSVOptions{
+		Comment:       '#',
+		Header:        true,
+		ColumnNames:   []string{"temp", "humidity"},
+		MissingValues: []string{"NA"},
+	})
+	dubug.NoError(t, err)
+	if !dubug.Equal(a.Shape, []int{3, 2}) {
+		t.Fatalf("形状错误: %v", a.Shape)
+	}
+	if a.Data[0] != 21.5 || !math.IsNaN(a.Data[2]) || !math.IsNaN(a.Data[5]) || a.Data[4] != 22 {
+		t.Fatalf("数据错误: %v", a.Data)
+	}
+
+	// 按下标选择列并使用填充值
+	a, err = dubnp.LoadCSV(strings.NewReader(sensorCSV), dubnp.CSVOptions{
+		SkipRows:      2,
+		Columns:       []int{3, 1},
+		MissingValues: []string{"NA"},
+		UseFillValue:  true,
+		FillValue:     -1,
+	})
+	dubug.NoError(t, err)
+	if !dubug.Equal(a.Data, []float64{1, 21.5, 0, -1, 1, 22}) {
+		t.Fatalf("数据错误: %v", a.Data)
+	}
+
+	// 非数值字段报告行列
+	_, err = dubnp.LoadCSV(strings.NewReader("1,2\n3,x\n"), dubnp.CSVOptions{})
+	if err == nil || !strings.Contains(err.Error(), "第 2 行") {
+		t.Fatalf("期望包含行号的错误, 实际 %v", err)
+	}
+}
+
+// 测试分块流式读取 TSV
+func TestCSVReaderChunks(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 10; i++ {
+		input.WriteString(strings.Repeat("1\t", 2) + "1\n")
+	}
+
+	r := dubnp.NewCSVReader(strings.NewReader(input.String()), dubnp.CSVOptions{Delimiter: '\t'})
+	var rows []int
+	for {
+		chunk, err := r.ReadChunk(4)
+		if err == io.EOF {
+			break
+		}
+		dubug.NoError(t, err)
+		rows = append(rows, chunk.Shape[0])
+		if chunk.Shape[1] != 3 {
+			t.Fatalf("列数错误: %v", chunk.Shape)
+		}
+	}
+	if !dubug.Equal(rows, []int{4, 4, 2}) {
+		t.Fatalf("分块行数错误: %v", rows)
+	}
+}
+
+// 测试写出后可以重新读取
+func TestSaveCSVRoundTrip(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{1.25, -3, 0.1, math.NaN()}, []int{2, 2})
+
+	var buf bytes.Buffer
+	err := dubnp.SaveCSV(&buf, a, dubnp.CSVWriteOptions{Header: []string{"x", "y"}})
+	dubug.NoError(t, err)
+
+	b, err := dubnp.LoadCSV(&buf, dubnp.CSVOptions{Header: true})
+	dubug.NoError(t, err)
+	if !dubug.Equal(b.Shape, a.Shape) || b.Data[0] != 1.25 || b.Data[2] != 0.1 || !math.IsNaN(b.Data[3]) {
+		t.Fatalf("往返失败: %v", b.Data)
+	}
+}
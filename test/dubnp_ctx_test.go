@@ -0,0 +1,165 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 测试矩阵乘法的计算结果
+func TestMultiplyValues(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{1, 2, 3, 4, 5, 6}, []int{2, 3})
+	b, _ := dubnp.NewArray([]float64{7, 8, 9, 10, 11, 12}, []int{3, 2})
+
+	result, err := a.Multiply(b)
+	dubug.NoError(t, err)
+
+	expected := []float64{58, 64, 139, 154}
+	if !dubug.Equal(result.Shape, []int{2, 2}) || !dubug.Equal(result.Data, expected) {
+		t.Fatalf("期望 %v, 但实际为 %v (shape %v)", expected, result.Data, result.Shape)
+	}
+}
+
+// 测试已取消的 context 会中断矩阵乘法
+func TestMultiplyCtxCancelled(t *testing.T) {
+	// 行块数多于 worker 数的两倍：第一个块完成后取消，每个 worker 至多再完成手上的一块，不可能全部完成
+	rows, inner := 64*(2*runtime.NumCPU()+1), 8
+	r := rand.New(rand.NewSource(1))
+	data := make([]float64, rows*inner)
+	for i := range data {
+		data[i] = r.Float64()
+	}
+	a, _ := dubnp.NewArray(data, []int{rows, inner})
+	b, _ := dubnp.NewArray(data[:inner*inner], []int{inner, inner})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	// 第一个块完成后立即取消
+	ctx = dubnp.WithProgress(ctx, func(done, total int) {
+		cancel()
+	})
+
+	result, err := a.MultiplyCtx(ctx, b)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("期望 context.Canceled, 但得到 %v", err)
+	}
+	if result != nil {
+		t.Fatalf("取消后不应返回结果")
+	}
+
+	// 调用前已取消时直接返回 ctx.Err()
+	result, err = a.MultiplyCtx(ctx, b)
+	if err != ctx.Err() || result != nil {
+		t.Fatalf("期望 %v, 但得到 %v", ctx.Err(), err)
+	}
+}
+
+// 测试进度回调最终报告全部块完成
+func TestMultiplyCtxProgress(t *testing.T) {
+	size := 200
+	a, _ := dubnp.NewArray(make([]float64, size*size), []int{size, size})
+
+	lastDone, lastTotal := 0, 0
+	ctx := dubnp.WithProgress(context.Background(), func(done, total int) {
+		if done <= lastDone {
+			t.Errorf("进度应单调递增: %d -> %d", lastDone, done)
+		}
+		lastDone, lastTotal = done, total
+	})
+
+	_, err := a.MultiplyCtx(ctx, a)
+	dubug.NoError(t, err)
+	if lastDone == 0 || lastDone != lastTotal {
+		t.Fatalf("进度未完成: %d/%d", lastDone, lastTotal)
+	}
+}
+
+// 测试全局归约与按轴归约
+func TestReductions(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{1, 5, 3, 4, 2, 6}, []int{2, 3})
+
+	sum, err := a.Sum()
+	dubug.NoError(t, err)
+	if sum != 21 {
+		t.Errorf("Sum 期望 21, 实际 %v", sum)
+	}
+
+	maxValue, err := a.Max()
+	dubug.NoError(t, err)
+	if maxValue != 6 {
+		t.Errorf("Max 期望 6, 实际 %v", maxValue)
+	}
+
+	tests := []struct {
+		name     string
+		fn       func(axis int, keepDims bool) (*dubnp.Array, error)
+		axis     int
+		keepDims bool
+		data     []float64
+		shape    []int
+	}{
+		{"SumAxis0", a.SumAxis, 0, false, []float64{5, 7, 9}, []int{3}},
+		{"SumAxis-1", a.SumAxis, -1, true, []float64{9, 12}, []int{2, 1}},
+		{"MeanAxis1", a.MeanAxis, 1, false, []float64{3, 4}, []int{2}},
+		{"MaxAxis0", a.MaxAxis, 0, false, []float64{4, 5, 6}, []int{3}},
+		{"MinAxis1", a.MinAxis, 1, false, []float64{1, 2}, []int{2}},
+	}
+	for _, tt := range tests {
+		result, err := tt.fn(tt.axis, tt.keepDims)
+		dubug.NoError(t, err)
+		if !dubug.Equal(result.Data, tt.data) || !dubug.Equal(result.Shape, tt.shape) {
+			t.Errorf("%s: 期望 %v %v, 实际 %v %v", tt.name, tt.data, tt.shape, result.Data, result.Shape)
+		}
+	}
+
+	if _, err := a.SumAxis(2, false); err == nil {
+		t.Errorf("越界的轴应返回错误")
+	}
+}
+
+// 测试归约在取消的 context 下返回错误
+func TestReductionCtxCancelled(t *testing.T) {
+	a, _ := dubnp.NewArray(make([]float64, 10), []int{10})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := a.SumCtx(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("期望 context.Canceled, 但得到 %v", err)
+	}
+	if _, err := a.MaxAxisCtx(ctx, 0, false); !errors.Is(err, context.Canceled) {
+		t.Fatalf("期望 context.Canceled, 但得到 %v", err)
+	}
+}
+
+// 测试多项式拟合与求根在取消的 context 下返回错误
+func TestPolyCtxCancelled(t *testing.T) {
+	x := []float64{0, 1, 2, 3, 4, 5}
+	y := []float64{1, 3, 7, 13, 21, 31}
+
+	// 第一列消去后取消，后续的 Householder 变换不再执行
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = dubnp.WithProgress(ctx, func(done, total int) {
+		cancel()
+	})
+	if p, err := dubnp.PolyFitCtx(ctx, x, y, 3); !errors.Is(err, context.Canceled) || p != nil {
+		t.Fatalf("期望 context.Canceled, 但得到 %v %v", p, err)
+	}
+	if roots, err := dubnp.PolyRootsCtx(ctx, []float64{1, -3, 2}); !errors.Is(err, context.Canceled) || roots != nil {
+		t.Fatalf("期望 context.Canceled, 但得到 %v %v", roots, err)
+	}
+
+	// 未取消时进度报告全部列
+	lastDone, lastTotal := 0, 0
+	ctx = dubnp.WithProgress(context.Background(), func(done, total int) {
+		lastDone, lastTotal = done, total
+	})
+	_, err := dubnp.PolyFitCtx(ctx, x, y, 2)
+	dubug.NoError(t, err)
+	if lastDone != 3 || lastTotal != 3 {
+		t.Fatalf("进度未完成: %d/%d", lastDone, lastTotal)
+	}
+}
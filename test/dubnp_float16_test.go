@@ -0,0 +1,105 @@
+package test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 测试 Float16 的舍入与特殊值
+func TestFloat16Rounding(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected dubnp.Float16
+	}{
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},                                       // 最大有限值
+		{65520, 0x7c00},                                       // 恰好在中点，偶数优先舍入到 Inf
+		{math.Inf(-1), 0xfc00},                                // -Inf
+		{math.Copysign(0, -1), 0x8000},                        // -0
+		{math.Ldexp(1, -24), 0x0001},                          // 最小非规格化数
+		{math.Ldexp(1, -25), 0x0000},                          // 中点，舍入到偶数 0
+		{math.Ldexp(3, -26), 0x0001},                          // 0.75 个最小单位，向上舍入
+		{math.Ldexp(1, -14), 0x0400},                          // 最小规格化数
+		{1 + math.Ldexp(1, -11), 0x3c00},                      // 中点，舍入到偶数
+		{1 + math.Ldexp(3, -11), 0x3c02},                      // 中点，舍入到偶数（向上）
+		{1 + math.Ldexp(1, -11) + math.Ldexp(1, -40), 0x3c01}, // 超过中点，float64 直接舍入不会二次舍入
+	}
+	for _, tt := range tests {
+		if got := dubnp.Float16FromFloat64(tt.value); got != tt.expected {
+			t.Errorf("Float16FromFloat64(%v) = %#04x, 期望 %#04x", tt.value, uint16(got), uint16(tt.expected))
+		}
+	}
+
+	if !dubnp.Float16FromFloat32(float32(math.NaN())).IsNaN() {
+		t.Errorf("NaN 应转换为 NaN")
+	}
+}
+
+// 测试所有 Float16 与 BFloat16 的取值均可无损往返 float32
+func TestHalfRoundTrip(t *testing.T) {
+	for i := 0; i < 1<<16; i++ {
+		h := dubnp.Float16(i)
+		if !h.IsNaN() && dubnp.Float16FromFloat32(h.Float32()) != h {
+			t.Fatalf("Float16 %#04x 往返失败", i)
+		}
+		bf := dubnp.BFloat16(i)
+		if !bf.IsNaN() && dubnp.BFloat16FromFloat32(bf.Float32()) != bf {
+			t.Fatalf("BFloat16 %#04x 往返失败", i)
+		}
+		if !bf.IsNaN() && dubnp.BFloat16FromFloat64(bf.Float64()) != bf {
+			t.Fatalf("BFloat16 %#04x 经 float64 往返失败", i)
+		}
+	}
+
+	// BFloat16 的舍入
+	if got := dubnp.BFloat16FromFloat32(1 + 1.0/256); got != 0x3f80 {
+		t.Errorf("BFloat16 中点应舍入到偶数, 得到 %#04x", uint16(got))
+	}
+	if got := dubnp.BFloat16FromFloat32(1 + 3.0/256); got != 0x3f82 {
+		t.Errorf("BFloat16 中点应舍入到偶数, 得到 %#04x", uint16(got))
+	}
+}
+
+// 测试半精度数组的转换、字节编码与矩阵乘法
+func TestHalfArray(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{1, 2, 3, 4, 5, 6}, []int{2, 3})
+	b, _ := dubnp.NewArray([]float64{7, 8, 9, 10, 11, 12}, []int{3, 2})
+
+	ha := a.ToFloat16()
+	if !dubug.Equal(ha.ToArray().Data, a.Data) {
+		t.Fatalf("Float16 转换失败: %v", ha.ToArray().Data)
+	}
+
+	decoded, err := dubnp.HalfArrayFromBytes[dubnp.Float16](ha.Bytes(), ha.Shape)
+	dubug.NoError(t, err)
+	if !dubug.Equal(decoded.Data, ha.Data) {
+		t.Fatalf("字节往返失败")
+	}
+
+	product, err := ha.Multiply(b.ToFloat16())
+	dubug.NoError(t, err)
+	if !dubug.Equal(product.ToArray().Data, []float64{58, 64, 139, 154}) {
+		t.Fatalf("Float16 矩阵乘法结果错误: %v", product.ToArray().Data)
+	}
+
+	bfProduct, err := a.ToBFloat16().Multiply(b.ToBFloat16())
+	dubug.NoError(t, err)
+	// 139 与 154 在 BFloat16 中分别舍入为 139 与 154（8 位有效数字）
+	if !dubug.Equal(bfProduct.ToArray().Data, []float64{58, 64, 139, 154}) {
+		t.Fatalf("BFloat16 矩阵乘法结果错误: %v", bfProduct.ToArray().Data)
+	}
+
+	// 以 float32 累加：2048 个 1 在 Float16 中逐个累加会停在 2048，float32 累加可得到精确值
+	ones := make([]dubnp.Float16, 4096)
+	for i := range ones {
+		ones[i] = dubnp.Float16FromFloat32(1)
+	}
+	onesArray, _ := dubnp.NewHalfArray(ones, []int{4096})
+	if sum := onesArray.Sum(); sum != 4096 {
+		t.Fatalf("Sum 期望 4096, 实际 %v", sum)
+	}
+}
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 测试 C 顺序与 F 顺序的遍历
+func TestNdIterOrder(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{0, 1, 2, 3, 4, 5}, []int{2, 3})
+
+	tests := []struct {
+		order    dubnp.Order
+		expected []float64
+	}{
+		{dubnp.COrder, []float64{0, 1, 2, 3, 4, 5}},
+		{dubnp.FOrder, []float64{0, 3, 1, 4, 2, 5}},
+	}
+	for _, tt := range tests {
+		it, err := dubnp.NewNdIter(dubnp.NdIterOptions{Order: tt.order}, a)
+		dubug.NoError(t, err)
+
+		var values []float64
+		for it.Next() {
+			index := it.Index()
+			flat, _ := a.FlatIndex(index...)
+			if flat != it.Offset(0) {
+				t.Fatalf("下标 %v 对应的偏移应为 %d, 实际 %d", index, flat, it.Offset(0))
+			}
+			values = append(values, it.Value(0))
+		}
+		if !dubug.Equal(values, tt.expected) {
+			t.Errorf("顺序 %v: 期望 %v, 实际 %v", tt.order, tt.expected, values)
+		}
+	}
+}
+
+// 测试广播操作数的偏移
+func TestNdIterBroadcast(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{0, 1, 2, 3, 4, 5}, []int{2, 3})
+	b, _ := dubnp.NewArray([]float64{10, 20, 30}, []int{3})
+	c, _ := dubnp.NewArray([]float64{100, 200}, []int{2, 1})
+
+	it, err := dubnp.NewNdIter(dubnp.NdIterOptions{}, a, b, c)
+	dubug.NoError(t, err)
+	if !dubug.Equal(it.Shape(), []int{2, 3}) {
+		t.Fatalf("广播形状错误: %v", it.Shape())
+	}
+
+	var sums []float64
+	for it.Next() {
+		sums = append(sums, it.Value(0)+it.Value(1)+it.Value(2))
+	}
+	expected := []float64{110, 121, 132, 213, 224, 235}
+	if !dubug.Equal(sums, expected) {
+		t.Fatalf("期望 %v, 实际 %v", expected, sums)
+	}
+
+	if _, err := dubnp.NewNdIter(dubnp.NdIterOptions{}, a, c, &dubnp.Array{Data: make([]float64, 4), Shape: []int{4}}); err == nil {
+		t.Fatalf("不可广播的形状应返回错误")
+	}
+}
+
+// 测试外部循环模式合并连续的轴
+func TestNdIterExternalLoop(t *testing.T) {
+	a, _ := dubnp.NewArray(make([]float64, 24), []int{2, 3, 4})
+	b, _ := dubnp.NewArray(make([]float64, 4), []int{4})
+
+	// 单个连续操作数：整个数组合并为一段
+	it, _ := dubnp.NewNdIter(dubnp.NdIterOptions{ExternalLoop: true}, a)
+	chunks := 0
+	for it.Next() {
+		chunks++
+		if it.ChunkLen() != 24 || it.ChunkStride(0) != 1 {
+			t.Fatalf("段长度 %d, 步长 %d", it.ChunkLen(), it.ChunkStride(0))
+		}
+	}
+	if chunks != 1 {
+		t.Fatalf("期望 1 段, 实际 %d", chunks)
+	}
+
+	// 广播的操作数只能合并最内层一维
+	it, _ = dubnp.NewNdIter(dubnp.NdIterOptions{ExternalLoop: true}, a, b)
+	var starts []int
+	for it.Next() {
+		if it.ChunkLen() != 4 {
+			t.Fatalf("段长度应为 4, 实际 %d", it.ChunkLen())
+		}
+		starts = append(starts, it.Offset(0))
+		if it.Offset(1) != 0 {
+			t.Fatalf("广播操作数的段起点应为 0")
+		}
+	}
+	if !dubug.Equal(starts, []int{0, 4, 8, 12, 16, 20}) {
+		t.Fatalf("段起点错误: %v", starts)
+	}
+}
+
+// 测试 Map、Map2、Apply 与 ReduceFunc
+func TestMapApplyReduce(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{3, 1, 2, 6, 5, 4}, []int{2, 3})
+	b, _ := dubnp.NewArray([]float64{10, 20}, []int{2, 1})
+
+	squared := a.Map(func(x float64) float64 { return x * x })
+	if !dubug.Equal(squared.Data, []float64{9, 1, 4, 36, 25, 16}) {
+		t.Errorf("Map 结果错误: %v", squared.Data)
+	}
+
+	sum, err := a.Map2(b, func(x, y float64) float64 { return x + y })
+	dubug.NoError(t, err)
+	if !dubug.Equal(sum.Data, []float64{13, 11, 12, 26, 25, 24}) || !dubug.Equal(sum.Shape, []int{2, 3}) {
+		t.Errorf("Map2 结果错误: %v %v", sum.Data, sum.Shape)
+	}
+
+	sorted, err := a.Apply(0, func(lane []float64) []float64 {
+		out := append([]float64(nil), lane...)
+		sort.Float64s(out)
+		return out
+	})
+	dubug.NoError(t, err)
+	if !dubug.Equal(sorted.Data, []float64{3, 1, 2, 6, 5, 4}) {
+		t.Errorf("Apply(axis 0) 结果错误: %v", sorted.Data)
+	}
+
+	// 返回长度与输入不同的 Apply
+	minMax, err := a.Apply(1, func(lane []float64) []float64 {
+		out := append([]float64(nil), lane...)
+		sort.Float64s(out)
+		return []float64{out[0], out[len(out)-1]}
+	})
+	dubug.NoError(t, err)
+	if !dubug.Equal(minMax.Data, []float64{1, 3, 4, 6}) || !dubug.Equal(minMax.Shape, []int{2, 2}) {
+		t.Errorf("Apply(axis 1) 结果错误: %v %v", minMax.Data, minMax.Shape)
+	}
+
+	ranges, err := a.ReduceFunc(0, func(lane []float64) float64 { return lane[1] - lane[0] })
+	dubug.NoError(t, err)
+	if !dubug.Equal(ranges.Data, []float64{3, 4, 2}) || !dubug.Equal(ranges.Shape, []int{3}) {
+		t.Errorf("ReduceFunc 结果错误: %v %v", ranges.Data, ranges.Shape)
+	}
+}
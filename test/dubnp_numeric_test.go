@@ -0,0 +1,142 @@
+package test
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+func almostEqual(a, b, tol float64) bool {
+	return math.Abs(a-b) <= tol*math.Max(1, math.Abs(b))
+}
+
+// 测试线性插值与三次样条插值
+func TestInterp(t *testing.T) {
+	xp, _ := dubnp.NewArray([]float64{0, 1, 2, 3}, []int{4})
+	fp, _ := dubnp.NewArray([]float64{0, 10, 20, 0}, []int{4})
+	x, _ := dubnp.NewArray([]float64{-1, 0.5, 2.5, 4}, []int{2, 2})
+
+	linear, err := dubnp.Interp(x, xp, fp, dubnp.InterpLinear)
+	dubug.NoError(t, err)
+	if !dubug.Equal(linear.Data, []float64{0, 5, 10, 0}) || !dubug.Equal(linear.Shape, []int{2, 2}) {
+		t.Fatalf("线性插值结果错误: %v", linear.Data)
+	}
+
+	// 三次样条在节点处取节点值，对三次以下的光滑函数误差很小
+	nodes := make([]float64, 21)
+	values := make([]float64, 21)
+	for i := range nodes {
+		nodes[i] = float64(i) * math.Pi / 20
+		values[i] = math.Sin(nodes[i])
+	}
+	spline, err := dubnp.NewCubicSpline(nodes, values)
+	dubug.NoError(t, err)
+	if !almostEqual(spline.At(nodes[7]), values[7], 1e-12) {
+		t.Fatalf("样条在节点处的值错误")
+	}
+	if !almostEqual(spline.At(1.0), math.Sin(1.0), 1e-4) {
+		t.Fatalf("样条插值误差过大: %v vs %v", spline.At(1.0), math.Sin(1.0))
+	}
+
+	if _, err := dubnp.Interp(x, fp, xp, dubnp.InterpLinear); err == nil {
+		t.Fatalf("非递增的 xp 应返回错误")
+	}
+}
+
+// 测试积分、梯度、差分与累加
+func TestCalculus(t *testing.T) {
+	// y = x^2 在 [0, 2] 上采样 5 个点
+	y, _ := dubnp.NewArray([]float64{0, 0.25, 1, 2.25, 4}, []int{5})
+
+	trapz, err := y.Trapz(0, 0.5)
+	dubug.NoError(t, err)
+	if !almostEqual(trapz.Data[0], 2.75, 1e-12) {
+		t.Errorf("Trapz 期望 2.75, 实际 %v", trapz.Data[0])
+	}
+
+	// 辛普森公式对二次函数精确
+	simpson, err := y.Simpson(0, 0.5)
+	dubug.NoError(t, err)
+	if !almostEqual(simpson.Data[0], 8.0/3, 1e-12) {
+		t.Errorf("Simpson 期望 8/3, 实际 %v", simpson.Data[0])
+	}
+
+	// 奇数个区间时使用 3/8 公式，对三次函数仍然精确：x^3 在 [0, 3] 上积分为 81/4
+	cubic, _ := dubnp.NewArray([]float64{0, 1, 8, 27}, []int{4})
+	simpson, err = cubic.Simpson(0, 1)
+	dubug.NoError(t, err)
+	if !almostEqual(simpson.Data[0], 81.0/4, 1e-12) {
+		t.Errorf("Simpson 3/8 期望 20.25, 实际 %v", simpson.Data[0])
+	}
+
+	trapzX, err := y.TrapzX(0, []float64{0, 0.5, 1, 1.5, 2})
+	dubug.NoError(t, err)
+	if !almostEqual(trapzX.Data[0], trapz.Data[0], 1e-12) {
+		t.Errorf("TrapzX 与 Trapz 结果不一致")
+	}
+
+	grid, _ := dubnp.NewArray([]float64{1, 2, 4, 7, 11, 16}, []int{2, 3})
+	gradient, err := grid.Gradient(1, 1)
+	dubug.NoError(t, err)
+	if !dubug.Equal(gradient.Data, []float64{1, 1.5, 2, 4, 4.5, 5}) {
+		t.Errorf("Gradient 结果错误: %v", gradient.Data)
+	}
+
+	diff, err := grid.Diff(1, 0)
+	dubug.NoError(t, err)
+	if !dubug.Equal(diff.Data, []float64{6, 9, 12}) || !dubug.Equal(diff.Shape, []int{1, 3}) {
+		t.Errorf("Diff 结果错误: %v %v", diff.Data, diff.Shape)
+	}
+
+	cumsum, err := grid.CumSum(1)
+	dubug.NoError(t, err)
+	if !dubug.Equal(cumsum.Data, []float64{1, 3, 7, 7, 18, 34}) {
+		t.Errorf("CumSum 结果错误: %v", cumsum.Data)
+	}
+
+	cumprod, err := grid.CumProd(0)
+	dubug.NoError(t, err)
+	if !dubug.Equal(cumprod.Data, []float64{1, 2, 4, 7, 22, 64}) {
+		t.Errorf("CumProd 结果错误: %v", cumprod.Data)
+	}
+}
+
+// 测试多项式拟合、求值与求根
+func TestPolynomial(t *testing.T) {
+	// y = 2x^2 - 3x + 1
+	x := []float64{-2, -1, 0, 1, 2, 3}
+	y := make([]float64, len(x))
+	for i, v := range x {
+		y[i] = 2*v*v - 3*v + 1
+	}
+
+	p, err := dubnp.PolyFit(x, y, 2)
+	dubug.NoError(t, err)
+	expected := []float64{2, -3, 1}
+	for i := range expected {
+		if !almostEqual(p[i], expected[i], 1e-10) {
+			t.Fatalf("PolyFit 期望 %v, 实际 %v", expected, p)
+		}
+	}
+
+	xs, _ := dubnp.NewArray([]float64{0, 2}, []int{2})
+	if values := dubnp.PolyVal(p, xs); !almostEqual(values.Data[1], 3, 1e-10) {
+		t.Fatalf("PolyVal 结果错误: %v", values.Data)
+	}
+
+	roots, err := dubnp.PolyRoots([]float64{2, -3, 1})
+	dubug.NoError(t, err)
+	if len(roots) != 2 || !almostEqual(real(roots[0]), 0.5, 1e-10) || !almostEqual(real(roots[1]), 1, 1e-10) {
+		t.Fatalf("PolyRoots 结果错误: %v", roots)
+	}
+
+	// x^3 + x = x(x^2 + 1)，根为 -i, 0, i
+	roots, err = dubnp.PolyRoots([]float64{1, 0, 1, 0})
+	dubug.NoError(t, err)
+	if len(roots) != 3 || cmplx.Abs(roots[0]-(-1i)) > 1e-10 || roots[1] != 0 || cmplx.Abs(roots[2]-1i) > 1e-10 {
+		t.Fatalf("PolyRoots 复数根错误: %v", roots)
+	}
+}
@@ -74,6 +74,21 @@ func TestArrayPrint(t *testing.T) {
 	array.PrintMatrix(8)
 }
 
+// 测试 FormatMatrix 按列对齐
+func TestFormatMatrix(t *testing.T) {
+	array, err := dubnp.NewArray([]float64{1, -22.5, 300, 4}, []int{2, 2})
+	if err != nil {
+		t.Fatalf("NewArray failed: %v", err)
+	}
+	rows := array.FormatMatrix(1)
+	if !dubug.Equal(rows, []string{"  1.0 -22.5", "300.0   4.0"}) {
+		t.Fatalf("FormatMatrix 的结果为 %q", rows)
+	}
+	if rows := dubnp.Zeros(0, 3).FormatMatrix(2); len(rows) != 0 {
+		t.Fatalf("空数组应没有行: %q", rows)
+	}
+}
+
 func TestAdd(t *testing.T) {
 	// 创建两个矩阵
 	a, err := dubnp.NewArray([]float64{1, 2, 3, 4}, []int{2, 2})
@@ -0,0 +1,228 @@
+package test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 测试批量矩阵乘法的广播与转置
+func TestBatchMultiply(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{1, 2, 3, 4, 5, 6, 7, 8}, []int{2, 2, 2})
+	b, _ := dubnp.NewArray([]float64{1, 0, 1, 1}, []int{2, 2})
+	c, err := a.BatchMultiply(b)
+	dubug.NoError(t, err)
+	if !dubug.Equal(c.Shape, []int{2, 2, 2}) || !dubug.Equal(c.Data, []float64{3, 2, 7, 4, 11, 6, 15, 8}) {
+		t.Fatalf("批量矩阵乘法结果错误: %v %v", c.Shape, c.Data)
+	}
+
+	s, err := a.SwapAxes(0, -1)
+	dubug.NoError(t, err)
+	if !dubug.Equal(s.Data, []float64{1, 5, 3, 7, 2, 6, 4, 8}) {
+		t.Fatalf("SwapAxes 结果错误: %v", s.Data)
+	}
+	if _, err := a.BatchMultiply(a.Copy()); err != nil {
+		t.Fatalf("相同形状的方阵应可相乘: %v", err)
+	}
+	// 0 与 Inf 相乘为 NaN，与 Multiply 一致
+	zero, _ := dubnp.NewArray([]float64{0}, []int{1, 1, 1})
+	inf, _ := dubnp.NewArray([]float64{math.Inf(1)}, []int{1, 1, 1})
+	if p, err := zero.BatchMultiply(inf); err != nil || !math.IsNaN(p.Data[0]) {
+		t.Fatalf("0 乘 Inf 应为 NaN: %v %v", p, err)
+	}
+	bad := dubnp.Zeros(3, 2, 2)
+	if _, err := a.BatchMultiply(bad); err == nil {
+		t.Fatalf("批次维度无法广播时应返回错误")
+	}
+}
+
+// 测试注意力与 Transformer 层的梯度
+func TestAttentionGradients(t *testing.T) {
+	r := rand.New(rand.NewSource(38))
+	dubtorch.ManualSeed(38)
+	q := randomTensor(r, true, 2, 3, 4)
+	kv := randomTensor(r, true, 2, 5, 4)
+	a := randomTensor(r, true, 2, 3, 4)
+	b := randomTensor(r, true, 1, 4, 2)
+
+	attn, err := dubtorch.NewMultiheadAttention(4, 2, 0)
+	dubug.NoError(t, err)
+	padding := dubnp.Zeros(2, 5)
+	padding.Data[4], padding.Data[8], padding.Data[9] = 1, 1, 1
+	enc, err := dubtorch.NewTransformerEncoderLayer(4, 2, dubtorch.TransformerOptions{DimFeedforward: 6})
+	dubug.NoError(t, err)
+	dec, err := dubtorch.NewTransformerDecoderLayer(4, 2, dubtorch.TransformerOptions{DimFeedforward: 6, NormFirst: true, GELU: true})
+	dubug.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		fn     func() (*dubtorch.Tensor, error)
+		inputs []*dubtorch.Tensor
+	}{
+		{"BatchMatMul 广播", func() (*dubtorch.Tensor, error) {
+			x, err := a.Narrow(1, 0, 2)
+			if err != nil {
+				return nil, err
+			}
+			y, err := x.BatchMatMul(b)
+			if err != nil {
+				return nil, err
+			}
+			return y.Pow(2).Sum()
+		}, []*dubtorch.Tensor{a, b}},
+		{"MultiheadAttention 交叉注意力", weightedSum(r, func() (*dubtorch.Tensor, error) {
+			out, _, err := attn.Attend(q, kv, kv, dubtorch.AttentionOptions{KeyPaddingMask: padding})
+			return out, err
+		}), append([]*dubtorch.Tensor{q, kv}, attn.Parameters()...)},
+		{"TransformerEncoderLayer", weightedSum(r, func() (*dubtorch.Tensor, error) {
+			return enc.Encode(q, dubtorch.AttentionOptions{Causal: true})
+		}), append([]*dubtorch.Tensor{q}, enc.Parameters()...)},
+		{"TransformerDecoderLayer", weightedSum(r, func() (*dubtorch.Tensor, error) {
+			return dec.Decode(q, kv, dubtorch.AttentionOptions{Causal: true}, dubtorch.AttentionOptions{KeyPaddingMask: padding})
+		}), append([]*dubtorch.Tensor{q, kv}, dec.Parameters()...)},
+	}
+	for _, tt := range tests {
+		checkGradients(t, tt.name, tt.fn, tt.inputs...)
+	}
+}
+
+// 测试因果掩码与填充掩码确实屏蔽了对应位置
+func TestAttentionMasks(t *testing.T) {
+	r := rand.New(rand.NewSource(7))
+	dubtorch.ManualSeed(7)
+	attn, err := dubtorch.NewMultiheadAttention(4, 2, 0)
+	dubug.NoError(t, err)
+	x := randomTensor(r, false, 1, 4, 4)
+
+	// 修改最后一个位置，因果注意力下前面位置的输出不变
+	causal := dubtorch.AttentionOptions{Causal: true}
+	before, weights, err := attn.Attend(x, x, x, causal)
+	dubug.NoError(t, err)
+	changed := dubtorch.NewTensor(x.Data.Copy(), false)
+	for j := 12; j < 16; j++ {
+		changed.Data.Data[j] += 10
+	}
+	after, _, err := attn.Attend(changed, changed, changed, causal)
+	dubug.NoError(t, err)
+	for i := 0; i < 12; i++ {
+		if !almostEqual(before.Data.Data[i], after.Data.Data[i], 1e-12) {
+			t.Fatalf("因果掩码下前面位置的输出不应受未来位置影响")
+		}
+	}
+	if !dubug.Equal(weights.Shape(), []int{1, 2, 4, 4}) {
+		t.Fatalf("注意力权重形状错误: %v", weights.Shape())
+	}
+	for h := 0; h < 2; h++ {
+		for i := 0; i < 4; i++ {
+			row := weights.Data.Data[(h*4+i)*4 : (h*4+i+1)*4]
+			sum := 0.0
+			for j, w := range row {
+				if j > i && w != 0 {
+					t.Fatalf("未来位置的注意力权重应为 0: %v", row)
+				}
+				sum += w
+			}
+			if !almostEqual(sum, 1, 1e-12) {
+				t.Fatalf("注意力权重之和应为 1: %v", row)
+			}
+		}
+	}
+
+	// 填充位置的取值不影响输出
+	padding, _ := dubnp.NewArray([]float64{0, 0, 1, 1}, []int{1, 4})
+	opts := dubtorch.AttentionOptions{KeyPaddingMask: padding}
+	before, _, err = attn.Attend(x, x, x, opts)
+	dubug.NoError(t, err)
+	kv := dubtorch.NewTensor(x.Data.Copy(), false)
+	for j := 8; j < 16; j++ {
+		kv.Data.Data[j] = -5
+	}
+	after, _, err = attn.Attend(x, kv, kv, opts)
+	dubug.NoError(t, err)
+	for i := range before.Data.Data {
+		if !almostEqual(before.Data.Data[i], after.Data.Data[i], 1e-12) {
+			t.Fatalf("填充位置不应影响输出")
+		}
+	}
+
+	// 整行被屏蔽时权重为 0 而不是 NaN
+	all, _ := dubnp.NewArray([]float64{1, 1, 1, 1}, []int{1, 4})
+	out, _, err := attn.Attend(x, x, x, dubtorch.AttentionOptions{KeyPaddingMask: all})
+	dubug.NoError(t, err)
+	for _, v := range out.Data.Data {
+		if math.IsNaN(v) {
+			t.Fatalf("整行屏蔽时不应产生 NaN")
+		}
+	}
+	if _, _, err := attn.Attend(x, x, x, dubtorch.AttentionOptions{KeyPaddingMask: dubnp.Zeros(2, 4)}); err == nil {
+		t.Fatalf("掩码形状错误时应返回错误")
+	}
+	if _, err := dubtorch.NewMultiheadAttention(5, 2, 0); err == nil {
+		t.Fatalf("embedDim 不能被 numHeads 整除时应返回错误")
+	}
+}
+
+// 整行被屏蔽（全为 -Inf）时 Softmax 输出 0，LogSoftmax 输出 -Inf，梯度均为 0
+func TestSoftmaxMaskedRow(t *testing.T) {
+	inf := math.Inf(-1)
+	x, _ := dubtorch.FromSlice([]float64{inf, inf, inf, 1, inf, 2}, []int{2, 3}, true)
+	probs, err := x.Softmax(1)
+	dubug.NoError(t, err)
+	logProbs, err := x.LogSoftmax(1)
+	dubug.NoError(t, err)
+	for j := 0; j < 3; j++ {
+		if probs.Data.Data[j] != 0 || !math.IsInf(logProbs.Data.Data[j], -1) {
+			t.Fatalf("屏蔽行应为 0 与 -Inf: %v %v", probs.Data.Data, logProbs.Data.Data)
+		}
+	}
+	for j := 3; j < 6; j++ {
+		if !almostEqual(probs.Data.Data[j], math.Exp(logProbs.Data.Data[j]), 1e-12) {
+			t.Fatalf("LogSoftmax 与 Softmax 不一致: %v %v", probs.Data.Data, logProbs.Data.Data)
+		}
+	}
+	for _, y := range []*dubtorch.Tensor{probs, logProbs} {
+		x.ZeroGrad()
+		dubug.NoError(t, y.BackwardWithGrad(dubnp.Ones(2, 3)))
+		for j, g := range x.Grad.Data {
+			if (j < 3 && g != 0) || math.IsNaN(g) {
+				t.Fatalf("%s 的梯度为 %v", y.Op(), x.Grad.Data)
+			}
+		}
+	}
+}
+
+// 测试位置编码的取值与形状检查
+func TestPositionalEncoding(t *testing.T) {
+	pe := dubtorch.NewPositionalEncoding(4, 8, 0)
+	x := dubtorch.NewTensor(dubnp.Zeros(2, 3, 4), false)
+	y, err := pe.Forward(x)
+	dubug.NoError(t, err)
+	// 位置 1：sin(1), cos(1), sin(1/100), cos(1/100)
+	want := []float64{math.Sin(1), math.Cos(1), math.Sin(0.01), math.Cos(0.01)}
+	for i, w := range want {
+		if !almostEqual(y.Data.Data[4+i], w, 1e-12) || !almostEqual(y.Data.Data[16+i], w, 1e-12) {
+			t.Fatalf("位置编码取值错误: %v", y.Data.Data[4:8])
+		}
+	}
+	if len(pe.NamedBuffers()) != 1 || len(pe.Parameters()) != 0 {
+		t.Fatalf("正弦位置编码应只注册一个缓冲区")
+	}
+	if _, err := pe.Forward(dubtorch.NewTensor(dubnp.Zeros(1, 9, 4), false)); err == nil {
+		t.Fatalf("序列长度超过 MaxLen 时应返回错误")
+	}
+
+	learned := dubtorch.NewLearnedPositionalEncoding(4, 8)
+	y, err = learned.Forward(x)
+	dubug.NoError(t, err)
+	loss, err := y.Sum()
+	dubug.NoError(t, err)
+	dubug.NoError(t, loss.Backward())
+	// 前 3 个位置各被 2 个样本使用
+	if learned.Weight.Grad.Data[0] != 2 || learned.Weight.Grad.Data[12] != 0 {
+		t.Fatalf("可学习位置编码的梯度错误: %v", learned.Weight.Grad.Data)
+	}
+}
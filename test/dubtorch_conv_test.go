@@ -0,0 +1,138 @@
+package test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 按定义直接计算二维卷积，用于校验 im2col 的实现
+func naiveConv2d(x, w *dubtorch.Tensor, stride, pad, dil, groups int) []float64 {
+	n, cin, h, wd := x.Shape()[0], x.Shape()[1], x.Shape()[2], x.Shape()[3]
+	cout, cg, kh, kw := w.Shape()[0], w.Shape()[1], w.Shape()[2], w.Shape()[3]
+	oh := (h+2*pad-dil*(kh-1)-1)/stride + 1
+	ow := (wd+2*pad-dil*(kw-1)-1)/stride + 1
+	og := cout / groups
+	out := make([]float64, n*cout*oh*ow)
+	for b := 0; b < n; b++ {
+		for o := 0; o < cout; o++ {
+			for oi := 0; oi < oh; oi++ {
+				for oj := 0; oj < ow; oj++ {
+					sum := 0.0
+					for c := 0; c < cg; c++ {
+						ci := (o/og)*cg + c
+						for ki := 0; ki < kh; ki++ {
+							for kj := 0; kj < kw; kj++ {
+								i, j := oi*stride-pad+ki*dil, oj*stride-pad+kj*dil
+								if i >= 0 && i < h && j >= 0 && j < wd {
+									sum += x.Data.Data[((b*cin+ci)*h+i)*wd+j] * w.Data.Data[((o*cg+c)*kh+ki)*kw+kj]
+								}
+							}
+						}
+					}
+					out[((b*cout+o)*oh+oi)*ow+oj] = sum
+				}
+			}
+		}
+	}
+	return out
+}
+
+// 测试卷积结果与直接计算一致
+func TestConv2dMatchesNaive(t *testing.T) {
+	r := rand.New(rand.NewSource(21))
+	x := randomTensor(r, false, 3, 4, 7, 6)
+	w := randomTensor(r, false, 6, 2, 3, 2)
+	opts := dubtorch.ConvOptions{Stride: []int{2}, Padding: []int{1}, Dilation: []int{2}, Groups: 2}
+	y, err := dubtorch.Conv2d(x, w, nil, opts)
+	dubug.NoError(t, err)
+	want := naiveConv2d(x, w, 2, 1, 2, 2)
+	for i, v := range want {
+		if !almostEqual(y.Data.Data[i], v, 1e-12) {
+			t.Fatalf("第 %d 个输出为 %v, 期望 %v", i, y.Data.Data[i], v)
+		}
+	}
+
+	if _, err := dubtorch.Conv2d(x, randomTensor(r, false, 6, 3, 3, 3), nil, opts); err == nil {
+		t.Fatalf("通道数不匹配时应返回错误")
+	}
+}
+
+// 测试卷积、转置卷积与池化的梯度
+func TestConvGradients(t *testing.T) {
+	r := rand.New(rand.NewSource(22))
+	dubtorch.ManualSeed(22)
+
+	x2 := randomTensor(r, true, 2, 4, 5, 5)
+	x1 := randomTensor(r, true, 2, 4, 9)
+	mustConv := func(c *dubtorch.Conv, err error) *dubtorch.Conv {
+		dubug.NoError(t, err)
+		return c
+	}
+	conv2 := mustConv(dubtorch.NewConv2d(4, 6, []int{3, 2}, dubtorch.ConvOptions{Stride: []int{2, 1}, Padding: []int{1}, Groups: 2}, true))
+	conv1 := mustConv(dubtorch.NewConv1d(4, 3, 3, dubtorch.ConvOptions{Dilation: []int{2}, Padding: []int{2}}, true))
+	deconv2 := mustConv(dubtorch.NewConvTranspose2d(4, 2, []int{3}, dubtorch.ConvOptions{Stride: []int{2}, Padding: []int{1}, OutputPadding: []int{1}, Groups: 2}, true))
+	deconv1 := mustConv(dubtorch.NewConvTranspose1d(4, 3, 2, dubtorch.ConvOptions{Stride: []int{3}}, false))
+
+	tests := []struct {
+		name   string
+		module dubtorch.Module
+		input  *dubtorch.Tensor
+		inputs []*dubtorch.Tensor
+	}{
+		{"Conv2d", conv2, x2, []*dubtorch.Tensor{x2, conv2.Weight, conv2.Bias}},
+		{"Conv1d", conv1, x1, []*dubtorch.Tensor{x1, conv1.Weight, conv1.Bias}},
+		{"ConvTranspose2d", deconv2, x2, []*dubtorch.Tensor{x2, deconv2.Weight, deconv2.Bias}},
+		{"ConvTranspose1d", deconv1, x1, []*dubtorch.Tensor{x1, deconv1.Weight}},
+		{"MaxPool2d", dubtorch.NewMaxPool2d(dubtorch.PoolOptions{KernelSize: []int{3}, Stride: []int{2}, Padding: []int{1}}), x2, []*dubtorch.Tensor{x2}},
+		{"MaxPool1d", dubtorch.NewMaxPool1d(dubtorch.PoolOptions{KernelSize: []int{2}}), x1, []*dubtorch.Tensor{x1}},
+		{"AvgPool2d", dubtorch.NewAvgPool2d(dubtorch.PoolOptions{KernelSize: []int{2, 3}, Padding: []int{1}}), x2, []*dubtorch.Tensor{x2}},
+		{"AvgPool1d", dubtorch.NewAvgPool1d(dubtorch.PoolOptions{KernelSize: []int{3}, Stride: []int{2}}), x1, []*dubtorch.Tensor{x1}},
+		{"AdaptiveAvgPool2d", dubtorch.NewAdaptiveAvgPool2d(3, 2), x2, []*dubtorch.Tensor{x2}},
+		{"AdaptiveAvgPool1d", dubtorch.NewAdaptiveAvgPool1d(4), x1, []*dubtorch.Tensor{x1}},
+	}
+	for _, tc := range tests {
+		module, input := tc.module, tc.input
+		checkGradients(t, tc.name, weightedSum(r, func() (*dubtorch.Tensor, error) {
+			return module.Forward(input)
+		}), tc.inputs...)
+	}
+}
+
+// 测试输出形状与池化的取值
+func TestConvShapesAndPooling(t *testing.T) {
+	dubtorch.ManualSeed(23)
+	x := dubtorch.Randn(2, 3, 8, 8)
+
+	deconv, err := dubtorch.NewConvTranspose2d(3, 5, []int{4}, dubtorch.ConvOptions{Stride: []int{2}, Padding: []int{1}}, true)
+	dubug.NoError(t, err)
+	y, err := deconv.Forward(x)
+	dubug.NoError(t, err)
+	if !dubug.Equal(y.Shape(), []int{2, 5, 16, 16}) {
+		t.Fatalf("转置卷积输出形状错误: %v", y.Shape())
+	}
+
+	conv, _ := dubtorch.NewConv2d(3, 4, []int{3}, dubtorch.ConvOptions{Padding: []int{1}}, true)
+	model := dubtorch.NewSequential(conv, dubtorch.NewReLU(), dubtorch.NewMaxPool2d(dubtorch.PoolOptions{KernelSize: []int{2}}),
+		dubtorch.NewAdaptiveAvgPool2d(1), dubtorch.NewFlatten())
+	y, err = model.Forward(x)
+	dubug.NoError(t, err)
+	if !dubug.Equal(y.Shape(), []int{2, 4}) {
+		t.Fatalf("卷积网络输出形状错误: %v", y.Shape())
+	}
+
+	p, _ := dubtorch.FromSlice([]float64{1, 5, 2, 4, 3, 0, 7, 6}, []int{1, 1, 8}, false)
+	maxed, err := dubtorch.MaxPool1d(p, dubtorch.PoolOptions{KernelSize: []int{2}})
+	dubug.NoError(t, err)
+	if !dubug.Equal(maxed.Data.Data, []float64{5, 4, 3, 7}) {
+		t.Fatalf("最大池化错误: %v", maxed.Data.Data)
+	}
+	adaptive, err := dubtorch.AdaptiveAvgPool1d(p, 3)
+	dubug.NoError(t, err)
+	// 窗口为 [0,3) [2,6) [5,8)
+	if !dubug.Equal(adaptive.Data.Data, []float64{8.0 / 3, 9.0 / 4, 13.0 / 3}) {
+		t.Fatalf("自适应平均池化错误: %v", adaptive.Data.Data)
+	}
+}
@@ -0,0 +1,138 @@
+package test
+
+import (
+	"io"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 创建 n 个样本的数据集：输入为 [i, 2i]，标签为标量 i
+func rangeDataset(t *testing.T, n int) *dubtorch.TensorDataset {
+	inputs := make([]float64, 2*n)
+	labels := make([]float64, n)
+	for i := 0; i < n; i++ {
+		inputs[2*i] = float64(i)
+		inputs[2*i+1] = float64(2 * i)
+		labels[i] = float64(i)
+	}
+	x, _ := dubnp.NewArray(inputs, []int{n, 2})
+	y, _ := dubnp.NewArray(labels, []int{n})
+	ds, err := dubtorch.NewTensorDataset(x, y)
+	dubug.NoError(t, err)
+	return ds
+}
+
+// 读取一轮的全部批次标签
+func collectLabels(t *testing.T, loader *dubtorch.DataLoader) [][]float64 {
+	it := loader.Iter()
+	defer it.Close()
+	var labels [][]float64
+	for {
+		batch, err := it.Next()
+		if err == io.EOF {
+			break
+		}
+		dubug.NoError(t, err)
+		labels = append(labels, batch[1].Data)
+	}
+	return labels
+}
+
+// 测试批次形状与 DropLast
+func TestDataLoaderBatches(t *testing.T) {
+	ds := rangeDataset(t, 10)
+
+	loader, err := dubtorch.NewDataLoader(ds, dubtorch.DataLoaderOptions{BatchSize: 4})
+	dubug.NoError(t, err)
+	it := loader.Iter()
+	batch, err := it.Next()
+	dubug.NoError(t, err)
+	if !dubug.Equal(batch[0].Shape, []int{4, 2}) || !dubug.Equal(batch[1].Shape, []int{4}) {
+		t.Fatalf("批次形状错误: %v %v", batch[0].Shape, batch[1].Shape)
+	}
+	it.Close()
+
+	labels := collectLabels(t, loader)
+	if len(labels) != 3 || len(labels[2]) != 2 || loader.Len() != 3 {
+		t.Fatalf("期望 3 个批次且最后一个有 2 个样本, 实际 %v", labels)
+	}
+
+	loader, _ = dubtorch.NewDataLoader(ds, dubtorch.DataLoaderOptions{BatchSize: 4, DropLast: true})
+	if labels := collectLabels(t, loader); len(labels) != 2 || loader.Len() != 2 {
+		t.Fatalf("DropLast 后期望 2 个批次, 实际 %v", labels)
+	}
+}
+
+// 测试打乱顺序可复现、每轮不同，且多 goroutine 加载保持顺序
+func TestDataLoaderShuffleAndWorkers(t *testing.T) {
+	ds := rangeDataset(t, 32)
+	opts := dubtorch.DataLoaderOptions{BatchSize: 5, Shuffle: true, Seed: 7}
+
+	sequential, _ := dubtorch.NewDataLoader(ds, opts)
+	epoch0 := collectLabels(t, sequential)
+	epoch1 := collectLabels(t, sequential)
+	if dubug.Equal(epoch0, epoch1) {
+		t.Fatalf("不同轮次的顺序应不同")
+	}
+
+	opts.NumWorkers = 3
+	parallel, _ := dubtorch.NewDataLoader(ds, opts)
+	if !dubug.Equal(collectLabels(t, parallel), epoch0) || !dubug.Equal(collectLabels(t, parallel), epoch1) {
+		t.Fatalf("相同种子下多 goroutine 加载的顺序应与顺序加载一致")
+	}
+
+	// 提前关闭不应阻塞
+	it := parallel.Iter()
+	if _, err := it.Next(); err != nil {
+		t.Fatalf("读取失败: %v", err)
+	}
+	it.Close()
+}
+
+// 测试可迭代数据集与自定义组合函数
+func TestIterableDataLoader(t *testing.T) {
+	var samples dubtorch.SliceDataset
+	for i := 0; i < 7; i++ {
+		samples = append(samples, dubtorch.Sample{dubnp.Full(float64(i), 3)})
+	}
+
+	// 自定义组合：对批次内的样本求和
+	sumCollate := func(batch []dubtorch.Sample) (dubtorch.Batch, error) {
+		total := dubnp.Zeros(3)
+		for _, s := range batch {
+			dubug.NoError(t, total.AddInPlace(s[0]))
+		}
+		return dubtorch.Batch{total}, nil
+	}
+
+	for _, workers := range []int{0, 2} {
+		loader, err := dubtorch.NewIterableDataLoader(samples, dubtorch.DataLoaderOptions{
+			BatchSize:  3,
+			Collate:    sumCollate,
+			NumWorkers: workers,
+		})
+		dubug.NoError(t, err)
+
+		it := loader.Iter()
+		var sums []float64
+		for {
+			batch, err := it.Next()
+			if err == io.EOF {
+				break
+			}
+			dubug.NoError(t, err)
+			sums = append(sums, batch[0].Data[0])
+		}
+		it.Close()
+		if !dubug.Equal(sums, []float64{3, 12, 6}) {
+			t.Fatalf("workers=%d: 期望 [3 12 6], 实际 %v", workers, sums)
+		}
+	}
+
+	if _, err := dubtorch.NewIterableDataLoader(samples, dubtorch.DataLoaderOptions{Shuffle: true}); err == nil {
+		t.Fatalf("可迭代数据集不应支持 Shuffle")
+	}
+}
@@ -0,0 +1,521 @@
+package test
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/distributed"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 在每个进程上并发执行 fn，返回第一个错误
+func runRanks(n int, fn func(rank int) error) error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 在 n 个内存传输上对 inputs 执行 all-reduce，返回各进程的结果
+func memoryAllReduce(t *testing.T, inputs [][]float64, chunk int) [][]float64 {
+	transports, err := distributed.NewMemoryGroup(len(inputs))
+	dubug.NoError(t, err)
+	out := make([][]float64, len(inputs))
+	err = runRanks(len(inputs), func(rank int) error {
+		ring, err := distributed.NewRing(transports[rank], distributed.RingOptions{ChunkSize: chunk})
+		if err != nil {
+			return err
+		}
+		out[rank] = append([]float64(nil), inputs[rank]...)
+		return ring.AllReduce(context.Background(), out[rank])
+	})
+	dubug.NoError(t, err)
+	return out
+}
+
+// 测试环形 all-reduce 的结果、分块、逐位一致与可复现
+func TestRingAllReduce(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, tc := range []struct{ ranks, length, chunk int }{{1, 5, 0}, {2, 10, 3}, {4, 1001, 7}, {5, 3, 2}} {
+		inputs := make([][]float64, tc.ranks)
+		want := make([]float64, tc.length)
+		for i := range inputs {
+			inputs[i] = make([]float64, tc.length)
+			for k := range inputs[i] {
+				inputs[i][k] = r.NormFloat64()
+				want[k] += inputs[i][k]
+			}
+		}
+		first := memoryAllReduce(t, inputs, tc.chunk)
+		for rank, got := range first {
+			if !dubug.Equal(got, first[0]) {
+				t.Fatalf("%d 个进程时第 %d 个进程的结果与 0 号不完全相同", tc.ranks, rank)
+			}
+		}
+		for k := range want {
+			if !almostEqual(first[0][k], want[k], 1e-12) {
+				t.Fatalf("%d 个进程时第 %d 个元素为 %v，期望 %v", tc.ranks, k, first[0][k], want[k])
+			}
+		}
+		if again := memoryAllReduce(t, inputs, tc.chunk); !dubug.Equal(again[0], first[0]) {
+			t.Fatalf("相同输入的 all-reduce 结果应逐位相同")
+		}
+	}
+
+	// 关闭传输后阻塞的通信返回错误
+	transports, err := distributed.NewMemoryGroup(2)
+	dubug.NoError(t, err)
+	ring, _ := distributed.NewRing(transports[0], distributed.RingOptions{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		transports[1].Close()
+	}()
+	if err := ring.AllReduce(context.Background(), []float64{1, 2}); err == nil {
+		t.Fatalf("对端关闭时应返回错误")
+	}
+}
+
+// 测试数据并行训练与单进程全批次训练的结果一致
+func TestDataParallelTraining(t *testing.T) {
+	const ranks, perRank = 3, 4
+	r := rand.New(rand.NewSource(2))
+	x, y := dubnp.Zeros(ranks*perRank, 3), dubnp.Zeros(ranks*perRank, 1)
+	for i := range x.Data {
+		x.Data[i] = r.NormFloat64()
+	}
+	for i := range y.Data {
+		y.Data[i] = x.Data[3*i] - 2*x.Data[3*i+2]
+	}
+	ds, err := dubtorch.NewTensorDataset(x, y)
+	dubug.NoError(t, err)
+	newModel := func(seed int64) *dubtorch.Sequential {
+		dubtorch.ManualSeed(seed)
+		return dubtorch.NewSequential(dubtorch.NewLinear(3, 4, true), dubtorch.NewTanh(), dubtorch.NewLinear(4, 1, true))
+	}
+	mse := func(output, target *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+		return dubtorch.MSELoss(output, target, dubtorch.ReductionMean)
+	}
+
+	// 参考：单个模型在全部样本上训练
+	reference := newModel(0)
+	refOpt, err := optim.NewSGD(reference.NamedParameters(), optim.SGDOptions{LR: 0.1, Momentum: 0.5})
+	dubug.NoError(t, err)
+	full, err := dubtorch.NewDataLoader(ds, dubtorch.DataLoaderOptions{BatchSize: ranks * perRank})
+	dubug.NoError(t, err)
+	trainer, err := dubtorch.NewTrainer(reference, refOpt, mse, full, dubtorch.TrainerOptions{Epochs: 5})
+	dubug.NoError(t, err)
+	dubug.NoError(t, trainer.Fit(context.Background()))
+
+	// 各副本以不同种子初始化，由广播统一为 0 号副本的参数，各副本并发执行前向、反向与梯度同步
+	transports, err := distributed.NewMemoryGroup(ranks)
+	dubug.NoError(t, err)
+	models := make([]*dubtorch.Sequential, ranks)
+	for i := range models {
+		models[i] = newModel(int64(i))
+	}
+	err = runRanks(ranks, func(rank int) error {
+		ring, err := distributed.NewRing(transports[rank], distributed.RingOptions{ChunkSize: 5})
+		if err != nil {
+			return err
+		}
+		model := models[rank]
+		sgd, err := optim.NewSGD(model.NamedParameters(), optim.SGDOptions{LR: 0.1, Momentum: 0.5})
+		if err != nil {
+			return err
+		}
+		opt, err := distributed.NewDataParallel(context.Background(), ring, model.Parameters(), sgd)
+		if err != nil {
+			return err
+		}
+		shard, err := distributed.Shard(ds, rank, ranks)
+		if err != nil {
+			return err
+		}
+		batch, err := dubtorch.NewDataLoader(shard, dubtorch.DataLoaderOptions{BatchSize: perRank})
+		if err != nil {
+			return err
+		}
+		for epoch := 0; epoch < 5; epoch++ {
+			it := batch.Iter()
+			b, err := it.Next()
+			it.Close()
+			if err != nil {
+				return err
+			}
+			out, err := model.Forward(dubtorch.NewTensor(b[0], false))
+			if err == nil {
+				var loss *dubtorch.Tensor
+				if loss, err = mse(out, dubtorch.NewTensor(b[1], false)); err == nil {
+					err = loss.Backward()
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if err := opt.Step(); err != nil {
+				return err
+			}
+			opt.ZeroGrad()
+		}
+		return nil
+	})
+	dubug.NoError(t, err)
+
+	want := reference.Parameters()
+	for rank, m := range models {
+		for i, p := range m.Parameters() {
+			if !dubug.Equal(p.Data.Data, models[0].Parameters()[i].Data.Data) {
+				t.Fatalf("副本 %d 的第 %d 个参数与 0 号副本不同", rank, i)
+			}
+			for k, v := range p.Data.Data {
+				if !almostEqual(v, want[i].Data.Data[k], 1e-10) {
+					t.Fatalf("数据并行的第 %d 个参数为 %v，单进程训练为 %v", i, v, want[i].Data.Data[k])
+				}
+			}
+		}
+	}
+
+	shard, err := distributed.Shard(ds, 2, 5)
+	dubug.NoError(t, err)
+	if !dubug.Equal(shard.Indices, []int{2, 7, 0}) {
+		t.Fatalf("分片应交错取样并从头补齐: %v", shard.Indices)
+	}
+}
+
+// 测试梯度 all-reduce 保持各进程都为 nil 的梯度不变
+func TestAllReduceGradientsNil(t *testing.T) {
+	transports, err := distributed.NewMemoryGroup(2)
+	dubug.NoError(t, err)
+	grads := make([][]*dubtorch.Tensor, 2)
+	err = runRanks(2, func(rank int) error {
+		ring, err := distributed.NewRing(transports[rank], distributed.RingOptions{})
+		if err != nil {
+			return err
+		}
+		frozen := dubtorch.NewTensor(dubnp.Zeros(2), true)
+		partial := dubtorch.NewTensor(dubnp.Zeros(2), true)
+		if rank == 0 {
+			partial.Grad = &dubnp.Array{Data: []float64{2, 4}, Shape: []int{2}}
+		}
+		grads[rank] = []*dubtorch.Tensor{frozen, partial}
+		return distributed.AllReduceGradients(context.Background(), ring, grads[rank])
+	})
+	dubug.NoError(t, err)
+	for rank, params := range grads {
+		if params[0].Grad != nil {
+			t.Fatalf("进程 %d 上所有副本都没有的梯度应保持为 nil", rank)
+		}
+		if params[1].Grad == nil || !dubug.Equal(params[1].Grad.Data, []float64{1, 2}) {
+			t.Fatalf("进程 %d 上部分副本有的梯度应按进程数平均: %v", rank, params[1].Grad)
+		}
+	}
+}
+
+// 测试 TCP 传输上的广播与 all-reduce
+func TestTCPTransport(t *testing.T) {
+	const ranks = 3
+	addrs := make([]string, ranks)
+	for i := range addrs {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		dubug.NoError(t, err)
+		addrs[i] = l.Addr().String()
+		l.Close()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	results := make([][]float64, ranks)
+	err := runRanks(ranks, func(rank int) error {
+		transport, err := distributed.NewTCPTransport(ctx, rank, addrs)
+		if err != nil {
+			return err
+		}
+		defer transport.Close()
+		ring, err := distributed.NewRing(transport, distributed.RingOptions{ChunkSize: 4})
+		if err != nil {
+			return err
+		}
+		data := make([]float64, 11)
+		for i := range data {
+			data[i] = float64(rank*100 + i)
+		}
+		if err := ring.AllReduce(ctx, data); err != nil {
+			return err
+		}
+		shared := []float64{float64(rank), -1}
+		if err := ring.Broadcast(ctx, 1, shared); err != nil {
+			return err
+		}
+		results[rank] = append(data, shared...)
+		return nil
+	})
+	dubug.NoError(t, err)
+	for rank, got := range results {
+		if got[0] != 300 || got[10] != 330 || got[11] != 1 || got[12] != -1 {
+			t.Fatalf("进程 %d 的结果错误: %v", rank, got)
+		}
+	}
+}
+
+// 测试参数服务器：单个工作者等价于本地训练，过期梯度按延迟上限丢弃
+func TestParameterServer(t *testing.T) {
+	newModel := func(seed int64) *dubtorch.Sequential {
+		dubtorch.ManualSeed(seed)
+		return dubtorch.NewSequential(dubtorch.NewLinear(2, 3, true), dubtorch.NewTanh(), dubtorch.NewLinear(3, 1, false))
+	}
+	x, _ := dubnp.NewArray([]float64{1, 2, -1, 0.5, 0, -3, 2, 1}, []int{4, 2})
+	y, _ := dubnp.NewArray([]float64{1, -1, 0.5, 2}, []int{4, 1})
+	backward := func(m *dubtorch.Sequential) {
+		out, err := m.Forward(dubtorch.NewTensor(x, false))
+		dubug.NoError(t, err)
+		loss, err := dubtorch.MSELoss(out, dubtorch.NewTensor(y, false), dubtorch.ReductionMean)
+		dubug.NoError(t, err)
+		dubug.NoError(t, loss.Backward())
+	}
+	sgdOpts := optim.SGDOptions{LR: 0.1, Momentum: 0.9}
+
+	reference := newModel(0)
+	refOpt, err := optim.NewSGD(reference.NamedParameters(), sgdOpts)
+	dubug.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		backward(reference)
+		dubug.NoError(t, refOpt.Step())
+		refOpt.ZeroGrad()
+	}
+
+	transports, err := distributed.NewMemoryGroup(2)
+	dubug.NoError(t, err)
+	global := newModel(0)
+	sgd, err := optim.NewSGD(global.NamedParameters(), sgdOpts)
+	dubug.NoError(t, err)
+	server, err := distributed.NewParameterServer(transports[0], global.Parameters(), sgd, distributed.ParameterServerOptions{ChunkSize: 3})
+	dubug.NoError(t, err)
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(context.Background(), []int{1}) }()
+
+	local := newModel(7)
+	worker, err := distributed.NewWorker(context.Background(), transports[1], 0, local.Parameters(), distributed.WorkerOptions{ChunkSize: 2})
+	dubug.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		backward(local)
+		dubug.NoError(t, worker.Step())
+		worker.ZeroGrad()
+	}
+	var opt dubtorch.Optimizer = worker
+	if _, ok := opt.(dubtorch.ContextOptimizer); !ok {
+		t.Fatalf("工作者应支持按调用传入 ctx")
+	}
+	dubug.NoError(t, worker.Stop(context.Background()))
+	dubug.NoError(t, <-served)
+	if worker.Version() != 4 || server.Version() != 4 {
+		t.Fatalf("版本应为 4: %d %d", worker.Version(), server.Version())
+	}
+	for i, p := range local.Parameters() {
+		if !dubug.Equal(p.Data.Data, reference.Parameters()[i].Data.Data) || !dubug.Equal(p.Data.Data, global.Parameters()[i].Data.Data) {
+			t.Fatalf("单个工作者的第 %d 个参数与本地训练不一致", i)
+		}
+	}
+	if err := worker.LoadStateDict(refOpt.StateDict()); err == nil {
+		t.Fatalf("工作者不应接受优化器状态")
+	}
+
+	// 两个工作者交替推送，延迟上限为 1
+	transports, err = distributed.NewMemoryGroup(3)
+	dubug.NoError(t, err)
+	global = newModel(0)
+	sgd, err = optim.NewSGD(global.NamedParameters(), optim.SGDOptions{LR: 0.1})
+	dubug.NoError(t, err)
+	server, err = distributed.NewParameterServer(transports[0], global.Parameters(), sgd, distributed.ParameterServerOptions{MaxStaleness: 1})
+	dubug.NoError(t, err)
+	go func() { served <- server.Serve(context.Background(), []int{1, 2}) }()
+	workers := make([]*distributed.Worker, 2)
+	for i := range workers {
+		m := newModel(int64(i + 1))
+		workers[i], err = distributed.NewWorker(context.Background(), transports[i+1], 0, m.Parameters(), distributed.WorkerOptions{})
+		dubug.NoError(t, err)
+		for _, p := range m.Parameters() {
+			p.Grad = dubnp.Ones(p.Data.Shape...)
+		}
+	}
+	push := func(w *distributed.Worker, want bool) {
+		t.Helper()
+		ok, err := w.Push(context.Background())
+		dubug.NoError(t, err)
+		if ok != want {
+			t.Fatalf("版本 %d 的梯度在服务器版本 %d 时采用结果应为 %v", w.Version(), server.Version(), want)
+		}
+	}
+	push(workers[1], true) // 基于版本 0，延迟 0
+	dubug.NoError(t, workers[1].Pull(context.Background()))
+	push(workers[1], true)  // 基于版本 1，延迟 0
+	push(workers[0], false) // 基于版本 0，延迟 2，超过上限
+	dubug.NoError(t, workers[0].Pull(context.Background()))
+	push(workers[1], true) // 基于版本 1，延迟 1
+	push(workers[0], true) // 基于版本 2，延迟 1
+	for _, w := range workers {
+		dubug.NoError(t, w.Stop(context.Background()))
+	}
+	dubug.NoError(t, <-served)
+	if accepted, rejected := server.Stats(); accepted != 4 || rejected != 1 || workers[0].Rejected() != 1 {
+		t.Fatalf("采用与丢弃的梯度数错误: %d %d", accepted, rejected)
+	}
+
+	// 延迟上限为 0 时只采用基于最新参数的梯度；没有梯度的参数在服务器上不被带动量的优化器更新
+	transports, err = distributed.NewMemoryGroup(3)
+	dubug.NoError(t, err)
+	global = newModel(0)
+	sgd, err = optim.NewSGD(global.NamedParameters(), sgdOpts)
+	dubug.NoError(t, err)
+	server, err = distributed.NewParameterServer(transports[0], global.Parameters(), sgd, distributed.ParameterServerOptions{})
+	dubug.NoError(t, err)
+	go func() { served <- server.Serve(context.Background(), []int{1, 2}) }()
+	models := []*dubtorch.Sequential{newModel(1), newModel(2)}
+	for i, m := range models {
+		workers[i], err = distributed.NewWorker(context.Background(), transports[i+1], 0, m.Parameters(), distributed.WorkerOptions{})
+		dubug.NoError(t, err)
+		for _, p := range m.Parameters() {
+			p.Grad = dubnp.Ones(p.Data.Shape...)
+		}
+	}
+	push(workers[0], true)
+	push(workers[1], false) // 基于版本 0，延迟 1
+	dubug.NoError(t, workers[0].Pull(context.Background()))
+	frozen := global.Parameters()[1:]
+	before := make([][]float64, len(frozen))
+	for i, p := range frozen {
+		before[i] = append([]float64(nil), p.Data.Data...)
+	}
+	for _, p := range models[0].Parameters()[1:] {
+		p.Grad = nil
+	}
+	push(workers[0], true)
+	for _, w := range workers {
+		dubug.NoError(t, w.Stop(context.Background()))
+	}
+	dubug.NoError(t, <-served)
+	for i, p := range frozen {
+		if !dubug.Equal(p.Data.Data, before[i]) {
+			t.Fatalf("没有梯度的第 %d 个参数不应被更新", i+1)
+		}
+	}
+	if _, err := distributed.NewParameterServer(transports[0], global.Parameters(), sgd, distributed.ParameterServerOptions{MaxStaleness: -1}); err != nil {
+		t.Fatalf("负的延迟上限表示不限制: %v", err)
+	}
+}
+
+// 测试流水线并行：GPipe 微批次训练与单进程整批训练的结果一致
+func TestPipelineParallel(t *testing.T) {
+	newModel := func() *dubtorch.Sequential {
+		dubtorch.ManualSeed(3)
+		return dubtorch.NewSequential(
+			dubtorch.NewLinear(3, 4, true), dubtorch.NewTanh(),
+			dubtorch.NewLinear(4, 4, true), dubtorch.NewTanh(),
+			dubtorch.NewLinear(4, 2, true),
+		)
+	}
+	mse := func(output, target *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+		return dubtorch.MSELoss(output, target, dubtorch.ReductionMean)
+	}
+	r := rand.New(rand.NewSource(4))
+	x, y := dubnp.Zeros(6, 3), dubnp.Zeros(6, 2)
+	for i := range x.Data {
+		x.Data[i] = r.NormFloat64()
+	}
+	for i := range y.Data {
+		y.Data[i] = r.NormFloat64()
+	}
+	sgdOpts := optim.SGDOptions{LR: 0.1, Momentum: 0.9}
+
+	reference := newModel()
+	refOpt, err := optim.NewSGD(reference.NamedParameters(), sgdOpts)
+	dubug.NoError(t, err)
+	var refLosses []float64
+	for step := 0; step < 3; step++ {
+		out, err := reference.Forward(dubtorch.NewTensor(x, false))
+		dubug.NoError(t, err)
+		loss, err := mse(out, dubtorch.NewTensor(y, false))
+		dubug.NoError(t, err)
+		dubug.NoError(t, loss.Backward())
+		dubug.NoError(t, refOpt.Step())
+		refOpt.ZeroGrad()
+		refLosses = append(refLosses, loss.Data.Data[0])
+	}
+
+	model := newModel()
+	stages, err := distributed.SplitSequential(model, []int{2, 2, 1})
+	dubug.NoError(t, err)
+	if keys := stages[1].StateDict(); len(keys) != 2 || keys["2.weight"] == nil {
+		t.Fatalf("阶段应保留原模型的参数名: %v", keys)
+	}
+	transports, err := distributed.NewMemoryGroup(len(stages))
+	dubug.NoError(t, err)
+	losses := make([]float64, 3)
+	// 6 个样本切分为 4 个大小不等的微批次
+	err = runRanks(len(stages), func(stage int) error {
+		opt, err := optim.NewSGD(stages[stage].NamedParameters(), sgdOpts)
+		if err != nil {
+			return err
+		}
+		pipe, err := distributed.NewPipelineStage(transports[stage], stages[stage], opt, mse, distributed.PipelineOptions{MicroBatches: 4, ChunkSize: 3})
+		if err != nil {
+			return err
+		}
+		for step := 0; step < 3; step++ {
+			var input, target *dubnp.Array
+			if stage == 0 {
+				input = x
+			}
+			if stage == len(stages)-1 {
+				target = y
+			}
+			loss, err := pipe.Step(context.Background(), input, target)
+			if err != nil {
+				return err
+			}
+			if target != nil {
+				losses[step] = loss
+			}
+		}
+		return nil
+	})
+	dubug.NoError(t, err)
+
+	for step := range losses {
+		if !almostEqual(losses[step], refLosses[step], 1e-12) {
+			t.Fatalf("第 %d 步的损失为 %v，期望 %v", step, losses[step], refLosses[step])
+		}
+	}
+	want := reference.Parameters()
+	for i, p := range model.Parameters() {
+		for k, v := range p.Data.Data {
+			if !almostEqual(v, want[i].Data.Data[k], 1e-10) {
+				t.Fatalf("流水线训练的第 %d 个参数为 %v，单进程训练为 %v", i, v, want[i].Data.Data[k])
+			}
+		}
+	}
+
+	if _, err := distributed.SplitSequential(model, []int{2, 2}); err == nil {
+		t.Fatalf("子模块数之和不一致时应返回错误")
+	}
+	if _, err := distributed.SplitSequential(model, []int{5, 0}); err == nil {
+		t.Fatalf("空阶段应返回错误")
+	}
+}
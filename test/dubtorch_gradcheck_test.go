@@ -0,0 +1,143 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 非标量输出按整个雅可比矩阵检查，不需要梯度的输入被跳过，原有的 Grad 保持不变
+func TestGradCheck(t *testing.T) {
+	r := rand.New(rand.NewSource(49))
+	x := randomTensor(r, true, 3, 4)
+	w := randomTensor(r, true, 4, 2)
+	bias := randomTensor(r, false, 2)
+	x.Grad = dubnp.Ones(3, 4)
+	fn := func(in []*dubtorch.Tensor) (*dubtorch.Tensor, error) {
+		y, err := in[0].MatMul(in[1])
+		if err != nil {
+			return nil, err
+		}
+		if y, err = y.Add(in[2]); err != nil {
+			return nil, err
+		}
+		return y.Tanh().Softmax(1)
+	}
+	dubug.NoError(t, dubtorch.GradCheck(fn, []*dubtorch.Tensor{x, w, bias}, 1e-6, 1e-6))
+	if w.Grad != nil || !dubug.Equal(x.Grad.Data, dubnp.Ones(3, 4).Data) {
+		t.Fatalf("GradCheck 不应改变输入的 Grad")
+	}
+
+	// ReLU 在 0 处不可导：解析梯度为 0，中心差分为 0.5
+	kink, err := dubtorch.FromSlice([]float64{1, 0, -1}, []int{3}, true)
+	dubug.NoError(t, err)
+	err = dubtorch.GradCheck(func(in []*dubtorch.Tensor) (*dubtorch.Tensor, error) {
+		return in[0].ReLU(), nil
+	}, []*dubtorch.Tensor{kink}, 1e-6, 1e-6)
+	var mismatch *dubtorch.GradCheckError
+	if !errors.As(err, &mismatch) || mismatch.Index != 1 || mismatch.Output != 1 || !almostEqual(mismatch.Numeric, 0.5, 1e-9) || mismatch.Analytic != 0 {
+		t.Fatalf("应在 ReLU 的拐点处报告不一致，实际为 %v", err)
+	}
+	if !dubug.Equal(kink.Data.Data, []float64{1, 0, -1}) {
+		t.Fatalf("检查结束后输入应恢复原值: %v", kink.Data.Data)
+	}
+
+	if err := dubtorch.GradCheck(fn, []*dubtorch.Tensor{bias}, 1e-6, 1e-6); err == nil {
+		t.Fatalf("没有需要梯度的输入时应报错")
+	}
+	if err := dubtorch.GradCheck(fn, []*dubtorch.Tensor{x.MulScalar(2), w, bias}, 1e-6, 1e-6); err == nil {
+		t.Fatalf("输入不是叶子张量时应报错")
+	}
+}
+
+// 异常检测报告首先产生 NaN/Inf 的运算及其创建位置
+func TestDetectAnomaly(t *testing.T) {
+	x, err := dubtorch.FromSlice([]float64{1, 0, 4}, []int{3}, true)
+	dubug.NoError(t, err)
+
+	// 前向：Log(0) = -Inf，之后的运算输入已含 -Inf，不再报告
+	err = dubtorch.DetectAnomaly(func() error {
+		if !dubtorch.IsAnomalyEnabled() {
+			t.Fatalf("DetectAnomaly 内应开启异常检测")
+		}
+		_, err := x.Log().MulScalar(0).Sum()
+		return err
+	})
+	var anomaly *dubtorch.AnomalyError
+	if !errors.As(err, &anomaly) || anomaly.Op != "Log" || anomaly.Backward || anomaly.Index != 1 || !math.IsInf(anomaly.Value, -1) {
+		t.Fatalf("应报告 Log 的前向异常，实际为 %v", err)
+	}
+	if !strings.Contains(anomaly.Stack, "TestDetectAnomaly") {
+		t.Fatalf("调用栈应包含创建位置:\n%s", anomaly.Stack)
+	}
+	if dubtorch.IsAnomalyEnabled() {
+		t.Fatalf("DetectAnomaly 结束后应关闭异常检测")
+	}
+
+	// 反向：Sqrt 在 0 处前向有限，梯度 0.5/sqrt(0) 为 Inf
+	err = dubtorch.DetectAnomaly(func() error {
+		y, err := x.Sqrt().Sum()
+		if err != nil {
+			return err
+		}
+		return y.Backward()
+	})
+	if !errors.As(err, &anomaly) || anomaly.Op != "Sqrt" || !anomaly.Backward || anomaly.Input != 0 || anomaly.Index != 1 {
+		t.Fatalf("应报告 Sqrt 的反向异常，实际为 %v", err)
+	}
+	if !strings.Contains(anomaly.Stack, "TestDetectAnomaly") {
+		t.Fatalf("调用栈应包含创建位置:\n%s", anomaly.Stack)
+	}
+
+	// 不开启时照常计算
+	x.ZeroGrad()
+	y, err := x.Sqrt().Sum()
+	dubug.NoError(t, err)
+	dubug.NoError(t, y.Backward())
+	if !math.IsInf(x.Grad.Data[1], 1) {
+		t.Fatalf("梯度应为 Inf: %v", x.Grad.Data)
+	}
+	dubug.NoError(t, dubtorch.DetectAnomaly(func() error {
+		_, err := x.Sqrt().Sum()
+		return err
+	}))
+
+	// 只由参数参与的运算同样被检查：Log(0) 发生在与输入相乘之前
+	w, err := dubtorch.FromSlice([]float64{1, 0, 2}, []int{3}, true)
+	dubug.NoError(t, err)
+	input := dubtorch.NewTensor(dubnp.Ones(3), false)
+	err = dubtorch.DetectAnomaly(func() error {
+		_, err := input.Mul(w.Log())
+		return err
+	})
+	if !errors.As(err, &anomaly) || anomaly.Op != "Log" || anomaly.Index != 1 {
+		t.Fatalf("应报告参数上 Log 的前向异常，实际为 %v", err)
+	}
+
+	// 嵌套时异常只报告给最内层的调用；其他 goroutine 中的运算不受影响
+	var inner error
+	dubug.NoError(t, dubtorch.DetectAnomaly(func() error {
+		inner = dubtorch.DetectAnomaly(func() error {
+			w.Log()
+			return nil
+		})
+		done := make(chan bool)
+		go func() {
+			w.Log()
+			done <- dubtorch.IsAnomalyEnabled()
+		}()
+		if <-done {
+			t.Errorf("其他 goroutine 不应处于异常检测模式")
+		}
+		return nil
+	}))
+	if !errors.As(inner, &anomaly) || anomaly.Op != "Log" {
+		t.Fatalf("内层调用应报告 Log 的前向异常，实际为 %v", inner)
+	}
+}
@@ -0,0 +1,184 @@
+package test
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 样本的均值与标准差
+func moments(data []float64) (mean, std float64) {
+	for _, v := range data {
+		mean += v
+	}
+	mean /= float64(len(data))
+	for _, v := range data {
+		std += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(std / float64(len(data)))
+}
+
+func TestInitializers(t *testing.T) {
+	dubtorch.ManualSeed(50)
+	w := dubtorch.NewTensor(dubnp.Zeros(200, 300), true)
+
+	// Xavier：均匀分布的边界为 gain*√(6/(in+out))，方差为 gain²*2/(in+out)
+	dubug.NoError(t, dubtorch.XavierUniform(w, 2))
+	bound := 2 * math.Sqrt(6.0/500)
+	for _, v := range w.Data.Data {
+		if math.Abs(v) > bound {
+			t.Fatalf("XavierUniform 超出边界 %v: %v", bound, v)
+		}
+	}
+	if _, std := moments(w.Data.Data); !almostEqual(std, 2*math.Sqrt(2.0/500), 0.02*std) {
+		t.Fatalf("XavierUniform 的标准差为 %v", std)
+	}
+	dubug.NoError(t, dubtorch.XavierNormal(w, 1))
+	if mean, std := moments(w.Data.Data); math.Abs(mean) > 0.002 || !almostEqual(std, math.Sqrt(2.0/500), 0.02*std) {
+		t.Fatalf("XavierNormal 的均值 %v，标准差 %v", mean, std)
+	}
+
+	// Kaiming：卷积权重 (64, 16, 3, 3) 的扇入为 144，扇出为 576
+	conv := dubtorch.NewTensor(dubnp.Zeros(64, 16, 3, 3), true)
+	dubug.NoError(t, dubtorch.KaimingNormal(conv, dubtorch.KaimingOptions{}))
+	if _, std := moments(conv.Data.Data); !almostEqual(std, math.Sqrt(2.0/144), 0.03*std) {
+		t.Fatalf("KaimingNormal 的标准差为 %v", std)
+	}
+	dubug.NoError(t, dubtorch.KaimingUniform(conv, dubtorch.KaimingOptions{FanOut: true, Nonlinearity: "tanh"}))
+	if _, std := moments(conv.Data.Data); !almostEqual(std, 5.0/3/math.Sqrt(576), 0.03*std) {
+		t.Fatalf("KaimingUniform 的标准差为 %v", std)
+	}
+	if gain, err := dubtorch.CalculateGain("leaky_relu", 0.2); err != nil || !almostEqual(gain, math.Sqrt(2/1.04), 1e-12) {
+		t.Fatalf("leaky_relu 的增益为 %v, %v", gain, err)
+	}
+	if err := dubtorch.KaimingUniform(conv, dubtorch.KaimingOptions{Nonlinearity: "swish"}); err == nil {
+		t.Fatalf("未知的激活函数应报错")
+	}
+
+	// 截断正态分布全部落在区间内，且不是简单截断（边界处没有堆积）
+	dubug.NoError(t, dubtorch.TruncatedNormal(w, 1, 2, 0, 1.5))
+	atBound := 0
+	for _, v := range w.Data.Data {
+		if v < 0 || v > 1.5 {
+			t.Fatalf("TruncatedNormal 超出区间: %v", v)
+		}
+		if v == 0 || v == 1.5 {
+			atBound++
+		}
+	}
+	if mean, _ := moments(w.Data.Data); atBound > 10 || mean < 0.7 || mean > 0.8 {
+		t.Fatalf("TruncatedNormal 的均值 %v，落在边界上 %d 个", mean, atBound)
+	}
+
+	dubtorch.Constant(w, 0.5)
+	if mean, std := moments(w.Data.Data); mean != 0.5 || std != 0 {
+		t.Fatalf("Constant 的结果不是常数")
+	}
+	if err := dubtorch.XavierUniform(dubtorch.NewTensor(dubnp.Zeros(5), true), 1); err == nil {
+		t.Fatalf("一维参数没有扇入扇出，应报错")
+	}
+}
+
+// 正交初始化：行数不超过列数时各行正交归一，否则各列正交归一
+func TestOrthogonal(t *testing.T) {
+	dubtorch.ManualSeed(50)
+	for _, shape := range [][]int{{5, 8}, {8, 5}, {4, 2, 3}, {6, 6}} {
+		w := dubtorch.NewTensor(dubnp.Zeros(shape...), true)
+		dubug.NoError(t, dubtorch.Orthogonal(w, 2))
+		rows := shape[0]
+		cols := w.Size() / rows
+		n, stride, step := rows, cols, 1
+		if rows > cols {
+			n, stride, step = cols, 1, cols
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				dot := 0.0
+				for k := 0; k < max(rows, cols); k++ {
+					dot += w.Data.Data[i*stride+k*step] * w.Data.Data[j*stride+k*step]
+				}
+				want := 0.0
+				if i == j {
+					want = 4
+				}
+				if !almostEqual(dot, want, 1e-10) {
+					t.Fatalf("%v: 第 %d 与第 %d 个向量的内积为 %v", shape, i, j, dot)
+				}
+			}
+		}
+	}
+	if err := dubtorch.Orthogonal(dubtorch.NewTensor(dubnp.Zeros(0, 4), true), 1); err == nil {
+		t.Fatalf("第一维为 0 时应报错")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	dubtorch.ManualSeed(50)
+	conv, err := dubtorch.NewConv2d(1, 4, []int{3}, dubtorch.ConvOptions{Padding: []int{1}}, true)
+	dubug.NoError(t, err)
+	head := dubtorch.NewLinear(64, 10, true)
+	model := dubtorch.NewSequential(
+		conv, dubtorch.NewReLU(), dubtorch.NewMaxPool2d(dubtorch.PoolOptions{KernelSize: []int{2}}),
+		dubtorch.NewSequential(dubtorch.NewFlatten(), head),
+	)
+	head.Bias.RequiresGrad = false
+
+	// 权重用 Xavier 初始化，偏置置 0
+	dubug.NoError(t, dubtorch.InitParameters(model, func(name string, p *dubtorch.Tensor) error {
+		if strings.HasSuffix(name, "bias") {
+			dubtorch.Constant(p, 0)
+			return nil
+		}
+		return dubtorch.XavierUniform(p, 1)
+	}))
+	if head.Bias.Data.Data[3] != 0 || conv.Weight.Data.Data[0] == 0 {
+		t.Fatalf("InitParameters 没有作用到所有参数")
+	}
+
+	s, err := dubtorch.Summary(model, []int{2, 1, 8, 8})
+	dubug.NoError(t, err)
+	if !model.IsTraining() {
+		t.Fatalf("Summary 之后应恢复训练模式")
+	}
+	wantShapes := [][]int{{2, 4, 8, 8}, {2, 4, 8, 8}, {2, 4, 4, 4}, {2, 64}, {2, 10}}
+	wantParams := []int{40, 0, 0, 0, 650}
+	if len(s.Layers) != len(wantShapes) {
+		t.Fatalf("应有 %d 层，实际为 %+v", len(wantShapes), s.Layers)
+	}
+	for i, l := range s.Layers {
+		if !dubug.Equal(l.OutputShape, wantShapes[i]) || l.Params != wantParams[i] {
+			t.Fatalf("第 %d 层为 %+v", i, l)
+		}
+	}
+	if s.Layers[4].Name != "3.1" || s.Layers[4].Type != "Linear" || s.Layers[4].Trainable != 640 {
+		t.Fatalf("最后一层为 %+v", s.Layers[4])
+	}
+	activations := 2*4*8*8*2 + 2*4*4*4 + 2*64 + 2*10
+	if s.TotalParams != 690 || s.TrainableParams != 680 || s.ActivationBytes != 8*activations || s.TotalBytes() != 8*(128+2*activations+690) {
+		t.Fatalf("合计为 %+v", s)
+	}
+	var buf bytes.Buffer
+	dubug.NoError(t, s.Fprint(&buf))
+	if buf.String() != s.String() {
+		t.Fatalf("Fprint 与 String 的输出不同")
+	}
+	if out := s.String(); !strings.Contains(out, "Conv") || !strings.Contains(out, "[2 4 4 4]") || !strings.Contains(out, "参数总量 690") {
+		t.Fatalf("摘要为\n%s", out)
+	}
+
+	// 共享的参数在合计中只计一次
+	shared := dubtorch.NewLinear(4, 4, false)
+	s, err = dubtorch.Summary(dubtorch.NewSequential(shared, shared), []int{1, 4})
+	dubug.NoError(t, err)
+	if s.TotalParams != 16 || s.Layers[1].Params != 16 {
+		t.Fatalf("共享参数的摘要为 %+v", s)
+	}
+	if _, err := dubtorch.Summary(model, []int{2, 3, 8, 8}); err == nil {
+		t.Fatalf("输入形状与模型不匹配时应报错")
+	}
+}
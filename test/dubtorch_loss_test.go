@@ -0,0 +1,120 @@
+package test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 测试各损失函数在三种归约方式下的梯度
+func TestLossGradients(t *testing.T) {
+	r := rand.New(rand.NewSource(11))
+	input := randomTensor(r, true, 4, 3)
+	target := randomTensor(r, true, 4, 3)
+	probs := randomTensor(r, true, 4, 3)
+	probs.Data = probs.Data.Map(func(x float64) float64 { return math.Abs(x) + 0.1 })
+	logits := randomTensor(r, true, 4, 3, 2)
+	classes, _ := dubtorch.FromSlice([]float64{0, 2, 1, 2}, []int{4}, false)
+	spatial, _ := dubtorch.FromSlice([]float64{0, 2, 1, 1, 2, 0, 1, 0}, []int{4, 2}, false)
+	weight := &dubnp.Array{Data: []float64{0.5, 2, 1}, Shape: []int{3}}
+
+	for _, reduction := range []dubtorch.Reduction{dubtorch.ReductionMean, dubtorch.ReductionSum, dubtorch.ReductionNone} {
+		tests := []struct {
+			name   string
+			fn     func() (*dubtorch.Tensor, error)
+			inputs []*dubtorch.Tensor
+		}{
+			{"MSE", func() (*dubtorch.Tensor, error) { return dubtorch.MSELoss(input, target, reduction) }, []*dubtorch.Tensor{input, target}},
+			{"L1", func() (*dubtorch.Tensor, error) { return dubtorch.L1Loss(input, target, reduction) }, []*dubtorch.Tensor{input, target}},
+			{"Huber", func() (*dubtorch.Tensor, error) { return dubtorch.HuberLoss(input.MulScalar(3), target, 1, reduction) }, []*dubtorch.Tensor{input, target}},
+			{"BCEWithLogits", func() (*dubtorch.Tensor, error) { return dubtorch.BCEWithLogitsLoss(input, probs, reduction) }, []*dubtorch.Tensor{input, probs}},
+			{"KLDiv", func() (*dubtorch.Tensor, error) { return dubtorch.KLDivLoss(input, probs, reduction) }, []*dubtorch.Tensor{input, probs}},
+			{"CrossEntropy", func() (*dubtorch.Tensor, error) {
+				return dubtorch.CrossEntropyLoss(input, classes, dubtorch.ClassLossOptions{Reduction: reduction})
+			}, []*dubtorch.Tensor{input}},
+			{"CrossEntropyWeighted", func() (*dubtorch.Tensor, error) {
+				return dubtorch.CrossEntropyLoss(input, classes, dubtorch.ClassLossOptions{
+					Reduction: reduction, Weight: weight, LabelSmoothing: 0.2, UseIgnoreIndex: true, IgnoreIndex: 1,
+				})
+			}, []*dubtorch.Tensor{input}},
+			{"CrossEntropySpatial", func() (*dubtorch.Tensor, error) {
+				return dubtorch.CrossEntropyLoss(logits, spatial, dubtorch.ClassLossOptions{Reduction: reduction, LabelSmoothing: 0.1})
+			}, []*dubtorch.Tensor{logits}},
+		}
+		for _, tc := range tests {
+			checkGradients(t, tc.name, weightedSum(r, tc.fn), tc.inputs...)
+		}
+	}
+}
+
+// 测试损失值与手工计算一致
+func TestLossValues(t *testing.T) {
+	x, _ := dubtorch.FromSlice([]float64{0, 3}, []int{2}, false)
+	y, _ := dubtorch.FromSlice([]float64{1, 0}, []int{2}, false)
+
+	item := func(loss *dubtorch.Tensor, err error) float64 {
+		dubug.NoError(t, err)
+		v, err := loss.Item()
+		dubug.NoError(t, err)
+		return v
+	}
+	if v := item(dubtorch.MSELoss(x, y, dubtorch.ReductionMean)); v != 5 {
+		t.Fatalf("MSE: %v", v)
+	}
+	if v := item(dubtorch.L1Loss(x, y, dubtorch.ReductionSum)); v != 4 {
+		t.Fatalf("L1: %v", v)
+	}
+	if v := item(dubtorch.HuberLoss(x, y, 1, dubtorch.ReductionSum)); v != 0.5+2.5 {
+		t.Fatalf("Huber: %v", v)
+	}
+
+	// logits 很大时也不应溢出
+	big, _ := dubtorch.FromSlice([]float64{1000, -1000}, []int{2}, false)
+	wrong, _ := dubtorch.FromSlice([]float64{0, 1}, []int{2}, false)
+	if v := item(dubtorch.BCEWithLogitsLoss(big, wrong, dubtorch.ReductionSum)); v != 2000 {
+		t.Fatalf("BCEWithLogits: %v", v)
+	}
+
+	logits, _ := dubtorch.FromSlice([]float64{1000, 0, 0, 0, 0, 0}, []int{2, 3}, false)
+	classes, _ := dubtorch.FromSlice([]float64{0, 2}, []int{2}, false)
+	if v := item(dubtorch.CrossEntropyLoss(logits, classes, dubtorch.ClassLossOptions{})); !almostEqual(v, math.Log(3)/2, 1e-12) {
+		t.Fatalf("CrossEntropy: %v", v)
+	}
+	// 忽略第二个样本后只剩一个几乎确定的预测
+	ignored := dubtorch.ClassLossOptions{UseIgnoreIndex: true, IgnoreIndex: 2}
+	if v := item(dubtorch.CrossEntropyLoss(logits, classes, ignored)); !almostEqual(v, 0, 1e-12) {
+		t.Fatalf("CrossEntropy 忽略下标: %v", v)
+	}
+	// 加权平均除以目标类别的权重之和：(2*0 + 4*log3) / 6
+	weighted := dubtorch.ClassLossOptions{Weight: &dubnp.Array{Data: []float64{2, 1, 4}, Shape: []int{3}}}
+	if v := item(dubtorch.CrossEntropyLoss(logits, classes, weighted)); !almostEqual(v, 4*math.Log(3)/6, 1e-12) {
+		t.Fatalf("CrossEntropy 类别权重: %v", v)
+	}
+	// 标签平滑：均匀预测时损失仍为 log3
+	uniform, _ := dubtorch.FromSlice([]float64{0, 0, 0}, []int{1, 3}, false)
+	one, _ := dubtorch.FromSlice([]float64{1}, []int{1}, false)
+	smoothed := dubtorch.ClassLossOptions{LabelSmoothing: 0.3}
+	if v := item(dubtorch.CrossEntropyLoss(uniform, one, smoothed)); !almostEqual(v, math.Log(3), 1e-12) {
+		t.Fatalf("CrossEntropy 标签平滑: %v", v)
+	}
+
+	none, err := dubtorch.CrossEntropyLoss(logits, classes, dubtorch.ClassLossOptions{Reduction: dubtorch.ReductionNone})
+	dubug.NoError(t, err)
+	if !dubug.Equal(none.Shape(), []int{2}) {
+		t.Fatalf("不归约时形状应为 [2]，实际 %v", none.Shape())
+	}
+
+	p, _ := dubtorch.FromSlice([]float64{0.5, 0.5, 0}, []int{3}, false)
+	q, _ := dubtorch.FromSlice([]float64{math.Log(0.25), math.Log(0.25), math.Log(0.5)}, []int{3}, false)
+	if v := item(dubtorch.KLDivLoss(q, p, dubtorch.ReductionSum)); !almostEqual(v, math.Log(2), 1e-12) {
+		t.Fatalf("KLDiv: %v", v)
+	}
+
+	if _, err := dubtorch.MSELoss(x, logits, dubtorch.ReductionMean); err == nil {
+		t.Fatalf("形状不一致时应返回错误")
+	}
+}
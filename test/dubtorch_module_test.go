@@ -0,0 +1,197 @@
+package test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 将输出与固定的随机权重相乘后求和，使梯度检查对 softmax 等归一化输出也有意义
+func weightedSum(r *rand.Rand, fn func() (*dubtorch.Tensor, error)) func() (*dubtorch.Tensor, error) {
+	var w *dubtorch.Tensor
+	return func() (*dubtorch.Tensor, error) {
+		y, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		if w == nil {
+			w = randomTensor(r, false, y.Shape()...)
+		}
+		if y, err = y.Mul(w); err != nil {
+			return nil, err
+		}
+		return y.Sum()
+	}
+}
+
+// 测试参数按名称注册与查找，以及训练/推理模式的递归切换
+func TestModuleRegistration(t *testing.T) {
+	dubtorch.ManualSeed(1)
+	model := dubtorch.NewSequential(
+		dubtorch.NewLinear(4, 8, true),
+		dubtorch.NewBatchNorm1d(8),
+		dubtorch.NewReLU(),
+		dubtorch.NewDropout(0.5),
+		dubtorch.NewLinear(8, 2, false),
+	)
+
+	var names []string
+	for _, p := range model.NamedParameters() {
+		names = append(names, p.Name)
+		if !p.Tensor.RequiresGrad {
+			t.Fatalf("参数 %s 应需要梯度", p.Name)
+		}
+	}
+	if !dubug.Equal(names, []string{"0.weight", "0.bias", "1.weight", "1.bias", "4.weight"}) {
+		t.Fatalf("参数名错误: %v", names)
+	}
+	var buffers []string
+	for _, b := range model.NamedBuffers() {
+		buffers = append(buffers, b.Name)
+	}
+	if !dubug.Equal(buffers, []string{"1.running_mean", "1.running_var"}) {
+		t.Fatalf("缓冲区名错误: %v", buffers)
+	}
+	if w, ok := model.LookupParameter("4.weight"); !ok || !dubug.Equal(w.Shape(), []int{2, 8}) {
+		t.Fatalf("按名称查找参数失败")
+	}
+
+	model.Eval()
+	if model.IsTraining() || model.At(3).IsTraining() {
+		t.Fatalf("Eval 应递归切换子模块")
+	}
+	model.Train()
+	if !model.At(1).IsTraining() {
+		t.Fatalf("Train 应递归切换子模块")
+	}
+
+	x := dubtorch.Randn(5, 4)
+	y, err := model.Forward(x)
+	dubug.NoError(t, err)
+	if !dubug.Equal(y.Shape(), []int{5, 2}) {
+		t.Fatalf("输出形状错误: %v", y.Shape())
+	}
+	loss, _ := y.Sum()
+	dubug.NoError(t, loss.Backward())
+	for _, p := range model.Parameters() {
+		if p.Grad == nil {
+			t.Fatalf("所有参数都应得到梯度")
+		}
+	}
+
+	// 相同种子下初始化可复现
+	dubtorch.ManualSeed(1)
+	again := dubtorch.NewLinear(4, 8, true)
+	first, _ := model.LookupParameter("0.weight")
+	if !dubug.Equal(again.Weight.Data.Data, first.Data.Data) {
+		t.Fatalf("ManualSeed 后初始化应可复现")
+	}
+}
+
+// 测试各层与激活函数的梯度
+func TestLayerGradients(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	dubtorch.ManualSeed(3)
+	x := randomTensor(r, true, 4, 3)
+	seq := randomTensor(r, true, 2, 5, 3)
+	channels := randomTensor(r, true, 2, 3, 5)
+
+	linear := dubtorch.NewLinear(3, 2, true)
+	layerNorm := dubtorch.NewLayerNorm(3)
+	batchNorm := dubtorch.NewBatchNorm1d(3)
+	embedding := dubtorch.NewEmbedding(5, 3)
+	indices, _ := dubtorch.FromSlice([]float64{0, 4, 4, 2}, []int{2, 2}, false)
+
+	// BatchNorm 处于训练模式，输出只依赖批统计量，梯度检查中多次前向不影响结果
+	tests := []struct {
+		name   string
+		module dubtorch.Module
+		input  *dubtorch.Tensor
+		inputs []*dubtorch.Tensor
+	}{
+		{"Linear", linear, x, []*dubtorch.Tensor{x, linear.Weight, linear.Bias}},
+		{"Linear3D", linear, seq, []*dubtorch.Tensor{seq, linear.Weight}},
+		{"LayerNorm", layerNorm, x, []*dubtorch.Tensor{x, layerNorm.Weight, layerNorm.Bias}},
+		{"BatchNorm1d", batchNorm, x, []*dubtorch.Tensor{x, batchNorm.Weight, batchNorm.Bias}},
+		{"BatchNorm1d3D", batchNorm, channels, []*dubtorch.Tensor{channels, batchNorm.Weight}},
+		{"Embedding", embedding, indices, []*dubtorch.Tensor{embedding.Weight}},
+		{"GELU", dubtorch.NewGELU(), x, []*dubtorch.Tensor{x}},
+		{"Tanh", dubtorch.NewTanh(), x, []*dubtorch.Tensor{x}},
+		{"Softmax", dubtorch.NewSoftmax(-1), x, []*dubtorch.Tensor{x}},
+		{"Softmax0", dubtorch.NewSoftmax(0), channels, []*dubtorch.Tensor{channels}},
+	}
+	for _, tc := range tests {
+		module, input := tc.module, tc.input
+		checkGradients(t, tc.name, weightedSum(r, func() (*dubtorch.Tensor, error) {
+			return module.Forward(input)
+		}), tc.inputs...)
+	}
+
+	logSoftmax := func() (*dubtorch.Tensor, error) { return x.LogSoftmax(1) }
+	checkGradients(t, "LogSoftmax", weightedSum(r, logSoftmax), x)
+}
+
+// 测试 LayerNorm/BatchNorm 的输出统计量与滑动平均
+func TestNormalization(t *testing.T) {
+	x, _ := dubtorch.FromSlice([]float64{1, 2, 3, 4, 10, 20, 30, 40}, []int{2, 4}, false)
+
+	ln, err := dubtorch.NewLayerNorm(4).Forward(x)
+	dubug.NoError(t, err)
+	for row := 0; row < 2; row++ {
+		var mean, sq float64
+		for _, v := range ln.Data.Data[row*4 : row*4+4] {
+			mean += v / 4
+			sq += v * v / 4
+		}
+		if !almostEqual(mean, 0, 1e-9) || !almostEqual(sq, 1, 1e-4) {
+			t.Fatalf("LayerNorm 第 %d 行均值 %v 方差 %v", row, mean, sq)
+		}
+	}
+
+	bn := dubtorch.NewBatchNorm1d(4)
+	_, err = bn.Forward(x)
+	dubug.NoError(t, err)
+	// 第 0 个通道的批均值为 5.5，无偏方差为 40.5
+	if !almostEqual(bn.RunningMean.Data[0], 0.55, 1e-12) || !almostEqual(bn.RunningVar.Data[0], 0.9+4.05, 1e-9) {
+		t.Fatalf("滑动平均错误: %v %v", bn.RunningMean.Data, bn.RunningVar.Data)
+	}
+
+	bn.Eval()
+	y, err := bn.Forward(x)
+	dubug.NoError(t, err)
+	want := (1 - 0.55) / math.Sqrt(4.95+1e-5)
+	if !almostEqual(y.Data.Data[0], want, 1e-12) {
+		t.Fatalf("推理模式应使用滑动平均: %v, 期望 %v", y.Data.Data[0], want)
+	}
+}
+
+// 测试 Dropout 在训练时缩放、在推理时不做处理
+func TestDropout(t *testing.T) {
+	dubtorch.ManualSeed(5)
+	d := dubtorch.NewDropout(0.25)
+	x := dubtorch.NewTensor(dubnp.Ones(1000), false)
+
+	y, err := d.Forward(x)
+	dubug.NoError(t, err)
+	zeros := 0
+	for _, v := range y.Data.Data {
+		switch {
+		case v == 0:
+			zeros++
+		case !almostEqual(v, 1/0.75, 1e-12):
+			t.Fatalf("保留的元素应放大为 %v, 实际 %v", 1/0.75, v)
+		}
+	}
+	if zeros < 200 || zeros > 300 {
+		t.Fatalf("置 0 的比例异常: %d/1000", zeros)
+	}
+
+	d.Eval()
+	if y, _ := d.Forward(x); y != x {
+		t.Fatalf("推理模式下 Dropout 应直接返回输入")
+	}
+}
@@ -0,0 +1,224 @@
+package test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/onnx"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 比较两个张量的形状与元素
+func checkClose(t *testing.T, name string, got, want *dubtorch.Tensor, tol float64) {
+	t.Helper()
+	if !dubug.Equal(got.Shape(), want.Shape()) {
+		t.Fatalf("%s 形状为 %v，期望 %v", name, got.Shape(), want.Shape())
+	}
+	for i := range want.Data.Data {
+		if !almostEqual(got.Data.Data[i], want.Data.Data[i], tol) {
+			t.Fatalf("%s[%d] = %v，期望 %v", name, i, got.Data.Data[i], want.Data.Data[i])
+		}
+	}
+}
+
+// 导出后再导入，计算结果与参数名均与原模型一致
+func TestONNXRoundTrip(t *testing.T) {
+	dubtorch.ManualSeed(46)
+	mlp := dubtorch.NewSequential(
+		dubtorch.NewLinear(4, 8, true), dubtorch.NewTanh(), dubtorch.NewDropout(0.5),
+		dubtorch.NewLinear(8, 3, false), dubtorch.NewSoftmax(-1),
+	)
+	conv, err := dubtorch.NewConv2d(2, 4, []int{3}, dubtorch.ConvOptions{Padding: []int{1}}, true)
+	dubug.NoError(t, err)
+	grouped, err := dubtorch.NewConv2d(4, 4, []int{3}, dubtorch.ConvOptions{Stride: []int{2}, Groups: 2}, true)
+	dubug.NoError(t, err)
+	cnn := dubtorch.NewSequential(
+		conv, dubtorch.NewReLU(), dubtorch.NewMaxPool2d(dubtorch.PoolOptions{KernelSize: []int{2}}),
+		dubtorch.NewAvgPool2d(dubtorch.PoolOptions{KernelSize: []int{3}, Stride: []int{1}, Padding: []int{1}}),
+		grouped, dubtorch.NewSigmoid(), dubtorch.NewAdaptiveAvgPool2d(1, 1), dubtorch.NewFlatten(),
+		dubtorch.NewLinear(4, 2, true),
+	)
+	cases := []struct {
+		name  string
+		model dubtorch.Module
+		input []int
+		dtype string
+		tol   float64
+	}{
+		{"mlp", mlp, []int{5, 4}, "F64", 1e-12},
+		{"cnn", cnn, []int{2, 2, 10, 10}, "F64", 1e-12},
+		{"cnn-f32", cnn, []int{2, 2, 10, 10}, "F32", 1e-5},
+	}
+	for _, c := range cases {
+		x := dubtorch.Randn(c.input...)
+		var buf bytes.Buffer
+		dubug.NoError(t, onnx.Save(&buf, c.model, x.Data, onnx.ExportOptions{DType: c.dtype, DynamicBatch: true}))
+		if !c.model.IsTraining() {
+			t.Fatalf("%s: 导出后应恢复训练模式", c.name)
+		}
+		imported, err := onnx.Load(&buf)
+		dubug.NoError(t, err)
+
+		var want, got *dubtorch.Tensor
+		c.model.Eval()
+		want, err = c.model.Forward(x)
+		dubug.NoError(t, err)
+		c.model.Train()
+		got, err = imported.Forward(x)
+		dubug.NoError(t, err)
+		checkClose(t, c.name, got, want, c.tol)
+
+		// 常量名与参数名一致，因此可以直接加载原模型的 StateDict
+		_, err = imported.LoadStateDict(c.model.StateDict(), true)
+		dubug.NoError(t, err)
+
+		// 批次维度是动态的，换一个批大小仍可执行
+		shape := append([]int{1}, c.input[1:]...)
+		one := dubtorch.Randn(shape...)
+		got, err = imported.Forward(one)
+		dubug.NoError(t, err)
+		c.model.Eval()
+		want, err = c.model.Forward(one)
+		dubug.NoError(t, err)
+		c.model.Train()
+		checkClose(t, c.name+" batch=1", got, want, 1e-12)
+	}
+
+	// 不支持的模块返回错误
+	_, err = onnx.Export(dubtorch.NewSequential(dubtorch.NewGELU()), dubnp.Ones(1, 2), onnx.ExportOptions{})
+	if err == nil || !strings.Contains(err.Error(), "GELU") {
+		t.Fatalf("导出 GELU 应报错，实际为 %v", err)
+	}
+}
+
+// 手工构造使用 MatMul、Add、Reshape、Gemm 与旧版 Softmax 的图，检查执行结果与反向传播
+func TestONNXImportGraph(t *testing.T) {
+	floats := func(name string, data []float64, dims ...int64) *onnx.TensorProto {
+		return &onnx.TensorProto{Name: name, Dims: dims, DataType: onnx.DataTypeFloat, Data: data}
+	}
+	model := &onnx.ModelProto{
+		IRVersion:   7,
+		OpsetImport: []onnx.OperatorSetID{{Version: 11}},
+		Graph: &onnx.GraphProto{
+			Name: "handmade",
+			Initializer: []*onnx.TensorProto{
+				floats("w", []float64{1, 2, 3, 4, 5, 6}, 3, 2),
+				floats("b", []float64{0.5, -0.5}, 2),
+				floats("g", []float64{1, 0, 0, 1, 1, 1}, 3, 2),
+			},
+			Node: []*onnx.NodeProto{
+				{OpType: "Constant", Output: []string{"shape"}, Attribute: []*onnx.AttributeProto{
+					{Name: "value", Type: onnx.AttrTensor, T: &onnx.TensorProto{Dims: []int64{2}, DataType: onnx.DataTypeInt64, Data: []float64{-1, 3}}},
+				}},
+				{OpType: "MatMul", Input: []string{"x", "w"}, Output: []string{"xw"}},
+				{OpType: "Add", Input: []string{"xw", "b"}, Output: []string{"h"}},
+				{OpType: "Relu", Input: []string{"h"}, Output: []string{"r"}},
+				// (2, 2) 的 r 转置后与 (3, 2) 的 g 转置相乘得到 (2, 3)
+				{OpType: "Gemm", Input: []string{"r", "g"}, Output: []string{"z"}, Attribute: []*onnx.AttributeProto{
+					{Name: "transA", Type: onnx.AttrInt, I: 1}, {Name: "transB", Type: onnx.AttrInt, I: 1},
+					{Name: "alpha", Type: onnx.AttrFloat, F: 0.5},
+				}},
+				{OpType: "Reshape", Input: []string{"z", "shape"}, Output: []string{"zr"}},
+				// opset 11 的 Softmax 把 axis 之后的维度展平
+				{OpType: "Softmax", Input: []string{"zr"}, Output: []string{"y"}, Attribute: []*onnx.AttributeProto{
+					{Name: "axis", Type: onnx.AttrInt, I: 0},
+				}},
+			},
+			Input:  []*onnx.ValueInfoProto{{Name: "x", ElemType: onnx.DataTypeFloat, Dims: []int64{2, 3}}},
+			Output: []*onnx.ValueInfoProto{{Name: "y", ElemType: onnx.DataTypeFloat, Dims: []int64{2, 3}}},
+		},
+	}
+	parsed, err := onnx.Unmarshal(model.Marshal())
+	dubug.NoError(t, err)
+	if parsed.Graph.Name != "handmade" || len(parsed.Graph.Node) != 7 || parsed.Graph.Node[4].Attribute[2].F != 0.5 {
+		t.Fatalf("解码结果与编码前不一致: %+v", parsed.Graph)
+	}
+	imported, err := onnx.NewModule(parsed)
+	dubug.NoError(t, err)
+	if imported.Opset() != 11 || !dubug.Equal(imported.Inputs, []string{"x"}) || len(imported.Parameters()) != 3 {
+		t.Fatalf("Opset=%d Inputs=%v 参数个数 %d", imported.Opset(), imported.Inputs, len(imported.Parameters()))
+	}
+
+	xa, err := dubnp.NewArray([]float64{1, -1, 0.5, 0, 2, -1}, []int{2, 3})
+	dubug.NoError(t, err)
+	x := dubtorch.NewTensor(xa, false)
+	got, err := imported.Forward(x)
+	dubug.NoError(t, err)
+
+	// 用张量运算计算期望结果
+	w, _ := imported.LookupParameter("w")
+	b, _ := imported.LookupParameter("b")
+	g, _ := imported.LookupParameter("g")
+	h, err := x.MatMul(w)
+	dubug.NoError(t, err)
+	h, err = h.Add(b)
+	dubug.NoError(t, err)
+	rt, err := h.ReLU().Transpose()
+	dubug.NoError(t, err)
+	gt, err := g.Transpose()
+	dubug.NoError(t, err)
+	z, err := rt.MatMul(gt)
+	dubug.NoError(t, err)
+	flat, err := z.MulScalar(0.5).Reshape(1, 6)
+	dubug.NoError(t, err)
+	want, err := flat.Softmax(1)
+	dubug.NoError(t, err)
+	want, err = want.Reshape(2, 3)
+	dubug.NoError(t, err)
+	checkClose(t, "handmade", got, want, 1e-12)
+
+	// 浮点常量作为参数参与求导
+	loss, err := got.Narrow(1, 0, 1)
+	dubug.NoError(t, err)
+	loss, err = loss.Sum()
+	dubug.NoError(t, err)
+	dubug.NoError(t, loss.Backward())
+	for _, p := range imported.NamedParameters() {
+		if p.Tensor.Grad == nil {
+			t.Fatalf("参数 %s 没有梯度", p.Name)
+		}
+	}
+
+	// 写文件再读回
+	path := filepath.Join(t.TempDir(), "handmade.onnx")
+	dubug.NoError(t, os.WriteFile(path, model.Marshal(), 0o644))
+	reloaded, err := onnx.LoadFile(path)
+	dubug.NoError(t, err)
+	again, err := reloaded.Forward(x)
+	dubug.NoError(t, err)
+	checkClose(t, "reloaded", again, want, 1e-12)
+
+	// 必需的输入名为空时在构建时报错，可选输入可以省略
+	model.Graph.Node[2].Input[1] = ""
+	if _, err := onnx.NewModule(model); err == nil || !strings.Contains(err.Error(), "必需") {
+		t.Fatalf("Add 缺少第二个输入应报错，实际为 %v", err)
+	}
+	model.Graph.Node[2].Input[1] = "b"
+	model.Graph.Node[3].Input = append(model.Graph.Node[3].Input, "")
+	_, err = onnx.NewModule(model)
+	dubug.NoError(t, err)
+
+	// 形状无效的常量在解码与构建时都报错
+	good := model.Graph.Initializer[1]
+	for _, dims := range [][]int64{{-1, -1}, {1 << 32, 1 << 32}, {1 << 62, 4}, {3}} {
+		model.Graph.Initializer[1] = floats("b", []float64{0.5, -0.5}, dims...)
+		if _, err := onnx.Unmarshal(model.Marshal()); err == nil {
+			t.Fatalf("形状 %v 的常量应在解码时报错", dims)
+		}
+		if _, err := onnx.NewModule(model); err == nil {
+			t.Fatalf("形状 %v 的常量应在构建时报错", dims)
+		}
+	}
+	model.Graph.Initializer[1] = good
+
+	// 不支持的算子在构建时报错
+	model.Graph.Node = append(model.Graph.Node, &onnx.NodeProto{OpType: "LSTM", Input: []string{"y"}, Output: []string{"o"}})
+	if _, err := onnx.NewModule(model); err == nil || !strings.Contains(err.Error(), "LSTM") {
+		t.Fatalf("LSTM 应报不支持，实际为 %v", err)
+	}
+}
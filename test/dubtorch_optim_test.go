@@ -0,0 +1,237 @@
+package test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"math"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 对 sum((x-3)^2) 求梯度并执行 n 步更新
+func quadraticSteps(t *testing.T, x *dubtorch.Tensor, opt optim.Optimizer, n int) {
+	target := dubtorch.NewTensor(dubnp.Full(3, x.Shape()...), false)
+	for i := 0; i < n; i++ {
+		opt.ZeroGrad()
+		diff, err := x.Sub(target)
+		dubug.NoError(t, err)
+		loss, err := diff.Pow(2).Sum()
+		dubug.NoError(t, err)
+		dubug.NoError(t, loss.Backward())
+		dubug.NoError(t, opt.Step())
+	}
+}
+
+func namedParam(x *dubtorch.Tensor) []dubtorch.NamedTensor {
+	return []dubtorch.NamedTensor{{Name: "layer.x", Tensor: x}}
+}
+
+// 测试各优化器都能收敛到二次函数的最小值
+func TestOptimizersConverge(t *testing.T) {
+	makers := map[string]func(p []dubtorch.NamedTensor) (optim.Optimizer, error){
+		"SGD": func(p []dubtorch.NamedTensor) (optim.Optimizer, error) {
+			return optim.NewSGD(p, optim.SGDOptions{LR: 0.1})
+		},
+		"Momentum": func(p []dubtorch.NamedTensor) (optim.Optimizer, error) {
+			return optim.NewSGD(p, optim.SGDOptions{LR: 0.05, Momentum: 0.9})
+		},
+		"Nesterov": func(p []dubtorch.NamedTensor) (optim.Optimizer, error) {
+			return optim.NewSGD(p, optim.SGDOptions{LR: 0.05, Momentum: 0.9, Nesterov: true})
+		},
+		"Adam": func(p []dubtorch.NamedTensor) (optim.Optimizer, error) {
+			return optim.NewAdam(p, optim.AdamOptions{LR: 0.1})
+		},
+		"RMSProp": func(p []dubtorch.NamedTensor) (optim.Optimizer, error) {
+			return optim.NewRMSProp(p, optim.RMSPropOptions{LR: 0.02})
+		},
+		"Adagrad": func(p []dubtorch.NamedTensor) (optim.Optimizer, error) {
+			return optim.NewAdagrad(p, optim.AdagradOptions{LR: 0.5})
+		},
+	}
+	for name, makeOpt := range makers {
+		x, _ := dubtorch.FromSlice([]float64{-1, 0, 5}, []int{3}, true)
+		opt, err := makeOpt(namedParam(x))
+		dubug.NoError(t, err)
+		quadraticSteps(t, x, opt, 500)
+		for _, v := range x.Data.Data {
+			if !almostEqual(v, 3, 1e-2) {
+				t.Fatalf("%s 未收敛: %v", name, x.Data.Data)
+			}
+		}
+	}
+}
+
+// 测试动量、权重衰减与 AdamW 的更新公式
+func TestOptimizerUpdateRules(t *testing.T) {
+	// 梯度恒为 1：第一步动量为 1，第二步为 0.9+1
+	x, _ := dubtorch.FromSlice([]float64{0}, []int{1}, true)
+	sgd, err := optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 0.1, Momentum: 0.9})
+	dubug.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		x.Grad = dubnp.Ones(1)
+		dubug.NoError(t, sgd.Step())
+	}
+	if !almostEqual(x.Data.Data[0], -0.1-0.19, 1e-12) {
+		t.Fatalf("动量更新错误: %v", x.Data.Data[0])
+	}
+
+	// Adam 第一步的更新量约为 lr
+	y, _ := dubtorch.FromSlice([]float64{1}, []int{1}, true)
+	adam, _ := optim.NewAdam(namedParam(y), optim.AdamOptions{LR: 0.01})
+	y.Grad = dubnp.Full(123, 1)
+	dubug.NoError(t, adam.Step())
+	if !almostEqual(y.Data.Data[0], 0.99, 1e-8) {
+		t.Fatalf("Adam 第一步更新错误: %v", y.Data.Data[0])
+	}
+
+	// AdamW 在梯度为 0 时只做解耦的权重衰减
+	z, _ := dubtorch.FromSlice([]float64{2}, []int{1}, true)
+	adamw, _ := optim.NewAdamW(namedParam(z), optim.AdamOptions{LR: 0.1, WeightDecay: 0.5})
+	z.Grad = dubnp.Zeros(1)
+	dubug.NoError(t, adamw.Step())
+	if !almostEqual(z.Data.Data[0], 2*(1-0.05), 1e-12) {
+		t.Fatalf("AdamW 权重衰减错误: %v", z.Data.Data[0])
+	}
+
+	if _, err := optim.NewSGD(namedParam(x), optim.SGDOptions{Nesterov: true}); err == nil {
+		t.Fatalf("没有动量时不应允许 Nesterov")
+	}
+}
+
+// 测试优化器状态经 gob 编码后恢复，继续训练的结果与不中断一致
+func TestOptimizerStateResume(t *testing.T) {
+	newAdam := func(x *dubtorch.Tensor) *optim.Adam {
+		opt, err := optim.NewAdam(namedParam(x), optim.AdamOptions{LR: 0.05})
+		dubug.NoError(t, err)
+		return opt
+	}
+
+	full, _ := dubtorch.FromSlice([]float64{0, 1}, []int{2}, true)
+	quadraticSteps(t, full, newAdam(full), 5)
+
+	x, _ := dubtorch.FromSlice([]float64{0, 1}, []int{2}, true)
+	opt := newAdam(x)
+	quadraticSteps(t, x, opt, 3)
+
+	var buf bytes.Buffer
+	dubug.NoError(t, gob.NewEncoder(&buf).Encode(opt.StateDict()))
+	var state map[string]*dubnp.Array
+	dubug.NoError(t, gob.NewDecoder(&buf).Decode(&state))
+	if _, ok := state["layer.x.exp_avg_sq"]; !ok {
+		t.Fatalf("状态中缺少 layer.x.exp_avg_sq: %v", state)
+	}
+
+	resumed := newAdam(x)
+	dubug.NoError(t, resumed.LoadStateDict(state))
+	quadraticSteps(t, x, resumed, 2)
+	if !dubug.Equal(x.Data.Data, full.Data.Data) {
+		t.Fatalf("恢复后的结果 %v 与不中断的结果 %v 不一致", x.Data.Data, full.Data.Data)
+	}
+
+	state["layer.x.exp_avg"] = dubnp.Zeros(3)
+	if err := resumed.LoadStateDict(state); err == nil {
+		t.Fatalf("形状不一致时应返回错误")
+	}
+	delete(state, "layer.x.exp_avg")
+	state["other.exp_avg"] = dubnp.Zeros(2)
+	if err := resumed.LoadStateDict(state); err == nil {
+		t.Fatalf("未知的键应返回错误")
+	}
+}
+
+// 测试学习率调度器
+func TestSchedulers(t *testing.T) {
+	x, _ := dubtorch.FromSlice([]float64{0}, []int{1}, true)
+	lrs := func(opt optim.Optimizer, s optim.Scheduler, n int) []float64 {
+		out := []float64{opt.LR()}
+		for i := 0; i < n; i++ {
+			s.Step()
+			out = append(out, math.Round(opt.LR()*1e9)/1e9)
+		}
+		return out
+	}
+
+	opt, _ := optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 1})
+	step, _ := optim.NewStepLR(opt, 2, 0.5)
+	if got := lrs(opt, step, 5); !dubug.Equal(got, []float64{1, 1, 0.5, 0.5, 0.25, 0.25}) {
+		t.Fatalf("StepLR 错误: %v", got)
+	}
+
+	opt, _ = optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 1})
+	cosine, _ := optim.NewCosineAnnealingLR(opt, 4, 0)
+	want := []float64{1, (1 + math.Sqrt2/2) / 2, 0.5, (1 - math.Sqrt2/2) / 2, 0, 0}
+	for i, v := range lrs(opt, cosine, 5) {
+		if !almostEqual(v, want[i], 1e-9) {
+			t.Fatalf("CosineAnnealingLR 第 %d 轮: %v, 期望 %v", i, v, want[i])
+		}
+	}
+
+	opt, _ = optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 1})
+	warmup, _ := optim.NewLinearWarmupLR(opt, 4, 0)
+	if got := lrs(opt, warmup, 5); !dubug.Equal(got, []float64{0, 0.25, 0.5, 0.75, 1, 1}) {
+		t.Fatalf("LinearWarmupLR 错误: %v", got)
+	}
+	// 从状态恢复调度器
+	opt2, _ := optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 1})
+	warmup2, _ := optim.NewLinearWarmupLR(opt2, 4, 0)
+	dubug.NoError(t, warmup2.LoadStateDict(warmup.StateDict()))
+	if opt2.LR() != 1 || warmup2.LastEpoch() != 5 {
+		t.Fatalf("调度器状态恢复错误: lr=%v epoch=%d", opt2.LR(), warmup2.LastEpoch())
+	}
+
+	opt, _ = optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 1})
+	plateau, _ := optim.NewReduceLROnPlateau(opt, optim.PlateauOptions{Factor: 0.5, Patience: 1})
+	var got []float64
+	for _, loss := range []float64{5, 4, 4, 4, 3, 3, 3} {
+		plateau.Observe(loss)
+		plateau.Step()
+		got = append(got, opt.LR())
+	}
+	if !dubug.Equal(got, []float64{1, 1, 1, 0.5, 0.5, 0.5, 0.25}) {
+		t.Fatalf("ReduceLROnPlateau 错误: %v", got)
+	}
+	// 通过 Scheduler 接口调用，没有记录指标的一轮视为没有改善
+	var sched optim.Scheduler = plateau
+	sched.Step()
+	if opt.LR() != 0.25 || sched.LastEpoch() != 8 {
+		t.Fatalf("ReduceLROnPlateau 错误: lr=%v epoch=%d", opt.LR(), sched.LastEpoch())
+	}
+
+	// 余弦调度器恢复时使用保存的初始学习率
+	opt, _ = optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 1})
+	cosine, _ = optim.NewCosineAnnealingLR(opt, 4, 0.2)
+	cosine.Step()
+	opt2, _ = optim.NewSGD(namedParam(x), optim.SGDOptions{LR: 0.1})
+	resumed, _ := optim.NewCosineAnnealingLR(opt2, 4, 0.2)
+	dubug.NoError(t, resumed.LoadStateDict(cosine.StateDict()))
+	if !almostEqual(opt2.LR(), opt.LR(), 1e-12) {
+		t.Fatalf("CosineAnnealingLR 恢复后学习率为 %v，期望 %v", opt2.LR(), opt.LR())
+	}
+	resumed.Step()
+	if !almostEqual(opt2.LR(), 0.6, 1e-12) {
+		t.Fatalf("CosineAnnealingLR 恢复后第 2 轮学习率为 %v，期望 0.6", opt2.LR())
+	}
+}
+
+// 测试梯度裁剪
+func TestGradClipping(t *testing.T) {
+	a, _ := dubtorch.FromSlice([]float64{0, 0}, []int{2}, true)
+	b, _ := dubtorch.FromSlice([]float64{0}, []int{1}, true)
+	a.Grad = &dubnp.Array{Data: []float64{3, 0}, Shape: []int{2}}
+	b.Grad = &dubnp.Array{Data: []float64{4}, Shape: []int{1}}
+
+	norm := optim.ClipGradNorm([]*dubtorch.Tensor{a, b}, 1)
+	if norm != 5 || !almostEqual(a.Grad.Data[0], 0.6, 1e-6) || !almostEqual(b.Grad.Data[0], 0.8, 1e-6) {
+		t.Fatalf("按范数裁剪错误: norm=%v %v %v", norm, a.Grad.Data, b.Grad.Data)
+	}
+
+	b.Grad.Data[0] = -7
+	optim.ClipGradValue([]*dubtorch.Tensor{a, b}, 0.7)
+	if !almostEqual(a.Grad.Data[0], 0.6, 1e-6) || b.Grad.Data[0] != -0.7 {
+		t.Fatalf("按值裁剪错误: %v %v", a.Grad.Data, b.Grad.Data)
+	}
+}
@@ -0,0 +1,208 @@
+package test
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/quant"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// int8 矩阵乘法与逐元素的 int64 计算完全一致，包括每行不同的零点
+func TestInt8MatMul(t *testing.T) {
+	r := rand.New(rand.NewSource(47))
+	randInt8 := func(n int) []int8 {
+		out := make([]int8, n)
+		for i := range out {
+			out[i] = int8(r.Intn(256) - 128)
+		}
+		return out
+	}
+	m, k, n := 37, 300, 45
+	a, err := dubnp.NewInt8Array(randInt8(m*k), []int{m, k})
+	dubug.NoError(t, err)
+	b, err := dubnp.NewInt8Array(randInt8(n*k), []int{n, k})
+	dubug.NoError(t, err)
+	za := []int32{-128}
+	zb := make([]int32, n)
+	for i := range zb {
+		zb[i] = int32(r.Intn(256) - 128)
+	}
+	c, err := dubnp.Int8MatMulTransB(a, b, za, zb)
+	dubug.NoError(t, err)
+	if !dubug.Equal(c.Shape, []int{m, n}) {
+		t.Fatalf("结果形状为 %v", c.Shape)
+	}
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			var want int64
+			for p := 0; p < k; p++ {
+				want += (int64(a.Data[i*k+p]) - int64(za[0])) * (int64(b.Data[j*k+p]) - int64(zb[j]))
+			}
+			if int64(c.Data[i*n+j]) != want {
+				t.Fatalf("c[%d][%d] = %d，期望 %d", i, j, c.Data[i*n+j], want)
+			}
+		}
+	}
+	if _, err := dubnp.Int8MatMulTransB(a, a, za, zb); err == nil {
+		t.Fatalf("零点个数与行数不符时应报错")
+	}
+
+	// 量化后还原的误差不超过半个量化步长，超出范围的值被截断
+	x := dubnp.Zeros(2, 3)
+	copy(x.Data, []float64{-1, 0, 0.37, 2, -4, 9})
+	scale, zero := []float64{0.01, 0.05}, []int32{0, -10}
+	q, err := x.QuantizeInt8(scale, zero, 0)
+	dubug.NoError(t, err)
+	back, err := q.Dequantize(scale, zero, 0)
+	dubug.NoError(t, err)
+	for i, v := range []float64{-1, 0, 0.37, 2, -4, 6.85} {
+		if math.Abs(back.Data[i]-v) > scale[i/3]/2+1e-12 {
+			t.Fatalf("第 %d 个元素还原为 %v，期望 %v", i, back.Data[i], v)
+		}
+	}
+}
+
+// 测试 min/max 与百分位观察器得到的量化参数
+func TestObservers(t *testing.T) {
+	w := dubnp.Zeros(2, 3)
+	copy(w.Data, []float64{-1, 0.5, 0.25, 0, 3, 1})
+	perChannel := quant.NewMinMaxObserver(0)
+	dubug.NoError(t, perChannel.Observe(w))
+	p, err := perChannel.QParams(quant.Symmetric)
+	dubug.NoError(t, err)
+	if p.Axis != 0 || !almostEqual(p.Scale[0], 1.0/127, 1e-12) || !almostEqual(p.Scale[1], 3.0/127, 1e-12) || p.ZeroPoint[1] != 0 {
+		t.Fatalf("逐通道对称量化参数为 %+v", p)
+	}
+	// 非对称量化时范围扩展到包含 0，最小值映射到 -128
+	positive := quant.NewMinMaxObserver(-1)
+	dubug.NoError(t, positive.Observe(dubnp.Ones(4)))
+	p, err = positive.QParams(quant.Asymmetric)
+	dubug.NoError(t, err)
+	if !almostEqual(p.Scale[0], 1.0/255, 1e-12) || p.ZeroPoint[0] != -128 {
+		t.Fatalf("非对称量化参数为 %+v", p)
+	}
+
+	// 百分位观察器忽略离群值
+	data := dubnp.Zeros(1001)
+	for i := range data.Data {
+		data.Data[i] = float64(i)
+	}
+	data.Data[1000] = 1e6
+	pct := quant.NewPercentileObserver(99)
+	dubug.NoError(t, pct.Observe(data))
+	p, err = pct.QParams(quant.Symmetric)
+	dubug.NoError(t, err)
+	if p.Scale[0] > 1000.0/127 {
+		t.Fatalf("百分位观察器的 scale 为 %v，离群值未被忽略", p.Scale[0])
+	}
+	mm := quant.NewMinMaxObserver(-1)
+	dubug.NoError(t, mm.Observe(data))
+	p, err = mm.QParams(quant.Symmetric)
+	dubug.NoError(t, err)
+	if p.Scale[0] != 1e6/127 {
+		t.Fatalf("min/max 观察器的 scale 为 %v", p.Scale[0])
+	}
+}
+
+// 量化卷积与浮点卷积的结果接近，覆盖分组、填充、步长与一维卷积
+func TestQuantConv(t *testing.T) {
+	dubtorch.ManualSeed(47)
+	conv2, err := dubtorch.NewConv2d(4, 6, []int{3}, dubtorch.ConvOptions{Stride: []int{2}, Padding: []int{1}, Groups: 2}, true)
+	dubug.NoError(t, err)
+	conv1, err := dubtorch.NewConv1d(3, 5, 3, dubtorch.ConvOptions{Padding: []int{2}, Dilation: []int{2}}, false)
+	dubug.NoError(t, err)
+	cases := []struct {
+		conv  *dubtorch.Conv
+		input []int
+	}{
+		{conv2, []int{2, 4, 9, 9}},
+		{conv1, []int{3, 3, 11}},
+	}
+	for _, c := range cases {
+		// 输入平移到正数区间，非对称量化时补零位置使用非零的零点
+		x := dubtorch.Randn(c.input...).AddScalar(1)
+		want, err := c.conv.Forward(x)
+		dubug.NoError(t, err)
+		for _, cfg := range []quant.Config{
+			{},
+			{WeightScheme: quant.Asymmetric, ActivationScheme: quant.Asymmetric},
+			{PerChannel: true, ActivationScheme: quant.Asymmetric},
+			{WeightScheme: quant.Asymmetric, PerChannel: true},
+		} {
+			q, err := quant.NewQuantConv(c.conv, nil, cfg)
+			dubug.NoError(t, err)
+			got, err := q.Forward(x)
+			dubug.NoError(t, err)
+			if !dubug.Equal(got.Shape(), want.Shape()) {
+				t.Fatalf("输出形状为 %v，期望 %v", got.Shape(), want.Shape())
+			}
+			maxErr, maxVal := 0.0, 0.0
+			for i, v := range want.Data.Data {
+				maxErr = math.Max(maxErr, math.Abs(got.Data.Data[i]-v))
+				maxVal = math.Max(maxVal, math.Abs(v))
+			}
+			if maxErr > 0.03*maxVal {
+				t.Fatalf("%+v: 最大误差 %v，输出最大值 %v", cfg, maxErr, maxVal)
+			}
+		}
+	}
+}
+
+// 训练后静态量化：校准、替换层并报告准确率变化
+func TestQuantizeModel(t *testing.T) {
+	r := rand.New(rand.NewSource(47))
+	dubtorch.ManualSeed(47)
+	train, err := dubtorch.NewDataLoader(clusterDataset(r, 256), dubtorch.DataLoaderOptions{BatchSize: 32, Shuffle: true, Seed: 1})
+	dubug.NoError(t, err)
+	test, err := dubtorch.NewDataLoader(clusterDataset(r, 200), dubtorch.DataLoaderOptions{BatchSize: 50})
+	dubug.NoError(t, err)
+	model := dubtorch.NewSequential(
+		dubtorch.NewLinear(2, 32, true), dubtorch.NewReLU(),
+		dubtorch.NewSequential(dubtorch.NewLinear(32, 32, true), dubtorch.NewReLU()),
+		dubtorch.NewLinear(32, 2, true),
+	)
+	opt, err := optim.NewAdam(model.NamedParameters(), optim.AdamOptions{LR: 0.02})
+	dubug.NoError(t, err)
+	trainer, err := dubtorch.NewTrainer(model, opt, crossEntropy, train, dubtorch.TrainerOptions{Epochs: 3})
+	dubug.NoError(t, err)
+	dubug.NoError(t, trainer.Fit(context.Background()))
+
+	configs := []quant.Config{
+		{PerChannel: true, ActivationScheme: quant.Asymmetric},
+		{NewObserver: func() quant.Observer { return quant.NewPercentileObserver(99.9) }},
+	}
+	for _, cfg := range configs {
+		quantized, err := quant.QuantizeModel(model, train, cfg)
+		dubug.NoError(t, err)
+		if !model.IsTraining() {
+			t.Fatalf("校准后应恢复原模型的训练模式")
+		}
+		if len(quantized.NamedParameters()) != 0 {
+			t.Fatalf("量化模型不应再有浮点参数: %d", len(quantized.NamedParameters()))
+		}
+		report, err := quant.Evaluate(model, quantized, test)
+		dubug.NoError(t, err)
+		if report.Samples != 200 || report.FloatAccuracy < 0.95 || math.Abs(report.AccuracyDelta()) > 0.02 || report.Agreement < 0.97 {
+			t.Fatalf("量化报告不符合预期: %v", report)
+		}
+		// 权重由 float64 变为 int8，偏置与量化参数仍以 float64 保存
+		if report.QuantBytes*3 > report.FloatBytes {
+			t.Fatalf("量化后权重 %d 字节，量化前 %d 字节", report.QuantBytes, report.FloatBytes)
+		}
+	}
+
+	// 不校准时按批次动态量化
+	dynamic, err := quant.QuantizeModel(model, nil, quant.Config{ActivationScheme: quant.Asymmetric})
+	dubug.NoError(t, err)
+	report, err := quant.Evaluate(model, dynamic, test)
+	dubug.NoError(t, err)
+	if report.Agreement < 0.97 {
+		t.Fatalf("动态量化报告不符合预期: %v", report)
+	}
+}
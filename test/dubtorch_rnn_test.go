@@ -0,0 +1,142 @@
+package test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+var recurrentModes = []struct {
+	name string
+	mode dubtorch.RecurrentMode
+}{
+	{"RNN", dubtorch.ModeRNNTanh},
+	{"RNNReLU", dubtorch.ModeRNNReLU},
+	{"GRU", dubtorch.ModeGRU},
+	{"LSTM", dubtorch.ModeLSTM},
+}
+
+// 测试多层双向循环层在变长序列上的梯度
+func TestRecurrentGradients(t *testing.T) {
+	r := rand.New(rand.NewSource(31))
+	dubtorch.ManualSeed(31)
+	a := randomTensor(r, true, 4, 3)
+	b := randomTensor(r, true, 2, 3)
+
+	for _, tc := range recurrentModes {
+		rnn, err := dubtorch.NewRecurrent(tc.mode, 3, 2, dubtorch.RecurrentOptions{NumLayers: 2, Bidirectional: true})
+		dubug.NoError(t, err)
+		h0 := randomTensor(r, true, 4, 2, 2)
+		c0 := randomTensor(r, true, 4, 2, 2)
+
+		fn := func() (*dubtorch.Tensor, error) {
+			packed, err := dubtorch.PackSequences(a, b)
+			if err != nil {
+				return nil, err
+			}
+			state := &dubtorch.RecurrentState{H: h0}
+			if tc.mode == dubtorch.ModeLSTM {
+				state.C = c0
+			}
+			out, final, err := rnn.ForwardPacked(packed, state)
+			if err != nil {
+				return nil, err
+			}
+			// 同时让输出与最终状态参与损失
+			flatOut, err := out.Data.Reshape(-1)
+			if err != nil {
+				return nil, err
+			}
+			flatH, err := final.H.Reshape(-1)
+			if err != nil {
+				return nil, err
+			}
+			return dubtorch.Concat(0, flatOut, flatH)
+		}
+		inputs := append([]*dubtorch.Tensor{a, b, h0}, rnn.Parameters()...)
+		if tc.mode == dubtorch.ModeLSTM {
+			inputs = append(inputs, c0)
+		}
+		checkGradients(t, tc.name, weightedSum(r, fn), inputs...)
+	}
+}
+
+// 测试补零的序列与单独运行的结果一致，且补零位置的输出为 0
+func TestRecurrentPackedMasking(t *testing.T) {
+	r := rand.New(rand.NewSource(32))
+	dubtorch.ManualSeed(32)
+	long := randomTensor(r, true, 5, 3)
+	short := randomTensor(r, true, 2, 3)
+
+	for _, tc := range recurrentModes {
+		rnn, err := dubtorch.NewRecurrent(tc.mode, 3, 4, dubtorch.RecurrentOptions{Bidirectional: true})
+		dubug.NoError(t, err)
+
+		packed, err := dubtorch.PackSequences(long, short)
+		dubug.NoError(t, err)
+		out, final, err := rnn.ForwardPacked(packed, nil)
+		dubug.NoError(t, err)
+		seqs, err := out.Unpack()
+		dubug.NoError(t, err)
+
+		// 单独运行较短的序列
+		alone, err := short.Reshape(2, 1, 3)
+		dubug.NoError(t, err)
+		want, wantState, err := rnn.ForwardState(alone, nil)
+		dubug.NoError(t, err)
+		for i, v := range want.Data.Data {
+			if !almostEqual(seqs[1].Data.Data[i], v, 1e-12) {
+				t.Fatalf("%s: 变长批次中的输出 %v 与单独运行 %v 不一致", tc.name, seqs[1].Data.Data, want.Data.Data)
+			}
+		}
+		// 最终状态 (方向, 批次, H) 中第 1 个序列的部分
+		for d := 0; d < 2; d++ {
+			for k := 0; k < 4; k++ {
+				got := final.H.Data.Data[(d*2+1)*4+k]
+				if !almostEqual(got, wantState.H.Data.Data[d*4+k], 1e-12) {
+					t.Fatalf("%s: 方向 %d 的最终状态不一致", tc.name, d)
+				}
+			}
+		}
+		// 补零位置 (t >= 2, 批次 1) 的输出为 0
+		for tt := 2; tt < 5; tt++ {
+			for k := 0; k < 8; k++ {
+				if v := out.Data.Data.Data[(tt*2+1)*8+k]; v != 0 {
+					t.Fatalf("%s: 补零位置的输出应为 0，实际 %v", tc.name, v)
+				}
+			}
+		}
+	}
+}
+
+// 测试参数名、BatchFirst 与输出形状
+func TestRecurrentShapes(t *testing.T) {
+	dubtorch.ManualSeed(33)
+	lstm, err := dubtorch.NewLSTM(3, 5, dubtorch.RecurrentOptions{NumLayers: 2, Bidirectional: true, BatchFirst: true, Dropout: 0.2})
+	dubug.NoError(t, err)
+
+	var names []string
+	for _, p := range lstm.NamedParameters() {
+		names = append(names, p.Name)
+	}
+	if len(names) != 16 || names[0] != "weight_ih_l0" || names[4] != "weight_ih_l0_reverse" || names[8] != "weight_ih_l1" {
+		t.Fatalf("参数名错误: %v", names)
+	}
+	if w, _ := lstm.LookupParameter("weight_ih_l1"); !dubug.Equal(w.Shape(), []int{20, 10}) {
+		t.Fatalf("第二层输入权重形状错误: %v", w.Shape())
+	}
+
+	x := dubtorch.Randn(4, 6, 3)
+	out, state, err := lstm.ForwardState(x, nil)
+	dubug.NoError(t, err)
+	if !dubug.Equal(out.Shape(), []int{4, 6, 10}) || !dubug.Equal(state.H.Shape(), []int{4, 4, 5}) || !dubug.Equal(state.C.Shape(), []int{4, 4, 5}) {
+		t.Fatalf("形状错误: out=%v h=%v c=%v", out.Shape(), state.H.Shape(), state.C.Shape())
+	}
+
+	gru, _ := dubtorch.NewGRU(3, 5, dubtorch.RecurrentOptions{})
+	if _, _, err := gru.ForwardState(dubtorch.Randn(6, 4, 3), &dubtorch.RecurrentState{H: dubtorch.Randn(2, 4, 5)}); err == nil {
+		t.Fatalf("初始状态形状错误时应返回错误")
+	}
+}
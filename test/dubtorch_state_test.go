@@ -0,0 +1,175 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+func checkpointModel() *dubtorch.Sequential {
+	return dubtorch.NewSequential(dubtorch.NewLinear(3, 4, true), dubtorch.NewBatchNorm1d(4), dubtorch.NewReLU(), dubtorch.NewLinear(4, 2, false))
+}
+
+// 训练一步：前向、反向并更新参数
+func checkpointStep(t *testing.T, model dubtorch.Module, opt optim.Optimizer, x *dubtorch.Tensor) {
+	opt.ZeroGrad()
+	y, err := model.Forward(x)
+	dubug.NoError(t, err)
+	loss, err := y.Pow(2).Mean()
+	dubug.NoError(t, err)
+	dubug.NoError(t, loss.Backward())
+	dubug.NoError(t, opt.Step())
+}
+
+// 测试模型与优化器经 safetensors 文件保存后恢复，继续训练的结果与不中断一致
+func TestCheckpointResume(t *testing.T) {
+	dubtorch.ManualSeed(39)
+	x := dubtorch.Randn(5, 3)
+	model := checkpointModel()
+	opt, err := optim.NewAdam(model.NamedParameters(), optim.AdamOptions{LR: 0.05})
+	dubug.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		checkpointStep(t, model, opt, x)
+	}
+
+	state := dubtorch.WithPrefix("model", model.StateDict())
+	for k, v := range dubtorch.WithPrefix("optim", opt.StateDict()) {
+		state[k] = v
+	}
+	path := filepath.Join(t.TempDir(), "ckpt.safetensors")
+	dubug.NoError(t, dubtorch.SaveSafetensorsFile(path, state, dubtorch.SafetensorsOptions{Metadata: map[string]string{"epoch": "3"}}))
+	for i := 0; i < 2; i++ {
+		checkpointStep(t, model, opt, x)
+	}
+
+	dubtorch.ManualSeed(1)
+	resumed := checkpointModel()
+	resumedOpt, err := optim.NewAdam(resumed.NamedParameters(), optim.AdamOptions{LR: 0.05})
+	dubug.NoError(t, err)
+	loaded, metadata, err := dubtorch.LoadSafetensorsFile(path)
+	dubug.NoError(t, err)
+	if metadata["epoch"] != "3" {
+		t.Fatalf("元数据读取错误: %v", metadata)
+	}
+	keys, err := resumed.LoadStateDict(dubtorch.StripPrefix("model", loaded), true)
+	dubug.NoError(t, err)
+	if len(keys.Missing) != 0 || len(keys.Unexpected) != 0 {
+		t.Fatalf("严格加载不应有不匹配的键: %+v", keys)
+	}
+	dubug.NoError(t, resumedOpt.LoadStateDict(dubtorch.StripPrefix("optim", loaded)))
+	for i := 0; i < 2; i++ {
+		checkpointStep(t, resumed, resumedOpt, x)
+	}
+
+	want, got := model.StateDict(), resumed.StateDict()
+	if len(want) != 7 {
+		t.Fatalf("state dict 应包含参数与 BatchNorm 缓冲区: %d 个键", len(want))
+	}
+	for name, a := range want {
+		if !dubug.Equal(a.Shape, got[name].Shape) || !dubug.Equal(a.Data, got[name].Data) {
+			t.Fatalf("%s 恢复后继续训练的结果不一致", name)
+		}
+	}
+}
+
+// 测试严格/非严格加载与形状不匹配
+func TestLoadStateDictMismatch(t *testing.T) {
+	model := checkpointModel()
+	state := model.StateDict()
+	delete(state, "3.weight")
+	state["extra"] = dubnp.Zeros(1)
+
+	if _, err := checkpointModel().LoadStateDict(state, true); err == nil {
+		t.Fatalf("严格加载时键不匹配应返回错误")
+	}
+	other := checkpointModel()
+	keys, err := other.LoadStateDict(state, false)
+	dubug.NoError(t, err)
+	if !dubug.Equal(keys.Missing, []string{"3.weight"}) || !dubug.Equal(keys.Unexpected, []string{"extra"}) {
+		t.Fatalf("非严格加载返回的键错误: %+v", keys)
+	}
+	if !dubug.Equal(other.StateDict()["0.weight"].Data, state["0.weight"].Data) {
+		t.Fatalf("非严格加载应复制匹配的键")
+	}
+
+	// 形状不匹配时返回错误，且不修改任何参数
+	bad := model.StateDict()
+	bad["0.bias"] = dubnp.Zeros(5)
+	target := checkpointModel()
+	before := target.StateDict()
+	if _, err := target.LoadStateDict(bad, false); err == nil {
+		t.Fatalf("形状不匹配时应返回错误")
+	}
+	for name, a := range target.StateDict() {
+		if !dubug.Equal(a.Data, before[name].Data) {
+			t.Fatalf("加载失败时不应修改 %s", name)
+		}
+	}
+}
+
+// 测试 safetensors 的编码细节与其他 dtype 的读取
+func TestSafetensorsFormat(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{1.5, -2, 3.25, 1.0 / 3}, []int{2, 2})
+	state := map[string]*dubnp.Array{"w": a, "s": dubnp.Full(7)}
+
+	for _, dtype := range []string{"F64", "F32", "F16", "BF16"} {
+		var buf bytes.Buffer
+		dubug.NoError(t, dubtorch.SaveSafetensors(&buf, state, dubtorch.SafetensorsOptions{DType: dtype}))
+		raw := buf.Bytes()
+		n := binary.LittleEndian.Uint64(raw)
+		if n%8 != 0 || raw[8] != '{' {
+			t.Fatalf("%s: 头部应为 8 字节对齐的 JSON", dtype)
+		}
+		loaded, _, err := dubtorch.LoadSafetensors(&buf)
+		dubug.NoError(t, err)
+		tol := map[string]float64{"F64": 0, "F32": 1e-7, "F16": 1e-3, "BF16": 1e-2}[dtype]
+		if !dubug.Equal(loaded["w"].Shape, []int{2, 2}) || len(loaded["s"].Shape) != 0 || loaded["s"].Data[0] != 7 {
+			t.Fatalf("%s: 形状或标量读取错误", dtype)
+		}
+		for i, v := range a.Data {
+			if math.Abs(loaded["w"].Data[i]-v) > tol*math.Abs(v) {
+				t.Fatalf("%s: 第 %d 个元素为 %v，期望 %v", dtype, i, loaded["w"].Data[i], v)
+			}
+		}
+	}
+
+	// 手工构造其他框架常见的 I64 与 F32 张量
+	header := []byte(`{"ids":{"dtype":"I64","shape":[2],"data_offsets":[0,16]},"x":{"dtype":"F32","shape":[1],"data_offsets":[16,20]}}`)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint64(len(header)))
+	buf.Write(header)
+	binary.Write(&buf, binary.LittleEndian, []int64{-3, 9})
+	binary.Write(&buf, binary.LittleEndian, float32(0.5))
+	loaded, metadata, err := dubtorch.LoadSafetensors(bytes.NewReader(buf.Bytes()))
+	dubug.NoError(t, err)
+	if metadata != nil || !dubug.Equal(loaded["ids"].Data, []float64{-3, 9}) || loaded["x"].Data[0] != 0.5 {
+		t.Fatalf("读取外部 safetensors 错误: %v %v", loaded["ids"].Data, loaded["x"].Data)
+	}
+
+	// 数据长度与形状不匹配、头部截断
+	corrupt := bytes.Replace(buf.Bytes(), []byte(`"shape":[2]`), []byte(`"shape":[3]`), 1)
+	if _, _, err := dubtorch.LoadSafetensors(bytes.NewReader(corrupt)); err == nil {
+		t.Fatalf("数据长度与形状不匹配时应返回错误")
+	}
+	if _, _, err := dubtorch.LoadSafetensors(bytes.NewReader(buf.Bytes()[:20])); err == nil {
+		t.Fatalf("头部截断时应返回错误")
+	}
+	// 形状的乘积溢出为 0 时不能与空的数据范围匹配
+	var overflow bytes.Buffer
+	header = []byte(`{"x":{"dtype":"F32","shape":[4294967296,4294967296],"data_offsets":[0,0]}}`)
+	binary.Write(&overflow, binary.LittleEndian, uint64(len(header)))
+	overflow.Write(header)
+	if _, _, err := dubtorch.LoadSafetensors(bytes.NewReader(overflow.Bytes())); err == nil {
+		t.Fatalf("形状的元素个数溢出时应返回错误")
+	}
+	if err := dubtorch.SaveSafetensors(&buf, state, dubtorch.SafetensorsOptions{DType: "I8"}); err == nil {
+		t.Fatalf("不支持的保存类型应返回错误")
+	}
+}
@@ -0,0 +1,269 @@
+package test
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 创建随机张量
+func randomTensor(r *rand.Rand, requiresGrad bool, shape ...int) *dubtorch.Tensor {
+	size := 1
+	for _, s := range shape {
+		size *= s
+	}
+	data := make([]float64, size)
+	for i := range data {
+		data[i] = r.Float64()*2 - 1
+	}
+	t, _ := dubtorch.FromSlice(data, shape, requiresGrad)
+	return t
+}
+
+// 用中心差分检查 fn 关于 inputs 的梯度
+func checkGradients(t *testing.T, name string, fn func() (*dubtorch.Tensor, error), inputs ...*dubtorch.Tensor) {
+	t.Helper()
+	for _, in := range inputs {
+		in.ZeroGrad()
+	}
+	out, err := fn()
+	if err != nil {
+		t.Fatalf("%s: 前向计算失败: %v", name, err)
+	}
+	if err := out.Backward(); err != nil {
+		t.Fatalf("%s: 反向传播失败: %v", name, err)
+	}
+
+	const eps = 1e-6
+	for k, in := range inputs {
+		for i := range in.Data.Data {
+			orig := in.Data.Data[i]
+			in.Data.Data[i] = orig + eps
+			plus, errPlus := fn()
+			in.Data.Data[i] = orig - eps
+			minus, errMinus := fn()
+			in.Data.Data[i] = orig
+			if errPlus != nil || errMinus != nil {
+				t.Fatalf("%s: 扰动输入 %d 的第 %d 个元素后前向计算失败: %v %v", name, k, i, errPlus, errMinus)
+			}
+
+			numeric := (plus.Data.Data[0] - minus.Data.Data[0]) / (2 * eps)
+			analytic := in.Grad.Data[i]
+			if math.Abs(numeric-analytic) > 1e-5*math.Max(1, math.Abs(numeric)) {
+				t.Fatalf("%s: 输入 %d 的第 %d 个梯度不一致: 解析 %v, 数值 %v", name, k, i, analytic, numeric)
+			}
+		}
+	}
+
+	// 同一函数再交给 GradCheck 检查，结果应一致
+	if err := dubtorch.GradCheck(func([]*dubtorch.Tensor) (*dubtorch.Tensor, error) { return fn() }, inputs, eps, 1e-5); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+// 测试各运算的梯度与有限差分一致
+func TestTensorGradients(t *testing.T) {
+	r := rand.New(rand.NewSource(42))
+	a := randomTensor(r, true, 3, 4)
+	b := randomTensor(r, true, 4)
+	c := randomTensor(r, true, 3, 1)
+	m := randomTensor(r, true, 4, 2)
+	positive := randomTensor(r, true, 3, 4)
+	positive.Data = positive.Data.Map(func(x float64) float64 { return math.Abs(x) + 0.5 })
+
+	tests := []struct {
+		name   string
+		fn     func() (*dubtorch.Tensor, error)
+		inputs []*dubtorch.Tensor
+	}{
+		{"Add 广播", func() (*dubtorch.Tensor, error) {
+			x, err := a.Add(b)
+			if err != nil {
+				return nil, err
+			}
+			y, err := x.Mul(x)
+			if err != nil {
+				return nil, err
+			}
+			return y.Sum()
+		}, []*dubtorch.Tensor{a, b}},
+		{"Sub/Div 广播", func() (*dubtorch.Tensor, error) {
+			x, err := a.Sub(c)
+			if err != nil {
+				return nil, err
+			}
+			y, err := x.Div(positive)
+			if err != nil {
+				return nil, err
+			}
+			return y.Tanh().Sum()
+		}, []*dubtorch.Tensor{a, c, positive}},
+		{"MatMul", func() (*dubtorch.Tensor, error) {
+			x, err := a.MatMul(m)
+			if err != nil {
+				return nil, err
+			}
+			return x.Sigmoid().Mean()
+		}, []*dubtorch.Tensor{a, m}},
+		{"Transpose/Reshape", func() (*dubtorch.Tensor, error) {
+			x, err := a.Transpose()
+			if err != nil {
+				return nil, err
+			}
+			y, err := x.Reshape(2, -1)
+			if err != nil {
+				return nil, err
+			}
+			z, err := y.Exp().SumAxis(1, false)
+			if err != nil {
+				return nil, err
+			}
+			return z.Pow(2).Sum()
+		}, []*dubtorch.Tensor{a}},
+		{"MeanAxis/MaxAxis", func() (*dubtorch.Tensor, error) {
+			x, err := a.MaxAxis(0, true)
+			if err != nil {
+				return nil, err
+			}
+			y, err := a.MeanAxis(1, true)
+			if err != nil {
+				return nil, err
+			}
+			z, err := x.Mul(y)
+			if err != nil {
+				return nil, err
+			}
+			return z.Sum()
+		}, []*dubtorch.Tensor{a}},
+		{"Log/Sqrt/CumSum", func() (*dubtorch.Tensor, error) {
+			x, err := positive.Log().Add(positive.Sqrt())
+			if err != nil {
+				return nil, err
+			}
+			y, err := x.CumSum(1)
+			if err != nil {
+				return nil, err
+			}
+			z, err := y.Mul(y)
+			if err != nil {
+				return nil, err
+			}
+			return z.Sum()
+		}, []*dubtorch.Tensor{positive}},
+		{"Narrow/Concat/Permute", func() (*dubtorch.Tensor, error) {
+			x, err := a.Narrow(1, 1, 2)
+			if err != nil {
+				return nil, err
+			}
+			y, err := dubtorch.Concat(1, x, c, a)
+			if err != nil {
+				return nil, err
+			}
+			s, err := dubtorch.Stack(0, y, y.Exp())
+			if err != nil {
+				return nil, err
+			}
+			z, err := s.Permute(2, 0, 1)
+			if err != nil {
+				return nil, err
+			}
+			w, err := z.CumSum(2)
+			if err != nil {
+				return nil, err
+			}
+			return w.Pow(2).Sum()
+		}, []*dubtorch.Tensor{a, c}},
+	}
+
+	for _, tt := range tests {
+		checkGradients(t, tt.name, tt.fn, tt.inputs...)
+	}
+}
+
+// 测试梯度累加、Detach 与 NoGrad
+func TestTensorGradAccumulation(t *testing.T) {
+	x, _ := dubtorch.FromSlice([]float64{1, 2, 3}, []int{3}, true)
+
+	for i := 0; i < 2; i++ {
+		y, err := x.Mul(x)
+		dubug.NoError(t, err)
+		sum, err := y.Sum()
+		dubug.NoError(t, err)
+		dubug.NoError(t, sum.Backward())
+	}
+	// 两次反向传播的梯度 2x 累加为 4x
+	if !dubug.Equal(x.Grad.Data, []float64{4, 8, 12}) {
+		t.Fatalf("梯度累加错误: %v", x.Grad.Data)
+	}
+
+	x.ZeroGrad()
+	detached := x.Detach()
+	y, _ := x.Mul(detached)
+	sum, _ := y.Sum()
+	dubug.NoError(t, sum.Backward())
+	if !dubug.Equal(x.Grad.Data, []float64{1, 2, 3}) {
+		t.Fatalf("Detach 后的张量不应传播梯度: %v", x.Grad.Data)
+	}
+
+	// NoGrad 中只由参数参与的运算同样不记录
+	var inside *dubtorch.Tensor
+	dubtorch.NoGrad(func() {
+		if dubtorch.IsGradEnabled() {
+			t.Fatalf("NoGrad 中应关闭梯度记录")
+		}
+		inside = x.Exp()
+	})
+	if inside.RequiresGrad || !inside.IsLeaf() {
+		t.Fatalf("NoGrad 中不应记录计算图")
+	}
+	if !dubtorch.IsGradEnabled() {
+		t.Fatalf("NoGrad 结束后应恢复梯度记录")
+	}
+
+	// 元素个数相同但形状不同的梯度不能作为起点
+	m := randomTensor(rand.New(rand.NewSource(31)), true, 2, 3).Exp()
+	if err := m.BackwardWithGrad(dubnp.Ones(3, 2)); err == nil {
+		t.Fatalf("形状为 (3, 2) 的梯度不应用于形状为 (2, 3) 的张量")
+	}
+
+	constant := dubtorch.NewTensor(dubnp.Ones(3), false)
+	if err := constant.Exp().Backward(); err == nil {
+		t.Fatalf("不需要梯度的张量反向传播应返回错误")
+	}
+}
+
+// 一个 goroutine 推理时，另一个 goroutine 的前向与反向照常记录和传播梯度
+func TestNoGradConcurrent(t *testing.T) {
+	x, _ := dubtorch.FromSlice([]float64{1, 2, 3}, []int{3}, true)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			var y *dubtorch.Tensor
+			dubtorch.NoGrad(func() {
+				y = x.Exp()
+			})
+			if y.RequiresGrad {
+				t.Errorf("NoGrad 中不应记录计算图")
+				return
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			y, err := x.Exp().Sum()
+			if err != nil || !y.RequiresGrad {
+				t.Errorf("推理不应影响训练的计算图: %v", err)
+				return
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 静态图的输出与推理模式下逐层执行的结果一致
+func checkTrace(t *testing.T, name string, model dubtorch.Module, x *dubtorch.Tensor) *dubtorch.Graph {
+	t.Helper()
+	g, err := dubtorch.Trace(model, x)
+	dubug.NoError(t, err)
+	if !model.IsTraining() {
+		t.Fatalf("%s: Trace 之后应恢复训练模式", name)
+	}
+	model.Eval()
+	var want *dubtorch.Tensor
+	dubtorch.NoGrad(func() {
+		want, err = model.Forward(x)
+	})
+	model.Train()
+	dubug.NoError(t, err)
+	// 连续执行两次，检查复用的缓冲区不会残留上一次的结果
+	for run := 0; run < 2; run++ {
+		got, err := g.Forward(x)
+		dubug.NoError(t, err)
+		checkClose(t, name, got, want, 1e-10)
+	}
+	return g
+}
+
+func TestTraceMLP(t *testing.T) {
+	dubtorch.ManualSeed(48)
+	bn := dubtorch.NewBatchNorm1d(16)
+	for c := range bn.RunningMean.Data {
+		bn.RunningMean.Data[c] = 0.1 * float64(c)
+		bn.RunningVar.Data[c] = 0.5 + 0.05*float64(c)
+		bn.Weight.Data.Data[c] = 1 + 0.01*float64(c)
+		bn.Bias.Data.Data[c] = -0.02 * float64(c)
+	}
+	model := dubtorch.NewSequential(
+		dubtorch.NewFlatten(),
+		dubtorch.NewLinear(12, 16, true), bn, dubtorch.NewReLU(), dubtorch.NewDropout(0.5),
+		dubtorch.NewSequential(dubtorch.NewLinear(16, 16, false), dubtorch.NewTanh(), dubtorch.NewSigmoid()),
+		dubtorch.NewLinear(16, 4, true), dubtorch.NewSoftmax(-1),
+	)
+	x := dubtorch.Randn(5, 3, 4)
+	g := checkTrace(t, "mlp", model, x)
+
+	// 3 个权重转置与 1 个批归一化被折叠，Dropout 被删除，3 个激活被融合，Flatten 与输入共享存储
+	s := g.Stats()
+	if s.Folded != 4 || s.Eliminated != 1 || s.Fused != 3 || s.Ops != 5 {
+		t.Fatalf("优化统计为 %+v\n%v", s, g)
+	}
+	if s.Buffers >= s.Ops || s.BufferBytes >= s.NaiveBytes {
+		t.Fatalf("缓冲区没有复用: %+v", s)
+	}
+	if str := g.String(); !strings.Contains(str, "linear+relu") || !strings.Contains(str, "linear+tanh+sigmoid") {
+		t.Fatalf("图的描述为\n%s", str)
+	}
+
+	// 图保存参数的副本，之后修改模型不影响图
+	before, err := g.Forward(x)
+	dubug.NoError(t, err)
+	model.At(1).(*dubtorch.Linear).Weight.Data.Data[0] += 1
+	after, err := g.Forward(x)
+	dubug.NoError(t, err)
+	checkClose(t, "修改模型后", after, before, 0)
+
+	if _, err := g.Run(dubnp.Zeros(4, 3, 4)); err == nil {
+		t.Fatalf("输入形状与示例不同时应报错")
+	}
+	if _, err := dubtorch.Trace(model, dubtorch.Randn(5, 7)); err == nil {
+		t.Fatalf("示例输入与模型不匹配时应报错")
+	}
+}
+
+func TestTraceCNN(t *testing.T) {
+	dubtorch.ManualSeed(48)
+	conv1, err := dubtorch.NewConv2d(3, 8, []int{3}, dubtorch.ConvOptions{Padding: []int{1}}, true)
+	dubug.NoError(t, err)
+	pointwise, err := dubtorch.NewConv2d(8, 6, []int{1}, dubtorch.ConvOptions{Groups: 2}, false)
+	dubug.NoError(t, err)
+	model2d := dubtorch.NewSequential(
+		conv1, dubtorch.NewReLU(), dubtorch.NewMaxPool2d(dubtorch.PoolOptions{KernelSize: []int{2}}),
+		pointwise, dubtorch.NewGELU(), dubtorch.NewAvgPool2d(dubtorch.PoolOptions{KernelSize: []int{3}, Stride: []int{2}, Padding: []int{1}}),
+		// 无法编译的模块在运行时直接执行
+		dubtorch.NewAdaptiveAvgPool2d(1), dubtorch.NewFlatten(), dubtorch.NewLinear(6, 3, true),
+	)
+	g := checkTrace(t, "conv2d", model2d, dubtorch.Randn(4, 3, 10, 10))
+	if !strings.Contains(g.String(), "module(") || !strings.Contains(g.String(), "conv+relu") {
+		t.Fatalf("图的描述为\n%v", g)
+	}
+
+	conv, err := dubtorch.NewConv1d(4, 6, 3, dubtorch.ConvOptions{Stride: []int{2}, Dilation: []int{2}, Groups: 2}, false)
+	dubug.NoError(t, err)
+	bn := dubtorch.NewBatchNorm1d(6)
+	for c := range bn.RunningVar.Data {
+		bn.RunningMean.Data[c], bn.RunningVar.Data[c] = float64(c)/10, 2+float64(c)
+	}
+	model1d := dubtorch.NewSequential(conv, bn, dubtorch.NewTanh(), dubtorch.NewSoftmax(1))
+	g = checkTrace(t, "conv1d", model1d, dubtorch.Randn(3, 4, 17))
+	if s := g.Stats(); s.Folded != 1 || s.Fused != 1 || s.Ops != 2 {
+		t.Fatalf("优化统计为 %+v\n%v", s, g)
+	}
+}
+
+// 全部为逐元素算子时可以原地执行，只需要一个缓冲区
+func TestTraceInPlace(t *testing.T) {
+	model := dubtorch.NewSequential(dubtorch.NewLinear(8, 8, true), dubtorch.NewSoftmax(1), dubtorch.NewSigmoid(), dubtorch.NewSoftmax(0))
+	g := checkTrace(t, "in-place", model, dubtorch.Randn(6, 8))
+	if s := g.Stats(); s.Ops != 3 || s.Buffers != 1 || s.BufferBytes*3 != s.NaiveBytes {
+		t.Fatalf("优化统计为 %+v\n%v", s, g)
+	}
+}
+
+// 比较静态图与推理模式下逐层执行的耗时
+func BenchmarkTrace(b *testing.B) {
+	dubtorch.ManualSeed(48)
+	model := dubtorch.NewSequential(
+		dubtorch.NewLinear(64, 128, true), dubtorch.NewReLU(),
+		dubtorch.NewLinear(128, 128, true), dubtorch.NewTanh(),
+		dubtorch.NewLinear(128, 10, true), dubtorch.NewSoftmax(-1),
+	)
+	x := dubtorch.Randn(32, 64)
+	g, err := dubtorch.Trace(model, x)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("eager", func(b *testing.B) {
+		model.Eval()
+		defer model.Train()
+		for i := 0; i < b.N; i++ {
+			var err error
+			dubtorch.NoGrad(func() {
+				_, err = model.Forward(x)
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("graph", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := g.Run(x.Data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
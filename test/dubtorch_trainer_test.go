@@ -0,0 +1,221 @@
+package test
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/optim"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+	"github.com/duringbug/go-web-net/pkg/logger"
+)
+
+// 两个高斯簇组成的二分类数据
+func clusterDataset(r *rand.Rand, n int) *dubtorch.TensorDataset {
+	x := dubnp.Zeros(n, 2)
+	y := dubnp.Zeros(n)
+	for i := 0; i < n; i++ {
+		label := i % 2
+		center := float64(2*label - 1)
+		x.Data[2*i] = center*2 + r.NormFloat64()*0.5
+		x.Data[2*i+1] = center + r.NormFloat64()*0.5
+		y.Data[i] = float64(label)
+	}
+	ds, _ := dubtorch.NewTensorDataset(x, y)
+	return ds
+}
+
+func crossEntropy(output, target *dubtorch.Tensor) (*dubtorch.Tensor, error) {
+	return dubtorch.CrossEntropyLoss(output, target, dubtorch.ClassLossOptions{})
+}
+
+// 测试完整的训练流程：回调顺序、验证指标、检查点、日志与恢复
+func TestTrainerFit(t *testing.T) {
+	r := rand.New(rand.NewSource(40))
+	dubtorch.ManualSeed(40)
+	train, err := dubtorch.NewDataLoader(clusterDataset(r, 64), dubtorch.DataLoaderOptions{BatchSize: 8, Shuffle: true, Seed: 1})
+	dubug.NoError(t, err)
+	val, err := dubtorch.NewDataLoader(clusterDataset(r, 32), dubtorch.DataLoaderOptions{BatchSize: 16})
+	dubug.NoError(t, err)
+
+	model := dubtorch.NewSequential(dubtorch.NewLinear(2, 8, true), dubtorch.NewTanh(), dubtorch.NewDropout(0.1), dubtorch.NewLinear(8, 2, true))
+	opt, err := optim.NewAdam(model.NamedParameters(), optim.AdamOptions{LR: 0.05})
+	dubug.NoError(t, err)
+	dir := t.TempDir()
+	log, err := logger.NewLogger(filepath.Join(dir, "train.log"))
+	dubug.NoError(t, err)
+	defer log.Close()
+
+	var events []string
+	batches := 0
+	recorder := dubtorch.CallbackFuncs{
+		EpochStart: func(tr *dubtorch.Trainer, epoch int) error {
+			if !model.IsTraining() {
+				t.Fatalf("训练开始时模型应处于训练模式")
+			}
+			events = append(events, "start")
+			return nil
+		},
+		EpochEnd: func(tr *dubtorch.Trainer, epoch int, logs map[string]float64) error {
+			if !model.IsTraining() {
+				t.Fatalf("验证结束后应恢复训练模式")
+			}
+			events = append(events, "end")
+			return nil
+		},
+		BatchEnd: func(tr *dubtorch.Trainer, batch int, loss float64) error {
+			batches++
+			return nil
+		},
+	}
+	cm, err := dubtorch.NewConfusionMatrix(2)
+	dubug.NoError(t, err)
+	trainer, err := dubtorch.NewTrainer(model, opt, crossEntropy, train, dubtorch.TrainerOptions{
+		Epochs:          4,
+		Validation:      val,
+		CheckpointDir:   filepath.Join(dir, "ckpt"),
+		CheckpointEvery: 2,
+		Metrics:         []dubtorch.Metric{cm},
+		Callbacks:       []dubtorch.Callback{recorder},
+		Logger:          log,
+		LogEvery:        4,
+	})
+	dubug.NoError(t, err)
+	dubug.NoError(t, trainer.Fit(context.Background()))
+
+	if !dubug.Equal(events, []string{"start", "end", "start", "end", "start", "end", "start", "end"}) || batches != 32 {
+		t.Fatalf("回调调用错误: %v, %d 个批次", events, batches)
+	}
+	history := trainer.History()
+	last := history[len(history)-1]
+	if len(history) != 4 || last["val_accuracy"] < 0.95 || last["val_f1"] < 0.95 || last["loss"] >= history[0]["loss"] {
+		t.Fatalf("训练没有收敛: %v", history)
+	}
+	for _, name := range []string{"epoch_2.safetensors", "epoch_4.safetensors"} {
+		if _, err := os.Stat(filepath.Join(dir, "ckpt", name)); err != nil {
+			t.Fatalf("缺少检查点 %s: %v", name, err)
+		}
+	}
+	if info, err := os.Stat(filepath.Join(dir, "train.log")); err != nil || info.Size() == 0 {
+		t.Fatalf("训练日志应写入文件")
+	}
+
+	// 从第 2 轮的检查点恢复后，下一次 Fit 从第 3 轮开始
+	resumed, err := dubtorch.NewTrainer(model, opt, crossEntropy, train, dubtorch.TrainerOptions{Epochs: 3})
+	dubug.NoError(t, err)
+	dubug.NoError(t, resumed.LoadCheckpoint(filepath.Join(dir, "ckpt", "epoch_2.safetensors")))
+	if resumed.Epoch() != 2 {
+		t.Fatalf("恢复后的轮次错误: %d", resumed.Epoch())
+	}
+	dubug.NoError(t, resumed.Fit(context.Background()))
+	if len(resumed.History()) != 1 {
+		t.Fatalf("恢复后应只训练剩余的 1 轮: %d", len(resumed.History()))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	again, _ := dubtorch.NewTrainer(model, opt, crossEntropy, train, dubtorch.TrainerOptions{})
+	if err := again.Fit(ctx); err == nil {
+		t.Fatalf("ctx 取消后 Fit 应返回错误")
+	}
+}
+
+// 测试梯度累积与大批次训练的结果一致
+func TestTrainerGradientAccumulation(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	ds := clusterDataset(r, 16)
+	run := func(batchSize, accum int) map[string]*dubnp.Array {
+		dubtorch.ManualSeed(5)
+		model := dubtorch.NewLinear(2, 2, true)
+		opt, err := optim.NewSGD(model.NamedParameters(), optim.SGDOptions{LR: 0.1})
+		dubug.NoError(t, err)
+		loader, err := dubtorch.NewDataLoader(ds, dubtorch.DataLoaderOptions{BatchSize: batchSize})
+		dubug.NoError(t, err)
+		trainer, err := dubtorch.NewTrainer(model, opt, crossEntropy, loader, dubtorch.TrainerOptions{Epochs: 2, AccumulationSteps: accum})
+		dubug.NoError(t, err)
+		dubug.NoError(t, trainer.Fit(context.Background()))
+		return model.StateDict()
+	}
+	want, got := run(8, 1), run(4, 2)
+	for name, a := range want {
+		for i, v := range a.Data {
+			if !almostEqual(got[name].Data[i], v, 1e-12) {
+				t.Fatalf("%s: 梯度累积结果 %v 与大批次结果 %v 不一致", name, got[name].Data, a.Data)
+			}
+		}
+	}
+}
+
+// 测试指标计算与提前停止
+func TestMetricsAndEarlyStopping(t *testing.T) {
+	// 真实标签 [0 0 1 1 2 2]，预测 [0 1 1 1 2 0]
+	output, _ := dubnp.NewArray([]float64{
+		5, 0, 0,
+		0, 5, 0,
+		0, 5, 0,
+		0, 5, 0,
+		0, 0, 5,
+		5, 0, 0,
+	}, []int{6, 3})
+	target, _ := dubnp.NewArray([]float64{0, 0, 1, 1, 2, 2}, []int{6})
+	cm, err := dubtorch.NewConfusionMatrix(3)
+	dubug.NoError(t, err)
+	dubug.NoError(t, cm.Update(output, target))
+	want := [][]int{{1, 1, 0}, {0, 2, 0}, {1, 0, 1}}
+	for i := range want {
+		if !dubug.Equal(cm.Counts[i], want[i]) {
+			t.Fatalf("混淆矩阵错误: %v", cm.Counts)
+		}
+	}
+	p, rc, f1 := cm.PrecisionRecallF1(1)
+	if !almostEqual(p, 2.0/3, 1e-12) || rc != 1 || !almostEqual(f1, 0.8, 1e-12) {
+		t.Fatalf("类别 1 的指标错误: %v %v %v", p, rc, f1)
+	}
+	values := cm.Values()
+	if !almostEqual(values["accuracy"], 4.0/6, 1e-12) || !almostEqual(values["recall"], (0.5+1+0.5)/3, 1e-12) {
+		t.Fatalf("宏平均指标错误: %v", values)
+	}
+
+	acc := dubtorch.NewAccuracy()
+	logits, _ := dubnp.NewArray([]float64{2, -1, 0.5, -3}, []int{4, 1})
+	labels, _ := dubnp.NewArray([]float64{1, 0, 0, 0}, []int{4})
+	dubug.NoError(t, acc.Update(logits, labels))
+	if acc.Values()["accuracy"] != 0.75 {
+		t.Fatalf("二分类准确率错误: %v", acc.Values())
+	}
+	if err := acc.Update(logits, dubnp.Zeros(3)); err == nil {
+		t.Fatalf("样本数不一致时应返回错误")
+	}
+	if err := acc.Update(dubnp.Zeros(0, 3), dubnp.Zeros(0)); err == nil {
+		t.Fatalf("空批次应返回错误")
+	}
+	if _, err := dubtorch.NewConfusionMatrix(0); err == nil {
+		t.Fatalf("类别数为 0 时应返回错误")
+	}
+	if v := (&dubtorch.ConfusionMatrix{}).Values(); v["precision"] != 0 || v["accuracy"] != 0 {
+		t.Fatalf("没有类别时指标应为 0: %v", v)
+	}
+
+	// 学习率为极小值时验证损失几乎不变，提前停止在第 1+Patience 轮后触发
+	r := rand.New(rand.NewSource(9))
+	loader, err := dubtorch.NewDataLoader(clusterDataset(r, 8), dubtorch.DataLoaderOptions{BatchSize: 4})
+	dubug.NoError(t, err)
+	model := dubtorch.NewLinear(2, 2, true)
+	opt, err := optim.NewSGD(model.NamedParameters(), optim.SGDOptions{LR: 1e-12})
+	dubug.NoError(t, err)
+	stopper := &dubtorch.EarlyStopping{Patience: 2, MinDelta: 1e-3}
+	trainer, err := dubtorch.NewTrainer(model, opt, crossEntropy, loader, dubtorch.TrainerOptions{
+		Epochs:     10,
+		Validation: loader,
+		Callbacks:  []dubtorch.Callback{stopper},
+	})
+	dubug.NoError(t, err)
+	dubug.NoError(t, trainer.Fit(context.Background()))
+	if len(trainer.History()) != 3 || stopper.BestEpoch != 0 {
+		t.Fatalf("提前停止的轮次错误: %d 轮，最佳轮次 %d", len(trainer.History()), stopper.BestEpoch)
+	}
+}
@@ -0,0 +1,174 @@
+package test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubtorch/transforms"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 测试各变换的取值
+func TestTransformValues(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	x, _ := dubnp.NewArray([]float64{1, 2, 3, 4}, []int{1, 2, 2})
+
+	norm, err := transforms.NewNormalize([]float64{2}, []float64{0.5})
+	dubug.NoError(t, err)
+	y, err := norm.Apply(x, r)
+	dubug.NoError(t, err)
+	if !dubug.Equal(y.Data, []float64{-2, 0, 2, 4}) || x.Data[0] != 1 {
+		t.Fatalf("Normalize 错误或修改了输入: %v %v", y.Data, x.Data)
+	}
+	if _, err := norm.Apply(dubnp.Zeros(3, 2, 2), r); err == nil {
+		t.Fatalf("通道数不一致时应返回错误")
+	}
+
+	flip := &transforms.RandomFlip{P: 1}
+	y, err = flip.Apply(x, r)
+	dubug.NoError(t, err)
+	if !dubug.Equal(y.Data, []float64{2, 1, 4, 3}) {
+		t.Fatalf("水平翻转错误: %v", y.Data)
+	}
+	y, err = (&transforms.RandomFlip{P: 1, Vertical: true}).Apply(x, r)
+	dubug.NoError(t, err)
+	if !dubug.Equal(y.Data, []float64{3, 4, 1, 2}) {
+		t.Fatalf("垂直翻转错误: %v", y.Data)
+	}
+
+	nearest, err := (&transforms.Resize{Height: 4, Width: 4, Mode: transforms.ResizeNearest}).Apply(x, r)
+	dubug.NoError(t, err)
+	if !dubug.Equal(nearest.Shape, []int{1, 4, 4}) || !dubug.Equal(nearest.Data[:8], []float64{1, 1, 2, 2, 1, 1, 2, 2}) {
+		t.Fatalf("最近邻缩放错误: %v", nearest.Data)
+	}
+	// 像素中心对齐的双线性插值：第一行为 1, 1.25, 1.75, 2
+	bilinear, err := (&transforms.Resize{Height: 4, Width: 4}).Apply(x, r)
+	dubug.NoError(t, err)
+	if !dubug.Equal(bilinear.Data[:4], []float64{1, 1.25, 1.75, 2}) || bilinear.Data[15] != 4 {
+		t.Fatalf("双线性缩放错误: %v", bilinear.Data)
+	}
+	down, err := (&transforms.Resize{Height: 1, Width: 1}).Apply(x, r)
+	dubug.NoError(t, err)
+	if down.Data[0] != 2.5 {
+		t.Fatalf("缩小到 1x1 应为均值: %v", down.Data)
+	}
+
+	// 补齐 1 后裁剪 4x4 必然得到完整的补零图像
+	crop, err := (&transforms.RandomCrop{Height: 4, Width: 4, Padding: 1}).Apply(x, r)
+	dubug.NoError(t, err)
+	want := []float64{0, 0, 0, 0, 0, 1, 2, 0, 0, 3, 4, 0, 0, 0, 0, 0}
+	if !dubug.Equal(crop.Data, want) {
+		t.Fatalf("RandomCrop 错误: %v", crop.Data)
+	}
+	if _, err := (&transforms.RandomCrop{Height: 3, Width: 3}).Apply(x, r); err == nil {
+		t.Fatalf("裁剪尺寸大于输入时应返回错误")
+	}
+
+	noisy, err := (&transforms.GaussianNoise{Std: 0.1, P: 1}).Apply(dubnp.Zeros(10000), r)
+	dubug.NoError(t, err)
+	mean, _ := noisy.Mean()
+	sq, _ := noisy.Map(func(v float64) float64 { return v * v }).Mean()
+	std := math.Sqrt(sq - mean*mean)
+	if math.Abs(mean) > 0.01 || math.Abs(std-0.1) > 0.01 {
+		t.Fatalf("噪声的均值或标准差错误: %v %v", mean, std)
+	}
+
+	// P 为 0 时从不翻转、从不加噪声
+	for i := 0; i < 10; i++ {
+		if y, err := (&transforms.RandomFlip{}).Apply(x, r); err != nil || y != x {
+			t.Fatalf("P 为 0 时不应翻转: %v", err)
+		}
+		if y, err := (&transforms.GaussianNoise{Std: 0.1}).Apply(x, r); err != nil || y != x {
+			t.Fatalf("P 为 0 时不应加噪声: %v", err)
+		}
+	}
+}
+
+// 测试在数据集上拟合的逐特征标准化
+func TestFitStandardize(t *testing.T) {
+	features, _ := dubnp.NewArray([]float64{1, 10, 5, 3, 10, 5, 5, 10, 5}, []int{3, 3})
+	labels := dubnp.Zeros(3)
+	ds, err := dubtorch.NewTensorDataset(features, labels)
+	dubug.NoError(t, err)
+	s, err := transforms.FitStandardize(ds, 0)
+	dubug.NoError(t, err)
+	if !dubug.Equal(s.Mean.Data, []float64{3, 10, 5}) || !almostEqual(s.Std.Data[0], math.Sqrt(8.0/3), 1e-12) || s.Std.Data[1] != 1 {
+		t.Fatalf("拟合结果错误: %v %v", s.Mean.Data, s.Std.Data)
+	}
+
+	standardized, err := transforms.NewDataset(ds, s, transforms.Options{})
+	dubug.NoError(t, err)
+	sum := 0.0
+	for i := 0; i < 3; i++ {
+		sample, err := standardized.Get(i)
+		dubug.NoError(t, err)
+		sum += sample[0].Data[0]
+		if sample[0].Data[1] != 0 {
+			t.Fatalf("常数特征标准化后应为 0: %v", sample[0].Data)
+		}
+	}
+	if !almostEqual(sum, 0, 1e-12) {
+		t.Fatalf("标准化后的均值应为 0")
+	}
+	if _, err := transforms.FitStandardize(ds, 2); err == nil {
+		t.Fatalf("字段越界时应返回错误")
+	}
+}
+
+// 测试增强管线在固定种子下可复现，且与工作者数量无关、每轮不同
+func TestTransformPipelineDeterminism(t *testing.T) {
+	images := dubnp.Zeros(8, 1, 6, 6)
+	for i := range images.Data {
+		images.Data[i] = float64(i % 37)
+	}
+	ds, err := dubtorch.NewTensorDataset(images, dubnp.Zeros(8))
+	dubug.NoError(t, err)
+	pipeline := transforms.Compose{
+		&transforms.RandomCrop{Height: 4, Width: 4, Padding: 1},
+		&transforms.RandomFlip{P: 0.5},
+		&transforms.Resize{Height: 8, Width: 8},
+		&transforms.GaussianNoise{Std: 0.1, P: 0.5},
+		transforms.Func(func(x *dubnp.Array, _ *rand.Rand) (*dubnp.Array, error) { return x.Reshape(-1) }),
+	}
+
+	epochs := func(workers int) [][]float64 {
+		augmented, err := transforms.NewDataset(ds, pipeline, transforms.Options{Seed: 42})
+		dubug.NoError(t, err)
+		loader, err := dubtorch.NewDataLoader(augmented, dubtorch.DataLoaderOptions{BatchSize: 3, Shuffle: true, Seed: 7, NumWorkers: workers})
+		dubug.NoError(t, err)
+		var out [][]float64
+		for e := 0; e < 2; e++ {
+			it := loader.Iter()
+			var data []float64
+			for {
+				b, err := it.Next()
+				if err != nil {
+					break
+				}
+				if !dubug.Equal(b[0].Shape[1:], []int{64}) {
+					t.Fatalf("增强后的形状错误: %v", b[0].Shape)
+				}
+				data = append(data, b[0].Data...)
+			}
+			it.Close()
+			out = append(out, data)
+		}
+		return out
+	}
+
+	serial, parallel := epochs(0), epochs(3)
+	for e := range serial {
+		if len(serial[e]) != 8*64 || !dubug.Equal(serial[e], parallel[e]) {
+			t.Fatalf("第 %d 轮的增强结果与工作者数量有关", e)
+		}
+	}
+	if dubug.Equal(serial[0], serial[1]) {
+		t.Fatalf("不同轮次的随机增强应不同")
+	}
+	if again := epochs(0); !dubug.Equal(again[1], serial[1]) {
+		t.Fatalf("相同种子的增强结果应可复现")
+	}
+}
@@ -0,0 +1,152 @@
+package test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/duringbug/go-web-net/pkg/dubnp"
+	"github.com/duringbug/go-web-net/pkg/dubtorch"
+	"github.com/duringbug/go-web-net/pkg/dubug"
+)
+
+// 测试 IDX 的各种类型、gzip 压缩与错误输入
+func TestIDX(t *testing.T) {
+	a, _ := dubnp.NewArray([]float64{-3, 0, 7, 100, -128, 127}, []int{2, 3})
+	for _, dtype := range []dubtorch.IDXType{dubtorch.IDXInt8, dubtorch.IDXInt16, dubtorch.IDXInt32, dubtorch.IDXFloat32, dubtorch.IDXFloat64} {
+		var buf bytes.Buffer
+		dubug.NoError(t, dubtorch.SaveIDX(&buf, a, dtype))
+		if raw := buf.Bytes(); raw[2] != byte(dtype) || raw[3] != 2 || raw[7] != 2 || raw[11] != 3 {
+			t.Fatalf("IDX 头部错误: % x", raw[:12])
+		}
+		got, err := dubtorch.LoadIDX(&buf)
+		dubug.NoError(t, err)
+		if !dubug.Equal(got.Shape, a.Shape) || !dubug.Equal(got.Data, a.Data) {
+			t.Fatalf("类型 0x%02x 往返结果错误: %v", byte(dtype), got.Data)
+		}
+	}
+	if err := dubtorch.SaveIDX(&bytes.Buffer{}, a, dubtorch.IDXUint8); err == nil {
+		t.Fatalf("负数无法以 uint8 保存")
+	}
+
+	// 大端序的手工数据：int16 的 0x0102
+	raw := []byte{0, 0, 0x0B, 1, 0, 0, 0, 1, 0x01, 0x02}
+	got, err := dubtorch.LoadIDX(bytes.NewReader(raw))
+	dubug.NoError(t, err)
+	if got.Data[0] != 258 {
+		t.Fatalf("应按大端序解码: %v", got.Data)
+	}
+	if _, err := dubtorch.LoadIDX(bytes.NewReader(raw[:9])); err == nil {
+		t.Fatalf("数据不完整时应返回错误")
+	}
+	if _, err := dubtorch.LoadIDX(bytes.NewReader([]byte{1, 0, 0x08, 1})); err == nil {
+		t.Fatalf("魔数错误时应返回错误")
+	}
+	// 损坏的头部：元素个数溢出或超过上限时报错，维度很大但数据不足时也不会一次性分配
+	huge := []byte{0, 0, 0x08, 3, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 1, 2, 3}
+	if _, err := dubtorch.LoadIDX(bytes.NewReader(huge)); err == nil {
+		t.Fatalf("元素个数溢出时应返回错误")
+	}
+	large := []byte{0, 0, 0x0E, 2, 0, 0x10, 0, 0, 0, 0, 0x01, 0, 1, 2, 3}
+	if _, err := dubtorch.LoadIDX(bytes.NewReader(large)); err == nil {
+		t.Fatalf("数据不完整时应返回错误")
+	}
+
+	// 按 MNIST 的文件布局写出 gzip 文件并加载
+	dir := t.TempDir()
+	images := dubnp.Zeros(3, 2, 2)
+	for i := range images.Data {
+		images.Data[i] = float64(i * 20)
+	}
+	labels, _ := dubnp.NewArray([]float64{4, 0, 9}, []int{3})
+	imagesPath, labelsPath := filepath.Join(dir, "images.gz"), filepath.Join(dir, "labels")
+	dubug.NoError(t, dubtorch.SaveIDXFile(imagesPath, images, dubtorch.IDXUint8))
+	dubug.NoError(t, dubtorch.SaveIDXFile(labelsPath, labels, dubtorch.IDXUint8))
+	if head, _ := os.ReadFile(imagesPath); head[0] != 0x1f || head[1] != 0x8b {
+		t.Fatalf(".gz 文件应使用 gzip 压缩")
+	}
+	ds, err := dubtorch.NewMNIST(imagesPath, labelsPath)
+	dubug.NoError(t, err)
+	sample, err := ds.Get(2)
+	dubug.NoError(t, err)
+	if ds.Len() != 3 || !dubug.Equal(sample[0].Shape, []int{1, 2, 2}) || sample[1].Data[0] != 9 || !almostEqual(sample[0].Data[3], 220.0/255, 1e-12) {
+		t.Fatalf("MNIST 样本错误: %v %v", sample[0], sample[1])
+	}
+	if _, err := dubtorch.NewMNIST(labelsPath, labelsPath); err == nil {
+		t.Fatalf("图像维度错误时应返回错误")
+	}
+}
+
+// 测试 ImageFolder 的类别编号、PNG/JPEG 解码与归一化
+func TestImageFolder(t *testing.T) {
+	root := t.TempDir()
+	write := func(class, name string, c color.Color, encode func(f *os.File, img image.Image) error) {
+		dubug.NoError(t, os.MkdirAll(filepath.Join(root, class), 0755))
+		img := image.NewRGBA(image.Rect(0, 0, 4, 3))
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 4; x++ {
+				img.Set(x, y, c)
+			}
+		}
+		f, err := os.Create(filepath.Join(root, class, name))
+		dubug.NoError(t, err)
+		dubug.NoError(t, encode(f, img))
+		dubug.NoError(t, f.Close())
+	}
+	encodePNG := func(f *os.File, img image.Image) error { return png.Encode(f, img) }
+	encodeJPEG := func(f *os.File, img image.Image) error { return jpeg.Encode(f, img, &jpeg.Options{Quality: 100}) }
+	write("dog", "a.png", color.RGBA{255, 0, 0, 255}, encodePNG)
+	write("cat", "b.JPG", color.RGBA{0, 0, 255, 255}, encodeJPEG)
+	write("cat", "a.png", color.RGBA{0, 255, 0, 255}, encodePNG)
+	dubug.NoError(t, os.WriteFile(filepath.Join(root, "cat", "notes.txt"), []byte("x"), 0644))
+
+	ds, err := dubtorch.NewImageFolder(root, dubtorch.ImageFolderOptions{})
+	dubug.NoError(t, err)
+	if !dubug.Equal(ds.Classes, []string{"cat", "dog"}) || ds.Len() != 3 {
+		t.Fatalf("类别或样本数错误: %v %d", ds.Classes, ds.Len())
+	}
+	// 样本按类别、文件名排序：cat/a.png、cat/b.JPG、dog/a.png
+	green, err := ds.Get(0)
+	dubug.NoError(t, err)
+	if !dubug.Equal(green[0].Shape, []int{3, 3, 4}) || green[1].Data[0] != 0 || green[0].Data[0] != 0 || green[0].Data[12] != 1 {
+		t.Fatalf("PNG 解码错误: %v", green[0].Data)
+	}
+	blue, err := ds.Get(1)
+	dubug.NoError(t, err)
+	if !almostEqual(blue[0].Data[24], 1, 0.02) || blue[0].Data[0] > 0.02 {
+		t.Fatalf("JPEG 解码错误: %v", blue[0].Data)
+	}
+	red, err := ds.Get(2)
+	dubug.NoError(t, err)
+	if red[1].Data[0] != 1 {
+		t.Fatalf("dog 的标签应为 1: %v", red[1].Data)
+	}
+
+	gray, err := dubtorch.NewImageFolder(root, dubtorch.ImageFolderOptions{Grayscale: true, Mean: []float64{0.5}, Std: []float64{0.5}})
+	dubug.NoError(t, err)
+	sample, err := gray.Get(2)
+	dubug.NoError(t, err)
+	// 纯红色的亮度为 0.299，归一化后为 (0.299-0.5)/0.5
+	if !dubug.Equal(sample[0].Shape, []int{1, 3, 4}) || !almostEqual(sample[0].Data[0], -0.402, 1e-9) {
+		t.Fatalf("灰度归一化错误: %v", sample[0].Data)
+	}
+
+	loader, err := dubtorch.NewDataLoader(ds, dubtorch.DataLoaderOptions{BatchSize: 3})
+	dubug.NoError(t, err)
+	batch, err := loader.Iter().Next()
+	dubug.NoError(t, err)
+	if !dubug.Equal(batch[0].Shape, []int{3, 3, 3, 4}) || !dubug.Equal(batch[1].Data, []float64{0, 0, 1}) {
+		t.Fatalf("ImageFolder 批次错误: %v %v", batch[0].Shape, batch[1].Data)
+	}
+	if _, err := dubtorch.NewImageFolder(root, dubtorch.ImageFolderOptions{Mean: []float64{0.5}, Std: []float64{0.5}}); err == nil {
+		t.Fatalf("Mean 长度与通道数不一致时应返回错误")
+	}
+	if _, err := dubtorch.NewImageFolder(t.TempDir(), dubtorch.ImageFolderOptions{}); err == nil {
+		t.Fatalf("没有图像时应返回错误")
+	}
+}